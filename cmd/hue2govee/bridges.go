@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/rs/zerolog"
+)
+
+// hueClients holds a Hue client per configured bridge, keyed by the bridge name.
+type hueClients map[string]*hue.Client
+
+// newHueClients creates a Hue client for every configured bridge.
+func newHueClients(log zerolog.Logger) (hueClients, error) {
+	bridges, err := config.GetHueBridges()
+	if err != nil {
+		return nil, err
+	}
+
+	clients := make(hueClients, len(bridges))
+	for _, bridge := range bridges {
+		hueClient, err := newHueClient(log, bridge)
+		if err != nil {
+			return nil, fmt.Errorf("hue bridge %s: %w", bridge.Name, err)
+		}
+		clients[bridge.Name] = hueClient
+	}
+	return clients, nil
+}
+
+// names returns the names of the bridges in alphabetical order.
+func (c hueClients) names() []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// startAutoDiscovery starts the auto-discovery of every bridge, failing if any bridge can't be discovered.
+func (c hueClients) startAutoDiscovery(ctx context.Context) error {
+	for _, name := range c.names() {
+		if err := c[name].StartAutoDiscovery(ctx); err != nil {
+			return fmt.Errorf("hue bridge %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// rediscover rediscovers every bridge, returning the errors of all bridges that failed.
+func (c hueClients) rediscover(ctx context.Context) error {
+	var errs []error
+	for _, name := range c.names() {
+		if err := c[name].Rediscover(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("hue bridge %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BridgeAddress implements health.Bridge, returning the addresses of all bridges, or an empty string as long
+// as any bridge wasn't discovered yet.
+func (c hueClients) BridgeAddress() string {
+	addresses := make([]string, 0, len(c))
+	for _, name := range c.names() {
+		address := c[name].BridgeAddress()
+		if address == "" {
+			return ""
+		}
+		addresses = append(addresses, address)
+	}
+	return strings.Join(addresses, ", ")
+}
+
+// lookupHueBridge returns the configured bridge with the given name, or the first configured bridge if the name
+// is empty.
+func lookupHueBridge(name string) (config.HueBridge, error) {
+	bridges, err := config.GetHueBridges()
+	if err != nil {
+		return config.HueBridge{}, err
+	}
+	if name == "" {
+		return bridges[0], nil
+	}
+	for _, bridge := range bridges {
+		if bridge.Name == name {
+			return bridge, nil
+		}
+	}
+	return config.HueBridge{}, fmt.Errorf("no Hue bridge named %q", name)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/rs/zerolog"
+)
+
+// newCountingHueClient creates a Hue client of a test bridge serving a light, counting the requests it receives.
+func newCountingHueClient(t *testing.T, requests *atomic.Int32) *hue.Client {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"errors":[],"data":[{"id":"light-1","on":{"on":true}}]}`))
+	}))
+	t.Cleanup(server.Close)
+	client := hue.NewClient("", "test-user", zerolog.Nop())
+	if err := client.SetBridgeAddress(strings.TrimPrefix(server.URL, "https://")); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestSyncRunnerRoutesToBridge(t *testing.T) {
+	loadTestConfig(t, "hue_bridges:\n  - name: upstairs\n  - name: downstairs\n  - name: attic\n"+
+		"synchronizations:\n"+
+		"  - name: desk\n    hue_bridge: downstairs\n    hue_light_id: light-1\n    govee_device_id: "+
+		testGoveeDeviceID+"\n"+
+		"  - name: shelf\n    hue_bridge: attic\n    hue_light_id: light-1\n    govee_device_id: AA:BB:CC:DD:EE:FF:00:22\n")
+	synchronizations, err := config.GetSynchronizations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var upstairs, downstairs atomic.Int32
+	runner := newTestSyncRunner(t)
+	// the attic bridge was added to the config after startup, so there's no client for it
+	runner.hueClients = hueClients{
+		"upstairs":   newCountingHueClient(t, &upstairs),
+		"downstairs": newCountingHueClient(t, &downstairs),
+	}
+	runner.apply(synchronizations, nil)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for downstairs.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the light wasn't requested from the bridge of the synchronization")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := upstairs.Load(); got != 0 {
+		t.Errorf("other bridge received %d requests, want none", got)
+	}
+	syncs := runningSyncs(runner)
+	if s := syncs["desk"]; s == nil || s.hueClient != runner.hueClients["downstairs"] {
+		t.Error("synchronizer of desk doesn't use the client of its bridge")
+	}
+	if _, ok := syncs["shelf"]; ok {
+		t.Error("synchronization of a bridge without client was started")
+	}
+}
+
+func TestLookupHueBridge(t *testing.T) {
+	loadTestConfig(t, "hue_bridges:\n  - name: upstairs\n    username: user-1\n"+
+		"  - name: downstairs\n    username: user-2\n")
+
+	tests := []struct {
+		name     string
+		wantErr  bool
+		wantUser string
+	}{
+		{name: "", wantUser: "user-1"},
+		{name: "downstairs", wantUser: "user-2"},
+		{name: "attic", wantErr: true},
+	}
+	for _, tt := range tests {
+		bridge, err := lookupHueBridge(tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("lookupHueBridge(%q) = %+v, want an error", tt.name, bridge)
+			}
+			continue
+		}
+		if err != nil || bridge.Username != tt.wantUser {
+			t.Errorf("lookupHueBridge(%q) = %+v, %v, want the bridge of %s", tt.name, bridge, err, tt.wantUser)
+		}
+	}
+}
+
+func TestHueClientsBridgeAddress(t *testing.T) {
+	upstairs := hue.NewClient("", "test-user", zerolog.Nop())
+	downstairs := hue.NewClient("", "test-user", zerolog.Nop())
+	clients := hueClients{"upstairs": upstairs, "downstairs": downstairs}
+	if err := upstairs.SetBridgeAddress("192.168.1.20"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the bridges are only reported as discovered once all of them are
+	if got := clients.BridgeAddress(); got != "" {
+		t.Errorf("BridgeAddress() with an undiscovered bridge = %q, want none", got)
+	}
+	if err := downstairs.SetBridgeAddress("192.168.1.30"); err != nil {
+		t.Fatal(err)
+	}
+	if got := clients.BridgeAddress(); got != "192.168.1.30, 192.168.1.20" {
+		t.Errorf("BridgeAddress() = %q, want the addresses of both bridges sorted by name", got)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+)
+
+// debounceColorDistance is the largest RGB distance between two colors that still counts as no change while
+// debouncing, so conversion jitter doesn't restart the debounce
+const debounceColorDistance = 6.0
+
+// debouncer holds back changes of the Hue light until they have been stable for a while, so the intermediate
+// colors of a Hue transition aren't forwarded one by one.
+type debouncer struct {
+	candidate    *govee.State // the latest differing state, nil if nothing is held back
+	stableSince  time.Time    // when the candidate was first seen
+	pendingSince time.Time    // when the first held back change was seen
+}
+
+// Ready returns whether target should be forwarded now. Changes are forwarded once they have been stable for
+// the given duration, or once changes have been held back for maxHold so continuous changes still get through.
+// A maxHold of zero or less never forces a change through.
+func (d *debouncer) Ready(target govee.State, lastSent *govee.State, now time.Time, stable, maxHold time.Duration) bool {
+	if stable <= 0 || lastSent == nil || statesClose(target, *lastSent) {
+		d.Reset()
+		return true
+	}
+
+	if d.candidate == nil {
+		d.pendingSince = now
+	}
+	if d.candidate == nil || !statesClose(target, *d.candidate) {
+		d.candidate = &target
+		d.stableSince = now
+	}
+
+	if now.Sub(d.stableSince) >= stable || (maxHold > 0 && now.Sub(d.pendingSince) >= maxHold) {
+		d.Reset()
+		return true
+	}
+	return false
+}
+
+// Pending returns whether a change is held back.
+func (d *debouncer) Pending() bool {
+	return d.candidate != nil
+}
+
+// Reset forgets the change held back.
+func (d *debouncer) Reset() {
+	d.candidate = nil
+}
+
+// statesClose returns whether two states are close enough to count as the same state while debouncing.
+func statesClose(a, b govee.State) bool {
+	return colorDistance(a.Color, b.Color) <= debounceColorDistance && abs(a.Brightness-b.Brightness) <= 1
+}
+
+// colorChanged returns whether the Euclidean distance between two colors exceeds threshold.
+func colorChanged(prev, next govee.RGBColor, threshold int) bool {
+	return colorDistance(prev, next) > float64(threshold)
+}
+
+// colorDistance returns the Euclidean distance between two colors in RGB space.
+func colorDistance(a, b govee.RGBColor) float64 {
+	dr, dg, db := float64(a.R-b.R), float64(a.G-b.G), float64(a.B-b.B)
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
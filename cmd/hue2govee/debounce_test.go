@@ -0,0 +1,160 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+)
+
+func TestColorChanged(t *testing.T) {
+	base := govee.RGBColor{R: 100, G: 100, B: 100}
+
+	tests := []struct {
+		name      string
+		next      govee.RGBColor
+		threshold int
+		want      bool
+	}{
+		{name: "identical, zero threshold", next: base, threshold: 0, want: false},
+		{name: "one step, zero threshold", next: govee.RGBColor{R: 101, G: 100, B: 100}, threshold: 0, want: true},
+		// distance 10 = sqrt(6² + 8²)
+		{name: "exactly at threshold", next: govee.RGBColor{R: 106, G: 108, B: 100}, threshold: 10, want: false},
+		{name: "exactly at threshold, negative deltas", next: govee.RGBColor{R: 94, G: 92, B: 100}, threshold: 10,
+			want: false},
+		// distance sqrt(99) ≈ 9.95 = sqrt(7² + 7² + 1²)
+		{name: "just below threshold", next: govee.RGBColor{R: 107, G: 107, B: 101}, threshold: 10, want: false},
+		// distance sqrt(101) ≈ 10.05 = sqrt(10² + 1²)
+		{name: "just above threshold", next: govee.RGBColor{R: 110, G: 101, B: 100}, threshold: 10, want: true},
+		// distance 5 = sqrt(3² + 4²), per channel below the threshold but not in sum
+		{name: "channels below threshold", next: govee.RGBColor{R: 103, G: 104, B: 100}, threshold: 4, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := colorChanged(base, tt.next, tt.threshold); got != tt.want {
+				t.Errorf("colorChanged(%v, %v, %d) = %v, want %v (distance %.3f)", base, tt.next, tt.threshold, got,
+					tt.want, colorDistance(base, tt.next))
+			}
+		})
+	}
+}
+
+func TestDebouncer(t *testing.T) {
+	const (
+		stable  = 300 * time.Millisecond
+		maxHold = time.Second
+	)
+	state := func(r int) govee.State {
+		return govee.State{Color: govee.RGBColor{R: r}, Brightness: 50}
+	}
+	lastSent := state(0)
+
+	type sample struct {
+		at        time.Duration // time of the sample since the first one
+		target    govee.State
+		wantReady bool
+	}
+	tests := []struct {
+		name    string
+		samples []sample
+		// wantPending is whether a change is held back after the last sample
+		wantPending bool
+	}{
+		{
+			name:    "unchanged state",
+			samples: []sample{{at: 0, target: lastSent, wantReady: true}},
+		},
+		{
+			name: "jitter counts as unchanged",
+			// distance 5, within debounceColorDistance
+			samples: []sample{{at: 0, target: govee.State{Color: govee.RGBColor{R: 3, G: 4}, Brightness: 51},
+				wantReady: true}},
+		},
+		{
+			name: "change forwarded once stable",
+			samples: []sample{
+				{at: 0, target: state(100)},
+				{at: 100 * time.Millisecond, target: state(102)},
+				{at: 299 * time.Millisecond, target: state(100)},
+				{at: 300 * time.Millisecond, target: state(100), wantReady: true},
+			},
+		},
+		{
+			name: "held back until stable",
+			samples: []sample{
+				{at: 0, target: state(100)},
+				{at: 200 * time.Millisecond, target: state(100)},
+			},
+			wantPending: true,
+		},
+		{
+			name: "a new change restarts the stable window",
+			samples: []sample{
+				{at: 0, target: state(100)},
+				{at: 200 * time.Millisecond, target: state(150)},
+				{at: 400 * time.Millisecond, target: state(150)},
+				{at: 500 * time.Millisecond, target: state(150), wantReady: true},
+			},
+		},
+		{
+			name: "continuous changes are forced through after max hold",
+			samples: []sample{
+				{at: 0, target: state(20)},
+				{at: 250 * time.Millisecond, target: state(40)},
+				{at: 500 * time.Millisecond, target: state(60)},
+				{at: 750 * time.Millisecond, target: state(80)},
+				{at: time.Second, target: state(100), wantReady: true},
+				// the max hold starts over with the next change
+				{at: 1250 * time.Millisecond, target: state(120)},
+			},
+			wantPending: true,
+		},
+		{
+			name: "returning to the last sent state drops the held back change",
+			samples: []sample{
+				{at: 0, target: state(100)},
+				{at: 100 * time.Millisecond, target: lastSent, wantReady: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d debouncer
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, s := range tt.samples {
+				if got := d.Ready(s.target, &lastSent, start.Add(s.at), stable, maxHold); got != s.wantReady {
+					t.Errorf("Ready() of sample %d at %s = %v, want %v", i, s.at, got, s.wantReady)
+				}
+			}
+			if got := d.Pending(); got != tt.wantPending {
+				t.Errorf("Pending() = %v, want %v", got, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestDebouncerDisabled(t *testing.T) {
+	var d debouncer
+	lastSent := govee.State{Brightness: 50}
+	target := govee.State{Color: govee.RGBColor{R: 255}, Brightness: 50}
+	now := time.Now()
+
+	if !d.Ready(target, &lastSent, now, 0, time.Second) {
+		t.Error("Ready() without a stable window = false, want changes forwarded right away")
+	}
+	if !d.Ready(target, nil, now, time.Second, time.Second) {
+		t.Error("Ready() before the first state was sent = false, want it forwarded right away")
+	}
+
+	// without max hold, continuous changes are held back indefinitely
+	for i := 1; i <= 20; i++ {
+		next := govee.State{Color: govee.RGBColor{R: 10 * i}, Brightness: 50}
+		if d.Ready(next, &lastSent, now.Add(time.Duration(i)*200*time.Millisecond), 300*time.Millisecond, 0) {
+			t.Fatalf("Ready() of change %d without max hold = true, want it held back", i)
+		}
+	}
+	d.Reset()
+	if d.Pending() {
+		t.Error("Pending() after Reset() = true, want the held back change forgotten")
+	}
+}
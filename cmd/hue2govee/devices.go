@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+)
+
+// runDevices runs Govee discovery for a few seconds and prints the discovered devices.
+//
+// Usage: hue2govee devices [--json]
+func runDevices(log zerolog.Logger, args []string) error {
+	flags := pflag.NewFlagSet("devices", pflag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the devices as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	devices, err := discoverGoveeDevices(context.Background(), log)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if devices == nil {
+			devices = []govee.DeviceInfo{}
+		}
+		return json.NewEncoder(os.Stdout).Encode(devices)
+	}
+	return writeDeviceTable(os.Stdout, devices)
+}
+
+// writeDeviceTable writes the devices as a table with one device per line
+func writeDeviceTable(w io.Writer, devices []govee.DeviceInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DEVICE ID\tIP\tMODEL\tWIFI FIRMWARE\tBLE FIRMWARE")
+	for _, device := range devices {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", device.ID, device.IP, orDash(device.SKU),
+			firmwareVersion(device.WiFiVersionHard, device.WiFiVersionSoft),
+			firmwareVersion(device.BLEVersionHard, device.BLEVersionSoft))
+	}
+	return tw.Flush()
+}
+
+// firmwareVersion formats the hardware and software version of a device module, "-" if both are unknown
+func firmwareVersion(hard, soft string) string {
+	if hard == "" && soft == "" {
+		return "-"
+	}
+	return fmt.Sprintf("%s/%s", orDash(hard), orDash(soft))
+}
+
+// orDash returns s, or "-" if s is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+)
+
+func TestWriteDeviceTable(t *testing.T) {
+	devices := []govee.DeviceInfo{
+		{ID: "AA:BB:CC:DD:EE:FF:00:11", IP: "192.168.1.20", SKU: "H6199", WiFiVersionHard: "1.00.10",
+			WiFiVersionSoft: "1.02.03", BLEVersionSoft: "1.03.01"},
+		{ID: "11:22:33:44:55:66:77:88", IP: "192.168.1.21"},
+	}
+	var out bytes.Buffer
+	if err := writeDeviceTable(&out, devices); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := [][]string{
+		{"DEVICE", "ID", "IP", "MODEL", "WIFI", "FIRMWARE", "BLE", "FIRMWARE"},
+		{"AA:BB:CC:DD:EE:FF:00:11", "192.168.1.20", "H6199", "1.00.10/1.02.03", "-/1.03.01"},
+		{"11:22:33:44:55:66:77:88", "192.168.1.21", "-", "-", "-"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("device table has %d lines, want %d:\n%s", len(lines), len(want), out.String())
+	}
+	for i, line := range lines {
+		if got := strings.Fields(line); strings.Join(got, " ") != strings.Join(want[i], " ") {
+			t.Errorf("line %d = %q, want the columns %q", i, line, want[i])
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+)
+
+// runLights discovers the Hue bridge and prints its lights with the rooms they belong to.
+//
+// Usage: hue2govee lights [--bridge <name>]
+func runLights(log zerolog.Logger, args []string) error {
+	flags := pflag.NewFlagSet("lights", pflag.ContinueOnError)
+	bridgeName := flags.String("bridge", "", "name of the Hue bridge in hue_bridges, the first bridge if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bridge, err := lookupHueBridge(*bridgeName)
+	if err != nil {
+		return err
+	}
+	hueClient, err := newHueClient(log, bridge)
+	if err != nil {
+		return err
+	}
+	if err := hueClient.StartAutoDiscovery(ctx); err != nil {
+		return fmt.Errorf("failed to discover Hue bridge: %w", err)
+	}
+	lights, err := hueClient.GetLights(ctx)
+	if err != nil {
+		return err
+	}
+	rooms, err := hueClient.GetRooms(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writeLightTable(os.Stdout, lights, rooms)
+}
+
+// writeLightTable writes the lights as a table with one light per line, sorted by name
+func writeLightTable(w io.Writer, lights []hue.Light, rooms []hue.Room) error {
+	sort.Slice(lights, func(i, j int) bool {
+		return lights[i].Metadata.Name < lights[j].Metadata.Name
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LIGHT ID\tNAME\tROOM ID\tROOM")
+	for _, light := range lights {
+		roomID, roomName := "-", "-"
+		if room := lightRoom(light, rooms); room != nil {
+			roomID, roomName = room.ID, room.Metadata.Name
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", light.ID, light.Metadata.Name, roomID, roomName)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+)
+
+func TestWriteLightTable(t *testing.T) {
+	lights := []hue.Light{
+		{ID: "light-2", Owner: hue.Group{ID: "device-2"}, Metadata: hue.Metadata{Name: "Shelf"}},
+		{ID: "light-1", Owner: hue.Group{ID: "device-1"}, Metadata: hue.Metadata{Name: "Desk"}},
+	}
+	rooms := []hue.Room{{ID: "room-1", Metadata: hue.Metadata{Name: "Office"},
+		Children: []hue.Group{{ID: "device-1", Type: "device"}}}}
+
+	var out bytes.Buffer
+	if err := writeLightTable(&out, lights, rooms); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lights are sorted by name, lights without a room have dashes
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{"LIGHT ID NAME ROOM ID ROOM", "light-1 Desk room-1 Office", "light-2 Shelf - -"}
+	if len(lines) != len(want) {
+		t.Fatalf("light table has %d lines, want %d:\n%s", len(lines), len(want), out.String())
+	}
+	for i, line := range lines {
+		if got := strings.Join(strings.Fields(line), " "); got != want[i] {
+			t.Errorf("line %d = %q, want the columns %q", i, line, want[i])
+		}
+	}
+}
@@ -2,145 +2,346 @@ package main
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"github.com/cedrickring/hue-to-govee/internal/api"
 	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/control"
 	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/health"
 	"github.com/cedrickring/hue-to-govee/internal/hue"
 	"github.com/cedrickring/hue-to-govee/internal/logger"
+	"github.com/cedrickring/hue-to-govee/internal/metrics"
+	"github.com/cedrickring/hue-to-govee/internal/mqtt"
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 import "github.com/rs/zerolog"
 
 func main() {
-	config.MustLoad()
+	flags := pflag.NewFlagSet("hue2govee", pflag.ExitOnError)
+	flags.SetInterspersed(false) // flags after the subcommand belong to the subcommand
+	configFile := flags.String("config", config.DefaultFile, "path to the config file")
+	config.RegisterFlags(flags)
+	_ = flags.Parse(os.Args[1:])
+
+	config.MustLoad(*configFile, flags)
 	log := logger.Default()
 
-	log.Info().Msg("Starting Hue to Govee bridge")
+	if args := flags.Args(); len(args) > 0 {
+		if err := runCommand(log, args[0], args[1:]); err != nil {
+			log.Error().Err(err).Msgf("Command %s failed", args[0])
+			os.Exit(1)
+		}
+		return
+	}
 
-	hueBridgeID := viper.GetString("hue_bridge_id")
-	hueUsername := viper.GetString("hue_bridge_username")
+	log.Info().Msg("Starting Hue to Govee bridge")
 
-	hueClient := hue.NewClient(hueBridgeID, hueUsername, log.With().Str("component", "hue").Logger())
+	hueClients, err := newHueClients(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid Hue bridge config")
+		return
+	}
+	requestsPerSecond := viper.GetFloat64("hue_requests_per_second")
+	if requestsPerSecond <= 0 {
+		log.Error().Msg("Hue requests per second must be positive")
+		return
+	}
+	maxConcurrent := viper.GetInt("hue_max_concurrent")
+	if maxConcurrent <= 0 {
+		log.Error().Msg("Hue max concurrent requests must be positive")
+		return
+	}
+	sceneCacheTTL := viper.GetDuration("hue_scene_cache_ttl")
+	if sceneCacheTTL < 0 {
+		log.Error().Msg("Hue scene cache TTL must not be negative")
+		return
+	}
+	requestTimeout := viper.GetDuration("hue_request_timeout")
+	if requestTimeout <= 0 {
+		log.Error().Msg("Hue request timeout must be positive")
+		return
+	}
+	discoveryTimeout := viper.GetDuration("hue_discovery_timeout")
+	if discoveryTimeout <= 0 {
+		log.Error().Msg("Hue discovery timeout must be positive")
+		return
+	}
+	// the limits apply per bridge, as every bridge handles its own requests
+	for _, hueClient := range hueClients {
+		hueClient.SetRequestsPerSecond(requestsPerSecond)
+		hueClient.SetMaxConcurrentRequests(maxConcurrent)
+		hueClient.SetSceneCacheTTL(sceneCacheTTL)
+		hueClient.SetRequestTimeout(requestTimeout)
+		hueClient.SetDiscoveryTimeout(discoveryTimeout)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	catchCtrlC(cancel)
 
-	if err := hueClient.StartAutoDiscovery(ctx); err != nil {
+	if addr := viper.GetString("metrics_addr"); addr != "" {
+		go metrics.Serve(ctx, addr, logger.Component(log, "metrics"))
+	}
+
+	if err := hueClients.startAutoDiscovery(ctx); err != nil {
 		log.Error().Err(err).Msg("Failed to start Hue auto-discovery")
 		return
 	}
 
-	goveeClient := govee.NewClient(log, viper.GetString("govee_multicast_ip"))
+	if err := checkConfiguredLights(ctx, log, hueClients); err != nil {
+		log.Error().Err(err).Msg("Invalid Hue lights in config")
+		return
+	}
+
+	goveeClient, err := newGoveeClient(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid Govee config")
+		return
+	}
+	if err := applyCapabilityOverrides(goveeClient); err != nil {
+		log.Error().Err(err).Msg("Failed to load Govee device capabilities from config")
+		return
+	}
+	if err := applyDeviceSettings(goveeClient); err != nil {
+		log.Error().Err(err).Msg("Failed to load Govee device settings from config")
+		return
+	}
+	if addr := viper.GetString("health_addr"); addr != "" {
+		go health.Serve(ctx, addr, hueClients, goveeClient, logger.Component(log, "health"))
+	}
 	if err := goveeClient.Discover(ctx); err != nil {
 		log.Error().Err(err).Msg("Failed to discover Govee devices")
 		return
 	}
 	log.Info().Msg("Discovering Govee devices")
+	if err := checkConfiguredDevices(ctx, log, goveeClient); err != nil {
+		log.Error().Err(err).Msg("Invalid Govee devices in config")
+		return
+	}
+
+	brightnessCap, err := newBrightnessCap(log, goveeClient)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid brightness cap config")
+		return
+	}
+	brightnessCap.toggleNightModeOnSignal(ctx)
+
+	conflictPolicy, err := hue.ParseConflictPolicy(viper.GetString("scene_conflict_policy"))
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid scene conflict policy in config")
+		return
+	}
+
+	sceneController := hue.NewSceneController(goveeClient, logger.Component(log, "sceneController"))
+	if err := startStaticDevices(ctx, log, goveeClient, sceneController, conflictPolicy); err != nil {
+		return
+	}
+
+	store := state.NewStore()
+	if path := viper.GetString("state_file"); path != "" {
+		go state.WriteFilePeriodically(ctx, log, store, path, viper.GetDuration("state_file_interval"))
+	}
+	if broker := viper.GetString("mqtt_broker"); broker != "" {
+		go mqtt.Serve(ctx, mqtt.Options{
+			Broker:      broker,
+			Username:    viper.GetString("mqtt_username"),
+			Password:    viper.GetString("mqtt_password"),
+			TopicPrefix: viper.GetString("mqtt_topic_prefix"),
+		}, store, logger.Component(log, "mqtt"))
+	}
 
-	sceneController := hue.NewSceneController(goveeClient, log)
-	if err := startSynchronization(ctx, log, hueClient, goveeClient, sceneController); err != nil {
+	runner, err := startSynchronization(ctx, log, hueClients, goveeClient, sceneController, store)
+	if err != nil {
 		return
 	}
+	reloadSynchronizationsOnChange(ctx, log, runner)
+
+	controller := &bridgeController{
+		ctx:         ctx,
+		registry:    runner.registry,
+		store:       store,
+		hueClients:  hueClients,
+		goveeClient: goveeClient,
+		sc:          sceneController,
+		policy:      conflictPolicy,
+	}
+	if path := viper.GetString("control_socket"); path != "" {
+		server := control.NewServer(path, controller, logger.Component(log, "control"))
+		if err := server.Start(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to start control socket")
+			return
+		}
+	}
+	if addr := viper.GetString("api_addr"); addr != "" {
+		go api.Serve(ctx, addr, controller, store, viper.GetStringSlice("api_allowed_origins"),
+			logger.Component(log, "api"))
+	}
 
 	<-ctx.Done()
 
 	log.Info().Msg("Shutting down Hue to Govee bridge")
+	shutdownDevices(log, goveeClient, runner)
+	goveeClient.Close()
+}
+
+// runCommand runs the subcommand with the given name and arguments.
+func runCommand(log zerolog.Logger, name string, args []string) error {
+	switch name {
+	case "raw":
+		return runRaw(log, args)
+	case "suggest-config":
+		return runSuggestConfig(log, args)
+	case "pair":
+		return runPair(log, args)
+	case "devices":
+		return runDevices(log, args)
+	case "lights":
+		return runLights(log, args)
+	case "test-color":
+		return runTestColor(log, args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// newHueClient creates a Hue client for the given bridge, authenticating with the username of the bridge.
+func newHueClient(log zerolog.Logger, bridge config.HueBridge) (*hue.Client, error) {
+	if err := hue.ValidateBridgeID(bridge.ID); err != nil {
+		return nil, err
+	}
+	hueLog := logger.Component(log, "hue").With().Str("bridge", bridge.Name).Logger()
+	hueClient := hue.NewClient(bridge.ID, bridge.Username, hueLog)
+	hueClient.SetCloudDiscovery(viper.GetBool("hue_allow_cloud_discovery"))
+	hueClient.SetIPv6Discovery(viper.GetBool("hue_enable_ipv6"))
+	if viper.GetBool("hue_insecure_tls") {
+		hueLog.Warn().Msg("Not verifying the Hue bridge certificate, as configured by hue_insecure_tls")
+	} else if bridge.ID == "" {
+		hueLog.Warn().Msg("Not verifying the Hue bridge certificate without a bridge ID")
+	} else {
+		roots, err := loadHueRootCAs(viper.GetString("hue_root_ca_file"))
+		if err != nil {
+			return nil, err
+		}
+		if roots == nil {
+			hueLog.Warn().Msg("Only verifying the common name of the Hue bridge certificate without hue_root_ca_file")
+		}
+		if err := hueClient.VerifyCertificate(roots); err != nil {
+			return nil, fmt.Errorf("bridge %s: %w, or set hue_insecure_tls to skip the verification", bridge.Name, err)
+		}
+	}
+	if bridge.Address != "" {
+		if err := hueClient.SetBridgeAddress(bridge.Address); err != nil {
+			return nil, err
+		}
+	}
+	return hueClient, nil
+}
+
+// loadHueRootCAs loads the root CAs the Hue bridge certificate must be signed by from a PEM file, nil if path is
+// empty.
+func loadHueRootCAs(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading hue_root_ca_file: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("hue_root_ca_file %s doesn't contain a PEM encoded certificate", path)
+	}
+	return roots, nil
+}
+
+// newGoveeClient creates a Govee client discovering devices on the configured network interfaces and IP versions.
+func newGoveeClient(log zerolog.Logger) (*govee.Client, error) {
+	ipVersion, err := govee.ParseIPVersion(viper.GetString("govee_ip_version"))
+	if err != nil {
+		return nil, err
+	}
+
+	goveeClient := govee.NewClient(logger.Component(log, "govee"), viper.GetString("govee_multicast_ip"))
+	goveeClient.SetInterfaces(viper.GetStringSlice("govee_interfaces"))
+	goveeClient.SetIPVersion(ipVersion)
+	return goveeClient, nil
+}
+
+// applyCapabilityOverrides applies the device capabilities declared in the config to the Govee client.
+func applyCapabilityOverrides(goveeClient *govee.Client) error {
+	deviceCapabilities, err := config.GetDeviceCapabilities()
+	if err != nil {
+		return err
+	}
+
+	overrides := make(map[string]govee.Capabilities, len(deviceCapabilities))
+	for _, c := range deviceCapabilities {
+		overrides[c.GoveeDeviceId] = govee.Capabilities{
+			OnOff:            c.OnOff,
+			Brightness:       c.Brightness,
+			Color:            c.Color,
+			ColorTemperature: c.ColorTemperature,
+			Segments:         c.Segments,
+		}
+	}
+	goveeClient.SetCapabilityOverrides(overrides)
+	return nil
 }
 
-func startSynchronization(ctx context.Context, logger zerolog.Logger, hueClient *hue.Client, goveeClient *govee.Client, sc *hue.SceneController) error {
-	synchronizations, err := config.GetSynchronizations()
+// applyDeviceSettings applies the settings of the govee_devices config section to the Govee client.
+func applyDeviceSettings(goveeClient *govee.Client) error {
+	devices, err := config.GetGoveeDevices()
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to load synchronizations from config")
-		return fmt.Errorf("failed to load synchronizations: %w", err)
-	}
-
-	for _, sync := range synchronizations {
-		syncCopy := sync
-		logger.Info().Msgf("Synchronizing Hue light %s <--> Govee device %s", syncCopy.HueLightId,
-			syncCopy.GoveeDeviceId)
-
-		go func() {
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(500 * time.Millisecond):
-					light, err := hueClient.GetLight(syncCopy.HueLightId)
-					if err != nil {
-						logger.Error().Err(err).Str("lightId", syncCopy.HueLightId).Msg("Failed to get Hue light")
-						continue
-					}
-					if light.On.On {
-						if light.Dynamics.Status == hue.DynamicsStatusActive {
-							if sc.IsActive(syncCopy.GoveeDeviceId) {
-								logger.Debug().Str("deviceId", syncCopy.GoveeDeviceId).
-									Msg("Skipping Govee sync due to active scene")
-								continue
-							}
-
-							scene, err := hueClient.GetActiveScene(syncCopy.HueRoomId)
-							if err != nil {
-								logger.Error().Err(err).Str("roomId", syncCopy.HueRoomId).
-									Msg("Failed to get active scene for Hue room")
-								continue
-							}
-
-							if scene == nil {
-								logger.Warn().Str("roomId", syncCopy.HueRoomId).
-									Msg("No active scene found for Hue room")
-								continue
-							}
-
-							sc.SetScene(syncCopy.GoveeDeviceId, *scene)
-							continue
-						} else {
-							if sc.IsActive(syncCopy.GoveeDeviceId) {
-								sc.StopScene(syncCopy.GoveeDeviceId)
-								logger.Info().Str("goveeDeviceId", syncCopy.GoveeDeviceId).
-									Msgf("Stopped dynamic scene for Govee device %s", syncCopy.GoveeDeviceId)
-							}
-						}
-
-						r, g, b := hue.ColorToRGB(light, sync.FixedBrightness)
-						bri := int(float64(light.Dimming.Brightness) / 254.0 * 100)
-						if sync.FixedBrightness != nil {
-							bri = *sync.FixedBrightness
-						}
-
-						if err := goveeClient.SetColor(syncCopy.GoveeDeviceId, r, g, b); err != nil {
-							if govee.IsDeviceNotFound(err) {
-								continue
-							}
-							logger.Error().Err(err).Str("deviceId",
-								syncCopy.GoveeDeviceId).Msg("Failed to set Govee color")
-						}
-						if err := goveeClient.SetBrightness(syncCopy.GoveeDeviceId, bri); err != nil {
-							if govee.IsDeviceNotFound(err) {
-								continue
-							}
-
-							logger.Error().Err(err).Str("deviceId",
-								syncCopy.GoveeDeviceId).Msg("Failed to set Govee brightness")
-						}
-					} else {
-						if err := goveeClient.TurnOff(syncCopy.GoveeDeviceId); err != nil {
-							if govee.IsDeviceNotFound(err) {
-								continue
-							}
-							logger.Error().Err(err).Str("deviceId",
-								syncCopy.GoveeDeviceId).Msg("Failed to turn off Govee device")
-						}
-					}
-				}
-			}
-		}()
+		return err
+	}
+
+	retries := viper.GetInt("govee_command_retries")
+	defaults := govee.DeviceSettings{
+		CommandTimeout:   viper.GetDuration("govee_command_timeout"),
+		FailureThreshold: viper.GetInt("govee_failure_threshold"),
+		Retries:          &retries,
+		RetryDelay:       viper.GetDuration("govee_command_retry_delay"),
+	}
+	if defaults.CommandTimeout <= 0 || defaults.FailureThreshold <= 0 {
+		return fmt.Errorf("govee command timeout and failure threshold must be positive")
+	}
+	if retries < 0 || defaults.RetryDelay < 0 {
+		return fmt.Errorf("govee command retries and retry delay must not be negative")
 	}
 
+	settings := make(map[string]govee.DeviceSettings, len(devices))
+	for _, device := range devices {
+		deviceSettings := govee.DeviceSettings{
+			ColorTolerance:   device.ColorTolerance,
+			CommandTimeout:   device.CommandTimeout,
+			FailureThreshold: device.FailureThreshold,
+			Retries:          device.CommandRetries,
+		}
+		if device.ColorCorrection != nil {
+			correction := device.ColorCorrection.Correction()
+			deviceSettings.ColorCorrection = &correction
+		}
+		settings[device.GoveeDeviceId] = deviceSettings
+	}
+	goveeClient.SetDeviceSettings(defaults, settings)
+
+	ttl := viper.GetDuration("govee_device_ttl")
+	if ttl <= 0 {
+		return fmt.Errorf("govee device ttl must be positive")
+	}
+	goveeClient.SetDeviceTTL(ttl)
+
+	scanInterval := viper.GetDuration("govee_scan_interval")
+	if scanInterval <= 0 || scanInterval >= ttl {
+		return fmt.Errorf("govee scan interval must be positive and shorter than the device ttl")
+	}
+	goveeClient.SetScanInterval(scanInterval)
 	return nil
 }
 
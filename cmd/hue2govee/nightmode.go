@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// brightnessCap applies the global max_brightness and the night mode cap to all Govee devices.
+type brightnessCap struct {
+	maxBrightness       int
+	nightModeBrightness int
+	nightMode           atomic.Bool
+
+	logger      zerolog.Logger
+	goveeClient *govee.Client
+}
+
+// newBrightnessCap creates a brightnessCap from the config.
+func newBrightnessCap(logger zerolog.Logger, goveeClient *govee.Client) (*brightnessCap, error) {
+	maxBrightness := viper.GetInt("max_brightness")
+	if maxBrightness < 0 || maxBrightness > 100 {
+		return nil, fmt.Errorf("max brightness out of range, must be between 0 and 100")
+	}
+	nightModeBrightness := viper.GetInt("night_mode_brightness")
+	if nightModeBrightness < 1 || nightModeBrightness > 100 {
+		return nil, fmt.Errorf("night mode brightness out of range, must be between 1 and 100")
+	}
+
+	bc := &brightnessCap{
+		maxBrightness:       maxBrightness,
+		nightModeBrightness: nightModeBrightness,
+		logger:              logger,
+		goveeClient:         goveeClient,
+	}
+	bc.nightMode.Store(viper.GetBool("night_mode"))
+	bc.apply()
+	return bc, nil
+}
+
+// SetNightMode enables or disables night mode.
+func (bc *brightnessCap) SetNightMode(enabled bool) {
+	bc.nightMode.Store(enabled)
+	bc.apply()
+}
+
+// NightMode returns whether night mode is enabled.
+func (bc *brightnessCap) NightMode() bool {
+	return bc.nightMode.Load()
+}
+
+// effective returns the brightness cap in percent, the lower of max_brightness and the night mode cap while
+// night mode is enabled. Zero means no cap.
+func (bc *brightnessCap) effective() int {
+	effective := bc.maxBrightness
+	if bc.nightMode.Load() && (effective == 0 || bc.nightModeBrightness < effective) {
+		effective = bc.nightModeBrightness
+	}
+	return effective
+}
+
+// apply pushes the effective brightness cap to the Govee client.
+func (bc *brightnessCap) apply() {
+	effective := bc.effective()
+	bc.goveeClient.SetMaxBrightness(effective)
+	bc.logger.Info().Bool("nightMode", bc.nightMode.Load()).Int("maxBrightness", effective).
+		Msg("Applied brightness cap")
+}
+
+// toggleNightModeOnSignal toggles night mode whenever one of the night mode signals is received.
+func (bc *brightnessCap) toggleNightModeOnSignal(ctx context.Context) {
+	if len(nightModeSignals) == 0 {
+		return
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, nightModeSignals...)
+	go func() {
+		defer signal.Stop(c)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c:
+				bc.SetNightMode(!bc.NightMode())
+			}
+		}
+	}()
+}
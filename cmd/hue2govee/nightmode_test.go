@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestBrightnessCap(t *testing.T) {
+	tests := []struct {
+		name                string
+		maxBrightness       int
+		nightModeBrightness int
+		wantCap             int
+		wantNightModeCap    int
+		wantErr             bool
+	}{
+		{name: "no cap", nightModeBrightness: 30, wantCap: 0, wantNightModeCap: 30},
+		{name: "night mode lowers the cap", maxBrightness: 80, nightModeBrightness: 30, wantCap: 80,
+			wantNightModeCap: 30},
+		{name: "night mode never raises the cap", maxBrightness: 20, nightModeBrightness: 30, wantCap: 20,
+			wantNightModeCap: 20},
+		{name: "max brightness above 100", maxBrightness: 101, nightModeBrightness: 30, wantErr: true},
+		{name: "negative max brightness", maxBrightness: -1, nightModeBrightness: 30, wantErr: true},
+		{name: "night mode brightness of zero", nightModeBrightness: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("max_brightness", tt.maxBrightness)
+			viper.Set("night_mode_brightness", tt.nightModeBrightness)
+
+			bc, err := newBrightnessCap(zerolog.Nop(), govee.NewClient(zerolog.Nop(), govee.DefaultMulticastIP))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("newBrightnessCap() = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newBrightnessCap() returned error: %v", err)
+			}
+
+			if got := bc.effective(); got != tt.wantCap {
+				t.Errorf("cap = %d, want %d", got, tt.wantCap)
+			}
+			bc.SetNightMode(true)
+			if got := bc.effective(); !bc.NightMode() || got != tt.wantNightModeCap {
+				t.Errorf("cap in night mode = %d, want %d", got, tt.wantNightModeCap)
+			}
+			bc.SetNightMode(false)
+			if got := bc.effective(); bc.NightMode() || got != tt.wantCap {
+				t.Errorf("cap after night mode = %d, want %d", got, tt.wantCap)
+			}
+		})
+	}
+}
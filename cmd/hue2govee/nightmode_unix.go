@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// nightModeSignals toggle night mode when received
+var nightModeSignals = []os.Signal{syscall.SIGUSR1}
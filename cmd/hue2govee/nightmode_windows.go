@@ -0,0 +1,8 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// nightModeSignals toggle night mode when received, Windows has no user-defined signals
+var nightModeSignals []os.Signal
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// pairTimeout is how long pairing waits for the link button of the bridge to be pressed
+	pairTimeout = 30 * time.Second
+	// pairRetryInterval is the interval between two pairing attempts
+	pairRetryInterval = 2 * time.Second
+)
+
+// runPair discovers the Hue bridge and registers the bridge as an application once the link button of the
+// bridge was pressed, printing the credentials to put into the config.
+//
+// Usage: hue2govee pair [--bridge <name>]
+func runPair(log zerolog.Logger, args []string) error {
+	flags := pflag.NewFlagSet("pair", pflag.ContinueOnError)
+	bridgeName := flags.String("bridge", "", "name of the Hue bridge in hue_bridges, the first bridge if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pairTimeout)
+	defer cancel()
+
+	bridge, err := lookupHueBridge(*bridgeName)
+	if err != nil {
+		return err
+	}
+	bridge.Username = "" // pairing creates the username
+	hueClient, err := newHueClient(log, bridge)
+	if err != nil {
+		return err
+	}
+	if err := hueClient.Rediscover(ctx); err != nil {
+		return err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	fmt.Printf("Press the link button on your Hue bridge at %s within %s\n", hueClient.BridgeAddress(), pairTimeout)
+	credentials, err := waitForPairing(ctx, hueClient, "hue2govee#"+hostname, pairRetryInterval)
+	if err != nil {
+		return err
+	}
+	if bridge.Name != config.DefaultHueBridge {
+		fmt.Printf("Paired successfully, set the username of the Hue bridge %s in your config:\n\n"+
+			"username: %q\n", bridge.Name, credentials.Username)
+		return nil
+	}
+	fmt.Printf("Paired successfully, add the following to your config:\n\nhue_bridge_username: %q\n",
+		credentials.Username)
+	return nil
+}
+
+// waitForPairing retries pairing with the bridge every interval until its link button was pressed or the
+// context is done.
+func waitForPairing(ctx context.Context, hueClient *hue.Client, deviceType string,
+	interval time.Duration) (*hue.Credentials, error) {
+	for {
+		credentials, err := hueClient.Pair(deviceType)
+		if err == nil {
+			return credentials, nil
+		}
+		if !hue.IsLinkButtonNotPressed(err) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("link button wasn't pressed within %s", pairTimeout)
+		case <-time.After(interval):
+		}
+	}
+}
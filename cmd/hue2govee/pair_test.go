@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/rs/zerolog"
+)
+
+// newPairTestClient creates a Hue client of a bridge whose link button is pressed after the given number of
+// pairing attempts. The number of attempts is counted in attempts.
+func newPairTestClient(t *testing.T, pressedAfter int32, attempts *atomic.Int32) *hue.Client {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if attempts.Add(1) <= pressedAfter {
+			_, _ = w.Write([]byte(`[{"error":{"type":101,"address":"","description":"link button not pressed"}}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"success":{"username":"new-user","clientkey":"client-key"}}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	hueClient := hue.NewClient("", "", zerolog.Nop())
+	if err := hueClient.SetBridgeAddress(strings.TrimPrefix(server.URL, "https://")); err != nil {
+		t.Fatal(err)
+	}
+	return hueClient
+}
+
+func TestWaitForPairing(t *testing.T) {
+	var attempts atomic.Int32
+	hueClient := newPairTestClient(t, 2, &attempts)
+
+	credentials, err := waitForPairing(context.Background(), hueClient, "hue2govee#test", time.Millisecond)
+	if err != nil {
+		t.Fatalf("waitForPairing() returned error: %v", err)
+	}
+	if credentials.Username != "new-user" || credentials.ClientKey != "client-key" {
+		t.Errorf("waitForPairing() = %+v, want the credentials of the bridge", credentials)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("waitForPairing() tried %d times, want it to retry until the link button was pressed", got)
+	}
+}
+
+func TestWaitForPairingTimeout(t *testing.T) {
+	var attempts atomic.Int32
+	hueClient := newPairTestClient(t, 1000, &attempts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := waitForPairing(ctx, hueClient, "hue2govee#test", 10*time.Millisecond); err == nil ||
+		!strings.Contains(err.Error(), "link button wasn't pressed") {
+		t.Errorf("waitForPairing() = %v, want an error about the link button", err)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+)
+
+// previewWidth is the width in characters of the color block printed by previewColor
+const previewWidth = 8
+
+// ansiColorBlock returns a block of the given width with the color as 24-bit ANSI background, followed by a reset.
+func ansiColorBlock(rgb hue.RGBColor, width int) string {
+	return fmt.Sprintf("\x1b[48;2;%d;%d;%dm%s\x1b[0m", rgb.R, rgb.G, rgb.B, strings.Repeat(" ", width))
+}
+
+// isTerminal returns whether the file is a terminal, so escape sequences don't end up in piped output.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// previewColor prints a block of the color with its hex value to stdout if it's a terminal.
+func previewColor(rgb hue.RGBColor) {
+	if !isTerminal(os.Stdout) {
+		return
+	}
+	fmt.Printf("%s #%02X%02X%02X\n", ansiColorBlock(rgb, previewWidth), rgb.R, rgb.G, rgb.B)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+)
+
+func TestANSIColorBlock(t *testing.T) {
+	tests := []struct {
+		name  string
+		rgb   hue.RGBColor
+		width int
+		want  string
+	}{
+		{name: "orange", rgb: hue.RGBColor{R: 255, G: 128}, width: 4, want: "\x1b[48;2;255;128;0m    \x1b[0m"},
+		{name: "black", width: 2, want: "\x1b[48;2;0;0;0m  \x1b[0m"},
+		{name: "empty", rgb: hue.RGBColor{R: 1, G: 2, B: 3}, want: "\x1b[48;2;1;2;3m\x1b[0m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ansiColorBlock(tt.rgb, tt.width); got != tt.want {
+				t.Errorf("ansiColorBlock() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if isTerminal(w) {
+		t.Error("isTerminal() of a pipe = true, want false")
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if isTerminal(f) {
+		t.Error("isTerminal() of a file = true, want false")
+	}
+}
+
+func TestPreviewColorPiped(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	stdout := os.Stdout
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = stdout })
+
+	// piped output stays free of escape sequences
+	previewColor(hue.RGBColor{R: 255})
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 {
+		t.Errorf("previewColor() wrote %q to a pipe, want nothing", out)
+	}
+}
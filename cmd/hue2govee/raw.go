@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// runRaw sends an arbitrary command to a Govee device.
+//
+// Usage: hue2govee raw <device-id> <cmd> <json>
+//
+// This is an advanced, unsupported command meant for experimenting with device-specific commands
+// the bridge doesn't model yet. Payloads are sent as-is.
+func runRaw(log zerolog.Logger, args []string) error {
+	if len(args) != 3 {
+		return errors.New("usage: hue2govee raw <device-id> <cmd> <json>")
+	}
+	deviceID, cmd, payload := args[0], args[1], json.RawMessage(args[2])
+	if !json.Valid(payload) {
+		return fmt.Errorf("payload is not valid JSON: %s", payload)
+	}
+
+	log.Warn().Msg("Sending raw Govee command, this is unsupported and may misbehave")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goveeClient, err := newGoveeClient(log)
+	if err != nil {
+		return err
+	}
+	defer goveeClient.Close()
+	if err := goveeClient.Discover(ctx); err != nil {
+		return fmt.Errorf("failed to discover Govee devices: %w", err)
+	}
+	if err := goveeClient.WaitForDevice(ctx, deviceID); err != nil {
+		return err
+	}
+
+	if err := goveeClient.SendRaw(deviceID, cmd, payload); err != nil {
+		return fmt.Errorf("failed to send raw command: %w", err)
+	}
+	log.Info().Str("deviceId", deviceID).Str("cmd", cmd).Msg("Sent raw Govee command")
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRunRawArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "no arguments", wantErr: "usage"},
+		{name: "missing payload", args: []string{"AA:BB", "turn"}, wantErr: "usage"},
+		{name: "too many arguments", args: []string{"AA:BB", "turn", "{}", "extra"}, wantErr: "usage"},
+		{name: "invalid JSON", args: []string{"AA:BB", "turn", "{value: 1}"}, wantErr: "not valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// invalid arguments are rejected before discovery starts
+			if err := runRaw(zerolog.Nop(), tt.args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runRaw(%q) = %v, want an error containing %q", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
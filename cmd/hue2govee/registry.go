@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/cedrickring/hue-to-govee/internal/state"
+)
+
+// syncRegistry holds the running synchronizers keyed by their synchronization ID.
+type syncRegistry struct {
+	mu    sync.RWMutex // Mutex to protect syncs updates
+	syncs map[string]*synchronizer
+}
+
+// newSyncRegistry creates a new, empty syncRegistry
+func newSyncRegistry() *syncRegistry {
+	return &syncRegistry{
+		syncs: make(map[string]*synchronizer),
+	}
+}
+
+// add registers a synchronizer.
+func (r *syncRegistry) add(s *synchronizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.syncs[s.sync.ID()] = s
+}
+
+// remove unregisters the synchronizer with the given ID.
+func (r *syncRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.syncs, id)
+}
+
+// get returns the synchronizer with the given ID.
+func (r *syncRegistry) get(id string) (*synchronizer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.syncs[id]
+	if !ok {
+		return nil, fmt.Errorf("no synchronization with ID %q", id)
+	}
+	return s, nil
+}
+
+// all returns all synchronizers sorted by ID.
+func (r *syncRegistry) all() []*synchronizer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	syncs := make([]*synchronizer, 0, len(r.syncs))
+	for _, s := range r.syncs {
+		syncs = append(syncs, s)
+	}
+	sort.Slice(syncs, func(i, j int) bool {
+		return syncs[i].sync.ID() < syncs[j].sync.ID()
+	})
+	return syncs
+}
+
+// forDevice returns the synchronizers of the Govee device with the given ID.
+func (r *syncRegistry) forDevice(deviceID string) []*synchronizer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var syncs []*synchronizer
+	for _, s := range r.syncs {
+		if s.sync.GoveeDeviceId == deviceID {
+			syncs = append(syncs, s)
+		}
+	}
+	return syncs
+}
+
+// bridgeController implements control.Controller and api.Controller for the running bridge.
+type bridgeController struct {
+	ctx         context.Context
+	registry    *syncRegistry
+	store       *state.Store
+	hueClients  hueClients
+	goveeClient *govee.Client
+	sc          *hue.SceneController
+	policy      hue.ConflictPolicy
+}
+
+func (bc *bridgeController) Status() any {
+	states, _ := bc.store.Snapshot()
+	return states
+}
+
+func (bc *bridgeController) Syncs() []state.SyncState {
+	states, _ := bc.store.Snapshot()
+	return states
+}
+
+func (bc *bridgeController) Pause(id string) error {
+	s, err := bc.registry.get(id)
+	if err != nil {
+		return err
+	}
+	s.setPaused(true)
+	return nil
+}
+
+func (bc *bridgeController) Resume(id string) error {
+	s, err := bc.registry.get(id)
+	if err != nil {
+		return err
+	}
+	s.setPaused(false)
+	return nil
+}
+
+func (bc *bridgeController) AllOff() error {
+	var errs []error
+	for _, s := range bc.registry.all() {
+		s.setPaused(true)
+		if err := bc.goveeClient.TurnOff(s.sync.GoveeDeviceId); err != nil && !govee.IsDeviceNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to turn off %s: %w", s.sync.GoveeDeviceId, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (bc *bridgeController) Rediscover() error {
+	bc.goveeClient.Rescan()
+	return bc.hueClients.rediscover(bc.ctx)
+}
+
+func (bc *bridgeController) Set(device, color string) error {
+	deviceID, err := config.ResolveGoveeDeviceID(device)
+	if err != nil {
+		return err
+	}
+	rgb, err := hue.ParseColor(color)
+	if err != nil {
+		return err
+	}
+	if err := bc.sc.AcquireDevice(deviceID, bc.policy); err != nil {
+		return err
+	}
+	// the synchronizations of the device would overwrite the manual color on their next tick
+	for _, s := range bc.registry.forDevice(deviceID) {
+		s.hold()
+	}
+
+	if err := bc.goveeClient.TurnOn(deviceID); err != nil {
+		return err
+	}
+	// manual colors are read back from the device, so a lost command is reported instead of silently ignored
+	return bc.goveeClient.SetColorVerified(deviceID, rgb.R, rgb.G, rgb.B)
+}
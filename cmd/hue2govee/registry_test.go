@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+)
+
+func TestBridgeControllerPauseAndResume(t *testing.T) {
+	runner := newTestSyncRunner(t)
+	desk := config.Synchronization{Name: "desk", HueBridge: config.DefaultHueBridge, HueLightId: "light-1",
+		GoveeDeviceId: "AA:BB:CC:DD:EE:FF:00:11", PollIntervalMs: 500}
+	runner.apply([]config.Synchronization{desk}, nil)
+	s := runningSyncs(runner)["desk"]
+	controller := &bridgeController{registry: runner.registry, store: runner.store}
+
+	if err := controller.Pause("desk"); err != nil {
+		t.Fatalf("Pause() returned error: %v", err)
+	}
+	if syncs := controller.Syncs(); len(syncs) != 1 || !syncs[0].Paused {
+		t.Errorf("Syncs() = %+v, want desk paused", syncs)
+	}
+	// pausing stops forwarding, but the synchronization keeps running to be resumed
+	select {
+	case <-s.done:
+		t.Fatal("paused synchronization stopped, want it waiting for resume")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := controller.Resume("desk"); err != nil {
+		t.Fatalf("Resume() returned error: %v", err)
+	}
+	if syncs := controller.Syncs(); len(syncs) != 1 || syncs[0].Paused {
+		t.Errorf("Syncs() = %+v, want desk resumed", syncs)
+	}
+	if s.paused.Load() {
+		t.Error("synchronizer is still paused after resuming")
+	}
+
+	if err := controller.Pause("unknown"); err == nil {
+		t.Error("Pause() of an unknown synchronization returned no error")
+	}
+	if err := controller.Resume("unknown"); err == nil {
+		t.Error("Resume() of an unknown synchronization returned no error")
+	}
+}
+
+func TestBridgeControllerSetAlias(t *testing.T) {
+	loadTestConfig(t, "govee_devices:\n  - device_id: "+testGoveeDeviceID+"\n    alias: desk-lamp\n")
+	runner := newTestSyncRunner(t)
+	addStoppedSynchronizer(runner, "desk", testGoveeDeviceID)
+	controller := &bridgeController{registry: runner.registry, goveeClient: runner.goveeClient, sc: runner.sc,
+		policy: hue.ConflictPolicyStopScene}
+
+	// the test device isn't discovered, but the synchronization of the resolved device is held for the color
+	if err := controller.Set("desk-lamp", "red"); !govee.IsDeviceNotFound(err) {
+		t.Errorf("Set() of an alias = %v, want the alias resolved to the undiscovered device", err)
+	}
+	if s := runningSyncs(runner)["desk"]; !s.held.Load() {
+		t.Error("synchronization of the aliased device isn't held")
+	}
+
+	err := controller.Set("porch", "red")
+	if err == nil || !strings.Contains(err.Error(), "unknown govee device alias") {
+		t.Errorf("Set() of an unknown alias = %v, want an unknown alias error", err)
+	}
+}
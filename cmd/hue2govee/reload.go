@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// reloadSynchronizationsOnChange watches the config file and applies changed synchronizations and DIY scenes
+// to the running bridge. An invalid config is logged and ignored, keeping the synchronizations running.
+func reloadSynchronizationsOnChange(ctx context.Context, log zerolog.Logger, runner *syncRunner) {
+	if _, err := os.Stat(viper.ConfigFileUsed()); err != nil {
+		return // configured by environment variables only, there's no file to watch
+	}
+
+	viper.OnConfigChange(func(event fsnotify.Event) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		synchronizations, err := config.GetSynchronizations()
+		if err != nil {
+			log.Error().Err(err).Msg("Ignoring invalid synchronizations after config change")
+			return
+		}
+		diyScenes, err := config.GetDIYScenes()
+		if err != nil {
+			log.Error().Err(err).Msg("Ignoring invalid Govee DIY scenes after config change")
+			return
+		}
+
+		log.Info().Str("file", event.Name).Msg("Config changed, reloading synchronizations")
+		runner.apply(synchronizations, diyScenes)
+	})
+	viper.WatchConfig()
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	deskSync  = "  - name: desk\n    hue_light_id: light-1\n    govee_device_id: AA:BB:CC:DD:EE:FF:00:11\n"
+	shelfSync = "  - name: shelf\n    hue_light_id: light-2\n    govee_device_id: AA:BB:CC:DD:EE:FF:00:22\n"
+)
+
+// newTestSyncRunner creates a syncRunner whose Hue bridge isn't discovered and whose Govee devices are unknown, so
+// the synchronizers it starts don't send anything.
+func newTestSyncRunner(t *testing.T) *syncRunner {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	goveeClient := govee.NewClient(zerolog.Nop(), govee.DefaultMulticastIP)
+	runner := &syncRunner{
+		ctx:         ctx,
+		logger:      zerolog.Nop(),
+		hueClients:  hueClients{config.DefaultHueBridge: hue.NewClient("", "test-user", zerolog.Nop())},
+		goveeClient: goveeClient,
+		sc:          hue.NewSceneController(goveeClient, zerolog.Nop()),
+		store:       state.NewStore(),
+		streaming:   map[string]bool{},
+		registry:    newSyncRegistry(),
+		rng:         rand.New(rand.NewPCG(1, 1)),
+	}
+	t.Cleanup(func() {
+		runner.stopAll()
+		cancel()
+	})
+	return runner
+}
+
+// writeTestConfig writes the YAML config to the file.
+func writeTestConfig(t *testing.T, file, yaml string) {
+	t.Helper()
+
+	// the file is replaced like editors save it, the watcher would otherwise see the truncated file, which is a
+	// valid config without synchronizations
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runningSyncs returns the synchronizers of the runner by ID.
+func runningSyncs(runner *syncRunner) map[string]*synchronizer {
+	syncs := make(map[string]*synchronizer)
+	for _, s := range runner.registry.all() {
+		syncs[s.sync.ID()] = s
+	}
+	return syncs
+}
+
+func TestSyncRunnerApply(t *testing.T) {
+	runner := newTestSyncRunner(t)
+	desk := config.Synchronization{Name: "desk", HueBridge: config.DefaultHueBridge, HueLightId: "light-1",
+		GoveeDeviceId: "AA:BB:CC:DD:EE:FF:00:11", PollIntervalMs: 500}
+	shelf := config.Synchronization{Name: "shelf", HueBridge: config.DefaultHueBridge, HueLightId: "light-2",
+		GoveeDeviceId: "AA:BB:CC:DD:EE:FF:00:22", PollIntervalMs: 500}
+
+	runner.apply([]config.Synchronization{desk}, nil)
+	before := runningSyncs(runner)
+	if len(before) != 1 || before["desk"] == nil {
+		t.Fatalf("running synchronizations = %v, want desk", before)
+	}
+
+	// adding a synchronization leaves the unchanged one running
+	runner.apply([]config.Synchronization{desk, shelf}, nil)
+	added := runningSyncs(runner)
+	if len(added) != 2 || added["shelf"] == nil {
+		t.Fatalf("running synchronizations = %v, want desk and shelf", added)
+	}
+	if added["desk"] != before["desk"] {
+		t.Error("unchanged synchronization was restarted, want it undisturbed")
+	}
+	if _, ok := runner.store.Get("shelf"); !ok {
+		t.Error("added synchronization has no state, want it tracked")
+	}
+
+	// changing a synchronization restarts it
+	changed := shelf
+	changed.PollIntervalMs = 1000
+	runner.apply([]config.Synchronization{desk, changed}, nil)
+	restarted := runningSyncs(runner)
+	if restarted["shelf"] == added["shelf"] || restarted["shelf"].sync.PollIntervalMs != 1000 {
+		t.Error("changed synchronization wasn't restarted with the new config")
+	}
+	select {
+	case <-added["shelf"].done:
+	default:
+		t.Error("synchronizer of the changed synchronization is still running")
+	}
+
+	// removing a synchronization stops it
+	runner.apply([]config.Synchronization{changed}, nil)
+	removed := runningSyncs(runner)
+	if len(removed) != 1 || removed["shelf"] != restarted["shelf"] {
+		t.Fatalf("running synchronizations = %v, want shelf only", removed)
+	}
+	select {
+	case <-restarted["desk"].done:
+	default:
+		t.Error("synchronizer of the removed synchronization is still running")
+	}
+	if _, ok := runner.store.Get("desk"); ok {
+		t.Error("removed synchronization still has a state, want it removed")
+	}
+}
+
+func TestReloadSynchronizationsOnChange(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestConfig(t, file, "synchronizations:\n"+deskSync)
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	config.RegisterFlags(flags)
+	config.MustLoad(file, flags)
+
+	runner := newTestSyncRunner(t)
+	synchronizations, err := config.GetSynchronizations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.apply(synchronizations, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logs := make(logLines, 100)
+	reloadSynchronizationsOnChange(ctx, zerolog.New(logs), runner)
+
+	waitForSyncs := func(what string, want ...string) {
+		t.Helper()
+
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			syncs := runningSyncs(runner)
+			matches := len(syncs) == len(want)
+			for _, id := range want {
+				matches = matches && syncs[id] != nil
+			}
+			if matches {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("running synchronizations %v %s, want %v", syncs, what, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	writeTestConfig(t, file, "synchronizations:\n"+deskSync+shelfSync)
+	waitForSyncs("after adding shelf", "desk", "shelf")
+
+	writeTestConfig(t, file, "synchronizations:\n"+shelfSync)
+	waitForSyncs("after removing desk", "shelf")
+
+	// an invalid config keeps the synchronizations running
+	writeTestConfig(t, file, "synchronizations:\n  - hue_light_id: light-3\n")
+	// the watcher must be done reading the config before viper is reset
+	logs.waitFor(t, "Ignoring invalid synchronizations")
+	waitForSyncs("after an invalid change", "shelf")
+}
+
+// logLines receives the lines logged to it, dropping them if they aren't read.
+type logLines chan string
+
+func (l logLines) Write(p []byte) (int, error) {
+	select {
+	case l <- string(p):
+	default:
+	}
+	return len(p), nil
+}
+
+// waitFor waits until a line containing the message is logged.
+func (l logLines) waitFor(t *testing.T, message string) {
+	t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case line := <-l:
+			if strings.Contains(line, message) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("%q wasn't logged", message)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+)
+
+// deviceStateClient captures and restores the state of Govee devices, implemented by govee.Client.
+type deviceStateClient interface {
+	QueryStatus(ctx context.Context, deviceID string) (govee.StatusData, error)
+	ForceRefresh(deviceID string)
+	TurnOn(deviceID string) error
+	TurnOff(deviceID string) error
+	SetColor(deviceID string, r, g, b int) error
+	SetColorTemperature(deviceID string, kelvin int) error
+	SetBrightness(deviceID string, value int) error
+}
+
+// captureInitialState remembers the state of the Govee device before the synchronization sends its first command,
+// so it can be restored once the synchronization stops. Returns false while the device isn't discovered yet.
+func (s *synchronizer) captureInitialState(ctx context.Context) bool {
+	if !s.restore || s.initialCaptured {
+		return true
+	}
+
+	status, err := s.stateClient.QueryStatus(ctx, s.sync.GoveeDeviceId)
+	if govee.IsDeviceNotFound(err) || ctx.Err() != nil {
+		return false
+	}
+	s.initialCaptured = true
+	if err != nil {
+		s.logger.Warn().Err(err).Str("deviceId", s.sync.GoveeDeviceId).
+			Msg("Failed to query Govee device status, its state won't be restored")
+		return true
+	}
+	s.initialStatus = &status
+	return true
+}
+
+// restoreInitialState sends the state the Govee device had before the synchronization took over. The
+// synchronization must be stopped before, so it doesn't overwrite the restored state.
+func (s *synchronizer) restoreInitialState() {
+	status := s.initialStatus
+	if status == nil {
+		return
+	}
+
+	// the device was changed by the synchronization, so all commands have to be sent
+	s.stateClient.ForceRefresh(s.sync.GoveeDeviceId)
+	var err error
+	if !status.IsOn() {
+		err = s.stateClient.TurnOff(s.sync.GoveeDeviceId)
+	} else {
+		err = s.stateClient.TurnOn(s.sync.GoveeDeviceId)
+		if err == nil && status.ColorTemInKelvin > 0 {
+			err = s.stateClient.SetColorTemperature(s.sync.GoveeDeviceId, status.ColorTemInKelvin)
+		} else if err == nil {
+			err = s.stateClient.SetColor(s.sync.GoveeDeviceId, status.Color.R, status.Color.G, status.Color.B)
+		}
+		if err == nil {
+			err = s.stateClient.SetBrightness(s.sync.GoveeDeviceId, status.Brightness)
+		}
+	}
+	if err != nil && !govee.IsDeviceNotFound(err) {
+		s.logger.Error().Err(err).Str("deviceId", s.sync.GoveeDeviceId).Msg("Failed to restore Govee device state")
+		return
+	}
+	s.logger.Info().Str("deviceId", s.sync.GoveeDeviceId).Msg("Restored Govee device state")
+}
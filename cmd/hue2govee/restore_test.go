@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/rs/zerolog"
+)
+
+// stubDeviceState answers status queries with status or statusErr and records the commands sent to it.
+type stubDeviceState struct {
+	stubColorSender
+	status    govee.StatusData
+	statusErr error
+}
+
+func (s *stubDeviceState) QueryStatus(_ context.Context, deviceID string) (govee.StatusData, error) {
+	s.calls = append(s.calls, "status "+deviceID)
+	return s.status, s.statusErr
+}
+
+func (s *stubDeviceState) ForceRefresh(deviceID string) {
+	s.calls = append(s.calls, "refresh "+deviceID)
+}
+
+func (s *stubDeviceState) SetColorTemperature(deviceID string, kelvin int) error {
+	return s.record(fmt.Sprintf("temperature %s %d", deviceID, kelvin))
+}
+
+// newRestoreTestSynchronizer creates a synchronizer restoring the state of the test Govee device through client.
+func newRestoreTestSynchronizer(client *stubDeviceState) *synchronizer {
+	return &synchronizer{
+		sync:        config.Synchronization{HueLightId: "light-1", GoveeDeviceId: testGoveeDeviceID},
+		logger:      zerolog.Nop(),
+		restore:     true,
+		stateClient: client,
+	}
+}
+
+func TestCaptureInitialState(t *testing.T) {
+	status := govee.StatusData{OnOff: 1, Brightness: 40, Color: govee.RGBColor{R: 255, G: 128}}
+	tests := []struct {
+		name       string
+		restore    bool
+		statusErr  error
+		want       bool
+		wantStatus *govee.StatusData
+	}{
+		{name: "captured", restore: true, want: true, wantStatus: &status},
+		{name: "device not discovered", restore: true, statusErr: govee.ErrDeviceNotFound},
+		{name: "no response", restore: true, statusErr: errors.New("no status response"), want: true},
+		{name: "restore disabled", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &stubDeviceState{status: status, statusErr: tt.statusErr}
+			s := newRestoreTestSynchronizer(client)
+			s.restore = tt.restore
+
+			if got := s.captureInitialState(context.Background()); got != tt.want {
+				t.Errorf("captureInitialState() = %t, want %t", got, tt.want)
+			}
+			if !reflect.DeepEqual(s.initialStatus, tt.wantStatus) {
+				t.Errorf("initial status = %+v, want %+v", s.initialStatus, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCaptureInitialStateOnce(t *testing.T) {
+	client := &stubDeviceState{status: govee.StatusData{OnOff: 1, Brightness: 40}}
+	s := newRestoreTestSynchronizer(client)
+
+	// the state is captured before the first command only, later states were set by the synchronization
+	for range 2 {
+		if !s.captureInitialState(context.Background()) {
+			t.Fatal("captureInitialState() = false, want true")
+		}
+	}
+	if want := []string{"status " + testGoveeDeviceID}; !reflect.DeepEqual(client.calls, want) {
+		t.Errorf("commands = %v, want %v", client.calls, want)
+	}
+}
+
+func TestRestoreInitialState(t *testing.T) {
+	tests := []struct {
+		name   string
+		status *govee.StatusData
+		want   []string
+	}{
+		{name: "off", status: &govee.StatusData{OnOff: 0, Brightness: 40},
+			want: []string{"refresh " + testGoveeDeviceID, "off " + testGoveeDeviceID}},
+		{name: "color", status: &govee.StatusData{OnOff: 1, Brightness: 40, Color: govee.RGBColor{R: 255, G: 128}},
+			want: []string{"refresh " + testGoveeDeviceID, "on " + testGoveeDeviceID,
+				"color " + testGoveeDeviceID + " 255 128 0", "brightness " + testGoveeDeviceID + " 40"}},
+		{name: "color temperature", status: &govee.StatusData{OnOff: 1, Brightness: 70, ColorTemInKelvin: 2700},
+			want: []string{"refresh " + testGoveeDeviceID, "on " + testGoveeDeviceID,
+				"temperature " + testGoveeDeviceID + " 2700", "brightness " + testGoveeDeviceID + " 70"}},
+		{name: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &stubDeviceState{}
+			s := newRestoreTestSynchronizer(client)
+			s.initialCaptured, s.initialStatus = true, tt.status
+
+			s.restoreInitialState()
+			if !reflect.DeepEqual(client.calls, tt.want) {
+				t.Errorf("commands = %v, want %v", client.calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestShutdownRestoresDevices(t *testing.T) {
+	loadTestConfig(t, "restore_on_shutdown: true\n")
+	runner := newTestSyncRunner(t)
+	addStoppedSynchronizer(runner, "desk", testGoveeDeviceID)
+	s, err := runner.registry.get("desk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &stubDeviceState{}
+	s.stateClient = client
+	s.initialCaptured, s.initialStatus = true, &govee.StatusData{OnOff: 0}
+	sender := &stubColorSender{}
+
+	shutdownDevices(zerolog.Nop(), sender, runner)
+	want := []string{"refresh " + testGoveeDeviceID, "off " + testGoveeDeviceID}
+	if !reflect.DeepEqual(client.calls, want) {
+		t.Errorf("commands = %v, want %v", client.calls, want)
+	}
+	if len(sender.calls) != 0 {
+		t.Errorf("commands of the shutdown client = %v, want none", sender.calls)
+	}
+}
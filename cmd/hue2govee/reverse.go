@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+)
+
+// reverseDebounce is how long writes in one direction are suppressed after a change was copied in the other
+// direction, so a bidirectional synchronization doesn't echo its own changes back and forth
+const reverseDebounce = 2 * time.Second
+
+// reverseTick copies the state of the Govee device to the Hue light if it changed since the last tick.
+func (s *synchronizer) reverseTick(ctx context.Context) {
+	status, err := s.goveeClient.QueryStatus(ctx, s.sync.GoveeDeviceId)
+	if err != nil {
+		if !govee.IsDeviceNotFound(err) && !govee.IsDeviceFailed(err) {
+			s.logger.Debug().Err(err).Str("deviceId", s.sync.GoveeDeviceId).Msg("Failed to query Govee device status")
+		}
+		return
+	}
+
+	previous := s.lastStatus
+	s.lastStatus = &status
+	if previous != nil && *previous == status {
+		return
+	}
+	if previous == nil && s.sync.Direction == config.DirectionBidirectional {
+		// the first status is only the baseline, the Hue light is the source of truth on startup
+		return
+	}
+	if time.Since(s.lastForwardWrite) < reverseDebounce {
+		// the change was most likely caused by the forward synchronization itself
+		return
+	}
+
+	if s.lightGamut == nil {
+		// the gamut only keeps the color within what the light can show, so the default gamut is fine if unknown
+		if light, err := s.hueClient.GetLight(ctx, s.sync.HueLightId); err == nil {
+			gamut := hue.LightGamut(light)
+			s.lightGamut = &gamut
+		}
+	}
+	var gamut hue.Gamut
+	if s.lightGamut != nil {
+		gamut = *s.lightGamut
+	}
+
+	if err := s.hueClient.SetLightState(ctx, s.sync.HueLightId, lightUpdateFromStatus(status, gamut)); err != nil {
+		s.logger.Error().Err(err).Str("lightId", s.sync.HueLightId).Msg("Failed to update Hue light")
+		return
+	}
+	s.lastReverseWrite = time.Now()
+	s.logger.Debug().Str("deviceId", s.sync.GoveeDeviceId).Str("lightId", s.sync.HueLightId).
+		Any("status", status).Msg("Copied Govee device state to Hue light")
+}
+
+// reverseWriteRecent returns whether the Hue light was recently updated from the Govee device.
+func (s *synchronizer) reverseWriteRecent() bool {
+	return s.sync.Direction.ToHue() && time.Since(s.lastReverseWrite) < reverseDebounce
+}
+
+// lightUpdateFromStatus builds the Hue light update reproducing a Govee device status, keeping its color within the
+// gamut of the Hue light.
+func lightUpdateFromStatus(status govee.StatusData, gamut hue.Gamut) hue.LightStateUpdate {
+	if !status.IsOn() {
+		return hue.LightStateUpdate{On: &hue.On{On: false}}
+	}
+
+	update := hue.LightStateUpdate{
+		On:      &hue.On{On: true},
+		Dimming: &hue.Dimming{Brightness: float64(status.Brightness)},
+	}
+	if status.ColorTemInKelvin > 0 {
+		mirek := hue.ShiftMirek(1000000/status.ColorTemInKelvin, 0)
+		update.ColorTemperature = &hue.ColorTemperatureUpdate{Mirek: mirek}
+	} else {
+		xy := hue.RGBToXY(status.Color.R, status.Color.G, status.Color.B, gamut)
+		update.Color = &hue.ColorUpdate{XY: xy}
+	}
+	return update
+}
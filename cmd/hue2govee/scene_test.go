@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/rs/zerolog"
+)
+
+const testGoveeDeviceID = "AA:BB:CC:DD:EE:FF:00:11"
+
+// newSceneTestSynchronizer creates a synchronizer of the Hue room whose Hue bridge responds with the scenes of the
+// fixture shared with the hue package. The Govee device is unknown, so the scenes started don't send anything.
+func newSceneTestSynchronizer(t *testing.T, roomID string) *synchronizer {
+	t.Helper()
+
+	fixture, err := os.ReadFile("../../internal/hue/testdata/scenes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(fixture)
+	}))
+	t.Cleanup(server.Close)
+
+	hueClient := hue.NewClient("", "test-user", zerolog.Nop())
+	if err := hueClient.SetBridgeAddress(strings.TrimPrefix(server.URL, "https://")); err != nil {
+		t.Fatal(err)
+	}
+	goveeClient := govee.NewClient(zerolog.Nop(), govee.DefaultMulticastIP)
+	sc := hue.NewSceneController(goveeClient, zerolog.Nop())
+	t.Cleanup(func() { sc.StopScene(testGoveeDeviceID) })
+
+	diyScenes := &atomic.Pointer[map[string]int]{}
+	diyScenes.Store(&map[string]int{})
+	return &synchronizer{
+		sync:        config.Synchronization{HueLightId: "light-1", HueRoomId: roomID, GoveeDeviceId: testGoveeDeviceID},
+		logger:      zerolog.Nop(),
+		hueClient:   hueClient,
+		goveeClient: goveeClient,
+		sc:          sc,
+		store:       state.NewStore(),
+		diyScenes:   diyScenes,
+	}
+}
+
+func TestStartAutoDynamicScene(t *testing.T) {
+	s := newSceneTestSynchronizer(t, "room-1")
+	light := &hue.Light{ID: "light-1", On: hue.On{On: true}, Dimming: hue.Dimming{Brightness: 67.6}}
+
+	// the recalled scene is auto-dynamic, so its dynamic scene starts before the light's dynamics status updates
+	if !s.startAutoDynamicScene(context.Background(), light) {
+		t.Fatal("startAutoDynamicScene() = false, want the auto-dynamic scene started")
+	}
+	if !s.sc.IsActive(testGoveeDeviceID) {
+		t.Error("no scene is active on the Govee device, want the recalled scene")
+	}
+	if st, _ := s.store.Get(s.sync.ID()); st.ActiveScene != "scene-tropical-twilight" {
+		t.Errorf("ActiveScene = %q, want scene-tropical-twilight", st.ActiveScene)
+	}
+	if s.autoDynamicUntil.IsZero() {
+		t.Error("autoDynamicUntil isn't set, want a grace period for the light's dynamics status")
+	}
+
+	// the same recall isn't started again
+	if s.startAutoDynamicScene(context.Background(), light) {
+		t.Error("startAutoDynamicScene() for the same recall = true, want false")
+	}
+}
+
+func TestStartAutoDynamicSceneWithoutRecall(t *testing.T) {
+	// zone-1 recalled an auto-dynamic scene, but only scenes of rooms are considered
+	for _, roomID := range []string{"room-2", "zone-1"} {
+		t.Run(roomID, func(t *testing.T) {
+			s := newSceneTestSynchronizer(t, roomID)
+			light := &hue.Light{ID: "light-1", On: hue.On{On: true}}
+			if s.startAutoDynamicScene(context.Background(), light) {
+				t.Error("startAutoDynamicScene() = true, want no scene started")
+			}
+			if s.sc.IsActive(testGoveeDeviceID) {
+				t.Error("a scene is active on the Govee device, want none")
+			}
+		})
+	}
+}
+
+func TestSetSceneDIYScene(t *testing.T) {
+	tropicalTwilight := hue.Scene{ID: "scene-tropical-twilight", Palette: hue.Palette{Color: make([]hue.PaletteColor, 2)}}
+	tropicalTwilight.Metadata.Name = "Tropical twilight"
+	tropicalTwilight.Palette.Color[0].Color.XY = hue.Coords{X: 0.5, Y: 0.4}
+	tropicalTwilight.Palette.Color[1].Color.XY = hue.Coords{X: 0.2, Y: 0.1}
+
+	tests := []struct {
+		name        string
+		diyScenes   map[string]int
+		goveeScenes []config.GoveeScene
+		wantCycle   bool
+	}{
+		// the Govee device is unknown, so activating the DIY scene fails without falling back to palette cycling
+		{name: "mapped by name", diyScenes: map[string]int{"tropical twilight": 42}},
+		{name: "unmapped scene cycles the palette", diyScenes: map[string]int{"relax": 42}, wantCycle: true},
+		{name: "mapped to a built-in scene", diyScenes: map[string]int{},
+			goveeScenes: []config.GoveeScene{{HueSceneName: "Tropical Twilight", GoveeSceneCode: 1234}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSceneTestSynchronizer(t, "room-1")
+			s.diyScenes.Store(&tt.diyScenes)
+			s.sync.GoveeScenes = tt.goveeScenes
+
+			s.setScene(tropicalTwilight, hue.Gamut{})
+			if active := s.sc.IsActive(testGoveeDeviceID); active != tt.wantCycle {
+				t.Errorf("palette cycling active = %v, want %v", active, tt.wantCycle)
+			}
+			st, _ := s.store.Get(s.sync.ID())
+			if cycling := st.ActiveScene == tropicalTwilight.ID; cycling != tt.wantCycle {
+				t.Errorf("ActiveScene = %q, want it set only when cycling the palette", st.ActiveScene)
+			}
+		})
+	}
+}
+
+func TestSetEffect(t *testing.T) {
+	candle := &hue.Light{ID: "light-1", On: hue.On{On: true}, Dimming: hue.Dimming{Brightness: 60},
+		Color: hue.Color{XY: hue.Coords{X: 0.5, Y: 0.4}}, Effects: &hue.Effects{Status: "candle"}}
+
+	tests := []struct {
+		name         string
+		goveeEffects []config.GoveeEffect
+		wantApprox   bool
+	}{
+		{name: "approximated", wantApprox: true},
+		// the Govee device is unknown, so activating the built-in scene fails without falling back
+		{name: "mapped to a built-in scene",
+			goveeEffects: []config.GoveeEffect{{HueEffect: "Candle", GoveeSceneCode: 1234}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSceneTestSynchronizer(t, "room-1")
+			s.sync.GoveeEffects = tt.goveeEffects
+
+			s.setEffect(candle, "candle")
+			if active := s.sc.IsActive(testGoveeDeviceID); active != tt.wantApprox {
+				t.Errorf("effect approximation active = %v, want %v", active, tt.wantApprox)
+			}
+			if approximated := s.effect != nil; approximated != tt.wantApprox {
+				t.Errorf("effect = %+v, want it remembered only when playing", s.effect)
+			}
+		})
+	}
+}
+
+func TestSetEffectKeepsRunningEffect(t *testing.T) {
+	s := newSceneTestSynchronizer(t, "room-1")
+	light := &hue.Light{ID: "light-1", On: hue.On{On: true}, Dimming: hue.Dimming{Brightness: 60},
+		Color: hue.Color{XY: hue.Coords{X: 0.5, Y: 0.4}}, Effects: &hue.Effects{Status: "fire"}}
+
+	s.setEffect(light, "fire")
+	first := s.effect
+	s.setEffect(light, "fire")
+	if s.effect != first {
+		t.Error("the same effect was restarted, want the running effect kept")
+	}
+
+	// a change of the light's brightness restarts the effect around the new brightness
+	light.Dimming.Brightness = 30
+	s.setEffect(light, "fire")
+	if s.effect == first || s.effect.Brightness != 30 {
+		t.Errorf("effect = %+v, want it restarted at brightness 30", s.effect)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// shutdownTimeout bounds how long the Govee devices are updated on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// shutdownDevices stops all synchronizations and turns off or restores their Govee devices, depending on
+// turn_off_on_shutdown and restore_on_shutdown. The synchronizations keep running if neither is set.
+func shutdownDevices(log zerolog.Logger, goveeClient colorSender, runner *syncRunner) {
+	switch {
+	case viper.GetBool("turn_off_on_shutdown"):
+		runner.stopAll()
+		turnOffDevices(log, goveeClient, runner)
+	case viper.GetBool("restore_on_shutdown"):
+		runner.stopAll()
+		restoreDevices(log, runner)
+	}
+}
+
+// restoreDevices restores the state the Govee devices of all synchronizations had before the bridge took over. The
+// synchronizations must be stopped before. Gives up after shutdownTimeout.
+func restoreDevices(log zerolog.Logger, runner *syncRunner) {
+	syncs := runner.registry.all()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, s := range syncs {
+			s.restoreInitialState()
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		log.Warn().Msg("Timed out restoring Govee devices on shutdown")
+	}
+}
+
+// turnOffDevices turns off the Govee devices of all synchronizations and static devices. The synchronizations
+// must be stopped before, so they don't turn the devices on again. Gives up after shutdownTimeout.
+func turnOffDevices(log zerolog.Logger, goveeClient colorSender, runner *syncRunner) {
+	ids := make([]string, 0)
+	for _, s := range runner.registry.all() {
+		ids = append(ids, s.sync.GoveeDeviceId)
+	}
+	staticDevices, err := config.GetStaticDevices()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load static devices from config")
+	}
+	for _, device := range staticDevices {
+		ids = append(ids, device.GoveeDeviceId)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, id := range ids {
+			runner.sc.StopScene(id)
+			if err := goveeClient.TurnOff(id); err != nil && !govee.IsDeviceNotFound(err) {
+				log.Error().Err(err).Str("deviceId", id).Msg("Failed to turn off Govee device on shutdown")
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+		log.Info().Int("devices", len(ids)).Msg("Turned off Govee devices")
+	case <-time.After(shutdownTimeout):
+		log.Warn().Msg("Timed out turning off Govee devices on shutdown")
+	}
+}
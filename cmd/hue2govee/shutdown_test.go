@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// addStoppedSynchronizer registers a synchronizer of the Govee device that is already stopped.
+func addStoppedSynchronizer(runner *syncRunner, name, deviceID string) {
+	done := make(chan struct{})
+	close(done)
+	runner.registry.add(&synchronizer{
+		sync:   config.Synchronization{Name: name, HueLightId: "light-1", GoveeDeviceId: deviceID},
+		logger: zerolog.Nop(),
+		sc:     runner.sc,
+		store:  runner.store,
+		cancel: func() {},
+		done:   done,
+	})
+}
+
+func TestShutdownDevices(t *testing.T) {
+	const devices = "static_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    color: blue\n    brightness: 100\n"
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{name: "turn off", config: devices + "turn_off_on_shutdown: true\n",
+			want: []string{"off AA:BB:CC:DD:EE:FF:00:11", "off AA:BB:CC:DD:EE:FF:00:22", "off 11:22:33:44:55:66:77:88"}},
+		{name: "keep on", config: devices},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTestConfig(t, tt.config)
+			runner := newTestSyncRunner(t)
+			addStoppedSynchronizer(runner, "desk", "AA:BB:CC:DD:EE:FF:00:11")
+			addStoppedSynchronizer(runner, "shelf", "AA:BB:CC:DD:EE:FF:00:22")
+			client := &stubColorSender{}
+
+			shutdownDevices(zerolog.Nop(), client, runner)
+			if !reflect.DeepEqual(client.calls, tt.want) {
+				t.Errorf("commands = %v, want %v", client.calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestTurnOffDevicesIgnoresUnknownDevices(t *testing.T) {
+	loadTestConfig(t, "")
+	runner := newTestSyncRunner(t)
+	addStoppedSynchronizer(runner, "desk", "AA:BB:CC:DD:EE:FF:00:11")
+	addStoppedSynchronizer(runner, "shelf", "AA:BB:CC:DD:EE:FF:00:22")
+	client := &stubColorSender{failCommand: "off AA:BB:CC:DD:EE:FF:00:11"}
+
+	// a device that isn't discovered doesn't keep the others from being turned off
+	turnOffDevices(zerolog.Nop(), client, runner)
+	want := []string{"off AA:BB:CC:DD:EE:FF:00:11", "off AA:BB:CC:DD:EE:FF:00:22"}
+	if !reflect.DeepEqual(client.calls, want) {
+		t.Errorf("commands = %v, want %v", client.calls, want)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// startStaticDevices keeps the configured static devices at their fixed state, re-asserting it on
+// every resync interval to counter drift or manual changes.
+func startStaticDevices(ctx context.Context, logger zerolog.Logger, goveeClient *govee.Client, sc *hue.SceneController,
+	policy hue.ConflictPolicy) error {
+	staticDevices, err := config.GetStaticDevices()
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to load static devices from config")
+		return fmt.Errorf("failed to load static devices: %w", err)
+	}
+	if len(staticDevices) == 0 {
+		return nil
+	}
+
+	interval := viper.GetDuration("resync_interval")
+	logger.Info().Int("devices", len(staticDevices)).Dur("interval", interval).Msg("Managing static Govee devices")
+
+	go func() {
+		// devices are most likely not discovered yet on the first iteration, retry shortly after startup
+		wait := 2 * time.Second
+		for {
+			allApplied := true
+			for _, device := range staticDevices {
+				if err := sc.AcquireDevice(device.GoveeDeviceId, policy); err != nil {
+					logger.Warn().Err(err).Str("deviceId", device.GoveeDeviceId).
+						Msg("Not applying static device state")
+					continue
+				}
+				if err := applyStaticDevice(goveeClient, device); err != nil {
+					allApplied = false
+					if govee.IsDeviceNotFound(err) {
+						continue
+					}
+					logger.Error().Err(err).Str("deviceId", device.GoveeDeviceId).
+						Msg("Failed to apply static device state")
+				}
+			}
+			if allApplied {
+				wait = interval
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+
+	return nil
+}
+
+// applyStaticDevice sends the configured fixed state to a static device.
+func applyStaticDevice(goveeClient *govee.Client, device config.StaticDevice) error {
+	// the device may have been changed outside the bridge, so the state is always re-asserted
+	goveeClient.ForceRefresh(device.GoveeDeviceId)
+
+	if !device.IsOn() {
+		return goveeClient.TurnOff(device.GoveeDeviceId)
+	}
+
+	if err := goveeClient.TurnOn(device.GoveeDeviceId); err != nil {
+		return err
+	}
+	if err := goveeClient.SetColor(device.GoveeDeviceId, device.R, device.G, device.B); err != nil {
+		return err
+	}
+	return goveeClient.SetBrightness(device.GoveeDeviceId, device.Brightness)
+}
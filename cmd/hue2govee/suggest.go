@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// goveeDiscoveryDuration is how long commands listing Govee devices wait for discovery responses
+const goveeDiscoveryDuration = 5 * time.Second
+
+// runSuggestConfig discovers Govee devices and Hue lights and prints a starter config with one
+// synchronization per Govee device.
+//
+// Usage: hue2govee suggest-config [--bridge <name>]
+func runSuggestConfig(log zerolog.Logger, args []string) error {
+	flags := pflag.NewFlagSet("suggest-config", pflag.ContinueOnError)
+	bridgeName := flags.String("bridge", "", "name of the Hue bridge in hue_bridges, the first bridge if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bridge, err := lookupHueBridge(*bridgeName)
+	if err != nil {
+		return err
+	}
+	hueClient, err := newHueClient(log, bridge)
+	if err != nil {
+		return err
+	}
+	if err := hueClient.StartAutoDiscovery(ctx); err != nil {
+		return fmt.Errorf("failed to discover Hue bridge: %w", err)
+	}
+	lights, err := hueClient.GetLights(ctx)
+	if err != nil {
+		return err
+	}
+	rooms, err := hueClient.GetRooms(ctx)
+	if err != nil {
+		return err
+	}
+
+	devices, err := discoverGoveeDevices(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	writeSuggestedConfig(os.Stdout, bridge, devices, lights, rooms)
+	return nil
+}
+
+// discoverGoveeDevices runs Govee discovery for a few seconds and returns the discovered devices.
+func discoverGoveeDevices(ctx context.Context, log zerolog.Logger) ([]govee.DeviceInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, goveeDiscoveryDuration)
+	defer cancel()
+
+	goveeClient, err := newGoveeClient(log)
+	if err != nil {
+		return nil, err
+	}
+	defer goveeClient.Close()
+	if err := goveeClient.Discover(ctx); err != nil {
+		return nil, fmt.Errorf("failed to discover Govee devices: %w", err)
+	}
+	log.Info().Dur("duration", goveeDiscoveryDuration).Msg("Discovering Govee devices")
+
+	<-ctx.Done()
+	return goveeClient.DeviceInfos(), nil
+}
+
+// writeSuggestedConfig writes a starter config with one synchronization per Govee device and a commented
+// list of the available Hue lights of the given bridge. A bridge from hue_bridges is written as a hue_bridges
+// section that the synchronizations reference.
+func writeSuggestedConfig(w io.Writer, bridge config.HueBridge, devices []govee.DeviceInfo, lights []hue.Light,
+	rooms []hue.Room) {
+	fmt.Fprintln(w, "# Generated by hue2govee suggest-config, fill in the Hue light and room IDs from the list below")
+	named := bridge.Name != config.DefaultHueBridge
+	if named {
+		fmt.Fprintln(w, "hue_bridges:")
+		fmt.Fprintf(w, "- name: %q\n", bridge.Name)
+		fmt.Fprintf(w, "  id: %q\n", bridge.ID)
+		fmt.Fprintf(w, "  username: %q\n", bridge.Username)
+	} else {
+		fmt.Fprintf(w, "hue_bridge_id: %q\n", bridge.ID)
+		fmt.Fprintf(w, "hue_bridge_username: %q\n", bridge.Username)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "govee_multicast_ip: %q\n", viper.GetString("govee_multicast_ip"))
+	fmt.Fprintln(w)
+
+	sort.Slice(lights, func(i, j int) bool {
+		return lights[i].Metadata.Name < lights[j].Metadata.Name
+	})
+	fmt.Fprintln(w, "# Available Hue lights:")
+	for _, light := range lights {
+		room := lightRoom(light, rooms)
+		if room == nil {
+			fmt.Fprintf(w, "#   %s  %s\n", light.ID, light.Metadata.Name)
+			continue
+		}
+		fmt.Fprintf(w, "#   %s  %s (room %s: %s)\n", light.ID, light.Metadata.Name, room.Metadata.Name, room.ID)
+	}
+
+	fmt.Fprintln(w, "synchronizations:")
+	if len(devices) == 0 {
+		fmt.Fprintln(w, "# No Govee devices discovered, make sure LAN control is enabled in the Govee app")
+	}
+	for _, device := range devices {
+		comment := device.IP
+		if device.SKU != "" {
+			comment = device.SKU + " " + device.IP
+		}
+		fmt.Fprintf(w, "- govee_device_id: %q # %s\n", device.ID, comment)
+		if named {
+			fmt.Fprintf(w, "  hue_bridge: %q\n", bridge.Name)
+		}
+		fmt.Fprintln(w, `  hue_light_id: "" # TODO`)
+		fmt.Fprintln(w, `  hue_room_id: "" # TODO`)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `log_level: "INFO"`)
+}
+
+// lightRoom returns the room the light belongs to, or nil if it isn't assigned to a room.
+func lightRoom(light hue.Light, rooms []hue.Room) *hue.Room {
+	for _, room := range rooms {
+		if room.Contains(light) {
+			return &room
+		}
+	}
+	return nil
+}
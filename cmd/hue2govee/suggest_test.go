@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/spf13/viper"
+)
+
+func TestWriteSuggestedConfig(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("govee_multicast_ip", govee.DefaultMulticastIP)
+
+	devices := []govee.DeviceInfo{
+		{ID: "AA:BB:CC:DD:EE:FF:00:11", IP: "192.168.1.20", SKU: "H6199"},
+		{ID: "11:22:33:44:55:66:77:88", IP: "192.168.1.21"},
+	}
+	lights := []hue.Light{
+		{ID: "light-2", Metadata: hue.Metadata{Name: "Shelf"}},
+		{ID: "light-1", Owner: hue.Group{ID: "device-1"}, Metadata: hue.Metadata{Name: "Desk"}},
+	}
+	rooms := []hue.Room{{ID: "room-1", Metadata: hue.Metadata{Name: "Office"},
+		Children: []hue.Group{{ID: "device-1", Type: "device"}}}}
+
+	tests := []struct {
+		name   string
+		bridge config.HueBridge
+		want   []string
+	}{
+		{
+			name:   "default bridge",
+			bridge: config.HueBridge{Name: config.DefaultHueBridge, ID: "001788fffe000000", Username: "user"},
+			want: []string{
+				`hue_bridge_id: "001788fffe000000"`,
+				`hue_bridge_username: "user"`,
+				"#   light-1  Desk (room Office: room-1)\n#   light-2  Shelf\n",
+				`- govee_device_id: "AA:BB:CC:DD:EE:FF:00:11" # H6199 192.168.1.20`,
+				`- govee_device_id: "11:22:33:44:55:66:77:88" # 192.168.1.21`,
+			},
+		},
+		{
+			name:   "named bridge",
+			bridge: config.HueBridge{Name: "upstairs", ID: "001788fffe000000", Username: "user"},
+			want: []string{
+				"hue_bridges:\n- name: \"upstairs\"\n",
+				`  hue_bridge: "upstairs"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			writeSuggestedConfig(&out, tt.bridge, devices, lights, rooms)
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("suggested config doesn't contain %q:\n%s", want, out.String())
+				}
+			}
+
+			// the suggested config is valid YAML with one synchronization per device to fill in
+			v := viper.New()
+			v.SetConfigType("yaml")
+			if err := v.ReadConfig(&out); err != nil {
+				t.Fatalf("suggested config isn't valid YAML: %v", err)
+			}
+			var synchronizations []config.Synchronization
+			if err := v.UnmarshalKey("synchronizations", &synchronizations); err != nil {
+				t.Fatal(err)
+			}
+			if len(synchronizations) != len(devices) {
+				t.Fatalf("suggested %d synchronizations, want one per device", len(synchronizations))
+			}
+			for i, s := range synchronizations {
+				if s.GoveeDeviceId != devices[i].ID || s.HueLightId != "" {
+					t.Errorf("synchronization %d = %+v, want device %s with a light to fill in", i, s, devices[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteSuggestedConfigWithoutDevices(t *testing.T) {
+	var out bytes.Buffer
+	writeSuggestedConfig(&out, config.HueBridge{Name: config.DefaultHueBridge}, nil, nil, nil)
+	if !strings.Contains(out.String(), "No Govee devices discovered") {
+		t.Errorf("suggested config without devices doesn't explain why:\n%s", out.String())
+	}
+}
@@ -0,0 +1,993 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func startSynchronization(ctx context.Context, logger zerolog.Logger, hueClients hueClients, goveeClient *govee.Client, sc *hue.SceneController, store *state.Store) (*syncRunner, error) {
+	synchronizations, err := config.GetSynchronizations()
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to load synchronizations from config")
+		return nil, fmt.Errorf("failed to load synchronizations: %w", err)
+	}
+	for _, synchronization := range synchronizations {
+		if synchronization.BrightnessGamma != 0 {
+			logger.Warn().Str("syncId", synchronization.ID()).
+				Msgf("brightness_gamma is deprecated, use brightness_curve: gamma:%g instead",
+					synchronization.BrightnessGamma)
+		}
+	}
+
+	diyScenes, err := config.GetDIYScenes()
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to load Govee DIY scenes from config")
+		return nil, fmt.Errorf("failed to load govee DIY scenes: %w", err)
+	}
+
+	if viper.GetBool("restore_on_shutdown") && viper.GetBool("turn_off_on_shutdown") {
+		logger.Error().Msg("Only one of restore_on_shutdown and turn_off_on_shutdown can be set")
+		return nil, fmt.Errorf("both restore_on_shutdown and turn_off_on_shutdown are set")
+	}
+
+	minRGBDelta := viper.GetInt("min_rgb_delta")
+	if minRGBDelta < 0 {
+		logger.Error().Msg("Minimum RGB delta must not be negative")
+		return nil, fmt.Errorf("invalid min rgb delta %d", minRGBDelta)
+	}
+
+	registry := newSyncRegistry()
+	streaming := make(map[string]bool, len(hueClients))
+	for name, hueClient := range hueClients {
+		updates, err := hueClient.StreamEvents(ctx)
+		if err != nil {
+			logger.Warn().Err(err).Str("bridge", name).Msg("Hue event stream unavailable, falling back to polling")
+			continue
+		}
+		streaming[name] = true
+		// light IDs are unique across bridges, so the updates of all bridges are dispatched alike
+		go dispatchLightUpdates(updates, registry)
+	}
+
+	runner := &syncRunner{
+		ctx:         ctx,
+		logger:      logger,
+		hueClients:  hueClients,
+		goveeClient: goveeClient,
+		sc:          sc,
+		store:       store,
+		streaming:   streaming,
+		minRGBDelta: minRGBDelta,
+		restore:     viper.GetBool("restore_on_shutdown"),
+		registry:    registry,
+		rng:         rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+	runner.apply(synchronizations, diyScenes)
+
+	return runner, nil
+}
+
+// syncRunner starts and stops synchronizers, keeping the running synchronizers in line with the config.
+type syncRunner struct {
+	ctx         context.Context
+	logger      zerolog.Logger
+	hueClients  hueClients
+	goveeClient *govee.Client
+	sc          *hue.SceneController
+	store       *state.Store
+	streaming   map[string]bool // whether light updates of a bridge are streamed, keyed by the bridge name
+	minRGBDelta int
+	restore     bool // whether the state of the Govee devices is restored when their synchronization stops
+	registry    *syncRegistry
+	rng         *rand.Rand // offsets the first poll of each synchronization, replaceable for tests, r.mu must be held
+
+	mu        sync.Mutex // Mutex to serialize apply calls
+	diyScenes atomic.Pointer[map[string]int]
+}
+
+// apply starts synchronizers for new synchronizations, stops the ones of removed synchronizations and restarts
+// the ones of changed synchronizations. Unchanged synchronizations keep running undisturbed.
+func (r *syncRunner) apply(synchronizations []config.Synchronization, diyScenes map[string]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.diyScenes.Store(&diyScenes)
+
+	wanted := make(map[string]config.Synchronization, len(synchronizations))
+	for _, sync := range synchronizations {
+		wanted[sync.ID()] = sync
+	}
+
+	// restarted synchronizations keep the initial state of their Govee device, which they changed since
+	restarted := make(map[string]*synchronizer)
+	for _, s := range r.registry.all() {
+		sync, ok := wanted[s.sync.ID()]
+		if ok && reflect.DeepEqual(sync, s.sync) {
+			delete(wanted, s.sync.ID())
+			continue
+		}
+
+		s.stop()
+		r.registry.remove(s.sync.ID())
+		if ok && sync.GoveeDeviceId == s.sync.GoveeDeviceId {
+			restarted[sync.ID()] = s
+		} else {
+			s.restoreInitialState()
+		}
+		if !ok {
+			r.store.Remove(s.sync.ID())
+			r.logger.Info().Str("syncId", s.sync.ID()).Msg("Stopped removed synchronization")
+		}
+	}
+
+	for _, sync := range synchronizations {
+		if _, ok := wanted[sync.ID()]; ok {
+			r.start(sync, restarted[sync.ID()])
+		}
+	}
+}
+
+// stopAll stops all synchronizers, waiting until they don't send any more commands.
+func (r *syncRunner) stopAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.registry.all() {
+		s.stop()
+	}
+}
+
+// start starts a synchronizer for the given synchronization, taking over the initial state of the Govee device from
+// the previous synchronizer of a restarted synchronization, if any.
+func (r *syncRunner) start(sync config.Synchronization, previous *synchronizer) {
+	hueClient, ok := r.hueClients[sync.HueBridge]
+	if !ok {
+		// bridges are only connected on startup, a bridge added to the config later needs a restart
+		r.logger.Error().Str("syncId", sync.ID()).Msgf("Not synchronizing with unknown Hue bridge %s, restart to "+
+			"connect to bridges added to the config", sync.HueBridge)
+		if previous != nil {
+			previous.restoreInitialState()
+		}
+		return
+	}
+
+	r.logger.Info().Msgf("Synchronizing Hue light %s <--> Govee device %s", strings.Join(sync.LightIDs(), ", "),
+		sync.GoveeDeviceId)
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	s := &synchronizer{
+		sync:        sync,
+		logger:      r.logger,
+		hueClient:   hueClient,
+		goveeClient: r.goveeClient,
+		sc:          r.sc,
+		store:       r.store,
+		diyScenes:   &r.diyScenes,
+		streaming:   r.streaming[sync.HueBridge],
+		minRGBDelta: r.minRGBDelta,
+		restore:     r.restore,
+		stateClient: r.goveeClient,
+		pollOffset:  r.pollOffset(sync),
+		wake:        make(chan struct{}, 1),
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	if previous != nil {
+		s.initialCaptured, s.initialStatus = previous.initialCaptured, previous.initialStatus
+	}
+	if sync.BatchWindowMs > 0 {
+		s.batcher = govee.NewBatcher(r.goveeClient, time.Duration(sync.BatchWindowMs)*time.Millisecond, r.logger)
+	}
+	r.store.Update(sync.ID(), func(st *state.SyncState) {
+		st.HueLightID = strings.Join(sync.LightIDs(), ",")
+		st.GoveeDeviceID = sync.GoveeDeviceId
+	})
+	r.registry.add(s)
+	go s.run(ctx)
+}
+
+// pollOffset returns a random offset of the first poll within the poll interval of the synchronization, so
+// synchronizations started together don't poll the Hue bridge and send to the Govee devices in bursts. r.mu must
+// be held.
+func (r *syncRunner) pollOffset(sync config.Synchronization) time.Duration {
+	return time.Duration(r.rng.Int64N(int64(sync.PollInterval())))
+}
+
+// dispatchLightUpdates wakes every synchronization whose Hue lights changed according to the event stream.
+func dispatchLightUpdates(updates <-chan hue.LightUpdate, registry *syncRegistry) {
+	for update := range updates {
+		for _, s := range registry.all() {
+			if slices.Contains(s.sync.LightIDs(), update.ID) {
+				s.notify()
+			}
+		}
+	}
+}
+
+// synchronizer synchronizes a single Hue light with a Govee device.
+type synchronizer struct {
+	sync        config.Synchronization
+	logger      zerolog.Logger
+	hueClient   *hue.Client
+	goveeClient *govee.Client
+	sc          *hue.SceneController
+	store       *state.Store
+	diyScenes   *atomic.Pointer[map[string]int] // map[lowercase Hue scene name]Govee DIY code, shared by all synchronizers
+
+	cancel context.CancelFunc // stops the synchronizer
+	done   chan struct{}      // closed once the synchronizer stopped
+
+	estimator transitionEstimator
+	ramp      rampRunner
+	debounce  debouncer
+	batcher   *govee.Batcher // nil if batching is disabled
+	lastSent  *govee.State
+	paused    atomic.Bool
+	held      atomic.Bool // whether a manual color set on the Govee device is kept until resumed
+
+	animateStart bool // whether the next state is faded in from black
+
+	lastRecall       string    // last recall time of the auto-dynamic scene started early
+	autoDynamicUntil time.Time // grace period for the light's dynamics status to catch up with an auto-dynamic scene
+
+	streaming  bool          // whether Hue light changes are pushed by the event stream instead of polled
+	pollOffset time.Duration // delay of the first poll
+	wake       chan struct{} // signals a change of the Hue light received from the event stream
+	lastTick   time.Time     // last time the Hue light was synchronized
+
+	lightNames map[string]string // names of the Hue lights by ID, as of the last successful poll
+	dynamic    bool              // whether the Hue light played a dynamic scene as of the last successful poll
+	effect     *hue.Effect       // effect played on the Govee device, nil if none
+
+	minRGBDelta int // colors closer than this to the last sent color aren't sent
+
+	restore         bool              // whether the initial state of the Govee device is restored when stopped
+	initialCaptured bool              // whether the initial state of the Govee device was queried
+	initialStatus   *govee.StatusData // state of the Govee device before the first command, nil if unknown
+	stateClient     deviceStateClient // captures and restores the Govee device state, replaceable for tests
+
+	segmentsUnsupported bool // whether the Govee device turned out not to support segments
+
+	rateLimitBackoff time.Duration // current backoff after the Hue bridge rate limited requests, 0 if not limited
+	rateLimitedUntil time.Time     // the Hue light isn't polled before this time
+
+	lastStatus       *govee.StatusData // last observed Govee device status, nil until the first reverse sync
+	lightGamut       *hue.Gamut        // color gamut of the Hue light written by the reverse sync, nil until fetched
+	lastForwardWrite time.Time         // last time a changed state was sent to the Govee device
+	lastReverseWrite time.Time         // last time the Govee device state was written to the Hue light
+}
+
+// autoDynamicGracePeriod is how long a scene started from the auto_dynamic flag is kept running while the
+// light's dynamics status hasn't caught up yet
+const autoDynamicGracePeriod = 5 * time.Second
+
+// streamResyncInterval is how often the Hue light is polled even though the event stream is used, to recover
+// from missed events
+const streamResyncInterval = 10 * time.Second
+
+// run synchronizes the Hue light with the Govee device until the context is done. The Hue light is polled
+// unless the event stream is available, which wakes the synchronization up on changes.
+func (s *synchronizer) run(ctx context.Context) {
+	defer close(s.done)
+	defer s.stopPending()
+
+	s.animateStart = true
+	wasPaused := false
+	ticker := time.NewTicker(max(s.pollOffset, time.Millisecond))
+	defer ticker.Stop()
+	offset := true
+	for {
+		woken := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+			woken = true
+		case <-ticker.C:
+			if offset {
+				ticker.Reset(s.sync.PollInterval())
+				offset = false
+			}
+		}
+		if s.held.Load() {
+			// a manual color was set on the Govee device, which is kept until the synchronization is resumed
+			s.lastSent = nil
+			continue
+		}
+		if s.paused.Load() {
+			if !wasPaused {
+				s.playStopAnimation(ctx)
+			}
+			wasPaused = true
+			s.lastSent = nil
+			continue
+		}
+		if wasPaused {
+			s.animateStart = true
+			wasPaused = false
+		}
+		if s.sync.Direction.ToGovee() && time.Now().After(s.rateLimitedUntil) && (!s.streaming || woken ||
+			s.animateStart || s.debounce.Pending() || time.Since(s.lastTick) >= streamResyncInterval) {
+			s.lastTick = time.Now()
+			s.tick(ctx)
+		}
+		if s.sync.Direction.ToHue() {
+			s.reverseTick(ctx)
+		}
+	}
+}
+
+const (
+	// minRateLimitBackoff is the first backoff after the Hue bridge rate limited a request
+	minRateLimitBackoff = time.Second
+	// maxRateLimitBackoff caps the backoff after repeated rate limiting
+	maxRateLimitBackoff = 30 * time.Second
+)
+
+// rememberLightNames remembers the names of the Hue lights to refer to them by name in logs.
+func (s *synchronizer) rememberLightNames(lights []*hue.Light) {
+	if s.lightNames == nil {
+		s.lightNames = make(map[string]string, len(lights))
+	}
+	for _, light := range lights {
+		if light.Metadata.Name != "" {
+			s.lightNames[light.ID] = light.Metadata.Name
+		}
+	}
+}
+
+// describeLights returns the names of the Hue lights for logs, falling back to the ID of lights whose
+// name isn't known yet.
+func (s *synchronizer) describeLights() string {
+	ids := s.sync.LightIDs()
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := s.lightNames[id]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, id)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// getLights fetches the Hue lights of the synchronization, or its grouped light as a single light.
+func (s *synchronizer) getLights(ctx context.Context) ([]*hue.Light, error) {
+	if s.sync.HueGroupedLightId == "" {
+		return s.hueClient.GetLightsByID(ctx, s.sync.LightIDs())
+	}
+
+	group, err := s.hueClient.GetGroupedLight(ctx, s.sync.HueGroupedLightId)
+	if err != nil {
+		return nil, err
+	}
+	light := group.Light()
+	return []*hue.Light{&light}, nil
+}
+
+// backOffIfRateLimited pauses polling the Hue bridge if it rate limited the request, using the bridge's
+// Retry-After or an exponentially increasing backoff. Returns whether the error was a rate limit.
+func (s *synchronizer) backOffIfRateLimited(err error) bool {
+	if !hue.IsRateLimited(err) {
+		return false
+	}
+
+	s.rateLimitBackoff = min(max(s.rateLimitBackoff*2, minRateLimitBackoff), maxRateLimitBackoff)
+	backoff := s.rateLimitBackoff
+	var rateLimitErr *hue.RateLimitError
+	if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+		backoff = rateLimitErr.RetryAfter
+	}
+	s.rateLimitedUntil = time.Now().Add(backoff)
+	s.logger.Warn().Str("syncId", s.sync.ID()).Dur("backoff", backoff).Msg("Hue bridge rate limited requests, backing off")
+	return true
+}
+
+// stop stops the synchronizer and its dynamic scene, waiting until it doesn't send any more commands.
+func (s *synchronizer) stop() {
+	s.cancel()
+	<-s.done
+	s.sc.StopScene(s.sync.GoveeDeviceId)
+}
+
+// notify wakes the synchronization up because the Hue light changed.
+func (s *synchronizer) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// playStopAnimation fades the Govee device from the last sent state to black and turns it off, if a stop
+// animation is configured.
+func (s *synchronizer) playStopAnimation(ctx context.Context) {
+	if s.sync.StopAnimation <= 0 || s.lastSent == nil {
+		return
+	}
+
+	err := s.goveeClient.Ramp(ctx, s.sync.GoveeDeviceId, *s.lastSent, govee.State{}, s.sync.StopAnimation)
+	if err == nil {
+		err = s.goveeClient.TurnOff(s.sync.GoveeDeviceId)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) && !govee.IsDeviceNotFound(err) {
+		s.logger.Error().Err(err).Str("deviceId", s.sync.GoveeDeviceId).Msg("Failed to play stop animation")
+	}
+}
+
+// playStartAnimation fades the Govee device in from black to the target state if the synchronization just started
+// or was resumed and a start animation is configured. It returns whether the animation was started.
+func (s *synchronizer) playStartAnimation(ctx context.Context, target govee.State) bool {
+	if !s.animateStart {
+		return false
+	}
+	s.animateStart = false
+	if s.sync.StartAnimation <= 0 {
+		return false
+	}
+
+	s.ramp.Start(ctx, s.logger, s.goveeClient, s.sync.GoveeDeviceId, govee.State{}, target, s.sync.StartAnimation)
+	s.lastSent = &target
+	s.recordCommand(true, target)
+	return true
+}
+
+// tick performs a single synchronization of the Hue light with the Govee device.
+func (s *synchronizer) tick(ctx context.Context) {
+	lights, err := s.getLights(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			// the synchronization was stopped while waiting for the bridge
+			return
+		}
+		switch {
+		case s.backOffIfRateLimited(err):
+		case hue.IsLightNotFound(err):
+			s.logger.Error().Err(err).Strs("lightIds", s.sync.LightIDs()).Str("lights", s.describeLights()).
+				Msg("Hue light doesn't exist, check the configured light ID")
+		case hue.IsTransient(err):
+			s.logger.Warn().Err(err).Strs("lightIds", s.sync.LightIDs()).Str("lights", s.describeLights()).
+				Msg("Hue bridge temporarily unavailable")
+		default:
+			s.logger.Error().Err(err).Strs("lightIds", s.sync.LightIDs()).Str("lights", s.describeLights()).
+				Msg("Failed to get Hue light")
+		}
+		s.recordError(err)
+		return
+	}
+	s.rateLimitBackoff = 0
+	s.rememberLightNames(lights)
+	light := lights[0]
+	if len(lights) > 1 {
+		light = hue.AverageLights(lights)
+	}
+	s.store.Update(s.sync.ID(), func(st *state.SyncState) {
+		st.Hue = &state.HueState{
+			On:         light.On.On,
+			Brightness: light.Dimming.Brightness,
+			Dynamic:    light.Dynamics.Status == hue.DynamicsStatusActive,
+		}
+		st.Healthy = true
+		st.LastError = ""
+		st.DeviceFailedUntil = nil
+	})
+
+	if dynamic := light.Dynamics.Status == hue.DynamicsStatusActive; dynamic != s.dynamic {
+		// a scene was started or stopped, so the cached active scene of the room is outdated
+		s.dynamic = dynamic
+		s.hueClient.InvalidateActiveScene(s.sync.HueRoomId)
+	}
+
+	if s.reverseWriteRecent() {
+		// the Hue light still reflects the state copied from the Govee device
+		return
+	}
+
+	if !s.captureInitialState(ctx) {
+		// the state the Govee device has to be restored to isn't known until it's discovered
+		return
+	}
+
+	if !light.On.On {
+		s.stopPending()
+		s.debounce.Reset()
+		s.lastSent = nil
+		if s.effect != nil {
+			// the effect would turn the Govee device back on
+			s.sc.StopScene(s.sync.GoveeDeviceId)
+			s.effect = nil
+		}
+		if err := s.goveeClient.TurnOff(s.sync.GoveeDeviceId); err != nil {
+			if govee.IsDeviceNotFound(err) {
+				return
+			}
+			if !govee.IsDeviceFailed(err) {
+				s.logger.Error().Err(err).Str("deviceId",
+					s.sync.GoveeDeviceId).Msg("Failed to turn off Govee device")
+			}
+			s.recordError(err)
+			return
+		}
+		s.recordCommand(false, govee.State{})
+		return
+	}
+
+	// a fixed color ignores dynamic scenes just like any other color of the Hue light
+	if light.Dynamics.Status == hue.DynamicsStatusActive && s.sync.FixedRGB == nil {
+		s.stopPending()
+		s.debounce.Reset()
+		s.lastSent = nil
+		if s.effect != nil {
+			// the effect counts as an active scene and would keep the dynamic scene from starting
+			s.sc.StopScene(s.sync.GoveeDeviceId)
+			s.effect = nil
+		}
+		if s.sc.IsActive(s.sync.GoveeDeviceId) {
+			s.logger.Debug().Str("deviceId", s.sync.GoveeDeviceId).
+				Msg("Skipping Govee sync due to active scene")
+			return
+		}
+
+		scene, err := s.hueClient.GetActiveScene(ctx, s.sync.HueRoomId)
+		if err != nil {
+			if !s.backOffIfRateLimited(err) {
+				s.logger.Error().Err(err).Str("roomId", s.sync.HueRoomId).
+					Msg("Failed to get active scene for Hue room")
+			}
+			return
+		}
+
+		if scene == nil {
+			s.logger.Warn().Str("roomId", s.sync.HueRoomId).
+				Msg("No active scene found for Hue room")
+			return
+		}
+
+		s.setScene(*scene, hue.LightGamut(light))
+		return
+	}
+
+	// effects are ignored by a fixed color as well
+	if name, ok := light.ActiveEffect(); ok && s.sync.FixedRGB == nil {
+		s.setEffect(light, name)
+		return
+	}
+	s.effect = nil
+
+	if s.sc.IsActive(s.sync.GoveeDeviceId) {
+		if time.Now().Before(s.autoDynamicUntil) {
+			return
+		}
+		s.sc.StopScene(s.sync.GoveeDeviceId)
+		// the scene left the device in an unknown state, so the next color has to be sent
+		s.goveeClient.ForceRefresh(s.sync.GoveeDeviceId)
+		s.store.Update(s.sync.ID(), func(st *state.SyncState) {
+			st.ActiveScene = ""
+		})
+		s.logger.Info().Str("goveeDeviceId", s.sync.GoveeDeviceId).
+			Msgf("Stopped dynamic scene for Govee device %s", s.sync.GoveeDeviceId)
+	}
+
+	if s.sync.Segments && s.setSegments(lights, light) {
+		return
+	}
+
+	colorMode := light.ColorMode
+	if colorMode == hue.ColorModeCT && s.sync.CTOffset != 0 {
+		light.ColorTemperature.Mirek = hue.ShiftMirek(light.ColorTemperature.Mirek, s.sync.CTOffset)
+	}
+
+	if colorMode == hue.ColorModeCT && s.sync.Derive == nil && s.sync.FixedRGB == nil &&
+		s.setColorTemperature(light) {
+		return
+	}
+
+	if light.Gradient != nil && colorMode != hue.ColorModeCT {
+		if xy, ok := s.sync.GradientPointSelector.Select(light.Gradient.Points); ok {
+			light.Color.XY = xy
+		}
+	}
+
+	target := s.targetState(light)
+	if s.lastSent != nil && !colorChanged(s.lastSent.Color, target.Color, s.minRGBDelta) {
+		// keep the last sent color so conversion jitter doesn't cause needless updates
+		target.Color = s.lastSent.Color
+	}
+	if !s.debounce.Ready(target, s.lastSent, time.Now(), s.sync.Debounce(), s.sync.DebounceMaxHold()) {
+		return
+	}
+	if (s.lastSent == nil || *s.lastSent != target) && s.sync.FixedRGB == nil && s.startAutoDynamicScene(ctx, light) {
+		return
+	}
+
+	if s.sync.MatchTransitions {
+		duration := s.estimator.Observe(target, time.Now())
+		if duration > 0 && s.lastSent != nil {
+			s.logger.Debug().Str("deviceId", s.sync.GoveeDeviceId).Dur("duration", duration).
+				Msg("Hue light is transitioning, ramping Govee device")
+			s.ramp.Start(ctx, s.logger, s.goveeClient, s.sync.GoveeDeviceId, *s.lastSent, target, duration)
+			s.lastSent = &target
+			s.recordCommand(true, target)
+			return
+		}
+		s.ramp.Stop()
+	}
+
+	if s.playStartAnimation(ctx, target) {
+		return
+	}
+
+	if s.sync.TransitionMs > 0 && s.lastSent != nil {
+		current, fading := s.ramp.Current(time.Now())
+		if *s.lastSent == target {
+			if fading {
+				return // let the crossfade in flight finish
+			}
+		} else {
+			// a new target supersedes the crossfade in flight, continuing from where it is
+			from := *s.lastSent
+			if fading {
+				from = current
+			}
+			s.ramp.Start(ctx, s.logger, s.goveeClient, s.sync.GoveeDeviceId, from, target, s.sync.Transition())
+			s.lastSent = &target
+			s.recordCommand(true, target)
+			return
+		}
+	}
+
+	if s.batcher != nil {
+		s.batcher.Set(s.sync.GoveeDeviceId, target)
+		s.lastSent = &target
+		s.recordCommand(true, target)
+		return
+	}
+
+	if err := s.goveeClient.SetColor(s.sync.GoveeDeviceId, target.Color.R, target.Color.G, target.Color.B); err != nil {
+		if govee.IsDeviceNotFound(err) {
+			return
+		}
+		if !govee.IsDeviceFailed(err) {
+			s.logger.Error().Err(err).Str("deviceId",
+				s.sync.GoveeDeviceId).Msg("Failed to set Govee color")
+		}
+		s.recordError(err)
+	}
+	if err := s.goveeClient.SetBrightness(s.sync.GoveeDeviceId, target.Brightness); err != nil {
+		if govee.IsDeviceNotFound(err) {
+			return
+		}
+		if !govee.IsDeviceFailed(err) {
+			s.logger.Error().Err(err).Str("deviceId",
+				s.sync.GoveeDeviceId).Msg("Failed to set Govee brightness")
+		}
+		s.recordError(err)
+	}
+	s.lastSent = &target
+	s.recordCommand(true, target)
+}
+
+// targetState returns the color and brightness to send to the Govee device for the Hue light. The color is
+// converted at full brightness and dimmed by the brightness command, so a change of only the brightness doesn't
+// resend the color. A fixed color replaces the light's color, but is still dimmed with it.
+func (s *synchronizer) targetState(light *hue.Light) govee.State {
+	fullBrightness := 100
+	r, g, b := hue.ColorToRGB(light, &fullBrightness, s.conversionOptions())
+	if fixed := s.sync.FixedRGB; fixed != nil {
+		r, g, b = fixed.R, fixed.G, fixed.B
+	}
+	if s.sync.Derive != nil {
+		saturationScale, lightnessScale := s.sync.Derive.Scales()
+		r, g, b = hue.DeriveColor(r, g, b, s.sync.Derive.HueShift, saturationScale, lightnessScale)
+	}
+	return govee.State{Color: govee.RGBColor{R: r, G: g, B: b}, Brightness: s.brightness(light)}
+}
+
+// brightness returns the brightness in percent to send to the Govee device for the Hue light, which is sent
+// independently of the color.
+func (s *synchronizer) brightness(light *hue.Light) int {
+	if s.sync.FixedBrightness != nil {
+		return *s.sync.FixedBrightness
+	}
+	bri := hue.ApplyBrightnessGamma(light.Dimming.Percent(), s.sync.BrightnessCurveGamma)
+	floor, ceiling := s.sync.BrightnessRange()
+	return hue.RemapBrightness(bri, floor, ceiling)
+}
+
+// conversionOptions returns the options converting the Hue light's color to the RGB sent to the Govee device.
+func (s *synchronizer) conversionOptions() hue.ConversionOptions {
+	return hue.ConversionOptions{
+		ColorGamma:           s.sync.ColorGammaCurve,
+		BrightnessConversion: s.sync.BrightnessConversionMode,
+	}
+}
+
+// setSegments sends the colors of the synchronization's Hue lights, or the gradient points of a single gradient
+// light, to the segments of the Govee device, lights that are off leaving their segment black. Returns false if
+// there's only a single color or the device doesn't support segments, and the averaged color should be sent instead.
+func (s *synchronizer) setSegments(lights []*hue.Light, averaged *hue.Light) bool {
+	fullBrightness := 100
+	opts := s.conversionOptions()
+	var colors []govee.RGBColor
+	if len(lights) == 1 {
+		for _, color := range hue.GradientToRGBs(lights[0], &fullBrightness, opts) {
+			colors = append(colors, govee.RGBColor{R: color.R, G: color.G, B: color.B})
+		}
+	} else {
+		for _, light := range lights {
+			r, g, b := hue.ColorToRGB(light, &fullBrightness, opts)
+			colors = append(colors, govee.RGBColor{R: r, G: g, B: b})
+		}
+	}
+	if len(colors) < 2 {
+		return false
+	}
+	if len(colors) > govee.MaxSegments {
+		colors = colors[:govee.MaxSegments]
+	}
+	bri := s.brightness(averaged)
+
+	err := s.goveeClient.SetSegmentColors(s.sync.GoveeDeviceId, colors)
+	if govee.IsUnsupportedCommand(err) {
+		if !s.segmentsUnsupported {
+			s.segmentsUnsupported = true
+			s.logger.Warn().Str("deviceId", s.sync.GoveeDeviceId).
+				Msg("Govee device doesn't support segments, sending the averaged color instead")
+		}
+		return false
+	}
+
+	s.stopPending()
+	s.lastSent = nil // ramps can't start from segment colors
+	if err == nil {
+		err = s.goveeClient.SetBrightness(s.sync.GoveeDeviceId, bri)
+	}
+	if err != nil {
+		if govee.IsDeviceNotFound(err) {
+			return true
+		}
+		if !govee.IsDeviceFailed(err) {
+			s.logger.Error().Err(err).Str("deviceId",
+				s.sync.GoveeDeviceId).Msg("Failed to set Govee segment colors")
+		}
+		s.recordError(err)
+		return true
+	}
+
+	s.recordCommand(true, govee.State{Brightness: bri})
+	return true
+}
+
+// setColorTemperature sends the light's color temperature as a native Govee white instead of an RGB
+// approximation. Returns false if the device doesn't support color temperatures and RGB should be used instead.
+func (s *synchronizer) setColorTemperature(light *hue.Light) bool {
+	kelvin := 1000000 / light.ColorTemperature.Mirek
+	bri := s.brightness(light)
+
+	err := s.goveeClient.SetColorTemperature(s.sync.GoveeDeviceId, kelvin)
+	if govee.IsUnsupportedCommand(err) {
+		return false
+	}
+
+	s.stopPending()
+	s.lastSent = nil // RGB ramps can't start from a color temperature
+	if err == nil {
+		err = s.goveeClient.SetBrightness(s.sync.GoveeDeviceId, bri)
+	}
+	if err != nil {
+		if govee.IsDeviceNotFound(err) {
+			return true
+		}
+		if !govee.IsDeviceFailed(err) {
+			s.logger.Error().Err(err).Str("deviceId",
+				s.sync.GoveeDeviceId).Msg("Failed to set Govee color temperature")
+		}
+		s.recordError(err)
+		return true
+	}
+
+	s.store.Update(s.sync.ID(), func(st *state.SyncState) {
+		command := &state.Command{
+			On:               true,
+			ColorTemperature: kelvin,
+			Brightness:       bri,
+			SentAt:           time.Now(),
+		}
+		if st.LastCommand == nil || !st.LastCommand.SameState(*command) {
+			s.lastForwardWrite = command.SentAt
+		}
+		st.LastCommand = command
+	})
+	return true
+}
+
+// startAutoDynamicScene starts the dynamic scene right away if the scene recalled in the room has the
+// auto_dynamic flag set, instead of waiting for the light's dynamics status to update.
+// Returns true if a scene was started.
+func (s *synchronizer) startAutoDynamicScene(ctx context.Context, light *hue.Light) bool {
+	scene, err := s.hueClient.GetRecalledScene(ctx, s.sync.HueRoomId)
+	if err != nil {
+		s.logger.Error().Err(err).Str("roomId", s.sync.HueRoomId).
+			Msg("Failed to get recalled scene for Hue room")
+		return false
+	}
+	if scene == nil || !scene.AutoDynamic || len(scene.Palette.Color) == 0 || scene.Status.LastRecall == s.lastRecall {
+		return false
+	}
+
+	s.logger.Info().Str("sceneId", scene.ID).Str("deviceId", s.sync.GoveeDeviceId).
+		Msg("Recalled scene is auto-dynamic, starting dynamic scene")
+	s.lastRecall = scene.Status.LastRecall
+	s.autoDynamicUntil = time.Now().Add(autoDynamicGracePeriod)
+	s.lastSent = nil
+	s.setScene(*scene, hue.LightGamut(light))
+	return true
+}
+
+// setScene starts the dynamic scene on the Govee device. Scenes mapped to a built-in Govee scene or a Govee
+// DIY scene by name activate that scene, all others cycle through their palette limited to the configured size,
+// converted like the colors of the light within its gamut.
+func (s *synchronizer) setScene(scene hue.Scene, gamut hue.Gamut) {
+	if code, ok := s.sync.GoveeSceneCode(scene.Metadata.Name); ok {
+		err := s.sc.SetDeviceScene(s.sync.GoveeDeviceId, code)
+		if err == nil {
+			s.store.Update(s.sync.ID(), func(st *state.SyncState) {
+				st.ActiveScene = scene.ID
+			})
+			return
+		}
+		if govee.IsDeviceNotFound(err) {
+			return
+		}
+		s.logger.Error().Err(err).Str("sceneName", scene.Metadata.Name).
+			Msg("Failed to activate Govee scene, falling back to DIY scenes and palette cycling")
+	}
+
+	if code, ok := (*s.diyScenes.Load())[strings.ToLower(scene.Metadata.Name)]; ok {
+		err := s.sc.SetDIYScene(s.sync.GoveeDeviceId, code)
+		if err == nil {
+			s.store.Update(s.sync.ID(), func(st *state.SyncState) {
+				st.ActiveScene = scene.ID
+			})
+			return
+		}
+		if govee.IsDeviceNotFound(err) {
+			return
+		}
+		s.logger.Error().Err(err).Str("sceneName", scene.Metadata.Name).
+			Msg("Failed to activate Govee DIY scene, falling back to palette cycling")
+	}
+
+	scene.Palette.Color = hue.LimitPalette(scene.Palette.Color, s.sync.MaxPaletteColors)
+	if limit := s.sync.MaxPaletteColors; limit > 0 {
+		// color temperatures share the limit with the colors of the palette
+		remaining := limit - len(scene.Palette.Color)
+		if remaining > 0 {
+			scene.Palette.ColorTemperature = hue.LimitPalette(scene.Palette.ColorTemperature, remaining)
+		} else {
+			scene.Palette.ColorTemperature = nil
+		}
+	}
+	s.sc.SetScene(s.sync.GoveeDeviceId, scene, s.conversionOptions(), gamut)
+	s.store.Update(s.sync.ID(), func(st *state.SyncState) {
+		st.ActiveScene = scene.ID
+	})
+}
+
+// setEffect plays the effect of the Hue light on the Govee device, unless it already plays it. Effects mapped to a
+// built-in Govee scene activate that scene, all others are approximated by the scene controller.
+func (s *synchronizer) setEffect(light *hue.Light, name string) {
+	s.stopPending()
+	s.debounce.Reset()
+	s.lastSent = nil
+
+	fullBrightness := 100
+	r, g, b := hue.ColorToRGB(light, &fullBrightness, s.conversionOptions())
+	effect := hue.Effect{Name: name, Color: hue.RGBColor{R: r, G: g, B: b}, Brightness: s.brightness(light)}
+	if s.effect != nil && *s.effect == effect && s.sc.IsActive(s.sync.GoveeDeviceId) {
+		return
+	}
+
+	if code, ok := s.sync.GoveeEffectSceneCode(name); ok {
+		err := s.sc.SetDeviceScene(s.sync.GoveeDeviceId, code)
+		if err == nil {
+			s.effect = &effect
+			return
+		}
+		if govee.IsDeviceNotFound(err) {
+			return
+		}
+		s.logger.Error().Err(err).Str("effect", name).
+			Msg("Failed to activate Govee scene, falling back to approximating the effect")
+	}
+
+	s.sc.SetEffect(s.sync.GoveeDeviceId, effect)
+	s.effect = &effect
+}
+
+// setPaused pauses or resumes forwarding of the Hue light's state to the Govee device. Resuming also releases a
+// synchronization held for a manual color.
+func (s *synchronizer) setPaused(paused bool) {
+	wasHeld := !paused && s.held.Swap(false)
+	if s.paused.Swap(paused) == paused && !wasHeld {
+		return
+	}
+
+	if paused {
+		s.stopPending()
+		s.sc.StopScene(s.sync.GoveeDeviceId)
+	}
+	s.store.Update(s.sync.ID(), func(st *state.SyncState) {
+		st.Paused = paused
+		if paused {
+			st.ActiveScene = ""
+		}
+	})
+	s.logger.Info().Str("syncId", s.sync.ID()).Bool("paused", paused).Msg("Changed synchronization pause state")
+}
+
+// hold stops copying the Hue light state to the Govee device until the synchronization is resumed, so a manual
+// color set on the device isn't overwritten by the next tick. Held synchronizations are reported as paused.
+func (s *synchronizer) hold() {
+	if s.held.Swap(true) {
+		return
+	}
+
+	s.stopPending()
+	s.store.Update(s.sync.ID(), func(st *state.SyncState) {
+		st.Paused = true
+		st.ActiveScene = ""
+	})
+	s.logger.Info().Str("syncId", s.sync.ID()).Msg("Holding synchronization for a manual color until resumed")
+}
+
+// stopPending stops any in-flight ramp and discards batched updates.
+func (s *synchronizer) stopPending() {
+	s.ramp.Stop()
+	if s.batcher != nil {
+		s.batcher.Stop()
+	}
+}
+
+// recordCommand records the last command sent to the Govee device in the state store.
+func (s *synchronizer) recordCommand(on bool, sent govee.State) {
+	s.store.Update(s.sync.ID(), func(st *state.SyncState) {
+		command := &state.Command{
+			On:         on,
+			Color:      state.RGBColor{R: sent.Color.R, G: sent.Color.G, B: sent.Color.B},
+			Brightness: sent.Brightness,
+			SentAt:     time.Now(),
+		}
+		if st.LastCommand == nil || !st.LastCommand.SameState(*command) {
+			s.lastForwardWrite = command.SentAt
+		}
+		st.LastCommand = command
+	})
+}
+
+// recordError marks the synchronization as unhealthy in the state store.
+func (s *synchronizer) recordError(err error) {
+	health := s.goveeClient.DeviceHealth(s.sync.GoveeDeviceId)
+	s.store.Update(s.sync.ID(), func(st *state.SyncState) {
+		st.Healthy = false
+		st.LastError = err.Error()
+		st.DeviceFailedUntil = nil
+		if !health.FailedUntil.IsZero() {
+			st.DeviceFailedUntil = &health.FailedUntil
+		}
+	})
+}
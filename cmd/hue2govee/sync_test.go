@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/rs/zerolog"
+)
+
+func TestSynchronizerBrightness(t *testing.T) {
+	fixed := 40
+	tests := []struct {
+		name string
+		sync config.Synchronization
+		// want maps the Hue brightness in percent to the Govee brightness
+		want map[float64]int
+	}{
+		{name: "linear", sync: config.Synchronization{BrightnessCurveGamma: 1},
+			want: map[float64]int{0: 0, 1: 1, 25: 25, 50: 50, 99.6: 100, 100: 100}},
+		// regression: the V2 brightness is a percent, it was scaled like the V1 brightness of 0-254 before
+		{name: "V2 brightness is a percent", sync: config.Synchronization{BrightnessCurveGamma: 1},
+			want: map[float64]int{50.8: 51, 100: 100}},
+		{name: "unset curve is linear", sync: config.Synchronization{},
+			want: map[float64]int{0: 0, 25: 25, 50: 50, 100: 100}},
+		// 0.25^0.6 ≈ 0.435, 0.5^0.6 ≈ 0.660
+		{name: "gamma lifts low levels", sync: config.Synchronization{BrightnessCurveGamma: 0.6},
+			want: map[float64]int{0: 0, 25: 44, 50: 66, 100: 100}},
+		// 0.5^2 = 0.25
+		{name: "gamma above 1 darkens", sync: config.Synchronization{BrightnessCurveGamma: 2},
+			want: map[float64]int{0: 0, 50: 25, 100: 100}},
+		// a light that is on at its lowest level keeps the device at the floor instead of turning it off
+		{name: "floor", sync: config.Synchronization{MinBrightness: 20},
+			want: map[float64]int{0: 20, 0.4: 20, 1: 21, 50: 60, 100: 100}},
+		{name: "ceiling", sync: config.Synchronization{MaxBrightness: 50},
+			want: map[float64]int{0: 0, 50: 25, 100: 50}},
+		// the curve is applied before remapping, 0.25^0.5 = 0.5 lands in the middle of the range
+		{name: "gamma and range",
+			sync: config.Synchronization{BrightnessCurveGamma: 0.5, MinBrightness: 10, MaxBrightness: 90},
+			want: map[float64]int{0: 10, 25: 50, 100: 90}},
+		{name: "fixed brightness ignores the curve",
+			sync: config.Synchronization{BrightnessCurveGamma: 0.5, FixedBrightness: &fixed},
+			want: map[float64]int{0: 40, 50: 40, 100: 40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &synchronizer{sync: tt.sync}
+			for hueBri, want := range tt.want {
+				light := &hue.Light{Dimming: hue.Dimming{Brightness: hueBri}}
+				if got := s.brightness(light); got != want {
+					t.Errorf("brightness(%v%%) = %d, want %d", hueBri, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSynchronizerRunStopsOnCancel(t *testing.T) {
+	s := &synchronizer{
+		sync: config.Synchronization{PollIntervalMs: 5},
+		done: make(chan struct{}),
+		wake: make(chan struct{}, 1),
+	}
+	s.held.Store(true) // skips the ticks, which would need a Hue and Govee client
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.run(ctx)
+
+	// let the ticker fire a few times and wake the synchronization up in between
+	time.Sleep(30 * time.Millisecond)
+	s.notify()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+	select {
+	case <-s.done:
+	case <-time.After(time.Second):
+		t.Fatal("synchronizer didn't stop after its context was canceled")
+	}
+}
+
+// The benchmarks compare waiting for the next poll with a new timer per iteration to waiting with a ticker reused
+// across iterations, as in synchronizer.run. Each iteration is ended by a wake-up before the poll interval, as
+// with the Hue event stream, which abandons the timer created by time.After.
+
+func BenchmarkPollWaitTimeAfter(b *testing.B) {
+	wake := make(chan struct{}, 1)
+	b.ReportAllocs()
+	for range b.N {
+		wake <- struct{}{}
+		select {
+		case <-wake:
+		case <-time.After(time.Hour):
+		}
+	}
+}
+
+func BenchmarkPollWaitTicker(b *testing.B) {
+	wake := make(chan struct{}, 1)
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	b.ReportAllocs()
+	for range b.N {
+		wake <- struct{}{}
+		select {
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func TestPollOffset(t *testing.T) {
+	sync := config.Synchronization{PollIntervalMs: 500}
+	offsets := func(seed uint64) []time.Duration {
+		r := &syncRunner{rng: rand.New(rand.NewPCG(seed, seed))}
+		offsets := make([]time.Duration, 20)
+		for i := range offsets {
+			offsets[i] = r.pollOffset(sync)
+		}
+		return offsets
+	}
+
+	got := offsets(1)
+	seen := make(map[time.Duration]bool, len(got))
+	var quarters [4]int
+	for _, offset := range got {
+		if offset < 0 || offset >= sync.PollInterval() {
+			t.Errorf("offset %s out of the poll interval of %s", offset, sync.PollInterval())
+			continue
+		}
+		if seen[offset] {
+			t.Errorf("offset %s drawn twice, want distinct offsets", offset)
+		}
+		seen[offset] = true
+		quarters[offset*4/sync.PollInterval()]++
+	}
+	// the offsets spread across the whole poll interval
+	for i, n := range quarters {
+		if n == 0 {
+			t.Errorf("no offset in quarter %d of the poll interval, offsets: %v", i, got)
+		}
+	}
+
+	if again := offsets(1); !slices.Equal(again, got) {
+		t.Errorf("offsets with the same seed = %v, want %v", again, got)
+	}
+}
+
+func TestSynchronizerLogsLightNames(t *testing.T) {
+	var gone atomic.Bool
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gone.Load() {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[{"description":"Not Found"}],"data":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors":[],"data":[{"id":"light-1","metadata":{"name":"Living Room Lamp"},` +
+			`"on":{"on":true},"dimming":{"brightness":100}}]}`))
+	}))
+	t.Cleanup(server.Close)
+	hueClient := hue.NewClient("", "test-user", zerolog.Nop())
+	if err := hueClient.SetBridgeAddress(strings.TrimPrefix(server.URL, "https://")); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	s := &synchronizer{
+		sync:        config.Synchronization{HueLightId: "light-1", GoveeDeviceId: testGoveeDeviceID},
+		logger:      zerolog.New(&logs),
+		hueClient:   hueClient,
+		goveeClient: govee.NewClient(zerolog.Nop(), govee.DefaultMulticastIP),
+		store:       state.NewStore(),
+	}
+	if got := s.describeLights(); got != "light-1" {
+		t.Errorf("describeLights() before the first poll = %q, want the light ID", got)
+	}
+
+	lights, err := s.getLights(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.rememberLightNames(lights)
+	if got := s.describeLights(); got != "Living Room Lamp" {
+		t.Errorf("describeLights() = %q, want the name of the light", got)
+	}
+
+	// the light is deleted, the error refers to it by the name it had
+	gone.Store(true)
+	s.tick(context.Background())
+	if !strings.Contains(logs.String(), `"lights":"Living Room Lamp"`) {
+		t.Errorf("log doesn't refer to the light by name:\n%s", logs.String())
+	}
+}
+
+func TestSynchronizerGetsGroupedLight(t *testing.T) {
+	fixture, err := os.ReadFile("../../internal/hue/testdata/grouped_light.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_, _ = w.Write(fixture)
+	}))
+	t.Cleanup(server.Close)
+	hueClient := hue.NewClient("", "test-user", zerolog.Nop())
+	if err := hueClient.SetBridgeAddress(strings.TrimPrefix(server.URL, "https://")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &synchronizer{
+		sync:      config.Synchronization{HueGroupedLightId: "grouped-light-1", GoveeDeviceId: testGoveeDeviceID},
+		hueClient: hueClient,
+	}
+	lights, err := s.getLights(context.Background())
+	if err != nil {
+		t.Fatalf("getLights() returned error: %v", err)
+	}
+	if want := []string{"/clip/v2/resource/grouped_light/grouped-light-1"}; !slices.Equal(paths, want) {
+		t.Errorf("requested %v, want %v", paths, want)
+	}
+	if len(lights) != 1 || lights[0].ID != "grouped-light-1" || lights[0].ColorMode != hue.ColorModeXY {
+		t.Errorf("getLights() = %+v, want the grouped light as a single light", lights)
+	}
+}
+
+func TestSynchronizerBrightnessConversion(t *testing.T) {
+	warm := &hue.Light{On: hue.On{On: true}, Dimming: hue.Dimming{Brightness: 20}, ColorMode: hue.ColorModeXY,
+		Color: hue.Color{XY: hue.Coords{X: 0.4573, Y: 0.41}}}
+
+	// the color is converted at full brightness with the conversion of the synchronization, the brightness is sent
+	// separately
+	luminance := (&synchronizer{sync: config.Synchronization{
+		BrightnessConversionMode: hue.BrightnessConversionLuminance}}).targetState(warm)
+	multiply := (&synchronizer{sync: config.Synchronization{
+		BrightnessConversionMode: hue.BrightnessConversionMultiply}}).targetState(warm)
+	opts := hue.ConversionOptions{BrightnessConversion: hue.BrightnessConversionMultiply}
+	full := *warm
+	full.Dimming.Brightness = 100
+	r, g, b := hue.ColorToRGB(&full, nil, opts)
+	if want := (govee.RGBColor{R: r, G: g, B: b}); multiply.Color != want {
+		t.Errorf("targetState() with multiply = %+v, want the color converted with multiply %+v", multiply.Color, want)
+	}
+	if luminance.Color == multiply.Color {
+		t.Errorf("targetState() with luminance and multiply = %+v, want different colors", luminance.Color)
+	}
+	if luminance.Brightness != 20 || multiply.Brightness != 20 {
+		t.Errorf("brightness = %d and %d, want the light's brightness 20", luminance.Brightness, multiply.Brightness)
+	}
+}
+
+func TestSynchronizerFixedColor(t *testing.T) {
+	blue := &hue.Light{On: hue.On{On: true}, Dimming: hue.Dimming{Brightness: 40}, ColorMode: hue.ColorModeXY,
+		Color: hue.Color{XY: hue.Coords{X: 0.1532, Y: 0.0475}}}
+	accent := &govee.RGBColor{R: 255}
+
+	s := &synchronizer{sync: config.Synchronization{FixedRGB: accent}}
+	if got, want := s.targetState(blue), (govee.State{Color: *accent, Brightness: 40}); got != want {
+		t.Errorf("targetState() = %+v, want the fixed color at the light's brightness %+v", got, want)
+	}
+
+	// the brightness still follows the light and its configured range
+	s = &synchronizer{sync: config.Synchronization{FixedRGB: accent, MinBrightness: 20}}
+	dimmed := *blue
+	dimmed.Dimming.Brightness = 0
+	if got, want := s.targetState(&dimmed), (govee.State{Color: *accent, Brightness: 20}); got != want {
+		t.Errorf("targetState() of a dimmed light = %+v, want the fixed color at the floor %+v", got, want)
+	}
+
+	// without a fixed color, the light's color is sent
+	s = &synchronizer{sync: config.Synchronization{}}
+	if got := s.targetState(blue); got.Color == *accent || got.Color.B == 0 {
+		t.Errorf("targetState() without a fixed color = %+v, want the light's blue", got)
+	}
+}
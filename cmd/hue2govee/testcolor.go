@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+)
+
+// runTestColor sends a color and brightness to a Govee device, holds it for a while and exits.
+//
+// Usage: hue2govee test-color --device <id> [--color <color>] [--brightness <0-100>] [--hold <duration>] [--off]
+// [--preview=false]
+//
+// The color correction configured for the device in govee_devices is applied, so this can be used to calibrate it.
+// The color sent is previewed in the terminal unless stdout is piped.
+func runTestColor(log zerolog.Logger, args []string) error {
+	flags := pflag.NewFlagSet("test-color", pflag.ContinueOnError)
+	device := flags.String("device", "", "ID or alias of the Govee device")
+	color := flags.String("color", "white", "hex color or color name to send")
+	brightness := flags.Int("brightness", 100, "brightness (0-100) to send")
+	hold := flags.Duration("hold", 5*time.Second, "how long to hold the color before exiting")
+	off := flags.Bool("off", false, "turn the device off instead of sending a color")
+	preview := flags.Bool("preview", true, "print a block of the color sent if stdout is a terminal")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *device == "" {
+		return errors.New("usage: hue2govee test-color --device <id> [--color <color>] [--brightness <0-100>] [--hold <duration>] [--off]")
+	}
+	deviceID, err := config.ResolveGoveeDeviceID(*device)
+	if err != nil {
+		return err
+	}
+	rgb, err := hue.ParseColor(*color)
+	if err != nil {
+		return err
+	}
+	if *brightness < 0 || *brightness > 100 {
+		return fmt.Errorf("brightness must be between 0 and 100, got %d", *brightness)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goveeClient, err := newGoveeClient(log)
+	if err != nil {
+		return err
+	}
+	defer goveeClient.Close()
+	if err := applyDeviceSettings(goveeClient); err != nil {
+		return err
+	}
+	if err := goveeClient.Discover(ctx); err != nil {
+		return fmt.Errorf("failed to discover Govee devices: %w", err)
+	}
+	if err := sendTestColor(ctx, goveeClient, deviceID, rgb, *brightness, *off); err != nil {
+		return err
+	}
+	if *off {
+		log.Info().Str("deviceId", deviceID).Msg("Turned off Govee device")
+		return nil
+	}
+	log.Info().Str("deviceId", deviceID).Str("color", *color).Int("brightness", *brightness).
+		Msgf("Sent color to Govee device, holding it for %s", *hold)
+	if *preview {
+		previewColor(rgb)
+	}
+
+	time.Sleep(*hold)
+	return nil
+}
+
+// colorSender sends the test color to a Govee device, implemented by the Govee client
+type colorSender interface {
+	WaitForDevice(ctx context.Context, deviceID string) error
+	TurnOn(deviceID string) error
+	TurnOff(deviceID string) error
+	SetColor(deviceID string, r, g, b int) error
+	SetBrightness(deviceID string, value int) error
+}
+
+// sendTestColor waits until the Govee device is discovered and turns it on with the color and brightness, or turns
+// it off.
+func sendTestColor(ctx context.Context, client colorSender, deviceID string, rgb hue.RGBColor, brightness int,
+	off bool) error {
+	if err := client.WaitForDevice(ctx, deviceID); err != nil {
+		return err
+	}
+
+	if off {
+		if err := client.TurnOff(deviceID); err != nil {
+			return fmt.Errorf("failed to turn off device: %w", err)
+		}
+		return nil
+	}
+
+	if err := client.TurnOn(deviceID); err != nil {
+		return fmt.Errorf("failed to turn on device: %w", err)
+	}
+	if err := client.SetColor(deviceID, rgb.R, rgb.G, rgb.B); err != nil {
+		return fmt.Errorf("failed to set color: %w", err)
+	}
+	if err := client.SetBrightness(deviceID, brightness); err != nil {
+		return fmt.Errorf("failed to set brightness: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/rs/zerolog"
+)
+
+// stubColorSender records the commands sent to it, failing those starting with failCommand.
+type stubColorSender struct {
+	calls       []string
+	failCommand string
+}
+
+func (s *stubColorSender) record(call string) error {
+	s.calls = append(s.calls, call)
+	if s.failCommand != "" && strings.HasPrefix(call, s.failCommand) {
+		return fmt.Errorf("%w: %s", govee.ErrDeviceNotFound, call)
+	}
+	return nil
+}
+
+func (s *stubColorSender) WaitForDevice(_ context.Context, deviceID string) error {
+	return s.record("wait " + deviceID)
+}
+
+func (s *stubColorSender) TurnOn(deviceID string) error {
+	return s.record("on " + deviceID)
+}
+
+func (s *stubColorSender) TurnOff(deviceID string) error {
+	return s.record("off " + deviceID)
+}
+
+func (s *stubColorSender) SetColor(deviceID string, r, g, b int) error {
+	return s.record(fmt.Sprintf("color %s %d %d %d", deviceID, r, g, b))
+}
+
+func (s *stubColorSender) SetBrightness(deviceID string, value int) error {
+	return s.record(fmt.Sprintf("brightness %s %d", deviceID, value))
+}
+
+func TestSendTestColor(t *testing.T) {
+	color := hue.RGBColor{R: 0x33, G: 0xaa, B: 0xff}
+	tests := []struct {
+		name        string
+		off         bool
+		failCommand string
+		want        []string
+		wantErr     string
+	}{
+		{name: "color", want: []string{"wait " + testGoveeDeviceID, "on " + testGoveeDeviceID,
+			"color " + testGoveeDeviceID + " 51 170 255", "brightness " + testGoveeDeviceID + " 80"}},
+		{name: "off", off: true, want: []string{"wait " + testGoveeDeviceID, "off " + testGoveeDeviceID}},
+		{name: "device not discovered", failCommand: "wait", want: []string{"wait " + testGoveeDeviceID},
+			wantErr: "device not found"},
+		{name: "color fails", failCommand: "color", want: []string{"wait " + testGoveeDeviceID,
+			"on " + testGoveeDeviceID, "color " + testGoveeDeviceID + " 51 170 255"}, wantErr: "failed to set color"},
+		{name: "off fails", off: true, failCommand: "off",
+			want: []string{"wait " + testGoveeDeviceID, "off " + testGoveeDeviceID}, wantErr: "failed to turn off"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &stubColorSender{failCommand: tt.failCommand}
+
+			err := sendTestColor(context.Background(), client, testGoveeDeviceID, color, 80, tt.off)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("sendTestColor() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				if !errors.Is(err, govee.ErrDeviceNotFound) {
+					t.Errorf("sendTestColor() = %v, want the client's error wrapped", err)
+				}
+			} else if err != nil {
+				t.Errorf("sendTestColor() returned error: %v", err)
+			}
+			if !slices.Equal(client.calls, tt.want) {
+				t.Errorf("sent %q, want %q", client.calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunTestColorUsage(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "missing device", args: nil, wantErr: "usage: hue2govee test-color"},
+		{name: "invalid color", args: []string{"--device", testGoveeDeviceID, "--color", "chartreuse"},
+			wantErr: "must be a hex color"},
+		{name: "brightness out of range", args: []string{"--device", testGoveeDeviceID, "--brightness", "101"},
+			wantErr: "brightness must be between 0 and 100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runTestColor(zerolog.Nop(), tt.args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runTestColor(%q) = %v, want an error containing %q", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
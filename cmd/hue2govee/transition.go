@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/rs/zerolog"
+)
+
+// maxTransitionStep is the longest single ramp derived from consecutive Hue samples
+const maxTransitionStep = 5 * time.Second
+
+// transitionEstimator estimates whether a Hue light is in the middle of a transition from consecutive samples.
+//
+// When the Hue app changes a light with a transition time, the bridge reports intermediate states across
+// several polls. Once two consecutive samples differ, the light is considered transitioning and the time
+// between samples is used as the duration of the Govee ramp to the latest sample, so that the fade length
+// on Govee matches the one observed on Hue.
+type transitionEstimator struct {
+	last     govee.State
+	lastSeen time.Time
+	moving   bool
+}
+
+// Observe records a new sample and returns the duration to ramp to it, or zero if it should be applied directly.
+func (e *transitionEstimator) Observe(state govee.State, now time.Time) time.Duration {
+	if e.lastSeen.IsZero() {
+		e.last, e.lastSeen = state, now
+		return 0
+	}
+
+	changed := state != e.last
+	elapsed := now.Sub(e.lastSeen)
+	wasMoving := e.moving
+
+	e.last, e.lastSeen, e.moving = state, now, changed
+
+	if !changed || !wasMoving {
+		return 0
+	}
+	return min(elapsed, maxTransitionStep)
+}
+
+// rampRunner runs a single cancellable ramp per device, a new ramp superseding an in-flight one.
+type rampRunner struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+
+	from, to govee.State
+	started  time.Time
+	duration time.Duration
+}
+
+// Start starts a new ramp, stopping the ramp currently in flight.
+func (r *rampRunner) Start(ctx context.Context, logger zerolog.Logger, goveeClient *govee.Client, deviceID string,
+	from, to govee.State, duration time.Duration) {
+	r.Stop()
+
+	rampCtx, cancel := context.WithCancel(ctx)
+	r.mu.Lock()
+	r.cancel = cancel
+	r.from, r.to, r.started, r.duration = from, to, time.Now(), duration
+	r.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := goveeClient.Ramp(rampCtx, deviceID, from, to, duration)
+		if err != nil && !errors.Is(err, context.Canceled) && !govee.IsDeviceNotFound(err) && !govee.IsDeviceFailed(err) {
+			logger.Error().Err(err).Str("deviceId", deviceID).Msg("Failed to ramp Govee device")
+		}
+	}()
+}
+
+// Stop stops the ramp currently in flight, if any.
+func (r *rampRunner) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
+
+// Current returns the state the ramp in flight has reached at the given time, or false if no ramp is in flight.
+func (r *rampRunner) Current(now time.Time) (govee.State, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elapsed := now.Sub(r.started)
+	if r.cancel == nil || elapsed >= r.duration {
+		return govee.State{}, false
+	}
+	return govee.InterpolateState(r.from, r.to, float64(elapsed)/float64(r.duration)), true
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/rs/zerolog"
+)
+
+func TestTransitionEstimatorObserve(t *testing.T) {
+	red := govee.State{Color: govee.RGBColor{R: 255}, Brightness: 100}
+	dimRed := govee.State{Color: govee.RGBColor{R: 255}, Brightness: 60}
+	dimmerRed := govee.State{Color: govee.RGBColor{R: 255}, Brightness: 20}
+	blue := govee.State{Color: govee.RGBColor{B: 255}, Brightness: 20}
+
+	type sample struct {
+		state govee.State
+		at    time.Duration // time of the sample since the first one
+		want  time.Duration
+	}
+	tests := []struct {
+		name    string
+		samples []sample
+	}{
+		{
+			name: "steady",
+			samples: []sample{
+				{state: red, at: 0, want: 0},
+				{state: red, at: time.Second, want: 0},
+				{state: red, at: 2 * time.Second, want: 0},
+			},
+		},
+		{
+			name: "single change is applied directly",
+			samples: []sample{
+				{state: red, at: 0, want: 0},
+				{state: dimRed, at: time.Second, want: 0},
+				{state: dimRed, at: 2 * time.Second, want: 0},
+			},
+		},
+		{
+			name: "transition ramps over the sample interval",
+			samples: []sample{
+				{state: red, at: 0, want: 0},
+				{state: dimRed, at: 500 * time.Millisecond, want: 0},
+				{state: dimmerRed, at: time.Second, want: 500 * time.Millisecond},
+				{state: blue, at: 1700 * time.Millisecond, want: 700 * time.Millisecond},
+				{state: blue, at: 2200 * time.Millisecond, want: 0},
+			},
+		},
+		{
+			name: "ramp is capped",
+			samples: []sample{
+				{state: red, at: 0, want: 0},
+				{state: dimRed, at: time.Second, want: 0},
+				{state: dimmerRed, at: 11 * time.Second, want: maxTransitionStep},
+			},
+		},
+		{
+			name: "transition restarts after settling",
+			samples: []sample{
+				{state: red, at: 0, want: 0},
+				{state: dimRed, at: time.Second, want: 0},
+				{state: dimRed, at: 2 * time.Second, want: 0},
+				{state: dimmerRed, at: 3 * time.Second, want: 0},
+				{state: blue, at: 4 * time.Second, want: time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e transitionEstimator
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, s := range tt.samples {
+				if got := e.Observe(s.state, start.Add(s.at)); got != s.want {
+					t.Errorf("Observe() of sample %d = %s, want %s", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRampRunner(t *testing.T) {
+	// the device is unknown, so the ramp fails right away, but the runner still tracks its progress
+	goveeClient := govee.NewClient(zerolog.Nop(), govee.DefaultMulticastIP)
+	black := govee.State{Brightness: 0}
+	white := govee.State{Color: govee.RGBColor{R: 255, G: 255, B: 255}, Brightness: 100}
+
+	var r rampRunner
+	if _, ok := r.Current(time.Now()); ok {
+		t.Error("Current() before the first ramp = true, want no ramp in flight")
+	}
+
+	r.Start(context.Background(), zerolog.Nop(), goveeClient, "AA:BB", black, white, time.Minute)
+	halfway := r.started.Add(30 * time.Second)
+	if got, ok := r.Current(halfway); !ok || got != govee.InterpolateState(black, white, 0.5) {
+		t.Errorf("Current() halfway = %+v, %v, want the state halfway from black to white", got, ok)
+	}
+	if _, ok := r.Current(r.started.Add(time.Minute)); ok {
+		t.Error("Current() at the end of the ramp = true, want the ramp to be done")
+	}
+
+	// a new ramp supersedes the one in flight
+	r.Start(context.Background(), zerolog.Nop(), goveeClient, "AA:BB", white, black, 10*time.Second)
+	if got, ok := r.Current(r.started.Add(5 * time.Second)); !ok || got != govee.InterpolateState(white, black, 0.5) {
+		t.Errorf("Current() halfway through the new ramp = %+v, %v, want the state halfway to black", got, ok)
+	}
+
+	r.Stop()
+	if _, ok := r.Current(r.started.Add(5 * time.Second)); ok {
+		t.Error("Current() after Stop() = true, want no ramp in flight")
+	}
+}
+
+func TestPlayStartAnimation(t *testing.T) {
+	target := govee.State{Color: govee.RGBColor{R: 255, G: 128}, Brightness: 80}
+	newSynchronizer := func(animation time.Duration) *synchronizer {
+		return &synchronizer{
+			sync:         config.Synchronization{GoveeDeviceId: "AA:BB", StartAnimation: animation},
+			logger:       zerolog.Nop(),
+			goveeClient:  govee.NewClient(zerolog.Nop(), govee.DefaultMulticastIP),
+			store:        state.NewStore(),
+			animateStart: true,
+		}
+	}
+
+	s := newSynchronizer(time.Second)
+	if !s.playStartAnimation(context.Background(), target) {
+		t.Fatal("playStartAnimation() = false, want the fade-in started")
+	}
+	if got, ok := s.ramp.Current(s.ramp.started.Add(500 * time.Millisecond)); !ok ||
+		got != govee.InterpolateState(govee.State{}, target, 0.5) {
+		t.Errorf("Current() halfway = %+v, %v, want the state halfway from black to the target", got, ok)
+	}
+	if s.lastSent == nil || *s.lastSent != target {
+		t.Errorf("lastSent = %+v, want the target %+v", s.lastSent, target)
+	}
+	// the animation is only played once per start
+	if s.playStartAnimation(context.Background(), target) {
+		t.Error("playStartAnimation() after the fade-in = true, want false")
+	}
+
+	s = newSynchronizer(0)
+	if s.playStartAnimation(context.Background(), target) {
+		t.Error("playStartAnimation() without a start animation = true, want the target sent directly")
+	}
+	if s.animateStart {
+		t.Error("animateStart is still set, want it cleared by the first state")
+	}
+}
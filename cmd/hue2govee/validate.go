@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// checkConfiguredDevices waits for Govee discovery and checks that all Govee devices referenced in the config
+// were discovered. Unknown devices are logged as a warning, or returned as an error if strict_config is set.
+// Without strict_config, the check runs in the background to not delay the startup.
+func checkConfiguredDevices(ctx context.Context, log zerolog.Logger, goveeClient *govee.Client) error {
+	configured, err := configuredDeviceIDs()
+	if err != nil {
+		return err
+	}
+
+	check := func() error {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(goveeDiscoveryDuration):
+		}
+
+		unknown := unknownDeviceIDs(configured, goveeClient.KnownDeviceIDs())
+		if len(unknown) == 0 {
+			return nil
+		}
+		return fmt.Errorf("govee devices %s weren't discovered, discovered devices are: %s",
+			strings.Join(unknown, ", "), strings.Join(goveeClient.KnownDeviceIDs(), ", "))
+	}
+
+	if viper.GetBool("strict_config") {
+		return check()
+	}
+	go func() {
+		if err := check(); err != nil {
+			log.Warn().Err(err).Msg("Config references unknown Govee devices, check the device IDs for typos")
+		}
+	}()
+	return nil
+}
+
+// checkConfiguredLights fetches every Hue light referenced by a synchronization once, so typos and stale IDs are
+// reported at startup instead of by every poll. The result is logged as a single summary, and returned as an error
+// if strict_config is set and any light doesn't exist or couldn't be fetched.
+func checkConfiguredLights(ctx context.Context, log zerolog.Logger, hueClients hueClients) error {
+	synchronizations, err := config.GetSynchronizations()
+	if err != nil {
+		return err
+	}
+
+	var checked int
+	var missing, failed []string
+	seen := make(map[string]bool)
+	for _, sync := range synchronizations {
+		hueClient := hueClients[sync.HueBridge]
+		for _, id := range sync.LightIDs() {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			checked++
+
+			if sync.HueGroupedLightId != "" {
+				_, err = hueClient.GetGroupedLight(ctx, id)
+			} else {
+				_, err = hueClient.GetLight(ctx, id)
+			}
+			switch {
+			case err == nil:
+			case hue.IsLightNotFound(err):
+				missing = append(missing, fmt.Sprintf("%s (%s)", id, sync.ID()))
+			default:
+				failed = append(failed, fmt.Sprintf("%s (%s): %v", id, sync.ID(), err))
+			}
+		}
+	}
+
+	if len(missing) == 0 && len(failed) == 0 {
+		log.Info().Int("lights", checked).Msg("All configured Hue lights exist")
+		return nil
+	}
+	summary := fmt.Sprintf("%d of %d configured Hue lights failed the check, missing: [%s], failed: [%s]",
+		len(missing)+len(failed), checked, strings.Join(missing, ", "), strings.Join(failed, "; "))
+	if viper.GetBool("strict_config") {
+		return errors.New(summary)
+	}
+	log.Error().Msg(summary + ", check the light IDs for typos")
+	return nil
+}
+
+// configuredDeviceIDs returns the IDs of all Govee devices referenced in the config.
+func configuredDeviceIDs() ([]string, error) {
+	var ids []string
+
+	synchronizations, err := config.GetSynchronizations()
+	if err != nil {
+		return nil, err
+	}
+	for _, sync := range synchronizations {
+		ids = append(ids, sync.GoveeDeviceId)
+	}
+
+	staticDevices, err := config.GetStaticDevices()
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range staticDevices {
+		ids = append(ids, device.GoveeDeviceId)
+	}
+
+	devices, err := config.GetGoveeDevices()
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		ids = append(ids, device.GoveeDeviceId)
+	}
+
+	return ids, nil
+}
+
+// unknownDeviceIDs returns the sorted, distinct configured IDs that are not known.
+func unknownDeviceIDs(configured, known []string) []string {
+	var unknown []string
+	for _, id := range configured {
+		if !slices.Contains(known, id) && !slices.Contains(unknown, id) {
+			unknown = append(unknown, id)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/config"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestUnknownDeviceIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured []string
+		known      []string
+		want       []string
+	}{
+		{name: "all known", configured: []string{"AA", "BB"}, known: []string{"AA", "BB", "CC"}},
+		{name: "nothing discovered", configured: []string{"BB", "AA"}, want: []string{"AA", "BB"}},
+		{name: "typo", configured: []string{"AA", "BX", "CC"}, known: []string{"AA", "BB", "CC"},
+			want: []string{"BX"}},
+		{name: "duplicates are reported once", configured: []string{"BX", "AA", "BX"}, known: []string{"AA"},
+			want: []string{"BX"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unknownDeviceIDs(tt.configured, tt.known); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unknownDeviceIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// loadTestConfig loads the YAML config, resetting viper after the test.
+func loadTestConfig(t *testing.T, yaml string) {
+	t.Helper()
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestConfig(t, file, yaml)
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	config.RegisterFlags(flags)
+	config.MustLoad(file, flags)
+}
+
+func TestConfiguredDeviceIDs(t *testing.T) {
+	loadTestConfig(t, "govee_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    alias: porch\n"+
+		"static_devices:\n  - device_id: porch\n    color: blue\n    brightness: 100\n"+
+		"synchronizations:\n"+deskSync)
+
+	ids, err := configuredDeviceIDs()
+	if err != nil {
+		t.Fatalf("configuredDeviceIDs() returned error: %v", err)
+	}
+	// the alias of the static device is resolved to the device ID
+	want := []string{"AA:BB:CC:DD:EE:FF:00:11", "11:22:33:44:55:66:77:88", "11:22:33:44:55:66:77:88"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("configuredDeviceIDs() = %v, want %v", ids, want)
+	}
+}
+
+func TestCheckConfiguredLights(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/clip/v2/resource/light/light-1", "/clip/v2/resource/grouped_light/group-1":
+			_, _ = w.Write([]byte(`{"errors":[],"data":[{"id":"light-1","on":{"on":true}}]}`))
+		case "/clip/v2/resource/light/light-3":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"errors":[{"description":"internal error"}],"data":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[{"description":"Not Found"}],"data":[]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	hueClient := hue.NewClient("", "test-user", zerolog.Nop())
+	if err := hueClient.SetBridgeAddress(strings.TrimPrefix(server.URL, "https://")); err != nil {
+		t.Fatal(err)
+	}
+	hueClient.SetRequestsPerSecond(1000)
+	clients := hueClients{config.DefaultHueBridge: hueClient}
+
+	const syncs = "synchronizations:\n" +
+		"  - name: desk\n    hue_light_ids: [light-1, light-2]\n    govee_device_id: AA:BB:CC:DD:EE:FF:00:11\n" +
+		"  - name: shelf\n    hue_light_id: light-3\n    govee_device_id: AA:BB:CC:DD:EE:FF:00:22\n" +
+		"  - name: room\n    hue_grouped_light_id: group-1\n    govee_device_id: AA:BB:CC:DD:EE:FF:00:33\n" +
+		"  - name: lamp\n    hue_light_id: light-1\n    govee_device_id: AA:BB:CC:DD:EE:FF:00:44\n"
+
+	loadTestConfig(t, syncs)
+	var logs bytes.Buffer
+	if err := checkConfiguredLights(context.Background(), zerolog.New(&logs), clients); err != nil {
+		t.Fatalf("checkConfiguredLights() without strict_config returned error: %v", err)
+	}
+	// every light is checked once, even if several synchronizations use it
+	want := "2 of 4 configured Hue lights failed the check, missing: [light-2 (desk)], failed: [light-3 (shelf): "
+	if !strings.Contains(logs.String(), want) || strings.Count(logs.String(), "\n") != 1 {
+		t.Errorf("log = %s, want a single summary containing %q", logs.String(), want)
+	}
+
+	loadTestConfig(t, syncs+"strict_config: true\n")
+	err := checkConfiguredLights(context.Background(), zerolog.Nop(), clients)
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("checkConfiguredLights() with strict_config = %v, want the summary as error", err)
+	}
+
+	loadTestConfig(t, "synchronizations:\n"+
+		"  - name: lamp\n    hue_light_id: light-1\n    govee_device_id: AA:BB:CC:DD:EE:FF:00:44\n"+
+		"strict_config: true\n")
+	logs.Reset()
+	if err := checkConfiguredLights(context.Background(), zerolog.New(&logs), clients); err != nil {
+		t.Errorf("checkConfiguredLights() of existing lights returned error: %v", err)
+	}
+	if !strings.Contains(logs.String(), "All configured Hue lights exist") {
+		t.Errorf("log = %s, want the lights reported as existing", logs.String())
+	}
+}
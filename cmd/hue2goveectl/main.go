@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cedrickring/hue-to-govee/internal/control"
+)
+
+const usage = `Usage: hue2goveectl [-socket path] <command> [args]
+
+Commands:
+  status                  Print the status of all synchronizations
+  pause <id>              Pause the synchronization with the given ID
+  resume <id>             Resume the synchronization with the given ID
+  all-off                 Pause all synchronizations and turn off their Govee devices
+  rediscover              Rediscover the Hue bridge and Govee devices
+  set <device> <color>    Set the color of a Govee device by ID or alias, e.g. '#FF8800'
+`
+
+func main() {
+	socket := flag.String("socket", defaultSocket(), "path of the hue2govee control socket")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(os.Stdout, *socket, flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// run sends the command to the daemon and prints its response to out.
+func run(out io.Writer, socket, command string, args []string) error {
+	client, err := control.Dial(socket)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	data, err := client.Do(command, args...)
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		fmt.Fprintln(out, "OK")
+		return nil
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		return fmt.Errorf("invalid response data: %w", err)
+	}
+	fmt.Fprintln(out, indented.String())
+	return nil
+}
+
+// defaultSocket returns the socket path from the HUE2GOVEE_SOCKET environment variable, falling back to
+// hue2govee.sock in the working directory.
+func defaultSocket() string {
+	if socket := os.Getenv("HUE2GOVEE_SOCKET"); socket != "" {
+		return socket
+	}
+	return "hue2govee.sock"
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/control"
+	"github.com/rs/zerolog"
+)
+
+// testController is a control.Controller reporting a fixed status.
+type testController struct {
+	paused []string
+}
+
+func (c *testController) Status() any {
+	return map[string]bool{"nightMode": true}
+}
+
+func (c *testController) Pause(id string) error {
+	c.paused = append(c.paused, id)
+	return nil
+}
+
+func (c *testController) Resume(string) error          { return nil }
+func (c *testController) AllOff() error                { return nil }
+func (c *testController) Rediscover() error            { return nil }
+func (c *testController) Set(deviceID, _ string) error { return nil }
+
+func TestRun(t *testing.T) {
+	dir, err := os.MkdirTemp("", "hue2goveectl") // t.TempDir may exceed the maximum socket path length
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "hue2govee.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	controller := &testController{}
+	if err := control.NewServer(socket, controller, zerolog.Nop()).Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := run(&out, socket, control.CommandStatus, nil); err != nil {
+		t.Fatalf("run(status) returned error: %v", err)
+	}
+	if want := "{\n  \"nightMode\": true\n}\n"; out.String() != want {
+		t.Errorf("run(status) printed %q, want the indented status %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := run(&out, socket, control.CommandPause, []string{"desk"}); err != nil {
+		t.Fatalf("run(pause) returned error: %v", err)
+	}
+	if out.String() != "OK\n" || len(controller.paused) != 1 || controller.paused[0] != "desk" {
+		t.Errorf("run(pause desk) printed %q and paused %v, want OK and desk paused", out.String(),
+			controller.paused)
+	}
+
+	if err := run(&out, socket, "explode", nil); err == nil {
+		t.Error("run() of an unknown command = nil, want the error of the daemon")
+	}
+	if err := run(&out, filepath.Join(dir, "missing.sock"), control.CommandStatus, nil); err == nil {
+		t.Error("run() without a daemon = nil, want an error")
+	}
+}
+
+func TestDefaultSocket(t *testing.T) {
+	t.Setenv("HUE2GOVEE_SOCKET", "")
+	if got := defaultSocket(); got != "hue2govee.sock" {
+		t.Errorf("defaultSocket() = %q, want hue2govee.sock", got)
+	}
+	t.Setenv("HUE2GOVEE_SOCKET", "/run/hue2govee.sock")
+	if got := defaultSocket(); got != "/run/hue2govee.sock" {
+		t.Errorf("defaultSocket() with HUE2GOVEE_SOCKET = %q, want /run/hue2govee.sock", got)
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+)
+
+// writeTimeout is how long writing a single update to a WebSocket client may take
+const writeTimeout = 5 * time.Second
+
+// Feed notifies about every change of a synchronization state. Updates are dropped for subscribers that
+// don't keep up, so a slow client never blocks a synchronization.
+type Feed interface {
+	Subscribe() (<-chan state.SyncState, func())
+}
+
+// Update is sent to WebSocket clients whenever a command is sent to a Govee device
+type Update struct {
+	SyncID     string         `json:"syncId"`
+	Device     string         `json:"device"`
+	On         bool           `json:"on"`
+	RGB        state.RGBColor `json:"rgb"`
+	Brightness int            `json:"brightness"`
+	SentAt     time.Time      `json:"sentAt"`
+}
+
+// checkOrigin returns a check accepting WebSocket connections from clients that aren't browsers, from pages served
+// by the API's own host and from the allowed origins, e.g. "http://dashboard.local:3000". Browsers send the origin of
+// the page opening the connection, so any other web page the user visits can't subscribe to the feed.
+func checkOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if slices.ContainsFunc(allowedOrigins, func(allowed string) bool {
+			return strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin)
+		}) {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// serveFeed upgrades the request to a WebSocket connection and streams an Update for every command sent to a
+// Govee device until the client disconnects. Connections from other origins than allowedOrigins are rejected.
+func serveFeed(feed Feed, allowedOrigins []string, logger zerolog.Logger) http.HandlerFunc {
+	upgrader := websocket.Upgrader{CheckOrigin: checkOrigin(allowedOrigins)}
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader already responded with an error
+			return
+		}
+		defer conn.Close()
+
+		updates, unsubscribe := feed.Subscribe()
+		defer unsubscribe()
+
+		// clients don't send anything, but reading is required to notice that they closed the connection
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		sent := make(map[string]time.Time) // time of the last command sent per synchronization
+		for {
+			select {
+			case <-closed:
+				return
+			case <-r.Context().Done():
+				return
+			case st, ok := <-updates:
+				if !ok {
+					return
+				}
+				cmd := st.LastCommand
+				if cmd == nil || cmd.SentAt.Equal(sent[st.ID]) {
+					continue
+				}
+				sent[st.ID] = cmd.SentAt
+
+				conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+				err := conn.WriteJSON(Update{
+					SyncID:     st.ID,
+					Device:     st.GoveeDeviceID,
+					On:         cmd.On,
+					RGB:        cmd.Color,
+					Brightness: cmd.Brightness,
+					SentAt:     cmd.SentAt,
+				})
+				if err != nil {
+					logger.Debug().Err(err).Msg("Failed to write to WebSocket client, closing connection")
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+)
+
+// dialFeed connects a WebSocket client to the feed served with the given store.
+func dialFeed(t *testing.T, store *state.Store) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(Handler(&fakeController{store: store}, store, nil, zerolog.Nop()))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("failed to connect to the feed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUpdates reads the updates sent to the client until the connection is closed.
+func readUpdates(conn *websocket.Conn) <-chan Update {
+	updates := make(chan Update, 64)
+	go func() {
+		defer close(updates)
+		for {
+			var update Update
+			if err := conn.ReadJSON(&update); err != nil {
+				return
+			}
+			updates <- update
+		}
+	}()
+	return updates
+}
+
+// pushColor simulates the synchronization sending a color to its Govee device.
+func pushColor(store *state.Store, color state.RGBColor) {
+	store.Update("desk", func(st *state.SyncState) {
+		st.GoveeDeviceID = "AA:BB:CC:DD:EE:FF:00:11"
+		st.LastCommand = &state.Command{On: true, Color: color, Brightness: 80, SentAt: time.Now()}
+	})
+}
+
+func TestFeed(t *testing.T) {
+	store := state.NewStore()
+	updates := readUpdates(dialFeed(t, store))
+
+	// the client may not be subscribed right away, so the color is pushed until the first update arrives
+	red := state.RGBColor{R: 255}
+	var update Update
+	timeout := time.After(2 * time.Second)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+waitForUpdate:
+	for {
+		select {
+		case update = <-updates:
+			break waitForUpdate
+		case <-ticker.C:
+			pushColor(store, red)
+		case <-timeout:
+			t.Fatal("received no update after pushing a color")
+		}
+	}
+	if update.SyncID != "desk" || update.Device != "AA:BB:CC:DD:EE:FF:00:11" || !update.On || update.RGB != red ||
+		update.Brightness != 80 {
+		t.Errorf("update = %+v, want red at 80%% for desk", update)
+	}
+
+	// changes without a new command aren't sent
+	store.Update("desk", func(st *state.SyncState) { st.Paused = true })
+	green := state.RGBColor{G: 255}
+	pushColor(store, green)
+	sent := map[time.Time]bool{update.SentAt: true}
+	for update.RGB != green {
+		select {
+		case update = <-updates:
+		case <-time.After(2 * time.Second):
+			t.Fatal("received no update after pushing another color")
+		}
+		if sent[update.SentAt] {
+			t.Errorf("command sent at %v was sent twice", update.SentAt)
+		}
+		sent[update.SentAt] = true
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{name: "no browser", origin: "", want: true},
+		{name: "same host", origin: "http://bridge.local:8080", want: true},
+		{name: "allowed origin", origin: "http://dashboard.local:3000", want: true},
+		{name: "allowed origin in other case", origin: "http://Dashboard.local:3000", want: true},
+		{name: "other origin", origin: "http://evil.example", want: false},
+		{name: "other port", origin: "http://bridge.local:9090", want: false},
+	}
+	check := checkOrigin([]string{"http://dashboard.local:3000/"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://bridge.local:8080/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := check(r); got != tt.want {
+				t.Errorf("checkOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedRejectsOtherOrigins(t *testing.T) {
+	server := httptest.NewServer(Handler(newFakeController(), state.NewStore(), nil, zerolog.Nop()))
+	defer server.Close()
+
+	header := http.Header{"Origin": []string{"http://evil.example"}}
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", header)
+	if err == nil {
+		t.Fatal("connecting from another origin succeeded, want it rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("response = %v, want %d", resp, http.StatusForbidden)
+	}
+}
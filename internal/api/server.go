@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/rs/zerolog"
+)
+
+// Controller inspects and controls the running synchronizations
+type Controller interface {
+	// Syncs returns the state of all synchronizations
+	Syncs() []state.SyncState
+	// Pause pauses forwarding of the synchronization with the given ID
+	Pause(id string) error
+	// Resume resumes forwarding of the synchronization with the given ID
+	Resume(id string) error
+}
+
+// errorBody is the body of error responses
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// Handler returns the handler serving the REST API.
+//
+// GET /syncs lists all synchronizations with the state last pushed to their Govee device,
+// POST /syncs/{id}/pause and POST /syncs/{id}/resume pause and resume a synchronization and return its state.
+// GET /ws streams every command sent to a Govee device over a WebSocket connection, accepting browser connections
+// from the API's own host and the allowed origins only.
+func Handler(controller Controller, feed Feed, allowedOrigins []string, logger zerolog.Logger) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ws", serveFeed(feed, allowedOrigins, logger))
+	mux.HandleFunc("GET /syncs", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, controller.Syncs())
+	})
+	mux.HandleFunc("POST /syncs/{id}/pause", func(w http.ResponseWriter, r *http.Request) {
+		setPaused(w, controller, r.PathValue("id"), controller.Pause)
+	})
+	mux.HandleFunc("POST /syncs/{id}/resume", func(w http.ResponseWriter, r *http.Request) {
+		setPaused(w, controller, r.PathValue("id"), controller.Resume)
+	})
+	return mux
+}
+
+// setPaused pauses or resumes the synchronization with the given ID and writes its new state.
+func setPaused(w http.ResponseWriter, controller Controller, id string, change func(id string) error) {
+	if findSync(controller, id) == nil {
+		writeJSON(w, http.StatusNotFound, errorBody{Error: "no synchronization with ID " + id})
+		return
+	}
+	if err := change(id); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorBody{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, findSync(controller, id))
+}
+
+// findSync returns the state of the synchronization with the given ID, nil if there is none.
+func findSync(controller Controller, id string) *state.SyncState {
+	syncs := controller.Syncs()
+	i := slices.IndexFunc(syncs, func(st state.SyncState) bool {
+		return st.ID == id
+	})
+	if i < 0 {
+		return nil
+	}
+	return &syncs[i]
+}
+
+// Serve serves the REST API at the given address until the context is done.
+func Serve(ctx context.Context, addr string, controller Controller, feed Feed, allowedOrigins []string,
+	logger zerolog.Logger) {
+	server := &http.Server{Addr: addr, Handler: Handler(controller, feed, allowedOrigins, logger)}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info().Str("addr", addr).Msg("Serving REST API")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error().Err(err).Msg("REST API server failed")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
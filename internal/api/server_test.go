@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/rs/zerolog"
+)
+
+// fakeController pauses and resumes the synchronizations in its store. Pausing the broken synchronization fails.
+type fakeController struct {
+	store *state.Store
+}
+
+func newFakeController(ids ...string) *fakeController {
+	store := state.NewStore()
+	for _, id := range ids {
+		store.Update(id, func(st *state.SyncState) {
+			st.LastCommand = &state.Command{On: true, Color: state.RGBColor{R: 255}, Brightness: 80}
+		})
+	}
+	return &fakeController{store: store}
+}
+
+func (c *fakeController) Syncs() []state.SyncState {
+	states, _ := c.store.Snapshot()
+	return states
+}
+
+func (c *fakeController) Pause(id string) error {
+	if id == "broken" {
+		return errors.New("failed to pause")
+	}
+	c.store.Update(id, func(st *state.SyncState) { st.Paused = true })
+	return nil
+}
+
+func (c *fakeController) Resume(id string) error {
+	c.store.Update(id, func(st *state.SyncState) { st.Paused = false })
+	return nil
+}
+
+// do sends the request to the handler and decodes the JSON response into body.
+func do(t *testing.T, handler http.Handler, method, path string, body any) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("%s %s Content-Type = %q, want application/json", method, path, contentType)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(body); err != nil {
+		t.Fatalf("failed to decode response of %s %s: %v", method, path, err)
+	}
+	return rec.Code
+}
+
+func TestListSyncs(t *testing.T) {
+	handler := Handler(newFakeController("shelf", "desk"), nil, nil, zerolog.Nop())
+
+	var syncs []state.SyncState
+	if code := do(t, handler, http.MethodGet, "/syncs", &syncs); code != http.StatusOK {
+		t.Fatalf("GET /syncs status = %d, want %d", code, http.StatusOK)
+	}
+	if len(syncs) != 2 || syncs[0].ID != "desk" || syncs[1].ID != "shelf" {
+		t.Fatalf("GET /syncs = %+v, want desk and shelf", syncs)
+	}
+	want := state.Command{On: true, Color: state.RGBColor{R: 255}, Brightness: 80}
+	if cmd := syncs[0].LastCommand; cmd == nil || !cmd.SameState(want) {
+		t.Errorf("LastCommand = %+v, want the pushed color %+v", cmd, want)
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	controller := newFakeController("desk", "shelf")
+	handler := Handler(controller, nil, nil, zerolog.Nop())
+
+	var st state.SyncState
+	if code := do(t, handler, http.MethodPost, "/syncs/desk/pause", &st); code != http.StatusOK {
+		t.Fatalf("POST /syncs/desk/pause status = %d, want %d", code, http.StatusOK)
+	}
+	if st.ID != "desk" || !st.Paused {
+		t.Errorf("POST /syncs/desk/pause = %+v, want desk paused", st)
+	}
+	if shelf, _ := controller.store.Get("shelf"); shelf.Paused {
+		t.Error("shelf is paused, want only desk paused")
+	}
+
+	// pausing again keeps the synchronization paused
+	if code := do(t, handler, http.MethodPost, "/syncs/desk/pause", &st); code != http.StatusOK || !st.Paused {
+		t.Errorf("POST /syncs/desk/pause again = %d %+v, want desk still paused", code, st)
+	}
+
+	st = state.SyncState{}
+	if code := do(t, handler, http.MethodPost, "/syncs/desk/resume", &st); code != http.StatusOK {
+		t.Fatalf("POST /syncs/desk/resume status = %d, want %d", code, http.StatusOK)
+	}
+	if st.ID != "desk" || st.Paused {
+		t.Errorf("POST /syncs/desk/resume = %+v, want desk resumed", st)
+	}
+}
+
+func TestPauseErrors(t *testing.T) {
+	handler := Handler(newFakeController("desk", "broken"), nil, nil, zerolog.Nop())
+
+	tests := []struct {
+		path string
+		code int
+		err  string
+	}{
+		{path: "/syncs/unknown/pause", code: http.StatusNotFound, err: "no synchronization with ID unknown"},
+		{path: "/syncs/unknown/resume", code: http.StatusNotFound, err: "no synchronization with ID unknown"},
+		{path: "/syncs/broken/pause", code: http.StatusInternalServerError, err: "failed to pause"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var body errorBody
+			if code := do(t, handler, http.MethodPost, tt.path, &body); code != tt.code {
+				t.Errorf("POST %s status = %d, want %d", tt.path, code, tt.code)
+			}
+			if body.Error != tt.err {
+				t.Errorf("POST %s error = %q, want %q", tt.path, body.Error, tt.err)
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	handler := Handler(newFakeController("desk"), nil, nil, zerolog.Nop())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/syncs/desk/pause", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /syncs/desk/pause status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
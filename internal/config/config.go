@@ -1,40 +1,819 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/cedrickring/hue-to-govee/internal/mqtt"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 // Synchronization represents a single synchronization config between a Hue light and a Govee device.
 type Synchronization struct {
-	HueLightId      string `mapstructure:"hue_light_id"`
-	HueRoomId       string `mapstructure:"hue_room_id"`
-	GoveeDeviceId   string `mapstructure:"govee_device_id"`
-	FixedBrightness *int   `mapstructure:"fixed_brightness"`
+	Name            string   `mapstructure:"name"`
+	HueLightId      string   `mapstructure:"hue_light_id"`
+	HueLightIds     []string `mapstructure:"hue_light_ids"`
+	HueRoomId       string   `mapstructure:"hue_room_id"`
+	GoveeDeviceId   string   `mapstructure:"govee_device_id"`
+	FixedBrightness *int     `mapstructure:"fixed_brightness"`
+	CTOffset        int      `mapstructure:"ct_offset"`
+
+	// HueBridge is the name of the Hue bridge of the lights, defaulting to the only configured bridge
+	HueBridge string `mapstructure:"hue_bridge"`
+
+	// HueGroupedLightId synchronizes the aggregated state of a Hue room or zone instead of single lights
+	HueGroupedLightId string `mapstructure:"hue_grouped_light_id"`
+
+	// FixedColor replaces the color of the Hue light, while its on state and brightness are still synchronized
+	FixedColor string          `mapstructure:"fixed_color"`
+	FixedRGB   *govee.RGBColor `mapstructure:"-"`
+
+	// MatchTransitions ramps the Govee device when the Hue light is observed transitioning between polls
+	MatchTransitions bool `mapstructure:"match_transitions"`
+
+	Derive *DeriveRule `mapstructure:"derive"`
+
+	// BrightnessCurve maps the brightness of the Hue light onto the Govee device, "linear" or "gamma:N"
+	BrightnessCurve      string  `mapstructure:"brightness_curve"`
+	BrightnessCurveGamma float64 `mapstructure:"-"`
+	// Deprecated: BrightnessGamma is the exponent of the brightness curve, use BrightnessCurve "gamma:N" instead.
+	BrightnessGamma float64 `mapstructure:"brightness_gamma"`
+
+	// MinBrightness and MaxBrightness remap the brightness of the Hue light onto this range in percent
+	MinBrightness int `mapstructure:"min_brightness"`
+	MaxBrightness int `mapstructure:"max_brightness"`
+
+	// ColorGamma selects how the colors converted from the Hue light's XY coordinates are encoded
+	ColorGamma      string         `mapstructure:"color_gamma"`
+	ColorGammaCurve hue.ColorGamma `mapstructure:"-"`
+	// BrightnessConversion selects how the brightness is applied to the colors converted from XY coordinates
+	BrightnessConversion     string                   `mapstructure:"brightness_conversion"`
+	BrightnessConversionMode hue.BrightnessConversion `mapstructure:"-"`
+
+	MaxPaletteColors int `mapstructure:"max_palette_colors"`
+
+	BatchWindowMs int `mapstructure:"batch_window_ms"`
+
+	StartAnimation time.Duration `mapstructure:"start_animation"`
+	StopAnimation  time.Duration `mapstructure:"stop_animation"`
+
+	GradientPoint         string                    `mapstructure:"gradient_point"`
+	GradientPointSelector hue.GradientPointSelector `mapstructure:"-"`
+
+	Direction Direction `mapstructure:"direction"`
+
+	PollIntervalMs int `mapstructure:"poll_interval_ms"`
+
+	TransitionMs int `mapstructure:"transition_ms"`
+
+	// DebounceMs holds back changes of the Hue light until they have been stable for this long
+	DebounceMs        int `mapstructure:"debounce_ms"`
+	DebounceMaxHoldMs int `mapstructure:"debounce_max_hold_ms"`
+
+	// Segments maps the lights of HueLightIds, or the gradient points of a single gradient light, onto the
+	// segments of the Govee device in order
+	Segments bool `mapstructure:"segments"`
+
+	// GoveeScenes activates built-in scenes of the Govee device for dynamic Hue scenes by name
+	GoveeScenes []GoveeScene `mapstructure:"govee_scenes"`
+	// GoveeEffects activates built-in scenes of the Govee device for Hue light effects like candle by name
+	GoveeEffects []GoveeEffect `mapstructure:"govee_effects"`
+}
+
+// Direction is the direction in which a synchronization copies the light state.
+type Direction string
+
+const (
+	// DirectionHueToGovee copies the Hue light state to the Govee device
+	DirectionHueToGovee Direction = "hue_to_govee"
+	// DirectionGoveeToHue copies the Govee device state to the Hue light
+	DirectionGoveeToHue Direction = "govee_to_hue"
+	// DirectionBidirectional copies changes of either side to the other
+	DirectionBidirectional Direction = "bidirectional"
+)
+
+// ToGovee returns whether the Hue light state is copied to the Govee device.
+func (d Direction) ToGovee() bool {
+	return d == DirectionHueToGovee || d == DirectionBidirectional
+}
+
+// ToHue returns whether the Govee device state is copied to the Hue light.
+func (d Direction) ToHue() bool {
+	return d == DirectionGoveeToHue || d == DirectionBidirectional
+}
+
+// DeriveRule describes how an accent color is derived from the Hue light's color in HSL space.
+type DeriveRule struct {
+	HueShift        float64  `mapstructure:"hue_shift"`
+	SaturationScale *float64 `mapstructure:"saturation_scale"`
+	LightnessScale  *float64 `mapstructure:"lightness_scale"`
+}
+
+// Scales returns the saturation and lightness scales, defaulting to 1 if unset.
+func (d DeriveRule) Scales() (float64, float64) {
+	saturationScale, lightnessScale := 1.0, 1.0
+	if d.SaturationScale != nil {
+		saturationScale = *d.SaturationScale
+	}
+	if d.LightnessScale != nil {
+		lightnessScale = *d.LightnessScale
+	}
+	return saturationScale, lightnessScale
+}
+
+// ID returns a stable identifier of the synchronization, its name if set or the Govee device ID otherwise.
+func (s Synchronization) ID() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.GoveeDeviceId
+}
+
+// PollInterval returns how often the Hue light of the synchronization is polled.
+func (s Synchronization) PollInterval() time.Duration {
+	return time.Duration(s.PollIntervalMs) * time.Millisecond
+}
+
+// Transition returns the duration of the crossfade between two colors, zero if colors are applied instantly.
+func (s Synchronization) Transition() time.Duration {
+	return time.Duration(s.TransitionMs) * time.Millisecond
+}
+
+// defaultDebounceMaxHold is how long changes are held back at most while debouncing if not configured
+const defaultDebounceMaxHold = 2 * time.Second
+
+// Debounce returns how long a change of the Hue light has to be stable before it's forwarded, zero if changes
+// are forwarded immediately.
+func (s Synchronization) Debounce() time.Duration {
+	return time.Duration(s.DebounceMs) * time.Millisecond
+}
+
+// DebounceMaxHold returns how long changes are held back at most while debouncing.
+func (s Synchronization) DebounceMaxHold() time.Duration {
+	if s.DebounceMaxHoldMs == 0 {
+		return defaultDebounceMaxHold
+	}
+	return time.Duration(s.DebounceMaxHoldMs) * time.Millisecond
+}
+
+// BrightnessRange returns the range in percent the brightness of the Hue light is remapped onto, 0-100 unless
+// min_brightness or max_brightness are set.
+func (s Synchronization) BrightnessRange() (int, int) {
+	if s.MaxBrightness == 0 {
+		return s.MinBrightness, 100
+	}
+	return s.MinBrightness, s.MaxBrightness
+}
+
+// GoveeSceneCode returns the code of the built-in Govee scene mapped to the Hue scene with the given name,
+// matching the name case-insensitively.
+func (s Synchronization) GoveeSceneCode(hueSceneName string) (int, bool) {
+	for _, scene := range s.GoveeScenes {
+		if strings.EqualFold(scene.HueSceneName, hueSceneName) {
+			return scene.GoveeSceneCode, true
+		}
+	}
+	return 0, false
+}
+
+// GoveeEffectSceneCode returns the code of the built-in Govee scene mapped to the Hue effect with the given name,
+// matching the name case-insensitively.
+func (s Synchronization) GoveeEffectSceneCode(hueEffect string) (int, bool) {
+	for _, effect := range s.GoveeEffects {
+		if strings.EqualFold(effect.HueEffect, hueEffect) {
+			return effect.GoveeSceneCode, true
+		}
+	}
+	return 0, false
+}
+
+// LightIDs returns the IDs of all Hue lights of the synchronization, or the ID of its grouped light.
+func (s Synchronization) LightIDs() []string {
+	if s.HueGroupedLightId != "" {
+		return []string{s.HueGroupedLightId}
+	}
+	if len(s.HueLightIds) > 0 {
+		return s.HueLightIds
+	}
+	return []string{s.HueLightId}
+}
+
+// HueBridge is a Hue bridge the lights of synchronizations belong to, referenced by its name.
+type HueBridge struct {
+	Name     string `mapstructure:"name"`
+	ID       string `mapstructure:"id"`
+	Username string `mapstructure:"username"`
+	Address  string `mapstructure:"address"`
 }
 
-// MustLoad loads the config file and panics if it fails.
-func MustLoad() {
-	viper.SetConfigFile("config.yaml")
-	if err := viper.ReadInConfig(); err != nil {
+// DefaultHueBridge is the name of the bridge configured by hue_bridge_id, hue_bridge_username and hue_bridge_address
+const DefaultHueBridge = "default"
+
+// StaticDevice represents a Govee device that is kept at a fixed state without a Hue light.
+type StaticDevice struct {
+	GoveeDeviceId string `mapstructure:"device_id"`
+	Color         string `mapstructure:"color"`
+	Brightness    int    `mapstructure:"brightness"`
+	On            *bool  `mapstructure:"on"`
+
+	// R, G and B are parsed from Color when loading the config
+	R, G, B int `mapstructure:"-"`
+}
+
+// IsOn returns whether the device should be turned on, defaulting to true.
+func (d StaticDevice) IsOn() bool {
+	return d.On == nil || *d.On
+}
+
+// DeviceCapabilities declares the capabilities of a Govee device the built-in model map doesn't know.
+type DeviceCapabilities struct {
+	GoveeDeviceId    string `mapstructure:"device_id"`
+	OnOff            bool   `mapstructure:"on_off"`
+	Brightness       bool   `mapstructure:"brightness"`
+	Color            bool   `mapstructure:"color"`
+	ColorTemperature bool   `mapstructure:"color_temperature"`
+	Segments         bool   `mapstructure:"segments"`
+}
+
+// GoveeDevice holds settings of a single Govee device.
+type GoveeDevice struct {
+	GoveeDeviceId string `mapstructure:"device_id"`
+	// Alias is a friendly name other sections can use instead of the device ID
+	Alias string `mapstructure:"alias"`
+
+	ColorTolerance   int           `mapstructure:"color_tolerance"`
+	CommandTimeout   time.Duration `mapstructure:"command_timeout"`
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	// CommandRetries overrides govee_command_retries, nil if unset so zero can disable retries of a single device
+	CommandRetries *int `mapstructure:"command_retries"`
+
+	ColorCorrection *ColorCorrection `mapstructure:"color_correction"`
+}
+
+// ColorCorrection calibrates the colors sent to a Govee device, either by a gain per channel or by a full
+// 3x3 matrix whose rows yield the red, green and blue output.
+type ColorCorrection struct {
+	RGain  *float64    `mapstructure:"r_gain"`
+	GGain  *float64    `mapstructure:"g_gain"`
+	BGain  *float64    `mapstructure:"b_gain"`
+	Matrix [][]float64 `mapstructure:"matrix"`
+}
+
+// Correction returns the color correction applied by the Govee client. Unset gains default to 1.
+func (c ColorCorrection) Correction() govee.ColorCorrection {
+	if len(c.Matrix) > 0 {
+		var matrix govee.ColorCorrection
+		for i, row := range c.Matrix {
+			copy(matrix[i][:], row)
+		}
+		return matrix
+	}
+
+	gain := func(g *float64) float64 {
+		if g == nil {
+			return 1
+		}
+		return *g
+	}
+	return govee.GainCorrection(gain(c.RGain), gain(c.GGain), gain(c.BGain))
+}
+
+// validate checks that either gains or a 3x3 matrix are set and that gains are not negative.
+func (c ColorCorrection) validate() error {
+	if len(c.Matrix) > 0 {
+		if c.RGain != nil || c.GGain != nil || c.BGain != nil {
+			return fmt.Errorf("set either gains or a matrix")
+		}
+		if len(c.Matrix) != 3 {
+			return fmt.Errorf("matrix must have 3 rows")
+		}
+		for _, row := range c.Matrix {
+			if len(row) != 3 {
+				return fmt.Errorf("matrix rows must have 3 columns")
+			}
+		}
+		return nil
+	}
+	for _, g := range []*float64{c.RGain, c.GGain, c.BGain} {
+		if g != nil && *g < 0 {
+			return fmt.Errorf("gains must not be negative")
+		}
+	}
+	return nil
+}
+
+// DIYScene maps a Hue scene by name to a DIY scene created in the Govee app.
+type DIYScene struct {
+	HueSceneName string `mapstructure:"hue_scene"`
+	GoveeDIYCode int    `mapstructure:"govee_diy_code"`
+}
+
+// GoveeScene maps a Hue scene by name to a built-in scene of a Govee device.
+type GoveeScene struct {
+	HueSceneName   string `mapstructure:"hue_scene"`
+	GoveeSceneCode int    `mapstructure:"govee_scene_code"`
+}
+
+// GoveeEffect maps a Hue light effect by name to a built-in scene of a Govee device.
+type GoveeEffect struct {
+	HueEffect      string `mapstructure:"hue_effect"`
+	GoveeSceneCode int    `mapstructure:"govee_scene_code"`
+}
+
+// minPollIntervalMs is the shortest poll interval accepted, to avoid hammering the Hue bridge
+const minPollIntervalMs = 100
+
+// maxCTOffset is the maximum color temperature offset in mireds, spanning the whole range supported by Hue lights.
+const maxCTOffset = 500 - 153
+
+// DefaultFile is the config file loaded if no other file is given
+const DefaultFile = "config.yaml"
+
+// EnvPrefix is the prefix of environment variables overriding config values, e.g. HUE2GOVEE_HUE_BRIDGE_USERNAME
+const EnvPrefix = "HUE2GOVEE"
+
+// flagKeys maps command line flags to the config keys they override
+var flagKeys = map[string]string{
+	"log-level":          "log_level",
+	"govee-multicast-ip": "govee_multicast_ip",
+}
+
+// RegisterFlags registers the flags overriding config values on the flag set.
+func RegisterFlags(flags *pflag.FlagSet) {
+	flags.String("log-level", "", "log level, overrides log_level")
+	flags.String("govee-multicast-ip", "", "multicast IP for Govee device discovery, overrides govee_multicast_ip")
+}
+
+// MustLoad loads the given config file and panics if it fails. Flags registered with RegisterFlags that are
+// set on the command line take precedence over environment variables, which take precedence over the config
+// file. A missing default config file is ignored so the bridge can be configured by environment variables only.
+func MustLoad(file string, flags *pflag.FlagSet) {
+	viper.SetDefault("resync_interval", 30*time.Second)
+	viper.SetDefault("night_mode_brightness", 30)
+	viper.SetDefault("state_file_interval", 5*time.Second)
+	viper.SetDefault("govee_command_timeout", time.Second)
+	viper.SetDefault("govee_failure_threshold", 3)
+	viper.SetDefault("govee_command_retries", 0)
+	viper.SetDefault("govee_command_retry_delay", 50*time.Millisecond)
+	viper.SetDefault("govee_device_ttl", govee.DefaultDeviceTTL)
+	viper.SetDefault("govee_scan_interval", govee.DefaultScanInterval)
+	viper.SetDefault("govee_multicast_ip", govee.DefaultMulticastIP)
+	viper.SetDefault("default_poll_interval_ms", 500)
+	viper.SetDefault("hue_requests_per_second", hue.DefaultRequestsPerSecond)
+	viper.SetDefault("hue_max_concurrent", hue.DefaultMaxConcurrentRequests)
+	viper.SetDefault("hue_discovery_timeout", hue.DefaultDiscoveryTimeout)
+	viper.SetDefault("hue_scene_cache_ttl", hue.DefaultSceneCacheTTL)
+	viper.SetDefault("hue_request_timeout", hue.DefaultRequestTimeout)
+	viper.SetDefault("min_rgb_delta", 3)
+	viper.SetDefault("mqtt_topic_prefix", mqtt.DefaultTopicPrefix)
+
+	for flag, key := range flagKeys {
+		if err := viper.BindPFlag(key, flags.Lookup(flag)); err != nil {
+			panic("Failed to bind flag " + flag + ": " + err.Error())
+		}
+	}
+
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	viper.SetConfigFile(file)
+	if err := viper.ReadInConfig(); err != nil && !(file == DefaultFile && errors.Is(err, fs.ErrNotExist)) {
 		panic("Failed to read config file: " + err.Error())
 	}
 }
 
+// unmarshalSection unmarshals a list section of the config. If the section is set by an environment variable,
+// its value is parsed as YAML or JSON, e.g. HUE2GOVEE_SYNCHRONIZATIONS='[{"hue_light_id": "...", ...}]'.
+func unmarshalSection(key string, out any) error {
+	raw, ok := viper.Get(key).(string)
+	if !ok {
+		return viper.UnmarshalKey(key, out)
+	}
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(key + ": " + raw)); err != nil {
+		return fmt.Errorf("failed to parse %s from environment: %w", key, err)
+	}
+	return v.UnmarshalKey(key, out)
+}
+
 // GetSynchronizations returns the synchronizations section of the config.
 func GetSynchronizations() ([]Synchronization, error) {
 	var synchronizations []Synchronization
-	if err := viper.UnmarshalKey("synchronizations", &synchronizations); err != nil {
+	if err := unmarshalSection("synchronizations", &synchronizations); err != nil {
+		return nil, err
+	}
+
+	bridges, err := GetHueBridges()
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := goveeDeviceAliases()
+	if err != nil {
 		return nil, err
 	}
 
-	for _, synchronization := range synchronizations {
+	defaultPollIntervalMs := viper.GetInt("default_poll_interval_ms")
+	if defaultPollIntervalMs < minPollIntervalMs {
+		return nil, fmt.Errorf("default poll interval must be at least %dms", minPollIntervalMs)
+	}
+
+	for i, synchronization := range synchronizations {
+		if synchronization.PollIntervalMs == 0 {
+			synchronizations[i].PollIntervalMs = defaultPollIntervalMs
+		} else if synchronization.PollIntervalMs < minPollIntervalMs {
+			return nil, fmt.Errorf("poll interval of synchronization %s must be at least %dms",
+				synchronization.ID(), minPollIntervalMs)
+		}
+
+		switch {
+		case synchronization.HueBridge == "" && len(bridges) > 1:
+			return nil, fmt.Errorf("synchronization %s must set hue_bridge if there is more than one Hue bridge",
+				synchronization.ID())
+		case synchronization.HueBridge == "":
+			synchronizations[i].HueBridge = bridges[0].Name
+		case !slices.ContainsFunc(bridges, func(b HueBridge) bool { return b.Name == synchronization.HueBridge }):
+			return nil, fmt.Errorf("synchronization %s references unknown Hue bridge %q", synchronization.ID(),
+				synchronization.HueBridge)
+		}
+
+		lightSources := 0
+		for _, set := range []bool{synchronization.HueLightId != "", len(synchronization.HueLightIds) > 0,
+			synchronization.HueGroupedLightId != ""} {
+			if set {
+				lightSources++
+			}
+		}
+		if lightSources != 1 {
+			return nil, fmt.Errorf("synchronization %s must set exactly one of hue_light_id, hue_light_ids and "+
+				"hue_grouped_light_id", synchronization.ID())
+		}
+
+		switch synchronization.Direction {
+		case "":
+			synchronizations[i].Direction = DirectionHueToGovee
+		case DirectionHueToGovee:
+		case DirectionGoveeToHue, DirectionBidirectional:
+			if len(synchronization.HueLightIds) > 0 || synchronization.HueGroupedLightId != "" {
+				return nil, fmt.Errorf("synchronization %s can't copy the Govee state to multiple Hue lights",
+					synchronization.ID())
+			}
+		default:
+			return nil, fmt.Errorf("invalid direction %q of synchronization %s, must be one of %s, %s or %s",
+				synchronization.Direction, synchronization.ID(), DirectionHueToGovee, DirectionGoveeToHue,
+				DirectionBidirectional)
+		}
+
+		selector, err := hue.ParseGradientPointSelector(synchronization.GradientPoint)
+		if err != nil {
+			return nil, err
+		}
+		synchronizations[i].GradientPointSelector = selector
+
+		colorGamma, err := hue.ParseColorGamma(synchronization.ColorGamma)
+		if err != nil {
+			return nil, err
+		}
+		synchronizations[i].ColorGammaCurve = colorGamma
+
+		conversion, err := hue.ParseBrightnessConversion(synchronization.BrightnessConversion)
+		if err != nil {
+			return nil, err
+		}
+		synchronizations[i].BrightnessConversionMode = conversion
+
+		if synchronization.FixedColor != "" {
+			color, err := hue.ParseColor(synchronization.FixedColor)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fixed color of synchronization %s: %w", synchronization.ID(), err)
+			}
+			synchronizations[i].FixedRGB = &govee.RGBColor{R: color.R, G: color.G, B: color.B}
+			if synchronization.Segments {
+				return nil, fmt.Errorf("synchronization %s can't set both fixed_color and segments", synchronization.ID())
+			}
+		}
 		if synchronization.FixedBrightness != nil {
 			if *synchronization.FixedBrightness > 100 || *synchronization.FixedBrightness < 0 {
 				return nil, fmt.Errorf("fixed brightness out of range, must be between 0 and 100")
 			}
 		}
+		if floor, ceiling := synchronization.BrightnessRange(); floor < 0 || ceiling > 100 || floor >= ceiling {
+			return nil, fmt.Errorf("brightness range of synchronization %s out of range, min_brightness and "+
+				"max_brightness must be between 0 and 100 with min_brightness below max_brightness",
+				synchronization.ID())
+		}
+		if synchronization.CTOffset > maxCTOffset || synchronization.CTOffset < -maxCTOffset {
+			return nil, fmt.Errorf("ct offset out of range, must be between %d and %d", -maxCTOffset, maxCTOffset)
+		}
+		if synchronization.StartAnimation < 0 || synchronization.StopAnimation < 0 {
+			return nil, fmt.Errorf("start and stop animation durations must not be negative")
+		}
+		if synchronization.Segments && len(synchronization.HueLightIds) > govee.MaxSegments {
+			return nil, fmt.Errorf("synchronization %s maps at most %d lights onto segments",
+				synchronization.ID(), govee.MaxSegments)
+		}
+		if synchronization.TransitionMs < 0 {
+			return nil, fmt.Errorf("transition must not be negative")
+		}
+		if synchronization.DebounceMs < 0 || synchronization.DebounceMaxHoldMs < 0 {
+			return nil, fmt.Errorf("debounce and debounce max hold must not be negative")
+		}
+		if synchronization.BatchWindowMs < 0 {
+			return nil, fmt.Errorf("batch window must not be negative")
+		}
+		if synchronization.MaxPaletteColors < 0 {
+			return nil, fmt.Errorf("max palette colors must not be negative")
+		}
+		deviceID, err := resolveGoveeDeviceID(synchronization.GoveeDeviceId, aliases)
+		if err != nil {
+			return nil, fmt.Errorf("synchronization %s: %w", synchronization.ID(), err)
+		}
+		synchronizations[i].GoveeDeviceId = deviceID
+
+		for _, scene := range synchronization.GoveeScenes {
+			if scene.HueSceneName == "" {
+				return nil, fmt.Errorf("govee scene of synchronization %s is missing the hue scene name",
+					synchronization.ID())
+			}
+			if scene.GoveeSceneCode < 0 || scene.GoveeSceneCode > govee.MaxSceneCode {
+				return nil, fmt.Errorf("govee scene code must be between 0 and %d, got %d", govee.MaxSceneCode,
+					scene.GoveeSceneCode)
+			}
+		}
+		for _, effect := range synchronization.GoveeEffects {
+			if effect.HueEffect == "" {
+				return nil, fmt.Errorf("govee effect of synchronization %s is missing the hue effect name",
+					synchronization.ID())
+			}
+			if effect.GoveeSceneCode < 0 || effect.GoveeSceneCode > govee.MaxSceneCode {
+				return nil, fmt.Errorf("govee scene code must be between 0 and %d, got %d", govee.MaxSceneCode,
+					effect.GoveeSceneCode)
+			}
+		}
+		if synchronization.BrightnessGamma < 0 {
+			return nil, fmt.Errorf("brightness gamma must not be negative")
+		}
+		if synchronization.BrightnessCurve != "" && synchronization.BrightnessGamma != 0 {
+			return nil, fmt.Errorf("synchronization %s must set only one of brightness_curve and the deprecated "+
+				"brightness_gamma", synchronization.ID())
+		}
+		if synchronization.BrightnessCurve != "" {
+			gamma, err := hue.ParseBrightnessCurve(synchronization.BrightnessCurve)
+			if err != nil {
+				return nil, err
+			}
+			synchronizations[i].BrightnessCurveGamma = gamma
+		} else {
+			synchronizations[i].BrightnessCurveGamma = synchronization.BrightnessGamma
+		}
+		if synchronization.Derive != nil {
+			saturationScale, lightnessScale := synchronization.Derive.Scales()
+			if saturationScale < 0 || lightnessScale < 0 {
+				return nil, fmt.Errorf("derive scales must not be negative")
+			}
+		}
+	}
+
+	// synchronizations are started, stopped and controlled by their ID, so a duplicate would replace the other
+	seen := make(map[string]int, len(synchronizations))
+	for i, synchronization := range synchronizations {
+		if first, ok := seen[synchronization.ID()]; ok {
+			return nil, fmt.Errorf("synchronizations %d and %d have the same ID %q, give them unique names", first,
+				i, synchronization.ID())
+		}
+		seen[synchronization.ID()] = i
 	}
 	return synchronizations, nil
 }
+
+// GetHueBridges returns the hue_bridges section of the config. If it isn't set, the single bridge configured by
+// hue_bridge_id, hue_bridge_username and hue_bridge_address is returned, named DefaultHueBridge.
+func GetHueBridges() ([]HueBridge, error) {
+	var bridges []HueBridge
+	if err := unmarshalSection("hue_bridges", &bridges); err != nil {
+		return nil, err
+	}
+
+	legacy := HueBridge{
+		Name:     DefaultHueBridge,
+		ID:       viper.GetString("hue_bridge_id"),
+		Username: viper.GetString("hue_bridge_username"),
+		Address:  viper.GetString("hue_bridge_address"),
+	}
+	if len(bridges) == 0 {
+		return []HueBridge{legacy}, nil
+	}
+	if legacy.ID != "" || legacy.Username != "" || legacy.Address != "" {
+		return nil, fmt.Errorf("set either hue_bridges or hue_bridge_id, hue_bridge_username and hue_bridge_address")
+	}
+
+	names := make(map[string]bool, len(bridges))
+	for i, bridge := range bridges {
+		if bridge.Name == "" {
+			return nil, fmt.Errorf("hue bridge %d is missing a name", i)
+		}
+		if names[bridge.Name] {
+			return nil, fmt.Errorf("hue bridge name %q is used more than once", bridge.Name)
+		}
+		names[bridge.Name] = true
+
+		if err := hue.ValidateBridgeID(bridge.ID); err != nil {
+			return nil, fmt.Errorf("invalid id of hue bridge %s: %w", bridge.Name, err)
+		}
+	}
+	return bridges, nil
+}
+
+// GetStaticDevices returns the static_devices section of the config.
+func GetStaticDevices() ([]StaticDevice, error) {
+	var staticDevices []StaticDevice
+	if err := unmarshalSection("static_devices", &staticDevices); err != nil {
+		return nil, err
+	}
+
+	// a device is either static or synchronized to have a single writer per device
+	synchronizations, err := GetSynchronizations()
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := goveeDeviceAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, device := range staticDevices {
+		if device.GoveeDeviceId == "" {
+			return nil, fmt.Errorf("static device %d is missing a device_id", i)
+		}
+		deviceID, err := resolveGoveeDeviceID(device.GoveeDeviceId, aliases)
+		if err != nil {
+			return nil, fmt.Errorf("static device %d: %w", i, err)
+		}
+		device.GoveeDeviceId = deviceID
+		staticDevices[i].GoveeDeviceId = deviceID
+		if device.Brightness > 100 || device.Brightness < 0 {
+			return nil, fmt.Errorf("brightness of static device %s out of range, must be between 0 and 100",
+				device.GoveeDeviceId)
+		}
+
+		for _, synchronization := range synchronizations {
+			if synchronization.GoveeDeviceId == device.GoveeDeviceId {
+				return nil, fmt.Errorf("static device %s is also synchronized with a Hue light", device.GoveeDeviceId)
+			}
+		}
+
+		color, err := hue.ParseColor(device.Color)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color for static device %s: %w", device.GoveeDeviceId, err)
+		}
+		staticDevices[i].R, staticDevices[i].G, staticDevices[i].B = color.R, color.G, color.B
+	}
+	return staticDevices, nil
+}
+
+// GetDeviceCapabilities returns the govee_device_capabilities section of the config.
+func GetDeviceCapabilities() ([]DeviceCapabilities, error) {
+	var capabilities []DeviceCapabilities
+	if err := unmarshalSection("govee_device_capabilities", &capabilities); err != nil {
+		return nil, err
+	}
+
+	aliases, err := goveeDeviceAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, c := range capabilities {
+		if c.GoveeDeviceId == "" {
+			return nil, fmt.Errorf("device capabilities %d are missing a device_id", i)
+		}
+		deviceID, err := resolveGoveeDeviceID(c.GoveeDeviceId, aliases)
+		if err != nil {
+			return nil, fmt.Errorf("device capabilities %d: %w", i, err)
+		}
+		capabilities[i].GoveeDeviceId = deviceID
+	}
+	return capabilities, nil
+}
+
+// GetGoveeDevices returns the govee_devices section of the config.
+func GetGoveeDevices() ([]GoveeDevice, error) {
+	var devices []GoveeDevice
+	if err := unmarshalSection("govee_devices", &devices); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool, len(devices))
+	for i, device := range devices {
+		if device.GoveeDeviceId == "" {
+			return nil, fmt.Errorf("govee device %d is missing a device_id", i)
+		}
+		ids[device.GoveeDeviceId] = true
+	}
+
+	aliases := make(map[string]bool)
+	for _, device := range devices {
+		if device.Alias == "" {
+			continue
+		}
+		if strings.Contains(device.Alias, ":") || ids[device.Alias] {
+			return nil, fmt.Errorf("alias %q of govee device %s must not be or look like a device ID, which contains colons",
+				device.Alias, device.GoveeDeviceId)
+		}
+		if aliases[device.Alias] {
+			return nil, fmt.Errorf("govee device alias %q is used more than once", device.Alias)
+		}
+		aliases[device.Alias] = true
+	}
+
+	for _, device := range devices {
+		if device.ColorTolerance < 0 || device.ColorTolerance > 255 {
+			return nil, fmt.Errorf("color tolerance of govee device %s out of range, must be between 0 and 255",
+				device.GoveeDeviceId)
+		}
+		if device.CommandTimeout < 0 || device.FailureThreshold < 0 {
+			return nil, fmt.Errorf("command timeout and failure threshold of govee device %s must not be negative",
+				device.GoveeDeviceId)
+		}
+		if device.CommandRetries != nil && *device.CommandRetries < 0 {
+			return nil, fmt.Errorf("command retries of govee device %s must not be negative", device.GoveeDeviceId)
+		}
+		if device.ColorCorrection != nil {
+			if err := device.ColorCorrection.validate(); err != nil {
+				return nil, fmt.Errorf("invalid color correction of govee device %s: %w", device.GoveeDeviceId, err)
+			}
+		}
+	}
+	return devices, nil
+}
+
+// goveeDeviceAliases returns the device IDs of the govee_devices section keyed by their alias.
+func goveeDeviceAliases() (map[string]string, error) {
+	devices, err := GetGoveeDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := make(map[string]string, len(devices))
+	for _, device := range devices {
+		if device.Alias != "" {
+			aliases[device.Alias] = device.GoveeDeviceId
+		}
+	}
+	return aliases, nil
+}
+
+// ResolveGoveeDeviceID returns the device ID of a Govee device given by its ID or its alias in govee_devices.
+func ResolveGoveeDeviceID(idOrAlias string) (string, error) {
+	aliases, err := goveeDeviceAliases()
+	if err != nil {
+		return "", err
+	}
+	return resolveGoveeDeviceID(idOrAlias, aliases)
+}
+
+// resolveGoveeDeviceID returns the device ID of the given alias. Device IDs, which contain colons unlike aliases,
+// are returned as is, so every other value must be a known alias.
+func resolveGoveeDeviceID(idOrAlias string, aliases map[string]string) (string, error) {
+	if idOrAlias == "" {
+		return "", fmt.Errorf("missing a govee device ID")
+	}
+	if deviceID, ok := aliases[idOrAlias]; ok {
+		return deviceID, nil
+	}
+	if strings.Contains(idOrAlias, ":") {
+		return idOrAlias, nil
+	}
+	return "", fmt.Errorf("unknown govee device alias %q, add it to govee_devices", idOrAlias)
+}
+
+// GetDIYScenes returns the govee_diy_scenes section of the config as a map of lowercase Hue scene names
+// to Govee DIY scene codes.
+func GetDIYScenes() (map[string]int, error) {
+	var scenes []DIYScene
+	if err := unmarshalSection("govee_diy_scenes", &scenes); err != nil {
+		return nil, err
+	}
+
+	codes := make(map[string]int, len(scenes))
+	for i, scene := range scenes {
+		if scene.HueSceneName == "" {
+			return nil, fmt.Errorf("govee DIY scene %d is missing a hue_scene", i)
+		}
+		name := strings.ToLower(scene.HueSceneName)
+		if _, ok := codes[name]; ok {
+			return nil, fmt.Errorf("hue scene %q is mapped to more than one govee DIY scene", scene.HueSceneName)
+		}
+		codes[name] = scene.GoveeDIYCode
+	}
+	return codes, nil
+}
@@ -0,0 +1,846 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// loadTestConfig loads the YAML config with MustLoad, setting the given command line flags. Viper's global state
+// is reset before and after the test.
+func loadTestConfig(t *testing.T, yaml string, args ...string) {
+	t.Helper()
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	MustLoad(file, flags)
+}
+
+// testSync returns a config with a single synchronization with the given additional YAML keys
+func testSync(keys ...string) string {
+	yaml := "synchronizations:\n  - hue_light_id: light-1\n    govee_device_id: AA:BB:CC:DD:EE:FF:00:11\n"
+	for _, key := range keys {
+		yaml += "    " + key + "\n"
+	}
+	return yaml
+}
+
+func TestBrightnessCurve(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		want    float64
+		wantErr string
+	}{
+		{name: "unset", want: 0},
+		{name: "linear", keys: []string{"brightness_curve: linear"}, want: 1},
+		{name: "gamma", keys: []string{"brightness_curve: gamma:0.6"}, want: 0.6},
+		{name: "deprecated gamma", keys: []string{"brightness_gamma: 0.6"}, want: 0.6},
+		{name: "both", keys: []string{"brightness_curve: gamma:0.6", "brightness_gamma: 0.6"},
+			wantErr: "only one of brightness_curve"},
+		{name: "negative deprecated gamma", keys: []string{"brightness_gamma: -1"}, wantErr: "must not be negative"},
+		{name: "invalid curve", keys: []string{"brightness_curve: cubic"}, wantErr: "invalid brightness curve"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTestConfig(t, testSync(tt.keys...))
+
+			synchronizations, err := GetSynchronizations()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetSynchronizations() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetSynchronizations() returned error: %v", err)
+			}
+			if got := synchronizations[0].BrightnessCurveGamma; got != tt.want {
+				t.Errorf("BrightnessCurveGamma = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoveeDeviceCommandRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		retries string
+		want    *int
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "zero", retries: "0", want: ptr(0)},
+		{name: "positive", retries: "2", want: ptr(2)},
+		{name: "negative", retries: "-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := "govee_devices:\n  - device_id: AA:BB:CC:DD:EE:FF:00:11\n"
+			if tt.retries != "" {
+				yaml += "    command_retries: " + tt.retries + "\n"
+			}
+			loadTestConfig(t, yaml)
+
+			devices, err := GetGoveeDevices()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("GetGoveeDevices() = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetGoveeDevices() returned error: %v", err)
+			}
+			if got := devices[0].CommandRetries; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CommandRetries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestMustLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want string
+	}{
+		{name: "file", want: "info"},
+		{name: "env beats file", env: "warn", want: "warn"},
+		{name: "flag beats env", env: "warn", args: []string{"--log-level=debug"}, want: "debug"},
+		{name: "flag beats file", args: []string{"--log-level=error"}, want: "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv(EnvPrefix+"_LOG_LEVEL", tt.env)
+			}
+			loadTestConfig(t, "log_level: info\n", tt.args...)
+
+			if got := viper.GetString("log_level"); got != tt.want {
+				t.Errorf("log_level = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHueRequestTimeout(t *testing.T) {
+	loadTestConfig(t, "")
+	if got := viper.GetDuration("hue_request_timeout"); got != hue.DefaultRequestTimeout {
+		t.Errorf("hue_request_timeout = %v, want the default %v", got, hue.DefaultRequestTimeout)
+	}
+
+	loadTestConfig(t, "hue_request_timeout: 1500ms\n")
+	if got := viper.GetDuration("hue_request_timeout"); got != 1500*time.Millisecond {
+		t.Errorf("hue_request_timeout = %v, want 1.5s", got)
+	}
+}
+
+func TestHueMaxConcurrent(t *testing.T) {
+	loadTestConfig(t, "")
+	if got := viper.GetInt("hue_max_concurrent"); got != hue.DefaultMaxConcurrentRequests {
+		t.Errorf("hue_max_concurrent = %d, want the default %d", got, hue.DefaultMaxConcurrentRequests)
+	}
+
+	loadTestConfig(t, "hue_max_concurrent: 8\n")
+	if got := viper.GetInt("hue_max_concurrent"); got != 8 {
+		t.Errorf("hue_max_concurrent = %d, want 8", got)
+	}
+}
+
+func TestHueDiscoveryTimeout(t *testing.T) {
+	loadTestConfig(t, "")
+	if got := viper.GetDuration("hue_discovery_timeout"); got != hue.DefaultDiscoveryTimeout {
+		t.Errorf("hue_discovery_timeout = %v, want the default %v", got, hue.DefaultDiscoveryTimeout)
+	}
+
+	loadTestConfig(t, "hue_discovery_timeout: 5m\n")
+	if got := viper.GetDuration("hue_discovery_timeout"); got != 5*time.Minute {
+		t.Errorf("hue_discovery_timeout = %v, want 5m", got)
+	}
+}
+
+func TestMustLoadFlags(t *testing.T) {
+	loadTestConfig(t, "govee_multicast_ip: 239.255.255.250\n", "--govee-multicast-ip=239.255.255.251")
+	if got := viper.GetString("govee_multicast_ip"); got != "239.255.255.251" {
+		t.Errorf("govee_multicast_ip = %q, want the flag to override the file", got)
+	}
+
+	loadTestConfig(t, "")
+	if got := viper.GetString("govee_multicast_ip"); got != govee.DefaultMulticastIP {
+		t.Errorf("govee_multicast_ip = %q, want the default %q without flag and file value", got,
+			govee.DefaultMulticastIP)
+	}
+}
+
+func TestMustLoadMissingFile(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(flags)
+
+	// the default config file is optional, so the bridge can be configured by environment variables only
+	t.Chdir(t.TempDir())
+	MustLoad(DefaultFile, flags)
+
+	// a config file passed explicitly has to exist
+	defer func() {
+		if recover() == nil {
+			t.Error("MustLoad() of a missing config file didn't panic")
+		}
+	}()
+	MustLoad(filepath.Join(t.TempDir(), "missing.yaml"), flags)
+}
+
+func TestEnvironmentConfig(t *testing.T) {
+	t.Setenv(EnvPrefix+"_HUE_BRIDGE_USERNAME", "env-user")
+	t.Setenv(EnvPrefix+"_SYNCHRONIZATIONS",
+		`[{"hue_light_id": "light-2", "govee_device_id": "11:22:33:44:55:66:77:88"}]`)
+	loadTestConfig(t, "hue_bridge_username: file-user\n"+testSync())
+
+	if got := viper.GetString("hue_bridge_username"); got != "env-user" {
+		t.Errorf("hue_bridge_username = %q, want the environment variable to override the file", got)
+	}
+	synchronizations, err := GetSynchronizations()
+	if err != nil {
+		t.Fatalf("GetSynchronizations() returned error: %v", err)
+	}
+	if len(synchronizations) != 1 || synchronizations[0].HueLightId != "light-2" ||
+		synchronizations[0].GoveeDeviceId != "11:22:33:44:55:66:77:88" {
+		t.Errorf("GetSynchronizations() = %+v, want the synchronization of the environment variable", synchronizations)
+	}
+}
+
+func TestEnvironmentConfigInvalidSection(t *testing.T) {
+	t.Setenv(EnvPrefix+"_SYNCHRONIZATIONS", `[{"hue_light_id": "light-2"`)
+	loadTestConfig(t, "")
+
+	if _, err := GetSynchronizations(); err == nil || !strings.Contains(err.Error(), "from environment") {
+		t.Errorf("GetSynchronizations() = %v, want an error parsing the environment variable", err)
+	}
+}
+
+func TestPollIntervalPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  string
+		perSync string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "built-in default", want: 500 * time.Millisecond},
+		{name: "global beats default", global: "250", want: 250 * time.Millisecond},
+		{name: "per-sync beats global", global: "250", perSync: "150", want: 150 * time.Millisecond},
+		{name: "per-sync beats default", perSync: "1000", want: time.Second},
+		{name: "minimum", global: "100", perSync: "100", want: 100 * time.Millisecond},
+		{name: "per-sync too short", perSync: "99", wantErr: true},
+		{name: "global too short", global: "50", perSync: "200", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var yaml string
+			if tt.global != "" {
+				yaml = "default_poll_interval_ms: " + tt.global + "\n"
+			}
+			if tt.perSync != "" {
+				yaml += testSync("poll_interval_ms: " + tt.perSync)
+			} else {
+				yaml += testSync()
+			}
+			loadTestConfig(t, yaml)
+
+			synchronizations, err := GetSynchronizations()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("GetSynchronizations() = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetSynchronizations() returned error: %v", err)
+			}
+			if got := synchronizations[0].PollInterval(); got != tt.want {
+				t.Errorf("PollInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorGamma(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		want    hue.ColorGamma
+		wantErr string
+	}{
+		{name: "unset", want: hue.ColorGammaSRGB},
+		{name: "srgb", keys: []string{"color_gamma: srgb"}, want: hue.ColorGammaSRGB},
+		{name: "linear", keys: []string{"color_gamma: linear"}, want: hue.ColorGammaLinear},
+		{name: "exponent", keys: []string{"color_gamma: 1.8"}, want: 1.8},
+		{name: "invalid", keys: []string{"color_gamma: -1"}, wantErr: "invalid color gamma"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTestConfig(t, testSync(tt.keys...))
+
+			synchronizations, err := GetSynchronizations()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetSynchronizations() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetSynchronizations() returned error: %v", err)
+			}
+			if got := synchronizations[0].ColorGammaCurve; got != tt.want {
+				t.Errorf("ColorGammaCurve = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBrightnessConversion(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		want    hue.BrightnessConversion
+		wantErr string
+	}{
+		{name: "unset", want: hue.BrightnessConversionLuminance},
+		{name: "multiply", keys: []string{"brightness_conversion: multiply"}, want: hue.BrightnessConversionMultiply},
+		{name: "invalid", keys: []string{"brightness_conversion: scale"}, wantErr: "invalid brightness conversion"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTestConfig(t, testSync(tt.keys...))
+
+			synchronizations, err := GetSynchronizations()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetSynchronizations() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetSynchronizations() returned error: %v", err)
+			}
+			if got := synchronizations[0].BrightnessConversionMode; got != tt.want {
+				t.Errorf("BrightnessConversionMode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBrightnessRange(t *testing.T) {
+	tests := []struct {
+		sync                   Synchronization
+		wantFloor, wantCeiling int
+	}{
+		{sync: Synchronization{}, wantFloor: 0, wantCeiling: 100},
+		{sync: Synchronization{MinBrightness: 10}, wantFloor: 10, wantCeiling: 100},
+		{sync: Synchronization{MaxBrightness: 60}, wantFloor: 0, wantCeiling: 60},
+		{sync: Synchronization{MinBrightness: 10, MaxBrightness: 60}, wantFloor: 10, wantCeiling: 60},
+	}
+	for _, tt := range tests {
+		if floor, ceiling := tt.sync.BrightnessRange(); floor != tt.wantFloor || ceiling != tt.wantCeiling {
+			t.Errorf("BrightnessRange() of min %d and max %d = (%d, %d), want (%d, %d)", tt.sync.MinBrightness,
+				tt.sync.MaxBrightness, floor, ceiling, tt.wantFloor, tt.wantCeiling)
+		}
+	}
+}
+
+func TestFixedColor(t *testing.T) {
+	tests := []struct {
+		color string
+		want  govee.RGBColor
+	}{
+		{color: "'#FF0000'", want: govee.RGBColor{R: 255}},
+		{color: "'#f80'", want: govee.RGBColor{R: 255, G: 136}},
+		{color: "33AAFF", want: govee.RGBColor{R: 0x33, G: 0xaa, B: 0xff}},
+		{color: "warm", want: govee.RGBColor{R: 255, G: 180, B: 107}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.color, func(t *testing.T) {
+			loadTestConfig(t, testSync("fixed_color: "+tt.color))
+
+			synchronizations, err := GetSynchronizations()
+			if err != nil {
+				t.Fatalf("GetSynchronizations() returned error: %v", err)
+			}
+			if got := synchronizations[0].FixedRGB; got == nil || *got != tt.want {
+				t.Errorf("FixedRGB = %v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	loadTestConfig(t, testSync())
+	synchronizations, err := GetSynchronizations()
+	if err != nil || synchronizations[0].FixedRGB != nil {
+		t.Errorf("FixedRGB without fixed_color = %v, %v, want nil", synchronizations[0].FixedRGB, err)
+	}
+}
+
+func TestSynchronizationValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "valid", yaml: testSync()},
+		{name: "no light", yaml: "synchronizations:\n  - govee_device_id: AA:BB:CC:DD:EE:FF:00:11\n",
+			wantErr: "exactly one of hue_light_id"},
+		{name: "two light sources", yaml: testSync("hue_grouped_light_id: group-1"),
+			wantErr: "exactly one of hue_light_id"},
+		{name: "grouped light", yaml: "synchronizations:\n  - hue_grouped_light_id: group-1\n" +
+			"    govee_device_id: AA:BB:CC:DD:EE:FF:00:11\n"},
+		{name: "reverse sync of grouped light", yaml: "synchronizations:\n  - hue_grouped_light_id: group-1\n" +
+			"    govee_device_id: AA:BB:CC:DD:EE:FF:00:11\n    direction: bidirectional\n",
+			wantErr: "multiple Hue lights"},
+		{name: "missing device", yaml: "synchronizations:\n  - hue_light_id: light-1\n",
+			wantErr: "missing a govee device ID"},
+		{name: "unknown alias", yaml: "synchronizations:\n  - hue_light_id: light-1\n    govee_device_id: desk\n",
+			wantErr: "unknown govee device alias"},
+		{name: "alias", yaml: "govee_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    alias: desk\n" +
+			"synchronizations:\n  - hue_light_id: light-1\n    govee_device_id: desk\n"},
+		{name: "unknown bridge", yaml: testSync("hue_bridge: upstairs"), wantErr: "unknown Hue bridge"},
+		{name: "bridge of several", yaml: testBridges + testSync("hue_bridge: upstairs")},
+		{name: "no bridge of several", yaml: testBridges + testSync(), wantErr: "must set hue_bridge"},
+		{name: "invalid direction", yaml: testSync("direction: sideways"), wantErr: "invalid direction"},
+		{name: "reverse sync of multiple lights", yaml: "synchronizations:\n  - hue_light_ids: [light-1, light-2]\n" +
+			"    govee_device_id: AA:BB:CC:DD:EE:FF:00:11\n    direction: govee_to_hue\n",
+			wantErr: "multiple Hue lights"},
+		{name: "fixed brightness above 100", yaml: testSync("fixed_brightness: 101"),
+			wantErr: "fixed brightness out of range"},
+		{name: "min brightness above max", yaml: testSync("min_brightness: 60", "max_brightness: 40"),
+			wantErr: "brightness range"},
+		{name: "brightness range", yaml: testSync("min_brightness: 10", "max_brightness: 90")},
+		{name: "min brightness only", yaml: testSync("min_brightness: 99")},
+		{name: "min brightness of 100", yaml: testSync("min_brightness: 100"), wantErr: "brightness range"},
+		{name: "equal brightness range", yaml: testSync("min_brightness: 40", "max_brightness: 40"),
+			wantErr: "brightness range"},
+		{name: "negative min brightness", yaml: testSync("min_brightness: -1"), wantErr: "brightness range"},
+		{name: "max brightness above 100", yaml: testSync("max_brightness: 101"), wantErr: "brightness range"},
+		{name: "largest ct offset", yaml: testSync("ct_offset: 347")},
+		{name: "largest negative ct offset", yaml: testSync("ct_offset: -347")},
+		{name: "ct offset out of range", yaml: testSync("ct_offset: 348"), wantErr: "ct offset out of range"},
+		{name: "negative ct offset out of range", yaml: testSync("ct_offset: -348"),
+			wantErr: "ct offset out of range"},
+		{name: "negative start animation", yaml: testSync("start_animation: -1s"),
+			wantErr: "animation durations must not be negative"},
+		{name: "negative stop animation", yaml: testSync("stop_animation: -1s"),
+			wantErr: "animation durations must not be negative"},
+		{name: "negative transition", yaml: testSync("transition_ms: -1"), wantErr: "transition must not be negative"},
+		{name: "negative debounce", yaml: testSync("debounce_ms: -1"), wantErr: "debounce"},
+		{name: "negative batch window", yaml: testSync("batch_window_ms: -1"), wantErr: "batch window"},
+		{name: "negative max palette colors", yaml: testSync("max_palette_colors: -1"),
+			wantErr: "max palette colors"},
+		{name: "negative derive scale", yaml: testSync("derive:", "  saturation_scale: -0.5"),
+			wantErr: "derive scales must not be negative"},
+		{name: "invalid gradient point", yaml: testSync("gradient_point: middle"),
+			wantErr: "invalid gradient point"},
+		{name: "invalid fixed color", yaml: testSync("fixed_color: chartreuse"), wantErr: "invalid fixed color"},
+		{name: "fixed color with segments", yaml: testSync("fixed_color: red", "segments: true"),
+			wantErr: "both fixed_color and segments"},
+		{name: "scene without name", yaml: testSync("govee_scenes:", "  - govee_scene_code: 1"),
+			wantErr: "missing the hue scene name"},
+		{name: "scene code out of range", yaml: testSync("govee_scenes:",
+			"  - hue_scene: Relax", "    govee_scene_code: -1"), wantErr: "govee scene code must be between"},
+		{name: "duplicate IDs", yaml: testSync() + "  - hue_light_id: light-2\n" +
+			"    govee_device_id: AA:BB:CC:DD:EE:FF:00:11\n", wantErr: "same ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTestConfig(t, tt.yaml)
+
+			_, err := GetSynchronizations()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("GetSynchronizations() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GetSynchronizations() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testBridges is the hue_bridges section of two bridges.
+const testBridges = "hue_bridges:\n  - name: upstairs\n    id: 001788fffe4a5b6c\n    username: user-1\n" +
+	"  - name: downstairs\n    id: 001788fffe000000\n    username: user-2\n    address: 192.168.1.30\n"
+
+func TestGetHueBridges(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    []HueBridge
+		wantErr string
+	}{
+		{name: "legacy", yaml: "hue_bridge_id: 001788fffe4a5b6c\nhue_bridge_username: user\n",
+			want: []HueBridge{{Name: DefaultHueBridge, ID: "001788fffe4a5b6c", Username: "user"}}},
+		{name: "several", yaml: testBridges, want: []HueBridge{
+			{Name: "upstairs", ID: "001788fffe4a5b6c", Username: "user-1"},
+			{Name: "downstairs", ID: "001788fffe000000", Username: "user-2", Address: "192.168.1.30"},
+		}},
+		{name: "missing name", yaml: "hue_bridges:\n  - id: 001788fffe4a5b6c\n", wantErr: "missing a name"},
+		{name: "duplicate name", yaml: "hue_bridges:\n  - name: upstairs\n  - name: upstairs\n",
+			wantErr: "used more than once"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTestConfig(t, tt.yaml)
+
+			bridges, err := GetHueBridges()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GetHueBridges() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetHueBridges() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(bridges, tt.want) {
+				t.Errorf("GetHueBridges() = %+v, want %+v", bridges, tt.want)
+			}
+		})
+	}
+}
+
+func TestSynchronizationDefaultBridge(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{name: "legacy", yaml: testSync(), want: DefaultHueBridge},
+		{name: "single bridge", yaml: "hue_bridges:\n  - name: upstairs\n" + testSync(), want: "upstairs"},
+		{name: "referenced", yaml: testBridges + testSync("hue_bridge: downstairs"), want: "downstairs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTestConfig(t, tt.yaml)
+
+			synchronizations, err := GetSynchronizations()
+			if err != nil {
+				t.Fatalf("GetSynchronizations() returned error: %v", err)
+			}
+			if got := synchronizations[0].HueBridge; got != tt.want {
+				t.Errorf("HueBridge = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetHueBridgesLegacyAddress(t *testing.T) {
+	loadTestConfig(t, "hue_bridge_id: 001788fffe4a5b6c\nhue_bridge_username: user\nhue_bridge_address: 192.168.1.20\n")
+
+	bridges, err := GetHueBridges()
+	if err != nil {
+		t.Fatalf("GetHueBridges() returned error: %v", err)
+	}
+	want := []HueBridge{{Name: DefaultHueBridge, ID: "001788fffe4a5b6c", Username: "user", Address: "192.168.1.20"}}
+	if !reflect.DeepEqual(bridges, want) {
+		t.Errorf("GetHueBridges() = %+v, want %+v", bridges, want)
+	}
+
+	loadTestConfig(t, "hue_bridge_address: 192.168.1.20\nhue_bridges:\n  - name: upstairs\n")
+	if _, err := GetHueBridges(); err == nil || !strings.Contains(err.Error(), "set either hue_bridges") {
+		t.Errorf("GetHueBridges() with hue_bridges and hue_bridge_address = %v, want an error", err)
+	}
+}
+
+func TestGetHueBridgesInvalidID(t *testing.T) {
+	loadTestConfig(t, "hue_bridges:\n  - name: upstairs\n    id: 5b6c\n    username: user\n")
+	_, err := GetHueBridges()
+	if err == nil || !strings.Contains(err.Error(), "invalid id of hue bridge upstairs") ||
+		!strings.Contains(err.Error(), "got 4 characters") {
+		t.Errorf("GetHueBridges() with a too short bridge ID = %v, want a descriptive error", err)
+	}
+}
+
+func TestGetStaticDevices(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    []StaticDevice
+		wantErr string
+	}{
+		{
+			name: "color and brightness",
+			yaml: "static_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    color: '#FF8000'\n    brightness: 40\n",
+			want: []StaticDevice{{GoveeDeviceId: "11:22:33:44:55:66:77:88", Color: "#FF8000", Brightness: 40,
+				R: 255, G: 128, B: 0}},
+		},
+		{
+			name: "off",
+			yaml: "static_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    color: red\n    on: false\n",
+			want: []StaticDevice{{GoveeDeviceId: "11:22:33:44:55:66:77:88", Color: "red", On: ptr(false), R: 255}},
+		},
+		{
+			name: "alias",
+			yaml: "govee_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    alias: porch\n" +
+				"static_devices:\n  - device_id: porch\n    color: blue\n    brightness: 100\n",
+			want: []StaticDevice{{GoveeDeviceId: "11:22:33:44:55:66:77:88", Color: "blue", Brightness: 100, B: 255}},
+		},
+		{name: "missing device", yaml: "static_devices:\n  - color: red\n", wantErr: "missing a device_id"},
+		{name: "brightness above 100",
+			yaml:    "static_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    color: red\n    brightness: 101\n",
+			wantErr: "out of range"},
+		{name: "negative brightness",
+			yaml:    "static_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    color: red\n    brightness: -1\n",
+			wantErr: "out of range"},
+		{name: "invalid color",
+			yaml:    "static_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    color: '#GG0000'\n",
+			wantErr: "invalid color"},
+		{name: "synchronized device",
+			yaml:    testSync() + "static_devices:\n  - device_id: AA:BB:CC:DD:EE:FF:00:11\n    color: red\n",
+			wantErr: "also synchronized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTestConfig(t, tt.yaml)
+
+			got, err := GetStaticDevices()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetStaticDevices() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetStaticDevices() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetStaticDevices() = %+v, want %+v", got, tt.want)
+			}
+			if got[0].IsOn() != (tt.want[0].On == nil || *tt.want[0].On) {
+				t.Errorf("IsOn() = %v, want on unless turned off explicitly", got[0].IsOn())
+			}
+		})
+	}
+}
+
+func TestDeriveRuleScales(t *testing.T) {
+	tests := []struct {
+		name                                    string
+		rule                                    DeriveRule
+		wantSaturationScale, wantLightnessScale float64
+	}{
+		{name: "unset", wantSaturationScale: 1, wantLightnessScale: 1},
+		{name: "saturation only", rule: DeriveRule{SaturationScale: ptr(0.5)}, wantSaturationScale: 0.5,
+			wantLightnessScale: 1},
+		{name: "zero scales", rule: DeriveRule{SaturationScale: ptr(0.0), LightnessScale: ptr(0.0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saturationScale, lightnessScale := tt.rule.Scales()
+			if saturationScale != tt.wantSaturationScale || lightnessScale != tt.wantLightnessScale {
+				t.Errorf("Scales() = (%v, %v), want (%v, %v)", saturationScale, lightnessScale,
+					tt.wantSaturationScale, tt.wantLightnessScale)
+			}
+		})
+	}
+}
+
+func TestResolveGoveeDeviceID(t *testing.T) {
+	loadTestConfig(t, "govee_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    alias: desk\n")
+
+	tests := []struct {
+		idOrAlias string
+		want      string
+		wantErr   string
+	}{
+		{idOrAlias: "desk", want: "11:22:33:44:55:66:77:88"},
+		{idOrAlias: "AA:BB:CC:DD:EE:FF:00:11", want: "AA:BB:CC:DD:EE:FF:00:11"},
+		{idOrAlias: "porch", wantErr: "unknown govee device alias \"porch\""},
+		{wantErr: "missing a govee device ID"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveGoveeDeviceID(tt.idOrAlias)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ResolveGoveeDeviceID(%q) = %q, %v, want an error containing %q", tt.idOrAlias, got, err,
+					tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveGoveeDeviceID(%q) = %q, %v, want %q", tt.idOrAlias, got, err, tt.want)
+		}
+	}
+}
+
+func TestGoveeDeviceAliasCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "device ID", yaml: "govee_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n" +
+			"    alias: AA:BB:CC:DD:EE:FF:00:11\n", wantErr: "must not be or look like a device ID"},
+		{name: "ID of another device", yaml: "govee_devices:\n  - device_id: desk\n" +
+			"  - device_id: 11:22:33:44:55:66:77:88\n    alias: desk\n", wantErr: "must not be or look like a device ID"},
+		{name: "duplicate", yaml: "govee_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    alias: desk\n" +
+			"  - device_id: AA:BB:CC:DD:EE:FF:00:11\n    alias: desk\n", wantErr: "used more than once"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTestConfig(t, tt.yaml)
+
+			if _, err := GetGoveeDevices(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GetGoveeDevices() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSynchronizationAliasResolved(t *testing.T) {
+	loadTestConfig(t, "govee_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    alias: desk\n"+
+		"synchronizations:\n  - hue_light_id: light-1\n    govee_device_id: desk\n")
+
+	synchronizations, err := GetSynchronizations()
+	if err != nil {
+		t.Fatalf("GetSynchronizations() returned error: %v", err)
+	}
+	if got := synchronizations[0].GoveeDeviceId; got != "11:22:33:44:55:66:77:88" {
+		t.Errorf("GoveeDeviceId = %q, want the device ID of the alias", got)
+	}
+}
+
+func TestGetDeviceCapabilities(t *testing.T) {
+	loadTestConfig(t, "govee_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    alias: lamp\n"+
+		"govee_device_capabilities:\n  - device_id: lamp\n    on_off: true\n    color: true\n    segments: true\n")
+
+	got, err := GetDeviceCapabilities()
+	if err != nil {
+		t.Fatalf("GetDeviceCapabilities() returned error: %v", err)
+	}
+	want := []DeviceCapabilities{{GoveeDeviceId: "11:22:33:44:55:66:77:88", OnOff: true, Color: true, Segments: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDeviceCapabilities() = %+v, want %+v", got, want)
+	}
+
+	loadTestConfig(t, "govee_device_capabilities:\n  - on_off: true\n")
+	if _, err := GetDeviceCapabilities(); err == nil || !strings.Contains(err.Error(), "missing a device_id") {
+		t.Errorf("GetDeviceCapabilities() without a device ID = %v, want an error", err)
+	}
+}
+
+func TestGetDIYScenes(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    map[string]int
+		wantErr string
+	}{
+		{name: "none", want: map[string]int{}},
+		{name: "names are case-insensitive",
+			yaml: "govee_diy_scenes:\n  - hue_scene: Tropical Twilight\n    govee_diy_code: 42\n" +
+				"  - hue_scene: relax\n    govee_diy_code: 7\n",
+			want: map[string]int{"tropical twilight": 42, "relax": 7}},
+		{name: "missing name", yaml: "govee_diy_scenes:\n  - govee_diy_code: 42\n", wantErr: "missing a hue_scene"},
+		{name: "duplicate name",
+			yaml: "govee_diy_scenes:\n  - hue_scene: Relax\n    govee_diy_code: 1\n" +
+				"  - hue_scene: RELAX\n    govee_diy_code: 2\n",
+			wantErr: "more than one govee DIY scene"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTestConfig(t, tt.yaml)
+
+			got, err := GetDIYScenes()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetDIYScenes() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDIYScenes() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoveeSceneCode(t *testing.T) {
+	loadTestConfig(t, testSync("govee_scenes:", "  - hue_scene: Tropical twilight", "    govee_scene_code: 1234"))
+	synchronizations, err := GetSynchronizations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sync := synchronizations[0]
+	if code, ok := sync.GoveeSceneCode("tropical TWILIGHT"); !ok || code != 1234 {
+		t.Errorf("GoveeSceneCode() = %d, %v, want the code of the scene matched case-insensitively", code, ok)
+	}
+	if code, ok := sync.GoveeSceneCode("Relax"); ok {
+		t.Errorf("GoveeSceneCode() of an unmapped scene = %d, want no code", code)
+	}
+}
+
+func TestColorCorrection(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    govee.ColorCorrection
+		wantErr string
+	}{
+		{name: "gains", yaml: "r_gain: 1.1\n      g_gain: 0.8\n", want: govee.GainCorrection(1.1, 0.8, 1)},
+		{name: "matrix", yaml: "matrix: [[1, 0, 0], [0.1, 0.9, 0], [0, 0, 1]]\n",
+			want: govee.ColorCorrection{{1, 0, 0}, {0.1, 0.9, 0}, {0, 0, 1}}},
+		{name: "gains and matrix", yaml: "r_gain: 1\n      matrix: [[1, 0, 0], [0, 1, 0], [0, 0, 1]]\n",
+			wantErr: "either gains or a matrix"},
+		{name: "matrix rows", yaml: "matrix: [[1, 0, 0], [0, 1, 0]]\n", wantErr: "3 rows"},
+		{name: "matrix columns", yaml: "matrix: [[1, 0, 0], [0, 1], [0, 0, 1]]\n", wantErr: "3 columns"},
+		{name: "negative gain", yaml: "b_gain: -0.5\n", wantErr: "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadTestConfig(t, "govee_devices:\n  - device_id: 11:22:33:44:55:66:77:88\n    color_correction:\n      "+
+				tt.yaml)
+
+			devices, err := GetGoveeDevices()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetGoveeDevices() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetGoveeDevices() returned error: %v", err)
+			}
+			if len(devices) != 1 || devices[0].ColorCorrection == nil {
+				t.Fatalf("GetGoveeDevices() = %+v, want the device with its color correction", devices)
+			}
+			if got := devices[0].ColorCorrection.Correction(); got != tt.want {
+				t.Errorf("Correction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Client is a client for the control socket of a running bridge
+type Client struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+// Dial connects to the control socket at path.
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to control socket %s: %w", path, err)
+	}
+	return &Client{conn: conn, scanner: bufio.NewScanner(conn)}, nil
+}
+
+// Close closes the connection to the control socket.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Do sends a command and returns the data of the response, or an error if the command failed.
+func (c *Client) Do(command string, args ...string) (json.RawMessage, error) {
+	req := Request{Version: ProtocolVersion, Command: command, Args: args}
+	if err := json.NewEncoder(c.conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		return nil, errors.New("connection closed by daemon")
+	}
+
+	var resp Response
+	if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if resp.Version != ProtocolVersion {
+		return nil, fmt.Errorf("daemon speaks protocol version %d, client speaks version %d",
+			resp.Version, ProtocolVersion)
+	}
+	if !resp.OK {
+		return nil, errors.New(resp.Error)
+	}
+	return resp.Data, nil
+}
@@ -0,0 +1,97 @@
+package control
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	controller := &fakeController{}
+	client, err := Dial(startTestServer(t, controller))
+	if err != nil {
+		t.Fatalf("Dial() returned error: %v", err)
+	}
+	defer client.Close()
+
+	data, err := client.Do(CommandStatus)
+	if err != nil || string(data) != `{"synchronizations":2}` {
+		t.Errorf("Do(status) = %s, %v, want the status", data, err)
+	}
+	if data, err := client.Do(CommandPause, "desk"); err != nil || len(data) != 0 {
+		t.Errorf("Do(pause) = %s, %v, want no data", data, err)
+	}
+	if _, err := client.Do(CommandPause, "unknown"); err == nil || err.Error() != "unknown synchronization" {
+		t.Errorf("Do(pause unknown) = %v, want the error of the daemon", err)
+	}
+	if _, err := client.Do(CommandSet, "AA:BB"); err == nil || !strings.Contains(err.Error(), "requires") {
+		t.Errorf("Do(set) without a color = %v, want a usage error", err)
+	}
+}
+
+// serveOnce serves a single connection on a socket in a temp dir, answering every request with the response.
+func serveOnce(t *testing.T, response string) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "hue2govee.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			if response == "" {
+				return
+			}
+			conn.Write([]byte(response + "\n"))
+		}
+	}()
+	return path
+}
+
+func TestClientProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		wantErr  string
+	}{
+		{name: "newer daemon", response: `{"version":2,"ok":true}`, wantErr: "protocol version 2"},
+		{name: "invalid response", response: `OK`, wantErr: "invalid response"},
+		{name: "connection closed", wantErr: "connection closed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := Dial(serveOnce(t, tt.response))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			if _, err := client.Do(CommandStatus); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Do() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDialMissingSocket(t *testing.T) {
+	if _, err := Dial(filepath.Join(t.TempDir(), "missing.sock")); err == nil {
+		t.Error("Dial() of a missing socket = nil, want an error")
+	}
+}
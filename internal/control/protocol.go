@@ -0,0 +1,50 @@
+package control
+
+import (
+	"encoding/json"
+)
+
+// ProtocolVersion is the version of the control protocol. It is bumped on incompatible changes so that
+// the client and the daemon can detect a mismatch.
+const ProtocolVersion = 1
+
+// Commands supported by the control socket
+const (
+	CommandStatus     = "status"
+	CommandPause      = "pause"
+	CommandResume     = "resume"
+	CommandAllOff     = "all-off"
+	CommandRediscover = "rediscover"
+	CommandSet        = "set"
+)
+
+// Request is a single control request, sent as one JSON object per line
+type Request struct {
+	Version int      `json:"version"`
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// Response is the response to a control request, sent as one JSON object per line
+type Response struct {
+	Version int             `json:"version"`
+	OK      bool            `json:"ok"`
+	Error   string          `json:"error,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Controller controls the running bridge
+type Controller interface {
+	// Status returns the current status of the bridge
+	Status() any
+	// Pause pauses forwarding of the synchronization with the given ID
+	Pause(id string) error
+	// Resume resumes forwarding of the synchronization with the given ID
+	Resume(id string) error
+	// AllOff pauses all synchronizations and turns off their Govee devices
+	AllOff() error
+	// Rediscover triggers an immediate discovery of the Hue bridge and Govee devices
+	Rediscover() error
+	// Set sets the color of a Govee device, given as a hex color
+	Set(deviceID, color string) error
+}
@@ -0,0 +1,138 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// Server serves the control protocol on a Unix domain socket
+type Server struct {
+	path       string
+	controller Controller
+	logger     zerolog.Logger
+}
+
+// NewServer creates a new Server listening on the Unix domain socket at path
+func NewServer(path string, controller Controller, logger zerolog.Logger) *Server {
+	return &Server{
+		path:       path,
+		controller: controller,
+		logger:     logger,
+	}
+}
+
+// Start starts listening on the socket. The socket is closed and removed once the context is done.
+func (s *Server) Start(ctx context.Context) error {
+	// remove a stale socket left behind by a previous run
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale control socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control socket %s: %w", s.path, err)
+	}
+	s.logger.Info().Str("path", s.path).Msg("Listening on control socket")
+
+	go func() {
+		<-ctx.Done()
+		listener.Close() // also removes the socket file
+	}()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				s.logger.Error().Err(err).Msg("Failed to accept control connection")
+				continue
+			}
+			go s.handleConn(conn)
+		}
+	}()
+
+	return nil
+}
+
+// handleConn handles all requests of a single connection.
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req Request
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = Response{Error: fmt.Sprintf("invalid request: %s", err)}
+		} else {
+			resp = s.handle(req)
+		}
+
+		resp.Version = ProtocolVersion
+		if err := encoder.Encode(resp); err != nil {
+			s.logger.Error().Err(err).Msg("Failed to write control response")
+			return
+		}
+	}
+}
+
+// handle handles a single request.
+func (s *Server) handle(req Request) Response {
+	s.logger.Debug().Str("command", req.Command).Strs("args", req.Args).Msg("Received control request")
+
+	// requests without a version predate versioning and are treated as version 1
+	if req.Version != 0 && req.Version != ProtocolVersion {
+		return Response{Error: fmt.Sprintf("unsupported protocol version %d, daemon speaks version %d",
+			req.Version, ProtocolVersion)}
+	}
+
+	var data any
+	var err error
+	switch req.Command {
+	case CommandStatus:
+		data = s.controller.Status()
+	case CommandPause, CommandResume:
+		if len(req.Args) != 1 {
+			return Response{Error: fmt.Sprintf("%s requires exactly one synchronization ID", req.Command)}
+		}
+		if req.Command == CommandPause {
+			err = s.controller.Pause(req.Args[0])
+		} else {
+			err = s.controller.Resume(req.Args[0])
+		}
+	case CommandAllOff:
+		err = s.controller.AllOff()
+	case CommandRediscover:
+		err = s.controller.Rediscover()
+	case CommandSet:
+		if len(req.Args) != 2 {
+			return Response{Error: "set requires a device ID and a color"}
+		}
+		err = s.controller.Set(req.Args[0], req.Args[1])
+	default:
+		err = fmt.Errorf("unknown command %q", req.Command)
+	}
+
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	if data == nil {
+		return Response{OK: true}
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return Response{Error: fmt.Sprintf("failed to marshal response: %s", err)}
+	}
+	return Response{OK: true, Data: b}
+}
@@ -0,0 +1,178 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// fakeController records the calls it receives and fails those for the unknown synchronization.
+type fakeController struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *fakeController) record(call string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, call)
+}
+
+func (c *fakeController) Status() any {
+	return map[string]int{"synchronizations": 2}
+}
+
+func (c *fakeController) Pause(id string) error {
+	if id == "unknown" {
+		return errors.New("unknown synchronization")
+	}
+	c.record("pause " + id)
+	return nil
+}
+
+func (c *fakeController) Resume(id string) error {
+	c.record("resume " + id)
+	return nil
+}
+
+func (c *fakeController) AllOff() error {
+	c.record("all-off")
+	return nil
+}
+
+func (c *fakeController) Rediscover() error {
+	c.record("rediscover")
+	return nil
+}
+
+func (c *fakeController) Set(deviceID, color string) error {
+	c.record("set " + deviceID + " " + color)
+	return nil
+}
+
+// startTestServer starts a Server with the controller on a socket in a temp dir, returning the socket path.
+func startTestServer(t *testing.T, controller Controller) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "control") // t.TempDir may exceed the maximum socket path length
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "hue2govee.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := NewServer(path, controller, zerolog.Nop()).Start(ctx); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	return path
+}
+
+func TestServer(t *testing.T) {
+	controller := &fakeController{}
+	path := startTestServer(t, controller)
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+
+	tests := []struct {
+		name      string
+		request   string
+		wantOK    bool
+		wantData  string
+		wantError string
+	}{
+		{name: "status", request: `{"version":1,"command":"status"}`, wantOK: true,
+			wantData: `{"synchronizations":2}`},
+		{name: "pause", request: `{"version":1,"command":"pause","args":["desk"]}`, wantOK: true},
+		{name: "resume without version", request: `{"command":"resume","args":["desk"]}`, wantOK: true},
+		{name: "all off", request: `{"version":1,"command":"all-off"}`, wantOK: true},
+		{name: "rediscover", request: `{"version":1,"command":"rediscover"}`, wantOK: true},
+		{name: "set", request: `{"version":1,"command":"set","args":["AA:BB","#FF0000"]}`, wantOK: true},
+		{name: "pause without ID", request: `{"version":1,"command":"pause"}`,
+			wantError: "requires exactly one synchronization ID"},
+		{name: "set without color", request: `{"version":1,"command":"set","args":["AA:BB"]}`,
+			wantError: "requires a device ID and a color"},
+		{name: "controller error", request: `{"version":1,"command":"pause","args":["unknown"]}`,
+			wantError: "unknown synchronization"},
+		{name: "unknown command", request: `{"version":1,"command":"explode"}`, wantError: "unknown command"},
+		{name: "newer protocol version", request: `{"version":2,"command":"status"}`,
+			wantError: "unsupported protocol version 2"},
+		{name: "invalid request", request: `status`, wantError: "invalid request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := conn.Write([]byte(tt.request + "\n")); err != nil {
+				t.Fatal(err)
+			}
+			if !scanner.Scan() {
+				t.Fatalf("no response: %v", scanner.Err())
+			}
+			var resp Response
+			if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response %s: %v", scanner.Bytes(), err)
+			}
+
+			if resp.Version != ProtocolVersion || resp.OK != tt.wantOK || string(resp.Data) != tt.wantData ||
+				!strings.Contains(resp.Error, tt.wantError) || (tt.wantError == "") != (resp.Error == "") {
+				t.Errorf("response = %s, want ok %v, data %q and error %q", scanner.Bytes(), tt.wantOK,
+					tt.wantData, tt.wantError)
+			}
+		})
+	}
+
+	want := []string{"pause desk", "resume desk", "all-off", "rediscover", "set AA:BB #FF0000"}
+	if !slices.Equal(controller.calls, want) {
+		t.Errorf("controller calls = %v, want %v", controller.calls, want)
+	}
+}
+
+func TestServerSocketLifecycle(t *testing.T) {
+	dir, err := os.MkdirTemp("", "control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hue2govee.sock")
+
+	// a stale socket file of a previous run is replaced
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := NewServer(path, &fakeController{}, zerolog.Nop()).Start(ctx); err != nil {
+		t.Fatalf("Start() with a stale socket returned error: %v", err)
+	}
+	if conn, err := net.Dial("unix", path); err != nil {
+		t.Fatalf("failed to connect to the control socket: %v", err)
+	} else {
+		conn.Close()
+	}
+
+	// the socket is removed on shutdown
+	cancel()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("control socket still exists after shutdown")
+		}
+	}
+}
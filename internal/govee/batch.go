@@ -0,0 +1,81 @@
+package govee
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Batcher coalesces color and brightness updates to a device within a time window and only sends the
+// final state once the window ends, reducing packet count and flicker during rapid changes.
+type Batcher struct {
+	client *Client
+	window time.Duration
+	logger zerolog.Logger
+
+	mu      sync.Mutex // Mutex to protect pending and timers updates
+	pending map[string]State
+	timers  map[string]*time.Timer
+}
+
+// NewBatcher creates a new Batcher sending through the given client after each window.
+func NewBatcher(client *Client, window time.Duration, logger zerolog.Logger) *Batcher {
+	return &Batcher{
+		client:  client,
+		window:  window,
+		logger:  logger,
+		pending: make(map[string]State),
+		timers:  make(map[string]*time.Timer),
+	}
+}
+
+// Set records the state for the device, to be sent at the end of the current window. The first update
+// after a window ended opens a new window.
+func (b *Batcher) Set(deviceID string, state State) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending[deviceID] = state
+	if _, ok := b.timers[deviceID]; !ok {
+		b.timers[deviceID] = time.AfterFunc(b.window, func() {
+			b.flush(deviceID)
+		})
+	}
+}
+
+// Stop discards all pending updates without sending them. It's called when something else takes over the device,
+// like a manual color, a scene or turning the device off, which the pending state must not override.
+func (b *Batcher) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for deviceID, timer := range b.timers {
+		timer.Stop()
+		delete(b.timers, deviceID)
+		delete(b.pending, deviceID)
+	}
+}
+
+// flush sends the pending state of the device.
+func (b *Batcher) flush(deviceID string) {
+	b.mu.Lock()
+	state, ok := b.pending[deviceID]
+	delete(b.pending, deviceID)
+	delete(b.timers, deviceID)
+	b.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if err := b.client.SetColor(deviceID, state.Color.R, state.Color.G, state.Color.B); err != nil {
+		if !IsDeviceNotFound(err) && !IsDeviceFailed(err) {
+			b.logger.Error().Err(err).Str("deviceId", deviceID).Msg("Failed to set batched Govee color")
+		}
+		return
+	}
+	if err := b.client.SetBrightness(deviceID, state.Brightness); err != nil && !IsDeviceNotFound(err) && !IsDeviceFailed(err) {
+		b.logger.Error().Err(err).Str("deviceId", deviceID).Msg("Failed to set batched Govee brightness")
+	}
+}
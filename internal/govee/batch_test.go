@@ -0,0 +1,47 @@
+package govee
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestBatcherCollapsesUpdates(t *testing.T) {
+	client, device := newTestClient(t)
+	batcher := NewBatcher(client, 50*time.Millisecond, zerolog.Nop())
+
+	for i := range 5 {
+		batcher.Set(testDeviceID, State{Color: RGBColor{R: 10 * i, G: 0, B: 0}, Brightness: 10 + i})
+	}
+
+	// only the final state is sent once the window ends
+	if got, want := device.receiveColor(), (RGBColor{R: 40, G: 0, B: 0}); got != want {
+		t.Errorf("device received color %v, want the final %v", got, want)
+	}
+	if got := device.receiveBrightness(); got != 14 {
+		t.Errorf("device received brightness %d, want the final 14", got)
+	}
+	device.expectNothing()
+
+	// the next update opens a new window
+	batcher.Set(testDeviceID, State{Color: RGBColor{R: 0, G: 0, B: 255}, Brightness: 14})
+	if got, want := device.receiveColor(), (RGBColor{R: 0, G: 0, B: 255}); got != want {
+		t.Errorf("device received color %v in the next window, want %v", got, want)
+	}
+}
+
+func TestBatcherStopDiscardsPending(t *testing.T) {
+	client, device := newTestClient(t)
+	batcher := NewBatcher(client, 50*time.Millisecond, zerolog.Nop())
+
+	batcher.Set(testDeviceID, State{Color: RGBColor{R: 255, G: 0, B: 0}, Brightness: 50})
+	batcher.Stop()
+	device.expectNothing()
+
+	// the batcher keeps working after Stop
+	batcher.Set(testDeviceID, State{Color: RGBColor{R: 0, G: 255, B: 0}, Brightness: 50})
+	if got, want := device.receiveColor(), (RGBColor{R: 0, G: 255, B: 0}); got != want {
+		t.Errorf("device received color %v after Stop, want %v", got, want)
+	}
+}
@@ -0,0 +1,37 @@
+package govee
+
+// deviceState is the last known state of a device as sent by the client, nil fields are unknown
+type deviceState struct {
+	On               *bool
+	Color            *RGBColor
+	ColorTemperature *int
+	Brightness       *int
+	Segments         []RGBColor
+}
+
+// ForceRefresh forgets the last known state of a device so that the next commands are sent even if
+// they match it, e.g. after something else (like a scene) changed the device.
+func (c *Client) ForceRefresh(deviceID string) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	delete(c.states, deviceID)
+}
+
+// knownState returns the last known state of a device.
+func (c *Client) knownState(deviceID string) deviceState {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	return c.states[deviceID]
+}
+
+// rememberState updates the last known state of a device after a command was sent.
+func (c *Client) rememberState(deviceID string, update func(state *deviceState)) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	state := c.states[deviceID]
+	update(&state)
+	c.states[deviceID] = state
+}
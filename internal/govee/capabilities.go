@@ -0,0 +1,152 @@
+package govee
+
+import (
+	"fmt"
+)
+
+// Capabilities describes the commands a Govee device supports
+type Capabilities struct {
+	OnOff            bool `json:"onOff"`
+	Brightness       bool `json:"brightness"`
+	Color            bool `json:"color"`
+	ColorTemperature bool `json:"colorTemperature"`
+	Segments         bool `json:"segments"`
+}
+
+// DefaultCapabilities is the conservative capability set assumed for devices of unknown models
+var DefaultCapabilities = Capabilities{
+	OnOff:      true,
+	Brightness: true,
+	Color:      true,
+}
+
+var (
+	// rgbCapabilities are the capabilities of devices with a single color
+	rgbCapabilities = Capabilities{OnOff: true, Brightness: true, Color: true, ColorTemperature: true}
+	// rgbicCapabilities are the capabilities of RGBIC devices whose segments can be colored individually
+	rgbicCapabilities = Capabilities{OnOff: true, Brightness: true, Color: true, ColorTemperature: true, Segments: true}
+)
+
+// modelCapabilities maps known device models (SKUs) to their capabilities
+var modelCapabilities = map[string]Capabilities{
+	// LED strips
+	"H6110": rgbCapabilities,
+	"H6141": rgbCapabilities,
+	"H6159": rgbCapabilities,
+	"H6163": rgbCapabilities,
+	"H615A": rgbCapabilities,
+	"H615B": rgbCapabilities,
+	"H615C": rgbCapabilities,
+	"H615D": rgbCapabilities,
+	// RGBIC LED strips
+	"H619A": rgbicCapabilities,
+	"H619B": rgbicCapabilities,
+	"H619C": rgbicCapabilities,
+	"H619D": rgbicCapabilities,
+	"H619E": rgbicCapabilities,
+	"H61A0": rgbicCapabilities,
+	"H61A1": rgbicCapabilities,
+	"H61A2": rgbicCapabilities,
+	"H61A3": rgbicCapabilities,
+	// RGBIC floor lamps and light bars
+	"H6072": rgbicCapabilities,
+	"H6076": rgbicCapabilities,
+	"H6056": rgbicCapabilities,
+	"H6046": rgbicCapabilities,
+}
+
+// DeviceInfo describes a discovered Govee device, as reported by its last discovery response
+type DeviceInfo struct {
+	ID              string       `json:"id"`
+	IP              string       `json:"ip"`
+	SKU             string       `json:"sku,omitempty"` // model of the device, empty if the device didn't report it
+	BLEVersionHard  string       `json:"bleVersionHard,omitempty"`
+	BLEVersionSoft  string       `json:"bleVersionSoft,omitempty"`
+	WiFiVersionHard string       `json:"wifiVersionHard,omitempty"`
+	WiFiVersionSoft string       `json:"wifiVersionSoft,omitempty"`
+	Capabilities    Capabilities `json:"capabilities"`
+}
+
+var (
+	ErrUnsupportedCommand = fmt.Errorf("command not supported by device")
+)
+
+// SetCapabilityOverrides sets user-declared capabilities for devices, taking precedence over the built-in model map.
+func (c *Client) SetCapabilityOverrides(overrides map[string]Capabilities) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capabilityOverrides = overrides
+}
+
+// Capabilities returns the capabilities of the device with the given ID. Devices without a user-declared
+// override or a known model fall back to DefaultCapabilities.
+func (c *Client) Capabilities(deviceID string) Capabilities {
+	c.mu.RLock()
+	override, ok := c.capabilityOverrides[deviceID]
+	model := c.discovered[deviceID].SKU
+	c.mu.RUnlock()
+
+	if ok {
+		return override
+	}
+	if capabilities, ok := modelCapabilities[model]; ok {
+		return capabilities
+	}
+
+	if _, warned := c.capabilityFallbacks.LoadOrStore(deviceID, struct{}{}); !warned {
+		c.logger.Info().Str("deviceId", deviceID).Str("model", model).
+			Msg("Unknown Govee device model, assuming basic color, brightness and on/off support")
+	}
+	return DefaultCapabilities
+}
+
+// DeviceInfo returns the IP, model, firmware versions and capabilities of a discovered device, false if the
+// device isn't known.
+func (c *Client) DeviceInfo(deviceID string) (DeviceInfo, bool) {
+	ip, ok := c.deviceIP(deviceID)
+	if !ok {
+		return DeviceInfo{}, false
+	}
+
+	c.mu.RLock()
+	discovered := c.discovered[deviceID]
+	c.mu.RUnlock()
+	return DeviceInfo{
+		ID:              deviceID,
+		IP:              ip,
+		SKU:             discovered.SKU,
+		BLEVersionHard:  discovered.BLEVersionHard,
+		BLEVersionSoft:  discovered.BLEVersionSoft,
+		WiFiVersionHard: discovered.WiFiVersionHard,
+		WiFiVersionSoft: discovered.WiFiVersionSoft,
+		Capabilities:    c.Capabilities(deviceID),
+	}, true
+}
+
+// DeviceInfos returns the infos of all discovered devices, sorted by device ID.
+func (c *Client) DeviceInfos() []DeviceInfo {
+	var infos []DeviceInfo
+	for _, deviceID := range c.KnownDeviceIDs() {
+		if info, ok := c.DeviceInfo(deviceID); ok {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// requireCapability returns ErrUnsupportedCommand if the device doesn't support a command. The first unsupported
+// command per device and command is logged as a warning.
+func (c *Client) requireCapability(deviceID string, supported func(Capabilities) bool, cmd string) error {
+	if !supported(c.Capabilities(deviceID)) {
+		if _, warned := c.unsupportedWarnings.LoadOrStore(deviceID+"/"+cmd, struct{}{}); !warned {
+			c.mu.RLock()
+			model := c.discovered[deviceID].SKU
+			c.mu.RUnlock()
+			c.logger.Warn().Str("deviceId", deviceID).Str("model", model).Str("cmd", cmd).
+				Msg("Govee device doesn't support the command, declare its capabilities in govee_device_capabilities if it does")
+		}
+		return fmt.Errorf("%s: %w", cmd, ErrUnsupportedCommand)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package govee
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestCapabilitiesFallback(t *testing.T) {
+	client, device := newTestClient(t)
+	var logs bytes.Buffer
+	client.logger = zerolog.New(&logs)
+	client.SetCapabilityOverrides(nil)
+	client.discovered[testDeviceID] = DiscoveryData{DeviceID: testDeviceID, IP: "127.0.0.1", SKU: "H9999"}
+
+	// devices of unknown models get the conservative default capabilities
+	for range 3 {
+		if got := client.Capabilities(testDeviceID); got != DefaultCapabilities {
+			t.Errorf("Capabilities() of an unknown model = %+v, want %+v", got, DefaultCapabilities)
+		}
+	}
+	if n := strings.Count(logs.String(), "Unknown Govee device model"); n != 1 {
+		t.Errorf("fallback was logged %d times, want once:\n%s", n, logs.String())
+	}
+
+	// the basics work, commands beyond them are rejected without being sent
+	if err := client.SetColor(testDeviceID, 255, 0, 0); err != nil {
+		t.Errorf("SetColor() with default capabilities = %v, want it sent", err)
+	}
+	device.receiveColor()
+	if err := client.SetColorTemperature(testDeviceID, 2700); !IsUnsupportedCommand(err) {
+		t.Errorf("SetColorTemperature() with default capabilities = %v, want ErrUnsupportedCommand", err)
+	}
+	if err := client.SetSegmentColors(testDeviceID, []RGBColor{{R: 255}}); !IsUnsupportedCommand(err) {
+		t.Errorf("SetSegmentColors() with default capabilities = %v, want ErrUnsupportedCommand", err)
+	}
+	device.expectNothing()
+
+	// declared capabilities take precedence over the defaults
+	client.SetCapabilityOverrides(map[string]Capabilities{testDeviceID: {OnOff: true, ColorTemperature: true}})
+	if err := client.SetColorTemperature(testDeviceID, 2700); err != nil {
+		t.Errorf("SetColorTemperature() with declared support = %v, want it sent", err)
+	}
+	device.receive()
+}
+
+func TestCapabilitiesKnownModel(t *testing.T) {
+	client, _ := newTestClient(t)
+	client.SetCapabilityOverrides(nil)
+	client.discovered[testDeviceID] = DiscoveryData{DeviceID: testDeviceID, IP: "127.0.0.1", SKU: "H619A"}
+
+	if got := client.Capabilities(testDeviceID); got != rgbicCapabilities {
+		t.Errorf("Capabilities() of H619A = %+v, want %+v", got, rgbicCapabilities)
+	}
+
+	// an override replaces the capabilities of the model entirely
+	override := Capabilities{OnOff: true}
+	client.SetCapabilityOverrides(map[string]Capabilities{testDeviceID: override})
+	if got := client.Capabilities(testDeviceID); got != override {
+		t.Errorf("Capabilities() with an override = %+v, want %+v", got, override)
+	}
+}
+
+func TestModelCapabilities(t *testing.T) {
+	tests := []struct {
+		sku  string
+		want Capabilities
+	}{
+		{sku: "H6159", want: Capabilities{OnOff: true, Brightness: true, Color: true, ColorTemperature: true}},
+		{sku: "H619A", want: Capabilities{OnOff: true, Brightness: true, Color: true, ColorTemperature: true,
+			Segments: true}},
+		{sku: "H6076", want: Capabilities{OnOff: true, Brightness: true, Color: true, ColorTemperature: true,
+			Segments: true}},
+		{sku: "", want: DefaultCapabilities},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sku, func(t *testing.T) {
+			client, _ := newTestClient(t)
+			client.SetCapabilityOverrides(nil)
+			client.discovered[testDeviceID] = DiscoveryData{DeviceID: testDeviceID, IP: "127.0.0.1", SKU: tt.sku}
+
+			if got := client.Capabilities(testDeviceID); got != tt.want {
+				t.Errorf("Capabilities() of %q = %+v, want %+v", tt.sku, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeviceInfo(t *testing.T) {
+	client, _ := newTestClient(t)
+	client.SetCapabilityOverrides(nil)
+	client.discovered[testDeviceID] = DiscoveryData{DeviceID: testDeviceID, IP: "127.0.0.1", SKU: "H6159",
+		WiFiVersionSoft: "1.02.03"}
+
+	info, ok := client.DeviceInfo(testDeviceID)
+	want := DeviceInfo{ID: testDeviceID, IP: "127.0.0.1", SKU: "H6159", WiFiVersionSoft: "1.02.03",
+		Capabilities: rgbCapabilities}
+	if !ok || info != want {
+		t.Errorf("DeviceInfo() = %+v, %t, want %+v", info, ok, want)
+	}
+
+	if info, ok := client.DeviceInfo("00:00:00:00:00:00:00:00"); ok {
+		t.Errorf("DeviceInfo() of an unknown device = %+v, want none", info)
+	}
+}
+
+func TestUnsupportedCommandWarnedOnce(t *testing.T) {
+	client, device := newTestClient(t)
+	var logs bytes.Buffer
+	client.logger = zerolog.New(&logs)
+	client.SetCapabilityOverrides(map[string]Capabilities{testDeviceID: {OnOff: true, Brightness: true}})
+
+	// rejected commands aren't sent, and each is only warned about once
+	for range 3 {
+		if err := client.SetColor(testDeviceID, 255, 0, 0); !IsUnsupportedCommand(err) {
+			t.Errorf("SetColor() without color support = %v, want ErrUnsupportedCommand", err)
+		}
+		if err := client.SetSegmentColors(testDeviceID, []RGBColor{{R: 255}}); !IsUnsupportedCommand(err) {
+			t.Errorf("SetSegmentColors() without segment support = %v, want ErrUnsupportedCommand", err)
+		}
+	}
+	device.expectNothing()
+	if n := strings.Count(logs.String(), "doesn't support the command"); n != 2 {
+		t.Errorf("unsupported commands were warned about %d times, want once per command:\n%s", n, logs.String())
+	}
+
+	// supported commands are still sent
+	if err := client.SetBrightness(testDeviceID, 50); err != nil {
+		t.Errorf("SetBrightness() with brightness support = %v, want it sent", err)
+	}
+	if got := device.receiveBrightness(); got != 50 {
+		t.Errorf("device received brightness %d, want 50", got)
+	}
+}
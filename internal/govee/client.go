@@ -5,9 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/cedrickring/hue-to-govee/internal/metrics"
 	"github.com/rs/zerolog"
 )
 
@@ -24,8 +29,13 @@ type Message[DataType any] struct {
 
 // DiscoveryData is the data structure for Govee discovery messages
 type DiscoveryData struct {
-	DeviceID string `json:"device"`
-	IP       string `json:"ip"`
+	DeviceID        string `json:"device"`
+	IP              string `json:"ip"`
+	SKU             string `json:"sku"` // model of the device, e.g. H6159
+	BLEVersionHard  string `json:"bleVersionHard"`
+	BLEVersionSoft  string `json:"bleVersionSoft"`
+	WiFiVersionHard string `json:"wifiVersionHard"`
+	WiFiVersionSoft string `json:"wifiVersionSoft"`
 }
 
 // DiscoveryResponseData is the data structure for Govee discovery responses
@@ -51,17 +61,32 @@ type ColorData struct {
 	ColorTemperature int      `json:"colorTemperature"`
 }
 
+// DIYSceneData is the data structure for Govee DIY scene commands
+type DIYSceneData struct {
+	Value int `json:"value"`
+}
+
 // BrightnessData is the data structure for Govee brightness commands
 type BrightnessData struct {
 	Value int `json:"value"`
 }
 
+const (
+	// MinColorTemperature is the warmest color temperature in Kelvin accepted by most Govee devices
+	MinColorTemperature = 2000
+	// MaxColorTemperature is the coolest color temperature in Kelvin accepted by most Govee devices
+	MaxColorTemperature = 9000
+)
+
 const (
 	discoveryPort int = 4001
 	responsePort  int = 4002
 	controlPort   int = 4003
 )
 
+// DefaultMulticastIP is the multicast group Govee devices listen on for discovery requests
+const DefaultMulticastIP = "239.255.255.250"
+
 var (
 	ErrDeviceNotFound = fmt.Errorf("device not found")
 )
@@ -69,113 +94,398 @@ var (
 // Client is a client for the Govee API
 type Client struct {
 	multicastIP string
+	ipVersion   IPVersion // IP versions discovery runs over
 	logger      zerolog.Logger
-	devices     map[string]string // map[deviceID]IP
+
+	mu                  sync.RWMutex             // Mutex to protect devices, discovered and capabilityOverrides updates
+	devices             map[string]string        // map[deviceID]IP
+	discovered          map[string]DiscoveryData // map[deviceID]last discovery response
+	lastSeen            map[string]time.Time     // map[deviceID]last discovery response
+	deviceTTL           time.Duration            // devices not seen for longer are removed
+	scanInterval        time.Duration            // interval of discovery requests after the initial scans
+	capabilityOverrides map[string]Capabilities  // map[deviceID]Capabilities
+	interfaces          []string                 // names of the network interfaces discovery runs on
+	capabilityFallbacks sync.Map                 // device IDs already logged as falling back to default capabilities
+	unsupportedWarnings sync.Map                 // device ID and command pairs already logged as unsupported
+	defaultSettings     DeviceSettings
+	settings            map[string]DeviceSettings
+
+	healthMu sync.Mutex // Mutex to protect health updates
+	health   map[string]*deviceHealth
+
+	stateMu     sync.Mutex             // Mutex to protect states and commandSeqs updates
+	states      map[string]deviceState // last known state per device, used to skip redundant commands
+	commandSeqs map[string]uint64      // number of commands sent per device, superseded commands aren't retried
+
+	statusMu      sync.Mutex                   // Mutex to protect statusWaiters updates
+	statusWaiters map[string][]chan StatusData // map[IP]pending QueryStatus calls
+
+	maxBrightness atomic.Int32 // global brightness cap in percent, 0 means no cap
+
+	connMu sync.Mutex          // Mutex to protect conns updates
+	conns  map[string]net.Conn // map[IP]connection to the control port, reused across commands
+
+	rescan chan struct{} // triggers an immediate discovery request
+
+	discoveryMu   sync.Mutex           // Mutex to protect stopDiscovery updates
+	stopDiscovery []context.CancelFunc // stops the running discoveries
+	discovery     sync.WaitGroup       // running discovery goroutines
+
+	now         func() time.Time // returns the current time, replaceable for tests
+	controlPort int              // port commands are sent to, replaceable for tests
+	randFloat   func() float64   // returns a random number in [0, 1) to jitter scans, replaceable for tests
+	// dial dials the control port of a device, replaceable for tests
+	dial func(network, address string, timeout time.Duration) (net.Conn, error)
 }
 
 // NewClient creates a new Client
 func NewClient(logger zerolog.Logger, multicastIP string) *Client {
 	return &Client{
-		logger:      logger,
-		multicastIP: multicastIP,
-		devices:     make(map[string]string),
+		logger:       logger,
+		multicastIP:  multicastIP,
+		ipVersion:    IPv4,
+		devices:      make(map[string]string),
+		lastSeen:     make(map[string]time.Time),
+		conns:        make(map[string]net.Conn),
+		deviceTTL:    DefaultDeviceTTL,
+		scanInterval: DefaultScanInterval,
+		now:          time.Now,
+		randFloat:    rand.Float64,
+		controlPort:  controlPort,
+		discovered:   make(map[string]DiscoveryData),
+		rescan:       make(chan struct{}, 1),
+		states:       make(map[string]deviceState),
+		commandSeqs:  make(map[string]uint64),
+		dial:         net.DialTimeout,
+		health:       make(map[string]*deviceHealth),
+
+		statusWaiters: make(map[string][]chan StatusData),
 	}
 }
 
-// Discover discovers Govee devices on the local network
+// Discover discovers Govee devices on the local network, on the interfaces set with SetInterfaces or on all
+// multicast-capable interfaces otherwise, over the IP versions set with SetIPVersion
 func (c *Client) Discover(ctx context.Context) error {
-	addr, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:%d", c.multicastIP, responsePort))
+	group := net.ParseIP(c.multicastIP)
+	if group == nil || group.To4() == nil {
+		return fmt.Errorf("invalid multicast address %q", c.multicastIP)
+	}
+	ifaces, err := c.discoveryInterfaces()
 	if err != nil {
-		return fmt.Errorf("failed to resolve multicast address: %w", err)
+		return err
 	}
 
-	conn, err := net.ListenUDP("udp4", addr)
-	if err != nil {
-		return fmt.Errorf("failed to listen on UDP port %d: %w", responsePort, err)
+	c.mu.RLock()
+	networks := c.ipVersion.networks()
+	c.mu.RUnlock()
+
+	// the responses of all IP versions are merged into the same devices
+	var conns []*net.UDPConn
+	var senders []*multicastConn
+	closeAll := func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		for _, sender := range senders {
+			sender.Close()
+		}
+	}
+	for _, network := range networks {
+		networkGroup := group
+		if network == "udp6" {
+			networkGroup = net.ParseIP(DefaultMulticastIPv6)
+		}
+		conn, err := c.listenResponses(network, networkGroup, ifaces)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		conns = append(conns, conn)
+		sender, err := newDiscoverySender(network)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		senders = append(senders, sender)
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	c.discoveryMu.Lock()
+	c.stopDiscovery = append(c.stopDiscovery, cancel)
+	c.discoveryMu.Unlock()
+
+	c.discovery.Add(2 + 2*len(networks))
 	go func() {
-		buf := make([]byte, 2048)
-		for {
-			select {
-			case <-ctx.Done():
-				conn.Close()
-				return
-			default:
-				{
-					n, _, err := conn.ReadFromUDP(buf)
-					if err != nil {
-						c.logger.Error().Err(err).Msg("Failed to read from UDP")
-						continue
-					}
-					var msg Construct[DiscoveryData]
-					if err := json.Unmarshal(buf[:n], &msg); err != nil {
-						c.logger.Error().Err(err).Msg("Failed to unmarshal discovery message")
-						continue
-					}
-					c.logger.Debug().Any("message", msg).Msg("Received discovery message")
-					if msg.Message.Command == "scan" {
-						if _, ok := c.devices[msg.Message.Data.DeviceID]; !ok {
-							c.devices[msg.Message.Data.DeviceID] = msg.Message.Data.IP
-							c.logger.Info().Str("deviceId", msg.Message.Data.DeviceID).
-								Str("ip", msg.Message.Data.IP).
-								Msg("Found Govee device")
-						} else {
-							c.logger.Debug().Str("deviceId", msg.Message.Data.DeviceID).
-								Str("ip", msg.Message.Data.IP).
-								Msg("Govee device already known")
-						}
-					}
-				}
+		defer c.discovery.Done()
+		c.sweepExpiredDevices(ctx)
+	}()
+
+	go func() {
+		defer c.discovery.Done()
+		<-ctx.Done()
+		closeAll()
+		c.closeControlConns()
+	}()
 
+	for i, network := range networks {
+		// devices report their IPv4 address, which can only be used if discovery runs over IPv4
+		useSource := network == "udp6" && len(networks) == 1
+		go func() {
+			defer c.discovery.Done()
+			c.readResponses(ctx, conns[i], useSource)
+		}()
+	}
+
+	for i, network := range networks {
+		sender := senders[i]
+		dst := &net.UDPAddr{IP: group, Port: discoveryPort}
+		if network == "udp6" {
+			dst.IP = net.ParseIP(DefaultMulticastIPv6)
+		}
+		go func() {
+			defer c.discovery.Done()
+			for scans := 0; ; scans++ {
+				c.logger.Debug().Str("network", network).Msg("Sending discovery request")
+				c.sendDiscoveryRequest(sender, dst, ifaces)
+
+				select { // wait for responses
+				case <-ctx.Done():
+					return
+				case <-c.rescan:
+				case <-time.After(c.nextScanDelay(scans + 1)):
+				}
 			}
+		}()
+	}
 
+	return nil
+}
+
+// multicastTTL is the TTL of discovery requests, Govee devices being on the local network
+const multicastTTL = 1
+
+// listenResponses listens for discovery and status responses on the response port, joining the multicast group
+// on each interface. Devices answer scan requests by multicast and status requests by unicast, both of which
+// are received on the same socket.
+func (c *Client) listenResponses(network string, group net.IP, ifaces []net.Interface) (*net.UDPConn, error) {
+	conn, err := net.ListenUDP(network, &net.UDPAddr{Port: responsePort})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on UDP port %d: %w", responsePort, err)
+	}
+	if !group.IsMulticast() {
+		return conn, nil
+	}
+
+	p := newMulticastConn(network, conn)
+	if len(ifaces) == 0 {
+		if err := p.JoinGroup(nil, &net.UDPAddr{IP: group}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to join multicast group %s: %w", group, err)
 		}
-	}()
+		return conn, nil
+	}
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
+	joined := 0
+	for _, iface := range ifaces {
+		if err := p.JoinGroup(&iface, &net.UDPAddr{IP: group}); err != nil {
+			c.logger.Warn().Err(err).Str("interface", iface.Name).Msg("Failed to join multicast group on interface")
+			continue
+		}
+		joined++
+	}
+	if joined == 0 {
+		conn.Close()
+		return nil, fmt.Errorf("failed to join multicast group %s on any interface", group)
+	}
+	return conn, nil
+}
+
+// newDiscoverySender opens the socket discovery requests are sent from.
+func newDiscoverySender(network string) (*multicastConn, error) {
+	conn, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open discovery socket: %w", err)
+	}
+	p := newMulticastConn(network, conn)
+	if err := p.SetMulticastTTL(multicastTTL); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("failed to set multicast TTL: %w", err)
+	}
+	return p, nil
+}
+
+// sendDiscoveryRequest sends a scan request to the multicast address out of each interface, or out of the
+// default interface if there are none.
+func (c *Client) sendDiscoveryRequest(sender *multicastConn, dst *net.UDPAddr, ifaces []net.Interface) {
+	query := Construct[DiscoveryResponseData]{
+		Message: Message[DiscoveryResponseData]{
+			Command: "scan",
+			Data:    DiscoveryResponseData{AccountTopic: "reserve"},
+		},
+	}
+	b, _ := json.Marshal(query)
+
+	if len(ifaces) == 0 {
+		if _, err := sender.WriteTo(b, dst); err != nil {
+			c.logger.Debug().Err(err).Msg("Failed to send discovery request")
+		}
+		return
+	}
+	for _, iface := range ifaces {
+		if err := sender.SetMulticastInterface(&iface); err != nil {
+			c.logger.Debug().Err(err).Str("interface", iface.Name).Msg("Failed to set multicast interface")
+			continue
+		}
+		if _, err := sender.WriteTo(b, dst); err != nil {
+			c.logger.Debug().Err(err).Str("interface", iface.Name).Msg("Failed to send discovery request")
+		}
+	}
+}
+
+// readResponses handles discovery and status responses received on the connection until the context is done.
+// With useSource, devices are controlled at the address they answered from instead of the IP they report.
+func (c *Client) readResponses(ctx context.Context, conn *net.UDPConn, useSource bool) {
+	buf := make([]byte, 2048)
+	for {
+		n, sender, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
 				return
-			default:
-				{
-					c.logger.Debug().Msg("Sending discovery request")
-					addr, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:%d", c.multicastIP, discoveryPort))
-					if err != nil {
-						c.logger.Error().Err(err).Msg("Failed to resolve multicast address")
-						return
-					}
-
-					sock, err := net.DialUDP("udp4", nil, addr)
-					if err != nil {
-						continue
-					}
-
-					query := Construct[DiscoveryResponseData]{
-						Message: Message[DiscoveryResponseData]{
-							Command: "scan",
-							Data:    DiscoveryResponseData{AccountTopic: "reserve"},
-						},
-					}
-					b, _ := json.Marshal(query)
-					if _, err := sock.Write(b); err != nil {
-						sock.Close()
-						continue
-					}
-					sock.Close()
-					<-time.After(2 * time.Second) // wait for responses
-				}
 			}
+			c.logger.Error().Err(err).Msg("Failed to read from UDP")
+			continue
 		}
-	}()
+		var raw Construct[json.RawMessage]
+		if err := json.Unmarshal(buf[:n], &raw); err != nil {
+			c.logger.Error().Err(err).Msg("Failed to unmarshal discovery message")
+			continue
+		}
+		if raw.Message.Command == "devStatus" {
+			status, err := parseStatus(raw.Message.Data)
+			if err != nil {
+				c.logger.Error().Err(err).Msg("Failed to parse device status")
+				continue
+			}
+			c.deliverStatus(responseIP(sender), status)
+			continue
+		}
+
+		var msg Construct[DiscoveryData]
+		if err := json.Unmarshal(buf[:n], &msg); err != nil {
+			c.logger.Error().Err(err).Msg("Failed to unmarshal discovery message")
+			continue
+		}
+		c.logger.Debug().Any("message", msg).Msg("Received discovery message")
+		if useSource {
+			msg.Message.Data.IP = responseIP(sender)
+		}
+		if msg.Message.Command == "scan" {
+			c.mu.Lock()
+			ip, known := c.devices[msg.Message.Data.DeviceID]
+			c.devices[msg.Message.Data.DeviceID] = msg.Message.Data.IP
+			c.lastSeen[msg.Message.Data.DeviceID] = c.now()
+			c.discovered[msg.Message.Data.DeviceID] = msg.Message.Data
+			c.mu.Unlock()
+
+			if known && ip != msg.Message.Data.IP {
+				// the device reconnected with a new IP, its state may have changed meanwhile
+				c.ForceRefresh(msg.Message.Data.DeviceID)
+				c.closeControlConn(ip)
+			}
+
+			if !known {
+				c.logger.Info().Str("deviceId", msg.Message.Data.DeviceID).
+					Str("ip", msg.Message.Data.IP).
+					Str("model", msg.Message.Data.SKU).
+					Msg("Found Govee device")
+			} else {
+				c.logger.Debug().Str("deviceId", msg.Message.Data.DeviceID).
+					Str("ip", msg.Message.Data.IP).
+					Msg("Govee device already known")
+			}
+		}
+	}
+}
+
+// Close stops discovery, waiting until its sockets are closed and its goroutines exited, and closes the
+// connections to all devices. Canceling the context passed to Discover has the same effect without waiting.
+func (c *Client) Close() error {
+	c.discoveryMu.Lock()
+	for _, cancel := range c.stopDiscovery {
+		cancel()
+	}
+	c.stopDiscovery = nil
+	c.discoveryMu.Unlock()
 
+	c.discovery.Wait()
+	c.closeControlConns()
 	return nil
 }
 
+// Devices returns a copy of the discovered devices as a map of device ID to IP.
+func (c *Client) Devices() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	devices := make(map[string]string, len(c.devices))
+	for deviceID, ip := range c.devices {
+		devices[deviceID] = ip
+	}
+	return devices
+}
+
+// KnownDeviceIDs returns the sorted IDs of all discovered devices.
+func (c *Client) KnownDeviceIDs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]string, 0, len(c.devices))
+	for deviceID := range c.devices {
+		ids = append(ids, deviceID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// DeviceCount returns the number of discovered devices.
+func (c *Client) DeviceCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.devices)
+}
+
+// Rescan triggers an immediate discovery request instead of waiting for the next one.
+func (c *Client) Rescan() {
+	select {
+	case c.rescan <- struct{}{}:
+	default: // a rescan is already pending
+	}
+}
+
+// WaitForDevice blocks until the device with the given ID has been discovered or the context is done.
+func (c *Client) WaitForDevice(ctx context.Context, deviceID string) error {
+	for {
+		if _, ok := c.deviceIP(deviceID); ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for device %s: %w", deviceID, ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
 // sendCommand sends a command to a Govee device
 func (c *Client) sendCommand(deviceID string, cmd string, data interface{}) error {
-	if ip, ok := c.devices[deviceID]; ok {
+	ip, ok := c.deviceIP(deviceID)
+	if ok {
+		if err := c.checkHealth(deviceID); err != nil {
+			return err
+		}
+
 		payload := Construct[interface{}]{
 			Message: Message[interface{}]{
 				Command: cmd,
@@ -187,50 +497,204 @@ func (c *Client) sendCommand(deviceID string, cmd string, data interface{}) erro
 			return fmt.Errorf("failed to marshal command: %w", err)
 		}
 
-		conn, err := net.Dial("udp", fmt.Sprintf("%s:%d", ip, controlPort))
-		if err != nil {
-			return fmt.Errorf("failed to connect to device %s: %w", deviceID, err)
+		settings := c.deviceSettings(deviceID)
+		seq := c.nextCommandSeq(deviceID)
+		err = c.writeCommand(deviceID, ip, b, settings.CommandTimeout)
+		if err != nil && settings.retries() > 0 {
+			// the result is recorded once the retries are done
+			c.retryCommand(deviceID, cmd, ip, b, settings, seq, err)
+			return err
 		}
-		defer conn.Close()
+		c.recordResult(deviceID, settings.FailureThreshold, err)
+		metrics.GoveeCommands.WithLabelValues(cmd, metrics.Result(err)).Inc()
+		return err
+	}
 
-		if _, err := conn.Write(b); err != nil {
-			return fmt.Errorf("failed to send command to device %s: %w", deviceID, err)
+	return ErrDeviceNotFound
+}
+
+// retryCommand resends a command whose write failed in the background, so the caller isn't stalled by the retry
+// delay. Retrying stops at the first successful write, once the retries are used up, or once a newer command was
+// sent to the device, which the stale command must not override. The result of the last attempt is recorded.
+func (c *Client) retryCommand(deviceID, cmd, ip string, b []byte, settings DeviceSettings, seq uint64, err error) {
+	var retry func(attempt int, err error)
+	retry = func(attempt int, err error) {
+		if c.commandSeq(deviceID) == seq {
+			err = c.writeCommand(deviceID, ip, b, settings.CommandTimeout)
+			if err != nil && attempt < settings.retries() {
+				time.AfterFunc(settings.RetryDelay, func() { retry(attempt+1, err) })
+				return
+			}
 		}
-		return nil
+		c.recordResult(deviceID, settings.FailureThreshold, err)
+		metrics.GoveeCommands.WithLabelValues(cmd, metrics.Result(err)).Inc()
 	}
+	time.AfterFunc(settings.RetryDelay, func() { retry(1, err) })
+}
 
-	return ErrDeviceNotFound
+// nextCommandSeq counts a command sent to the device, returning its sequence number.
+func (c *Client) nextCommandSeq(deviceID string) uint64 {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	c.commandSeqs[deviceID]++
+	return c.commandSeqs[deviceID]
+}
+
+// commandSeq returns the sequence number of the last command sent to the device.
+func (c *Client) commandSeq(deviceID string) uint64 {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	return c.commandSeqs[deviceID]
 }
 
 // TurnOn turns on a Govee device
 func (c *Client) TurnOn(deviceID string) error {
-	return c.sendCommand(deviceID, "turn", TurnData{Value: 1})
+	if err := c.requireCapability(deviceID, func(c Capabilities) bool { return c.OnOff }, "turn"); err != nil {
+		return err
+	}
+	if on := c.knownState(deviceID).On; on != nil && *on {
+		return nil
+	}
+
+	if err := c.sendCommand(deviceID, "turn", TurnData{Value: 1}); err != nil {
+		return err
+	}
+	c.rememberState(deviceID, func(state *deviceState) {
+		state.On = &[]bool{true}[0]
+	})
+	return nil
 }
 
 // TurnOff turns off a Govee device
 func (c *Client) TurnOff(deviceID string) error {
-	return c.sendCommand(deviceID, "turn", TurnData{Value: 0})
+	if err := c.requireCapability(deviceID, func(c Capabilities) bool { return c.OnOff }, "turn"); err != nil {
+		return err
+	}
+	if on := c.knownState(deviceID).On; on != nil && !*on {
+		return nil
+	}
+
+	if err := c.sendCommand(deviceID, "turn", TurnData{Value: 0}); err != nil {
+		return err
+	}
+	// color and brightness are sent again once the device is turned back on
+	c.rememberState(deviceID, func(state *deviceState) {
+		*state = deviceState{On: &[]bool{false}[0]}
+	})
+	return nil
 }
 
-// SetColor sets the color of a Govee device
+// SetColor sets the color of a Govee device, corrected by the device's color correction if configured
 func (c *Client) SetColor(deviceID string, r, g, b int) error {
+	if err := c.requireCapability(deviceID, func(c Capabilities) bool { return c.Color }, "colorwc"); err != nil {
+		return err
+	}
+
+	color := RGBColor{
+		R: r,
+		G: g,
+		B: b,
+	}
+	if last := c.knownState(deviceID).Color; last != nil &&
+		withinTolerance(*last, color, c.deviceSettings(deviceID).ColorTolerance) {
+		return nil
+	}
+
+	// the requested color is remembered, so that the tolerance applies before the correction
 	colorData := ColorData{
-		Color: RGBColor{
-			R: r,
-			G: g,
-			B: b,
-		},
+		Color:            c.correctColor(deviceID, color),
 		ColorTemperature: 0, // Assuming no color temperature adjustment
 	}
-	return c.sendCommand(deviceID, "colorwc", colorData)
+	if err := c.sendCommand(deviceID, "colorwc", colorData); err != nil {
+		return err
+	}
+	c.rememberState(deviceID, func(state *deviceState) {
+		state.Color = &color
+		state.ColorTemperature = nil
+		state.Segments = nil
+	})
+	return nil
+}
+
+// SetDIYScene activates a DIY scene created in the Govee app, identified by its code
+func (c *Client) SetDIYScene(deviceID string, code int) error {
+	c.ForceRefresh(deviceID)
+	return c.sendCommand(deviceID, "diyScene", DIYSceneData{Value: code})
+}
+
+// SetMaxBrightness sets a global brightness cap in percent applied to all brightness commands.
+// A value of zero or 100 disables the cap.
+func (c *Client) SetMaxBrightness(percent int) {
+	c.maxBrightness.Store(int32(percent))
+}
+
+// SetColorTemperature sets the white color temperature of a Govee device in Kelvin, clamped to the range
+// supported by most Govee devices
+func (c *Client) SetColorTemperature(deviceID string, kelvin int) error {
+	if err := c.requireCapability(deviceID, func(c Capabilities) bool { return c.ColorTemperature }, "colorwc"); err != nil {
+		return err
+	}
+
+	clamped := min(max(kelvin, MinColorTemperature), MaxColorTemperature)
+	if last := c.knownState(deviceID).ColorTemperature; last != nil && *last == clamped {
+		return nil
+	}
+	c.logger.Debug().Str("deviceId", deviceID).Int("kelvin", kelvin).Int("clampedKelvin", clamped).
+		Msg("Setting Govee color temperature")
+
+	colorData := ColorData{
+		Color:            RGBColor{},
+		ColorTemperature: clamped,
+	}
+	if err := c.sendCommand(deviceID, "colorwc", colorData); err != nil {
+		return err
+	}
+	c.rememberState(deviceID, func(state *deviceState) {
+		state.ColorTemperature = &clamped
+		state.Color = nil
+		state.Segments = nil
+	})
+	return nil
 }
 
-// SetBrightness sets the brightness of a Govee device
+// SetBrightness sets the brightness of a Govee device as a percentage (0-100)
 func (c *Client) SetBrightness(deviceID string, value int) error {
+	if err := c.requireCapability(deviceID, func(c Capabilities) bool { return c.Brightness }, "brightness"); err != nil {
+		return err
+	}
+	if maxBrightness := int(c.maxBrightness.Load()); maxBrightness > 0 && value > maxBrightness {
+		value = maxBrightness
+	}
+	if last := c.knownState(deviceID).Brightness; last != nil && *last == value {
+		return nil
+	}
+
 	briData := BrightnessData{
 		Value: value,
 	}
-	return c.sendCommand(deviceID, "brightness", briData)
+	if err := c.sendCommand(deviceID, "brightness", briData); err != nil {
+		return err
+	}
+	c.rememberState(deviceID, func(state *deviceState) {
+		state.Brightness = &value
+	})
+	return nil
+}
+
+// SendRaw sends an arbitrary command with a raw JSON payload to a Govee device.
+//
+// This is an unsupported escape hatch for experimenting with device-specific commands the bridge
+// doesn't model. The payload is passed through as-is and no validation is performed.
+func (c *Client) SendRaw(deviceID, cmd string, data json.RawMessage) error {
+	c.ForceRefresh(deviceID)
+	return c.sendCommand(deviceID, cmd, data)
+}
+
+// IsUnsupportedCommand checks if an error is caused by a command the device doesn't support
+func IsUnsupportedCommand(err error) bool {
+	return err != nil && errors.Is(err, ErrUnsupportedCommand)
 }
 
 // IsDeviceNotFound checks if an error is caused by a device not being found
@@ -0,0 +1,423 @@
+package govee
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/metrics"
+	"github.com/rs/zerolog"
+)
+
+const testDeviceID = "AA:BB:CC:DD:EE:FF:00:11"
+
+// fakeDevice is a UDP listener standing in for the control port of a Govee device.
+type fakeDevice struct {
+	t    testing.TB
+	conn *net.UDPConn
+}
+
+// newTestClient creates a Client that knows a single device whose commands are received by the returned fakeDevice.
+func newTestClient(t testing.TB) (*Client, *fakeDevice) {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	client := NewClient(zerolog.Nop(), DefaultMulticastIP)
+	client.controlPort = conn.LocalAddr().(*net.UDPAddr).Port
+	client.devices[testDeviceID] = "127.0.0.1"
+	client.lastSeen[testDeviceID] = time.Now()
+	client.SetCapabilityOverrides(map[string]Capabilities{
+		testDeviceID: {OnOff: true, Brightness: true, Color: true, ColorTemperature: true},
+	})
+	t.Cleanup(client.closeControlConns)
+	return client, &fakeDevice{t: t, conn: conn}
+}
+
+// receive returns the next command received by the device, failing the test if none arrives in time.
+func (d *fakeDevice) receive() Message[json.RawMessage] {
+	d.t.Helper()
+
+	msg, ok := d.read(time.Second)
+	if !ok {
+		d.t.Fatal("device received no command, want one")
+	}
+	return msg
+}
+
+// expectNothing fails the test if the device receives a command within a short time.
+func (d *fakeDevice) expectNothing() {
+	d.t.Helper()
+
+	if msg, ok := d.read(200 * time.Millisecond); ok {
+		d.t.Fatalf("device received %s %s, want no command", msg.Command, msg.Data)
+	}
+}
+
+func (d *fakeDevice) read(timeout time.Duration) (Message[json.RawMessage], bool) {
+	d.t.Helper()
+
+	if err := d.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		d.t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	n, err := d.conn.Read(buf)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return Message[json.RawMessage]{}, false
+	}
+	if err != nil {
+		d.t.Fatal(err)
+	}
+
+	var construct Construct[json.RawMessage]
+	if err := json.Unmarshal(buf[:n], &construct); err != nil {
+		d.t.Fatalf("device received invalid command %q: %v", buf[:n], err)
+	}
+	return construct.Message, true
+}
+
+// receiveColor returns the color of the next command received by the device, which must be a color command.
+func (d *fakeDevice) receiveColor() RGBColor {
+	d.t.Helper()
+
+	msg := d.receive()
+	if msg.Command != "colorwc" {
+		d.t.Fatalf("device received %s, want colorwc", msg.Command)
+	}
+	var data ColorData
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		d.t.Fatal(err)
+	}
+	return data.Color
+}
+
+// receiveBrightness returns the brightness of the next command received by the device, which must be a
+// brightness command.
+func (d *fakeDevice) receiveBrightness() int {
+	d.t.Helper()
+
+	msg := d.receive()
+	if msg.Command != "brightness" {
+		d.t.Fatalf("device received %s, want brightness", msg.Command)
+	}
+	var data BrightnessData
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		d.t.Fatal(err)
+	}
+	return data.Value
+}
+
+func TestSetColorSkipsRepeatedColor(t *testing.T) {
+	client, device := newTestClient(t)
+
+	red := RGBColor{R: 255, G: 0, B: 0}
+	if err := client.SetColor(testDeviceID, red.R, red.G, red.B); err != nil {
+		t.Fatal(err)
+	}
+	if got := device.receiveColor(); got != red {
+		t.Errorf("device received color %v, want %v", got, red)
+	}
+
+	if err := client.SetColor(testDeviceID, red.R, red.G, red.B); err != nil {
+		t.Fatal(err)
+	}
+	device.expectNothing()
+
+	client.ForceRefresh(testDeviceID)
+	if err := client.SetColor(testDeviceID, red.R, red.G, red.B); err != nil {
+		t.Fatal(err)
+	}
+	if got := device.receiveColor(); got != red {
+		t.Errorf("device received color %v after ForceRefresh, want %v", got, red)
+	}
+}
+
+func TestSetColorTolerance(t *testing.T) {
+	client, device := newTestClient(t)
+	client.SetDeviceSettings(DeviceSettings{ColorTolerance: 5}, nil)
+
+	if err := client.SetColor(testDeviceID, 100, 100, 100); err != nil {
+		t.Fatal(err)
+	}
+	device.receiveColor()
+
+	// within the tolerance of the last sent color
+	if err := client.SetColor(testDeviceID, 105, 95, 100); err != nil {
+		t.Fatal(err)
+	}
+	device.expectNothing()
+
+	want := RGBColor{R: 106, G: 100, B: 100}
+	if err := client.SetColor(testDeviceID, want.R, want.G, want.B); err != nil {
+		t.Fatal(err)
+	}
+	if got := device.receiveColor(); got != want {
+		t.Errorf("device received color %v, want %v", got, want)
+	}
+}
+
+func TestTurnOnSkipsRepeatedCommand(t *testing.T) {
+	client, device := newTestClient(t)
+
+	if err := client.TurnOn(testDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	if msg := device.receive(); msg.Command != "turn" {
+		t.Errorf("device received %s, want turn", msg.Command)
+	}
+
+	if err := client.TurnOn(testDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	device.expectNothing()
+
+	// turning the device off forgets its color, so the same color is sent again afterwards
+	if err := client.SetColor(testDeviceID, 0, 0, 255); err != nil {
+		t.Fatal(err)
+	}
+	device.receiveColor()
+	if err := client.TurnOff(testDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	device.receive()
+	if err := client.SetColor(testDeviceID, 0, 0, 255); err != nil {
+		t.Fatal(err)
+	}
+	if got := device.receiveColor(); got != (RGBColor{R: 0, G: 0, B: 255}) {
+		t.Errorf("device received color %v after turning off, want blue", got)
+	}
+}
+
+func TestSendCommandMetrics(t *testing.T) {
+	client, device := newTestClient(t)
+	if err := client.SetColor(testDeviceID, 255, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	device.receiveColor()
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := `hue2govee_govee_commands_total{command="colorwc",result="ok"}`
+	if !strings.Contains(recorder.Body.String(), want) {
+		t.Errorf("metrics don't contain %s:\n%s", want, recorder.Body)
+	}
+}
+
+func TestSendRaw(t *testing.T) {
+	client, device := newTestClient(t)
+	if err := client.SetColor(testDeviceID, 255, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	device.receiveColor()
+
+	payload := json.RawMessage(`{"value":1,"mode":"night"}`)
+	if err := client.SendRaw(testDeviceID, "nightlight", payload); err != nil {
+		t.Fatalf("SendRaw() returned error: %v", err)
+	}
+	msg := device.receive()
+	if msg.Command != "nightlight" || string(msg.Data) != string(payload) {
+		t.Errorf("device received %s %s, want the raw command nightlight %s", msg.Command, msg.Data, payload)
+	}
+
+	// the raw command may have changed anything, so the same color is sent again
+	if err := client.SetColor(testDeviceID, 255, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := device.receiveColor(); got != (RGBColor{R: 255}) {
+		t.Errorf("device received color %v, want red", got)
+	}
+
+	if err := client.SendRaw("00:00:00:00:00:00:00:00", "turn", payload); !IsDeviceNotFound(err) {
+		t.Errorf("SendRaw() to an unknown device = %v, want ErrDeviceNotFound", err)
+	}
+}
+
+func TestSetMaxBrightness(t *testing.T) {
+	client, device := newTestClient(t)
+
+	// the global cap overrides the higher brightness a synchronization computed
+	client.SetMaxBrightness(30)
+	if err := client.SetBrightness(testDeviceID, 80); err != nil {
+		t.Fatal(err)
+	}
+	if got := device.receiveBrightness(); got != 30 {
+		t.Errorf("device received brightness %d, want it capped at 30", got)
+	}
+	if err := client.SetBrightness(testDeviceID, 20); err != nil {
+		t.Fatal(err)
+	}
+	if got := device.receiveBrightness(); got != 20 {
+		t.Errorf("device received brightness %d, want 20 below the cap", got)
+	}
+
+	client.SetMaxBrightness(0)
+	if err := client.SetBrightness(testDeviceID, 80); err != nil {
+		t.Fatal(err)
+	}
+	if got := device.receiveBrightness(); got != 80 {
+		t.Errorf("device received brightness %d after removing the cap, want 80", got)
+	}
+}
+
+func TestSetDIYScene(t *testing.T) {
+	client, device := newTestClient(t)
+
+	// DIY scenes are always sent, the device may have left the scene in the meantime
+	for range 2 {
+		if err := client.SetDIYScene(testDeviceID, 42); err != nil {
+			t.Fatalf("SetDIYScene() returned error: %v", err)
+		}
+		msg := device.receive()
+		if msg.Command != "diyScene" || string(msg.Data) != `{"value":42}` {
+			t.Errorf("device received %s %s, want diyScene {\"value\":42}", msg.Command, msg.Data)
+		}
+	}
+}
+
+func TestSetColorTemperature(t *testing.T) {
+	tests := []struct {
+		name   string
+		kelvin int
+		want   string
+	}{
+		{name: "in range", kelvin: 2700, want: `{"color":{"r":0,"g":0,"b":0},"colorTemperature":2700}`},
+		{name: "too warm", kelvin: 1000, want: `{"color":{"r":0,"g":0,"b":0},"colorTemperature":2000}`},
+		{name: "too cool", kelvin: 12000, want: `{"color":{"r":0,"g":0,"b":0},"colorTemperature":9000}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, device := newTestClient(t)
+
+			if err := client.SetColorTemperature(testDeviceID, tt.kelvin); err != nil {
+				t.Fatalf("SetColorTemperature() returned error: %v", err)
+			}
+			msg := device.receive()
+			if msg.Command != "colorwc" || string(msg.Data) != tt.want {
+				t.Errorf("device received %s %s, want colorwc %s", msg.Command, msg.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetColorTemperatureSkipsRepeatedTemperature(t *testing.T) {
+	client, device := newTestClient(t)
+
+	if err := client.SetColorTemperature(testDeviceID, 2700); err != nil {
+		t.Fatal(err)
+	}
+	device.receive()
+	if err := client.SetColorTemperature(testDeviceID, 2700); err != nil {
+		t.Fatal(err)
+	}
+	device.expectNothing()
+
+	// the color replaces the color temperature, so switching back has to be sent again
+	red := RGBColor{R: 255}
+	if err := client.SetColor(testDeviceID, red.R, red.G, red.B); err != nil {
+		t.Fatal(err)
+	}
+	if got := device.receiveColor(); got != red {
+		t.Errorf("device received color %v, want %v", got, red)
+	}
+	if err := client.SetColorTemperature(testDeviceID, 2700); err != nil {
+		t.Fatal(err)
+	}
+	device.receive()
+}
+
+func TestKnownDeviceIDs(t *testing.T) {
+	client := NewClient(zerolog.Nop(), DefaultMulticastIP)
+	if ids := client.KnownDeviceIDs(); len(ids) != 0 {
+		t.Errorf("KnownDeviceIDs() before discovery = %v, want none", ids)
+	}
+
+	client.devices["CC:DD"] = "192.168.1.21"
+	client.devices["AA:BB"] = "192.168.1.20"
+	if ids := client.KnownDeviceIDs(); strings.Join(ids, ",") != "AA:BB,CC:DD" {
+		t.Errorf("KnownDeviceIDs() = %v, want the discovered devices sorted", ids)
+	}
+}
+
+func TestUnmarshalScanResponse(t *testing.T) {
+	// scan response of an H618A LED strip
+	scan := `{"msg":{"cmd":"scan","data":{"ip":"192.168.1.23","device":"1F:80:C5:32:32:36:72:4E","sku":"H618A",` +
+		`"bleVersionHard":"3.01.01","bleVersionSoft":"1.03.01","wifiVersionHard":"1.00.10","wifiVersionSoft":"1.02.03"}}}`
+
+	var msg Construct[DiscoveryData]
+	if err := json.Unmarshal([]byte(scan), &msg); err != nil {
+		t.Fatalf("json.Unmarshal() returned error: %v", err)
+	}
+	want := DiscoveryData{DeviceID: "1F:80:C5:32:32:36:72:4E", IP: "192.168.1.23", SKU: "H618A",
+		BLEVersionHard: "3.01.01", BLEVersionSoft: "1.03.01", WiFiVersionHard: "1.00.10", WiFiVersionSoft: "1.02.03"}
+	if msg.Message.Command != "scan" || msg.Message.Data != want {
+		t.Errorf("scan response = %+v, want %+v", msg.Message, want)
+	}
+
+	// older firmware only reports the device and its IP
+	msg = Construct[DiscoveryData]{}
+	if err := json.Unmarshal([]byte(`{"msg":{"cmd":"scan","data":{"ip":"192.168.1.24","device":"`+testDeviceID+
+		`"}}}`), &msg); err != nil {
+		t.Fatalf("json.Unmarshal() returned error: %v", err)
+	}
+	if want := (DiscoveryData{DeviceID: testDeviceID, IP: "192.168.1.24"}); msg.Message.Data != want {
+		t.Errorf("minimal scan response = %+v, want %+v", msg.Message.Data, want)
+	}
+}
+
+func TestDevices(t *testing.T) {
+	client := NewClient(zerolog.Nop(), DefaultMulticastIP)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	responses, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer responses.Close()
+	go client.readResponses(ctx, responses, false)
+
+	// a device answers the scan
+	sender, err := net.DialUDP("udp4", nil, responses.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	scan := `{"msg":{"cmd":"scan","data":{"device":"` + testDeviceID + `","ip":"192.168.1.20","sku":"H6199",` +
+		`"wifiVersionSoft":"1.02.03"}}}`
+	if _, err := sender.Write([]byte(scan)); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(client.Devices()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Devices() is empty after the scan response, want the device")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	devices := client.Devices()
+	if len(devices) != 1 || devices[testDeviceID] != "192.168.1.20" {
+		t.Errorf("Devices() = %v, want the scanned device", devices)
+	}
+	infos := client.DeviceInfos()
+	if len(infos) != 1 || infos[0].SKU != "H6199" || infos[0].WiFiVersionSoft != "1.02.03" {
+		t.Errorf("DeviceInfos() = %+v, want the model and firmware of the scanned device", infos)
+	}
+
+	// the returned map is a copy
+	delete(devices, testDeviceID)
+	if len(client.Devices()) != 1 {
+		t.Error("modifying the map returned by Devices() changed the discovered devices")
+	}
+}
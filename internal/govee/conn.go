@@ -0,0 +1,68 @@
+package govee
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// controlConn returns the connection to the control port of the device with the given IP, dialing it
+// if there's no open connection yet.
+func (c *Client) controlConn(ip string, timeout time.Duration) (net.Conn, error) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	if conn, ok := c.conns[ip]; ok {
+		return conn, nil
+	}
+
+	conn, err := c.dial(controlNetwork(ip), net.JoinHostPort(ip, strconv.Itoa(c.controlPort)), timeout)
+	if err != nil {
+		return nil, err
+	}
+	c.conns[ip] = conn
+	return conn, nil
+}
+
+// closeControlConn closes the connection to the device with the given IP, if any. A new connection is dialed
+// on the next command.
+func (c *Client) closeControlConn(ip string) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	if conn, ok := c.conns[ip]; ok {
+		conn.Close()
+		delete(c.conns, ip)
+	}
+}
+
+// closeControlConns closes all connections to devices.
+func (c *Client) closeControlConns() {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	for ip, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, ip)
+	}
+}
+
+// writeCommand writes a marshalled command to the device, failing if dialing and writing take longer than timeout.
+// The connection to the device is reused across commands and only dialed again after a failed write.
+func (c *Client) writeCommand(deviceID, ip string, b []byte, timeout time.Duration) error {
+	conn, err := c.controlConn(ip, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to device %s: %w", deviceID, err)
+	}
+
+	if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		c.closeControlConn(ip)
+		return fmt.Errorf("failed to set write deadline for device %s: %w", deviceID, err)
+	}
+	if _, err := conn.Write(b); err != nil {
+		c.closeControlConn(ip)
+		return fmt.Errorf("failed to send command to device %s: %w", deviceID, err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package govee
+
+import (
+	"errors"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The benchmarks compare sending commands over the connection reused by writeCommand to dialing the control port
+// for every command.
+
+func BenchmarkWriteCommandReusedConn(b *testing.B) {
+	client, _ := newTestClient(b)
+	command := []byte(`{"msg":{"cmd":"colorwc","data":{"color":{"r":255,"g":0,"b":0},"colorTemperature":0}}}`)
+
+	b.ReportAllocs()
+	for range b.N {
+		if err := client.writeCommand(testDeviceID, "127.0.0.1", command, time.Second); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteCommandDialPerCommand(b *testing.B) {
+	client, _ := newTestClient(b)
+	command := []byte(`{"msg":{"cmd":"colorwc","data":{"color":{"r":255,"g":0,"b":0},"colorTemperature":0}}}`)
+
+	b.ReportAllocs()
+	for range b.N {
+		if err := client.writeCommand(testDeviceID, "127.0.0.1", command, time.Second); err != nil {
+			b.Fatal(err)
+		}
+		client.closeControlConn("127.0.0.1")
+	}
+}
+
+func TestWriteCommandReusesConn(t *testing.T) {
+	client, device := newTestClient(t)
+
+	for range 3 {
+		if err := client.SetBrightness(testDeviceID, 50); err != nil {
+			t.Fatal(err)
+		}
+		device.receive()
+		client.ForceRefresh(testDeviceID)
+	}
+
+	client.connMu.Lock()
+	defer client.connMu.Unlock()
+	if len(client.conns) != 1 {
+		t.Errorf("client has %d connections to the device, want 1 reused across commands", len(client.conns))
+	}
+}
+
+// mockConn is a control connection whose first failures writes fail, recording every written command. If slow is
+// set, writes block until the write deadline and time out.
+type mockConn struct {
+	net.Conn
+
+	mu       sync.Mutex
+	failures int
+	slow     bool
+	deadline time.Time
+	writes   []string
+}
+
+func (c *mockConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writes = append(c.writes, string(b))
+	if c.slow {
+		time.Sleep(time.Until(c.deadline))
+		return 0, os.ErrDeadlineExceeded
+	}
+	if len(c.writes) <= c.failures {
+		return 0, errors.New("network is unreachable")
+	}
+	return len(b), nil
+}
+
+func (c *mockConn) SetWriteDeadline(deadline time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deadline = deadline
+	return nil
+}
+
+// succeed lets all following writes succeed.
+func (c *mockConn) succeed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures = len(c.writes)
+}
+
+func (c *mockConn) Close() error { return nil }
+
+func (c *mockConn) written() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.writes)
+}
+
+// testRetryDelay is the delay between retries of commands sent to a mockConn
+const testRetryDelay = 20 * time.Millisecond
+
+// newMockConnClient creates a Client that sends the commands to the returned mockConn, redialing it after a failed
+// write, with the given default and per-device retries.
+func newMockConnClient(t *testing.T, failures int, defaultRetries, deviceRetries *int) (*Client, *mockConn) {
+	t.Helper()
+
+	client, _ := newTestClient(t)
+	conn := &mockConn{failures: failures}
+	client.dial = func(string, string, time.Duration) (net.Conn, error) { return conn, nil }
+	client.SetDeviceSettings(
+		DeviceSettings{Retries: defaultRetries, RetryDelay: testRetryDelay, FailureThreshold: 10},
+		map[string]DeviceSettings{testDeviceID: {Retries: deviceRetries}},
+	)
+	return client, conn
+}
+
+func TestSendCommandRetries(t *testing.T) {
+	tests := []struct {
+		name           string
+		failures       int
+		defaultRetries *int
+		deviceRetries  *int
+		wantWrites     int
+		wantFailed     bool
+	}{
+		{name: "no retries", failures: 1, wantWrites: 1, wantFailed: true},
+		{name: "success isn't resent", failures: 0, defaultRetries: ptr(3), wantWrites: 1},
+		{name: "retried until success", failures: 2, defaultRetries: ptr(3), wantWrites: 3},
+		{name: "retries used up", failures: 10, defaultRetries: ptr(3), wantWrites: 4, wantFailed: true},
+		{name: "device overrides default", failures: 10, defaultRetries: ptr(3), deviceRetries: ptr(1),
+			wantWrites: 2, wantFailed: true},
+		{name: "device disables retries", failures: 10, defaultRetries: ptr(3), deviceRetries: ptr(0),
+			wantWrites: 1, wantFailed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, conn := newMockConnClient(t, tt.failures, tt.defaultRetries, tt.deviceRetries)
+
+			start := time.Now()
+			err := client.SetBrightness(testDeviceID, 50)
+			if elapsed := time.Since(start); elapsed >= testRetryDelay {
+				t.Errorf("SetBrightness() returned after %s, want it not to wait for the retries", elapsed)
+			}
+			if (err != nil) != (tt.failures > 0) {
+				t.Errorf("SetBrightness() = %v, want the result of the first attempt", err)
+			}
+
+			time.Sleep(6 * testRetryDelay)
+			if got := len(conn.written()); got != tt.wantWrites {
+				t.Errorf("device was sent the command %d times, want %d", got, tt.wantWrites)
+			}
+			if failed := client.DeviceHealth(testDeviceID).ConsecutiveFailures > 0; failed != tt.wantFailed {
+				t.Errorf("command recorded as failed = %v, want %v", failed, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestSendCommandRetriesSuperseded(t *testing.T) {
+	client, conn := newMockConnClient(t, 2, ptr(3), nil)
+
+	// the first command fails and is retried, but the second one is sent before the retry
+	if err := client.SetBrightness(testDeviceID, 50); err == nil {
+		t.Fatal("SetBrightness() = nil, want the first write to fail")
+	}
+	if err := client.SetBrightness(testDeviceID, 60); err == nil {
+		t.Fatal("SetBrightness() = nil, want the second write to fail")
+	}
+
+	time.Sleep(6 * testRetryDelay)
+	writes := conn.written()
+	if len(writes) != 3 {
+		t.Fatalf("device was sent %d commands, want 3: both commands and a single retry of the second", len(writes))
+	}
+	if !strings.Contains(writes[2], `"value":60`) {
+		t.Errorf("retried %s, want only the newer brightness of 60 to be retried", writes[2])
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
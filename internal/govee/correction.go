@@ -0,0 +1,36 @@
+package govee
+
+import "math"
+
+// ColorCorrection calibrates the colors sent to a device whose LEDs render colors differently than Hue bulbs.
+// Each output channel is the sum of the input channels weighted by its row, e.g. the first row yields red.
+type ColorCorrection [3][3]float64
+
+// GainCorrection returns a ColorCorrection scaling each channel by its own gain.
+func GainCorrection(r, g, b float64) ColorCorrection {
+	return ColorCorrection{
+		{r, 0, 0},
+		{0, g, 0},
+		{0, 0, b},
+	}
+}
+
+// Apply returns the corrected color, with each channel clamped to 0-255.
+func (m ColorCorrection) Apply(color RGBColor) RGBColor {
+	in := [3]float64{float64(color.R), float64(color.G), float64(color.B)}
+	var out [3]int
+	for i, row := range m {
+		value := row[0]*in[0] + row[1]*in[1] + row[2]*in[2]
+		out[i] = int(math.Round(min(max(value, 0), 255)))
+	}
+	return RGBColor{R: out[0], G: out[1], B: out[2]}
+}
+
+// correctColor returns the color to send to the device, corrected by its color correction if configured.
+func (c *Client) correctColor(deviceID string, color RGBColor) RGBColor {
+	correction := c.deviceSettings(deviceID).ColorCorrection
+	if correction == nil {
+		return color
+	}
+	return correction.Apply(color)
+}
@@ -0,0 +1,64 @@
+package govee
+
+import "testing"
+
+func TestColorCorrectionApply(t *testing.T) {
+	tests := []struct {
+		name       string
+		correction ColorCorrection
+		color      RGBColor
+		want       RGBColor
+	}{
+		{name: "identity", correction: GainCorrection(1, 1, 1), color: RGBColor{R: 12, G: 34, B: 56},
+			want: RGBColor{R: 12, G: 34, B: 56}},
+		{name: "gains scale their channel", correction: GainCorrection(1, 0.8, 0.5),
+			color: RGBColor{R: 200, G: 200, B: 200}, want: RGBColor{R: 200, G: 160, B: 100}},
+		{name: "scaled channels are rounded", correction: GainCorrection(0.5, 0.5, 0.5),
+			color: RGBColor{R: 3, G: 5, B: 255}, want: RGBColor{R: 2, G: 3, B: 128}},
+		{name: "gains above 1 are clamped to 255", correction: GainCorrection(1.5, 2, 1),
+			color: RGBColor{R: 200, G: 128, B: 255}, want: RGBColor{R: 255, G: 255, B: 255}},
+		{name: "matrix mixes channels", correction: ColorCorrection{{1, 0, 0}, {0.1, 0.9, 0}, {0, 0, 1}},
+			color: RGBColor{R: 100, G: 100, B: 100}, want: RGBColor{R: 100, G: 100, B: 100}},
+		{name: "matrix removes green tint", correction: ColorCorrection{{1, 0, 0}, {0, 0.85, 0}, {0, 0.1, 1}},
+			color: RGBColor{G: 200}, want: RGBColor{G: 170, B: 20}},
+		{name: "negative results are clamped to 0", correction: ColorCorrection{{1, -1, 0}, {0, 1, 0}, {0, 0, 1}},
+			color: RGBColor{R: 50, G: 100, B: 0}, want: RGBColor{R: 0, G: 100, B: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.correction.Apply(tt.color); got != tt.want {
+				t.Errorf("Apply(%+v) = %+v, want %+v", tt.color, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetColorCorrected(t *testing.T) {
+	client, device := newTestClient(t)
+	correction := GainCorrection(1, 0.5, 2)
+	client.SetDeviceSettings(DeviceSettings{}, map[string]DeviceSettings{
+		testDeviceID: {ColorCorrection: &correction},
+	})
+
+	if err := client.SetColor(testDeviceID, 100, 100, 200); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := device.receiveColor(), (RGBColor{R: 100, G: 50, B: 255}); got != want {
+		t.Errorf("device received %+v, want the corrected and clamped %+v", got, want)
+	}
+
+	// the requested color is remembered, so repeating it isn't sent again
+	if err := client.SetColor(testDeviceID, 100, 100, 200); err != nil {
+		t.Fatal(err)
+	}
+	device.expectNothing()
+
+	// devices without a correction receive colors unchanged
+	client.SetDeviceSettings(DeviceSettings{}, nil)
+	if err := client.SetColor(testDeviceID, 10, 20, 30); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := device.receiveColor(), (RGBColor{R: 10, G: 20, B: 30}); got != want {
+		t.Errorf("device received %+v, want the uncorrected %+v", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package govee
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	minFailureBackoff = 5 * time.Second
+	maxFailureBackoff = 2 * time.Minute
+)
+
+var (
+	ErrDeviceFailed = errors.New("device temporarily failed")
+)
+
+// DeviceHealth is the command failure state of a device
+type DeviceHealth struct {
+	ConsecutiveFailures int
+	// FailedUntil is the time until which commands to the device are skipped, zero if the device is healthy
+	FailedUntil time.Time
+}
+
+// deviceHealth tracks consecutive command failures of a device
+type deviceHealth struct {
+	DeviceHealth
+	backoff time.Duration
+}
+
+// DeviceHealth returns the command failure state of the device with the given ID.
+func (c *Client) DeviceHealth(deviceID string) DeviceHealth {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+
+	if health, ok := c.health[deviceID]; ok {
+		return health.DeviceHealth
+	}
+	return DeviceHealth{}
+}
+
+// checkHealth returns ErrDeviceFailed while the device is marked as temporarily failed.
+func (c *Client) checkHealth(deviceID string) error {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+
+	if health, ok := c.health[deviceID]; ok && c.now().Before(health.FailedUntil) {
+		return fmt.Errorf("device %s until %s: %w", deviceID, health.FailedUntil.Format(time.TimeOnly), ErrDeviceFailed)
+	}
+	return nil
+}
+
+// recordResult records the result of a command. Once the failure threshold is reached, the device is marked as
+// failed for an exponentially growing backoff; a successful command resets its health.
+func (c *Client) recordResult(deviceID string, failureThreshold int, err error) {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+
+	if err == nil {
+		delete(c.health, deviceID)
+		return
+	}
+
+	health, ok := c.health[deviceID]
+	if !ok {
+		health = &deviceHealth{}
+		c.health[deviceID] = health
+	}
+
+	health.ConsecutiveFailures++
+	if health.ConsecutiveFailures < failureThreshold {
+		return
+	}
+
+	health.backoff = min(max(health.backoff*2, minFailureBackoff), maxFailureBackoff)
+	health.FailedUntil = c.now().Add(health.backoff)
+	c.logger.Warn().Err(err).Str("deviceId", deviceID).Int("failures", health.ConsecutiveFailures).
+		Dur("backoff", health.backoff).Msg("Govee device keeps failing, skipping it temporarily")
+}
+
+// IsDeviceFailed checks if an error is caused by a device being temporarily skipped after repeated failures
+func IsDeviceFailed(err error) bool {
+	return err != nil && errors.Is(err, ErrDeviceFailed)
+}
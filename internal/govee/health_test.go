@@ -0,0 +1,113 @@
+package govee
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCommandTimeout(t *testing.T) {
+	client, conn := newMockConnClient(t, 0, nil, nil)
+	conn.slow = true
+	client.SetDeviceSettings(DeviceSettings{CommandTimeout: 50 * time.Millisecond}, nil)
+
+	start := time.Now()
+	err := client.SetBrightness(testDeviceID, 50)
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("SetBrightness() = %v, want it to time out", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("SetBrightness() returned after %s, want it to give up after the command timeout of 50ms", elapsed)
+	}
+	if failures := client.DeviceHealth(testDeviceID).ConsecutiveFailures; failures != 1 {
+		t.Errorf("ConsecutiveFailures = %d, want the timeout counted as a failure", failures)
+	}
+}
+
+func TestDeviceHealth(t *testing.T) {
+	client, conn := newMockConnClient(t, 1000, nil, nil)
+	client.SetDeviceSettings(DeviceSettings{FailureThreshold: 3}, nil)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client.now = func() time.Time { return now }
+
+	send := func() error {
+		t.Helper()
+		client.ForceRefresh(testDeviceID)
+		return client.SetBrightness(testDeviceID, 50)
+	}
+	// fail sends failing commands, returning the consecutive failures and the backoff the device is skipped for
+	fail := func(commands int) (int, time.Duration) {
+		t.Helper()
+		for range commands {
+			if err := send(); err == nil || IsDeviceFailed(err) {
+				t.Fatalf("SetBrightness() = %v, want the write to fail", err)
+			}
+		}
+		health := client.DeviceHealth(testDeviceID)
+		return health.ConsecutiveFailures, health.FailedUntil.Sub(now)
+	}
+
+	// below the threshold, commands keep being sent
+	if failures, backoff := fail(2); failures != 2 || backoff > 0 {
+		t.Fatalf("after 2 failures: ConsecutiveFailures = %d, backoff = %s, want 2 and the device not skipped",
+			failures, backoff)
+	}
+	if failures, backoff := fail(1); failures != 3 || backoff != minFailureBackoff {
+		t.Errorf("at the failure threshold: ConsecutiveFailures = %d, backoff = %s, want 3 and %s", failures,
+			backoff, minFailureBackoff)
+	}
+	writes := len(conn.written())
+	if err := send(); !IsDeviceFailed(err) {
+		t.Errorf("SetBrightness() during the backoff = %v, want ErrDeviceFailed", err)
+	}
+	if len(conn.written()) != writes {
+		t.Error("command was sent during the backoff, want it skipped")
+	}
+
+	// every further failure after the backoff doubles it
+	for _, want := range []time.Duration{2 * minFailureBackoff, 4 * minFailureBackoff} {
+		now = now.Add(want / 2)
+		if _, backoff := fail(1); backoff != want {
+			t.Errorf("backoff after the next failure = %s, want %s", backoff, want)
+		}
+	}
+
+	// a successful command resets the health, including the backoff
+	now = now.Add(4 * minFailureBackoff)
+	conn.succeed()
+	if err := send(); err != nil {
+		t.Fatalf("SetBrightness() = %v, want it to succeed", err)
+	}
+	if health := client.DeviceHealth(testDeviceID); health != (DeviceHealth{}) {
+		t.Errorf("DeviceHealth() after a success = %+v, want it reset", health)
+	}
+	conn.mu.Lock()
+	conn.failures = 1000
+	conn.mu.Unlock()
+	if failures, backoff := fail(3); failures != 3 || backoff != minFailureBackoff {
+		t.Errorf("after the reset: ConsecutiveFailures = %d, backoff = %s, want 3 and %s", failures, backoff,
+			minFailureBackoff)
+	}
+}
+
+func TestDeviceHealthBackoffCap(t *testing.T) {
+	client, _ := newMockConnClient(t, 1000, nil, nil)
+	client.SetDeviceSettings(DeviceSettings{FailureThreshold: 1}, nil)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client.now = func() time.Time { return now }
+
+	for range 10 {
+		client.ForceRefresh(testDeviceID)
+		if err := client.SetBrightness(testDeviceID, 50); err == nil || IsDeviceFailed(err) {
+			t.Fatalf("SetBrightness() = %v, want the write to fail", err)
+		}
+		now = client.DeviceHealth(testDeviceID).FailedUntil
+	}
+	client.healthMu.Lock()
+	backoff := client.health[testDeviceID].backoff
+	client.healthMu.Unlock()
+	if backoff != maxFailureBackoff {
+		t.Errorf("backoff after 10 failures = %s, want the cap of %s", backoff, maxFailureBackoff)
+	}
+}
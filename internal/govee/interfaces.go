@@ -0,0 +1,52 @@
+package govee
+
+import (
+	"fmt"
+	"net"
+)
+
+// SetInterfaces sets the names of the network interfaces discovery runs on. If no interfaces are set, discovery
+// runs on all interfaces that are up and support multicast, except loopback interfaces.
+func (c *Client) SetInterfaces(names []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interfaces = names
+}
+
+// discoveryInterfaces returns the network interfaces discovery runs on.
+func (c *Client) discoveryInterfaces() ([]net.Interface, error) {
+	c.mu.RLock()
+	names := c.interfaces
+	c.mu.RUnlock()
+
+	if len(names) == 0 {
+		ifaces, err := net.Interfaces()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+		}
+		return multicastInterfaces(ifaces), nil
+	}
+
+	ifaces := make([]net.Interface, 0, len(names))
+	for _, name := range names {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("network interface %s: %w", name, err)
+		}
+		ifaces = append(ifaces, *iface)
+	}
+	return ifaces, nil
+}
+
+// multicastInterfaces returns the interfaces that are up and support multicast, skipping loopback interfaces.
+func multicastInterfaces(ifaces []net.Interface) []net.Interface {
+	var usable []net.Interface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		usable = append(usable, iface)
+	}
+	return usable
+}
@@ -0,0 +1,50 @@
+package govee
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestMulticastInterfaces(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags net.Flags
+		want  bool
+	}{
+		{name: "up multicast", flags: net.FlagUp | net.FlagBroadcast | net.FlagMulticast, want: true},
+		{name: "point-to-point multicast", flags: net.FlagUp | net.FlagPointToPoint | net.FlagMulticast, want: true},
+		{name: "loopback", flags: net.FlagUp | net.FlagLoopback | net.FlagMulticast},
+		{name: "down", flags: net.FlagBroadcast | net.FlagMulticast},
+		{name: "non-multicast", flags: net.FlagUp | net.FlagBroadcast},
+		{name: "no flags"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iface := net.Interface{Index: 1, Name: "eth0", Flags: tt.flags}
+			got := multicastInterfaces([]net.Interface{iface})
+			if (len(got) == 1) != tt.want {
+				t.Errorf("multicastInterfaces() with flags %v = %v, want usable = %v", tt.flags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMulticastInterfacesKeepsOrder(t *testing.T) {
+	usable := net.FlagUp | net.FlagMulticast
+	ifaces := []net.Interface{
+		{Index: 1, Name: "lo", Flags: usable | net.FlagLoopback},
+		{Index: 2, Name: "eth0", Flags: usable},
+		{Index: 3, Name: "wlan0", Flags: net.FlagMulticast},
+		{Index: 4, Name: "tun0", Flags: net.FlagUp | net.FlagPointToPoint},
+		{Index: 5, Name: "wlan1", Flags: usable},
+	}
+
+	var names []string
+	for _, iface := range multicastInterfaces(ifaces) {
+		names = append(names, iface.Name)
+	}
+	if want := []string{"eth0", "wlan1"}; !slices.Equal(names, want) {
+		t.Errorf("multicastInterfaces() = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,122 @@
+package govee
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// IPVersion selects the IP versions discovery and control run over
+type IPVersion string
+
+const (
+	IPv4   IPVersion = "ipv4"
+	IPv6   IPVersion = "ipv6"
+	IPBoth IPVersion = "both"
+)
+
+// DefaultMulticastIPv6 is the link-local IPv6 counterpart of DefaultMulticastIP discovery requests are sent to
+// over IPv6
+const DefaultMulticastIPv6 = "ff02::c"
+
+// ParseIPVersion parses an IP version, defaulting to IPv4 if empty.
+func ParseIPVersion(s string) (IPVersion, error) {
+	switch version := IPVersion(s); version {
+	case "":
+		return IPv4, nil
+	case IPv4, IPv6, IPBoth:
+		return version, nil
+	default:
+		return "", fmt.Errorf("invalid IP version %q, must be one of %s, %s or %s", s, IPv4, IPv6, IPBoth)
+	}
+}
+
+// networks returns the UDP networks discovery listens and sends on for the IP version.
+func (v IPVersion) networks() []string {
+	switch v {
+	case IPv6:
+		return []string{"udp6"}
+	case IPBoth:
+		return []string{"udp4", "udp6"}
+	default:
+		return []string{"udp4"}
+	}
+}
+
+// SetIPVersion sets the IP versions discovery runs over. Over IPv6, discovery requests are sent to
+// DefaultMulticastIPv6 and devices are controlled at the address they answered from. If both versions are used,
+// devices answering over both are controlled over IPv4. Must be called before Discover.
+func (c *Client) SetIPVersion(version IPVersion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ipVersion = version
+}
+
+// controlNetwork returns the UDP network a device with the given IP is controlled over.
+func controlNetwork(ip string) string {
+	host, _, _ := strings.Cut(ip, "%")
+	if parsed := net.ParseIP(host); parsed != nil && parsed.To4() == nil {
+		return "udp6"
+	}
+	return "udp4"
+}
+
+// responseIP returns the IP a response was received from, including the zone of link-local IPv6 addresses.
+func responseIP(addr *net.UDPAddr) string {
+	if addr.Zone != "" {
+		return addr.IP.String() + "%" + addr.Zone
+	}
+	return addr.IP.String()
+}
+
+// multicastConn sends and receives multicast packets over IPv4 or IPv6
+type multicastConn struct {
+	conn *net.UDPConn
+	v4   *ipv4.PacketConn // nil for IPv6
+	v6   *ipv6.PacketConn // nil for IPv4
+}
+
+// newMulticastConn wraps a UDP connection of the given network.
+func newMulticastConn(network string, conn *net.UDPConn) *multicastConn {
+	if network == "udp6" {
+		return &multicastConn{conn: conn, v6: ipv6.NewPacketConn(conn)}
+	}
+	return &multicastConn{conn: conn, v4: ipv4.NewPacketConn(conn)}
+}
+
+func (m *multicastConn) JoinGroup(iface *net.Interface, group net.Addr) error {
+	if m.v6 != nil {
+		return m.v6.JoinGroup(iface, group)
+	}
+	return m.v4.JoinGroup(iface, group)
+}
+
+func (m *multicastConn) SetMulticastInterface(iface *net.Interface) error {
+	if m.v6 != nil {
+		return m.v6.SetMulticastInterface(iface)
+	}
+	return m.v4.SetMulticastInterface(iface)
+}
+
+// SetMulticastTTL sets the TTL of IPv4 or the hop limit of IPv6 multicast packets.
+func (m *multicastConn) SetMulticastTTL(ttl int) error {
+	if m.v6 != nil {
+		return m.v6.SetMulticastHopLimit(ttl)
+	}
+	return m.v4.SetMulticastTTL(ttl)
+}
+
+func (m *multicastConn) WriteTo(b []byte, dst net.Addr) (int, error) {
+	if m.v6 != nil {
+		return m.v6.WriteTo(b, nil, dst)
+	}
+	return m.v4.WriteTo(b, nil, dst)
+}
+
+func (m *multicastConn) Close() error {
+	return m.conn.Close()
+}
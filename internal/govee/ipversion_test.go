@@ -0,0 +1,97 @@
+package govee
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestParseIPVersion(t *testing.T) {
+	tests := []struct {
+		version      string
+		want         IPVersion
+		wantNetworks []string
+		wantErr      bool
+	}{
+		{version: "", want: IPv4, wantNetworks: []string{"udp4"}},
+		{version: "ipv4", want: IPv4, wantNetworks: []string{"udp4"}},
+		{version: "ipv6", want: IPv6, wantNetworks: []string{"udp6"}},
+		{version: "both", want: IPBoth, wantNetworks: []string{"udp4", "udp6"}},
+		{version: "IPv6", wantErr: true},
+		{version: "ipv5", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := ParseIPVersion(tt.version)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseIPVersion(%q) = %q, want an error", tt.version, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseIPVersion(%q) = %q, %v, want %q", tt.version, got, err, tt.want)
+			}
+			if networks := got.networks(); !slices.Equal(networks, tt.wantNetworks) {
+				t.Errorf("networks() = %v, want %v", networks, tt.wantNetworks)
+			}
+		})
+	}
+}
+
+func TestControlNetwork(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "192.168.1.20", want: "udp4"},
+		{ip: "::ffff:192.168.1.20", want: "udp4"},
+		{ip: "2001:db8::20", want: "udp6"},
+		{ip: "fe80::1%eth0", want: "udp6"},
+		{ip: "::1", want: "udp6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := controlNetwork(tt.ip); got != tt.want {
+				t.Errorf("controlNetwork(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseIP(t *testing.T) {
+	tests := []struct {
+		addr *net.UDPAddr
+		want string
+	}{
+		{addr: &net.UDPAddr{IP: net.IPv4(192, 168, 1, 20)}, want: "192.168.1.20"},
+		{addr: &net.UDPAddr{IP: net.ParseIP("2001:db8::20")}, want: "2001:db8::20"},
+		{addr: &net.UDPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}, want: "fe80::1%eth0"},
+	}
+	for _, tt := range tests {
+		if got := responseIP(tt.addr); got != tt.want {
+			t.Errorf("responseIP(%s) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestSendCommandIPv6(t *testing.T) {
+	conn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	client, device := newTestClient(t)
+	device.conn.Close()
+	device.conn = conn
+	t.Cleanup(func() { conn.Close() })
+	client.controlPort = conn.LocalAddr().(*net.UDPAddr).Port
+	client.devices[testDeviceID] = "::1"
+
+	// the device is controlled over IPv6 as it was discovered at an IPv6 address
+	if err := client.SetColor(testDeviceID, 0, 0, 255); err != nil {
+		t.Fatalf("SetColor() returned error: %v", err)
+	}
+	if got := device.receiveColor(); got != (RGBColor{B: 255}) {
+		t.Errorf("device received color %v, want blue", got)
+	}
+}
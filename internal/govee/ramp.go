@@ -0,0 +1,73 @@
+package govee
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// rampStepInterval is the interval between two commands of a ramp
+const rampStepInterval = 100 * time.Millisecond
+
+// State is the color and brightness state of a Govee device
+type State struct {
+	Color      RGBColor
+	Brightness int
+}
+
+// InterpolateColor linearly interpolates between two colors, t being in the range [0, 1].
+func InterpolateColor(from, to RGBColor, t float64) RGBColor {
+	return RGBColor{
+		R: interpolate(from.R, to.R, t),
+		G: interpolate(from.G, to.G, t),
+		B: interpolate(from.B, to.B, t),
+	}
+}
+
+// InterpolateState linearly interpolates between two states, t being in the range [0, 1].
+func InterpolateState(from, to State, t float64) State {
+	return State{
+		Color:      InterpolateColor(from.Color, to.Color, t),
+		Brightness: interpolate(from.Brightness, to.Brightness, t),
+	}
+}
+
+// RampSteps returns the intermediate states of a ramp from one state to another, ending with the target state.
+func RampSteps(from, to State, steps int) []State {
+	if steps < 1 {
+		steps = 1
+	}
+
+	states := make([]State, steps)
+	for i := range steps {
+		states[i] = InterpolateState(from, to, float64(i+1)/float64(steps))
+	}
+	return states
+}
+
+// Ramp gradually moves a device from one state to another over the given duration.
+// It returns early with the context's error if the context is done before the ramp completes.
+func (c *Client) Ramp(ctx context.Context, deviceID string, from, to State, duration time.Duration) error {
+	steps := int(duration / rampStepInterval)
+	for i, state := range RampSteps(from, to, steps) {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(rampStepInterval):
+			}
+		}
+
+		if err := c.SetColor(deviceID, state.Color.R, state.Color.G, state.Color.B); err != nil {
+			return err
+		}
+		if err := c.SetBrightness(deviceID, state.Brightness); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func interpolate(from, to int, t float64) int {
+	return int(math.Round(float64(from) + float64(to-from)*t))
+}
@@ -0,0 +1,89 @@
+package govee
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRampSteps(t *testing.T) {
+	from := State{Color: RGBColor{R: 0, G: 100, B: 255}, Brightness: 10}
+	to := State{Color: RGBColor{R: 255, G: 100, B: 0}, Brightness: 90}
+
+	tests := []struct {
+		name  string
+		steps int
+		want  []State
+	}{
+		{
+			name:  "four steps",
+			steps: 4,
+			want: []State{
+				{Color: RGBColor{R: 64, G: 100, B: 191}, Brightness: 30},
+				{Color: RGBColor{R: 128, G: 100, B: 128}, Brightness: 50},
+				{Color: RGBColor{R: 191, G: 100, B: 64}, Brightness: 70},
+				{Color: RGBColor{R: 255, G: 100, B: 0}, Brightness: 90},
+			},
+		},
+		{name: "single step", steps: 1, want: []State{to}},
+		{name: "no steps jumps to the target", steps: 0, want: []State{to}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RampSteps(from, to, tt.steps); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RampSteps() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRamp(t *testing.T) {
+	client, device := newTestClient(t)
+	from := State{Color: RGBColor{R: 0, G: 0, B: 0}, Brightness: 0}
+	to := State{Color: RGBColor{R: 90, G: 180, B: 30}, Brightness: 60}
+
+	if err := client.Ramp(context.Background(), testDeviceID, from, to, 3*rampStepInterval); err != nil {
+		t.Fatalf("Ramp() returned error: %v", err)
+	}
+	for _, want := range RampSteps(from, to, 3) {
+		if got := device.receiveColor(); got != want.Color {
+			t.Errorf("device received color %v, want %v", got, want.Color)
+		}
+		if got := device.receiveBrightness(); got != want.Brightness {
+			t.Errorf("device received brightness %d, want %d", got, want.Brightness)
+		}
+	}
+	device.expectNothing()
+}
+
+func TestRampCancel(t *testing.T) {
+	client, device := newTestClient(t)
+	from := State{Color: RGBColor{R: 0, G: 0, B: 0}, Brightness: 0}
+	to := State{Color: RGBColor{R: 255, G: 255, B: 255}, Brightness: 100}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Ramp(ctx, testDeviceID, from, to, 10*rampStepInterval)
+	}()
+
+	// the first step is sent right away, the ramp is cancelled before the next one
+	first := RampSteps(from, to, 10)[0]
+	if got := device.receiveColor(); got != first.Color {
+		t.Errorf("device received color %v, want the first step %v", got, first.Color)
+	}
+	device.receiveBrightness()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Ramp() after cancel = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Ramp() didn't return after cancel")
+	}
+	device.expectNothing()
+}
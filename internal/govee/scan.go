@@ -0,0 +1,40 @@
+package govee
+
+import (
+	"time"
+)
+
+const (
+	// DefaultScanInterval is how often discovery requests are sent by default once the initial scans are done
+	DefaultScanInterval = 30 * time.Second
+	// initialScanInterval is the interval of the first scans, to find the devices quickly after startup
+	initialScanInterval = 2 * time.Second
+	// initialScans is the number of scans sent at initialScanInterval
+	initialScans = 5
+	// scanJitter is the fraction by which the steady-state scan interval is randomly varied, so multiple
+	// instances don't scan in sync
+	scanJitter = 0.1
+)
+
+// SetScanInterval sets how often discovery requests are sent to detect new devices once the initial scans are
+// done. Known devices keep responding to the requests, so the interval should be shorter than the device TTL.
+func (c *Client) SetScanInterval(interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scanInterval = interval
+}
+
+// nextScanDelay returns the delay before the next discovery request, given the number of requests sent so far.
+func (c *Client) nextScanDelay(scans int) time.Duration {
+	if scans < initialScans {
+		return initialScanInterval
+	}
+
+	c.mu.RLock()
+	interval := c.scanInterval
+	c.mu.RUnlock()
+
+	jitter := (c.randFloat()*2 - 1) * scanJitter
+	return time.Duration(float64(interval) * (1 + jitter))
+}
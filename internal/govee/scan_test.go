@@ -0,0 +1,204 @@
+package govee
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNextScanDelay(t *testing.T) {
+	client := NewClient(zerolog.Nop(), DefaultMulticastIP)
+	client.randFloat = rand.New(rand.NewPCG(1, 1)).Float64
+	client.SetScanInterval(30 * time.Second)
+
+	// the first scans are sent quickly to find the devices after startup
+	for scans := 1; scans < initialScans; scans++ {
+		if got := client.nextScanDelay(scans); got != initialScanInterval {
+			t.Errorf("nextScanDelay(%d) = %s, want the initial interval of %s", scans, got, initialScanInterval)
+		}
+	}
+
+	// afterwards, scans slow down to the jittered steady-state interval
+	minDelay, maxDelay := 27*time.Second, 33*time.Second
+	seen := make(map[time.Duration]bool)
+	for scans := initialScans; scans < initialScans+20; scans++ {
+		got := client.nextScanDelay(scans)
+		if got < minDelay || got > maxDelay {
+			t.Errorf("nextScanDelay(%d) = %s, want the steady-state interval between %s and %s", scans, got,
+				minDelay, maxDelay)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("steady-state scan delays are all %v, want them jittered", seen)
+	}
+}
+
+func TestNextScanDelayJitterBounds(t *testing.T) {
+	client := NewClient(zerolog.Nop(), DefaultMulticastIP)
+	client.SetScanInterval(10 * time.Second)
+
+	tests := []struct {
+		random float64
+		want   time.Duration
+	}{
+		{random: 0, want: 9 * time.Second},
+		{random: 0.5, want: 10 * time.Second},
+		{random: 0.75, want: 10500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		client.randFloat = func() float64 { return tt.random }
+		if got := client.nextScanDelay(initialScans); got != tt.want {
+			t.Errorf("nextScanDelay() with random %v = %s, want %s", tt.random, got, tt.want)
+		}
+	}
+}
+
+// loopbackInterface returns the loopback interface, skipping the test if there is none.
+func loopbackInterface(t *testing.T) net.Interface {
+	t.Helper()
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagUp != 0 {
+			return iface
+		}
+	}
+	t.Skip("no loopback interface")
+	return net.Interface{}
+}
+
+func TestListenResponsesJoinsGroup(t *testing.T) {
+	lo := loopbackInterface(t)
+	client := NewClient(zerolog.Nop(), DefaultMulticastIP)
+	group := net.ParseIP(DefaultMulticastIP)
+
+	conn, err := client.listenResponses("udp4", group, []net.Interface{lo})
+	if err != nil {
+		t.Skipf("can't join the multicast group on the loopback interface: %v", err)
+	}
+	defer conn.Close()
+
+	// a packet sent to the group out of the loopback interface is received by the member
+	sender, err := newDiscoverySender("udp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	if err := sender.SetMulticastInterface(&lo); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.v4.SetMulticastLoopback(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sender.WriteTo([]byte("scan"), &net.UDPAddr{IP: group, Port: responsePort}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("listener didn't receive the packet sent to the group: %v", err)
+	}
+	if got := string(buf[:n]); got != "scan" {
+		t.Errorf("listener received %q, want scan", got)
+	}
+}
+
+func TestDiscoverySenderTTL(t *testing.T) {
+	sender, err := newDiscoverySender("udp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+
+	if ttl, err := sender.v4.MulticastTTL(); err != nil || ttl != multicastTTL {
+		t.Errorf("MulticastTTL() = %d, %v, want %d to keep discovery on the local network", ttl, err, multicastTTL)
+	}
+}
+
+// startTestDiscovery starts discovery of the client on the loopback interface, skipping the test if multicast
+// isn't available.
+func startTestDiscovery(t *testing.T, ctx context.Context, client *Client) {
+	t.Helper()
+
+	client.SetInterfaces([]string{loopbackInterface(t).Name})
+	if err := client.Discover(ctx); err != nil {
+		t.Skipf("can't run discovery on the loopback interface: %v", err)
+	}
+}
+
+// waitForGoroutines waits until at most the given number of goroutines are running.
+func waitForGoroutines(t *testing.T, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > want {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines are running, want at most %d", runtime.NumGoroutine(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// expectResponsePortFree fails the test if the response port is still bound by discovery.
+func expectResponsePortFree(t *testing.T) {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: responsePort})
+	if err != nil {
+		t.Fatalf("response port is still in use: %v", err)
+	}
+	conn.Close()
+}
+
+func TestCloseReleasesDiscovery(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	client, device := newTestClient(t)
+	startTestDiscovery(t, context.Background(), client)
+
+	// a command opens a connection to the device
+	if err := client.TurnOn(testDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	device.receive()
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+	expectResponsePortFree(t)
+	waitForGoroutines(t, goroutines)
+	client.connMu.Lock()
+	if len(client.conns) != 0 {
+		t.Errorf("%d connections to devices are open after Close(), want none", len(client.conns))
+	}
+	client.connMu.Unlock()
+
+	// closing again is a no-op
+	if err := client.Close(); err != nil {
+		t.Errorf("second Close() returned error: %v", err)
+	}
+}
+
+func TestCancelReleasesDiscovery(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewClient(zerolog.Nop(), DefaultMulticastIP)
+	startTestDiscovery(t, ctx, client)
+
+	cancel()
+	client.discovery.Wait()
+	expectResponsePortFree(t)
+	waitForGoroutines(t, goroutines)
+}
@@ -0,0 +1,67 @@
+package govee
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestDeviceSceneData(t *testing.T) {
+	tests := []struct {
+		code    int
+		want    []byte
+		wantErr bool
+	}{
+		{code: 0, want: []byte{0x33, 0x05, 0x04, 0x00, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x32}},
+		// the code is sent little endian
+		{code: 0x1234, want: []byte{0x33, 0x05, 0x04, 0x34, 0x12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x14}},
+		{code: -1, wantErr: true},
+		{code: MaxSceneCode + 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		data, err := deviceSceneData(tt.code)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("deviceSceneData(%d) = %+v, want an error", tt.code, data)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("deviceSceneData(%d) returned error: %v", tt.code, err)
+		}
+
+		b, err := json.Marshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `{"command":["` + base64.StdEncoding.EncodeToString(tt.want) + `"]}`
+		if string(b) != want {
+			t.Errorf("deviceSceneData(%d) marshals to %s, want %s", tt.code, b, want)
+		}
+	}
+}
+
+func TestSetDeviceScene(t *testing.T) {
+	client, device := newTestClient(t)
+
+	// the scene is activated every time, as the device may have left it meanwhile
+	for range 2 {
+		if err := client.SetDeviceScene(testDeviceID, 0x1234); err != nil {
+			t.Fatalf("SetDeviceScene() returned error: %v", err)
+		}
+		msg := device.receive()
+		var data PassThroughData
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := deviceSceneData(0x1234)
+		if msg.Command != "ptReal" || len(data.Command) != 1 || data.Command[0] != want.Command[0] {
+			t.Errorf("device received %s %s, want ptReal %v", msg.Command, msg.Data, want.Command)
+		}
+	}
+
+	if err := client.SetDeviceScene(testDeviceID, -1); err == nil {
+		t.Error("SetDeviceScene() with an invalid code returned no error")
+	}
+	device.expectNothing()
+}
@@ -0,0 +1,91 @@
+package govee
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+)
+
+// MaxSegments is the number of segments addressable with a single segment color packet
+const MaxSegments = 16
+
+// PassThroughData is the data structure for Govee ptReal commands, carrying raw BLE packets encoded as base64
+type PassThroughData struct {
+	Command []string `json:"command"`
+}
+
+// SetSegmentColors sets the color of each segment of a Govee device, the first color being applied to the
+// first segment. Returns ErrUnsupportedCommand if the device doesn't support segments.
+func (c *Client) SetSegmentColors(deviceID string, colors []RGBColor) error {
+	if err := c.requireCapability(deviceID, func(c Capabilities) bool { return c.Segments }, "ptReal"); err != nil {
+		return err
+	}
+
+	if last := c.knownState(deviceID).Segments; last != nil && slices.Equal(last, colors) {
+		return nil
+	}
+
+	corrected := make([]RGBColor, len(colors))
+	for i, color := range colors {
+		corrected[i] = c.correctColor(deviceID, color)
+	}
+	data, err := segmentColorData(corrected)
+	if err != nil {
+		return err
+	}
+	if err := c.sendCommand(deviceID, "ptReal", data); err != nil {
+		return err
+	}
+	c.rememberState(deviceID, func(state *deviceState) {
+		state.Segments = slices.Clone(colors)
+		state.Color = nil
+		state.ColorTemperature = nil
+	})
+	return nil
+}
+
+// segmentColorData builds the ptReal payload setting the segment colors, with one packet per distinct color
+// addressing all segments of that color.
+func segmentColorData(colors []RGBColor) (PassThroughData, error) {
+	if len(colors) == 0 || len(colors) > MaxSegments {
+		return PassThroughData{}, fmt.Errorf("number of segments must be between 1 and %d, got %d", MaxSegments, len(colors))
+	}
+
+	var distinct []RGBColor
+	masks := make(map[RGBColor]uint16)
+	for i, color := range colors {
+		if _, ok := masks[color]; !ok {
+			distinct = append(distinct, color)
+		}
+		masks[color] |= 1 << i
+	}
+
+	data := PassThroughData{Command: make([]string, 0, len(distinct))}
+	for _, color := range distinct {
+		packet := segmentColorPacket(color, masks[color])
+		data.Command = append(data.Command, base64.StdEncoding.EncodeToString(packet[:]))
+	}
+	return data, nil
+}
+
+// segmentColorPacket builds the BLE packet setting the color of the segments in the mask, the lowest bit
+// addressing the first segment.
+func segmentColorPacket(color RGBColor, mask uint16) [20]byte {
+	packet := [20]byte{
+		0x33, 0x05, 0x15, 0x01,
+		byte(color.R), byte(color.G), byte(color.B),
+		0x00, 0x00, 0x00, 0x00, 0x00,
+		byte(mask), byte(mask >> 8),
+	}
+
+	setChecksum(&packet)
+	return packet
+}
+
+// setChecksum sets the last byte of a BLE packet to the XOR checksum of all other bytes
+func setChecksum(packet *[20]byte) {
+	packet[19] = 0
+	for _, b := range packet[:19] {
+		packet[19] ^= b
+	}
+}
@@ -0,0 +1,113 @@
+package govee
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestSegmentColorData(t *testing.T) {
+	red := RGBColor{R: 255}
+	green := RGBColor{G: 255}
+	blue := RGBColor{B: 255}
+
+	tests := []struct {
+		name    string
+		colors  []RGBColor
+		want    [][]byte
+		wantErr bool
+	}{
+		{
+			name:   "segments of the same color share a packet",
+			colors: []RGBColor{red, green, red},
+			want: [][]byte{
+				{0x33, 0x05, 0x15, 0x01, 0xff, 0x00, 0x00, 0, 0, 0, 0, 0, 0x05, 0x00, 0, 0, 0, 0, 0, 0xd8},
+				{0x33, 0x05, 0x15, 0x01, 0x00, 0xff, 0x00, 0, 0, 0, 0, 0, 0x02, 0x00, 0, 0, 0, 0, 0, 0xdf},
+			},
+		},
+		{
+			name:   "segments beyond the eighth",
+			colors: []RGBColor{red, red, red, red, red, red, red, red, red, blue},
+			want: [][]byte{
+				{0x33, 0x05, 0x15, 0x01, 0xff, 0x00, 0x00, 0, 0, 0, 0, 0, 0xff, 0x01, 0, 0, 0, 0, 0, 0x23},
+				{0x33, 0x05, 0x15, 0x01, 0x00, 0x00, 0xff, 0, 0, 0, 0, 0, 0x00, 0x02, 0, 0, 0, 0, 0, 0xdf},
+			},
+		},
+		{name: "no segments", wantErr: true},
+		{name: "too many segments", colors: make([]RGBColor, MaxSegments+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := segmentColorData(tt.colors)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("segmentColorData() = %+v, want an error", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("segmentColorData() returned error: %v", err)
+			}
+
+			// the packets are marshalled as base64 strings in the command array
+			b, err := json.Marshal(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var payload struct {
+				Command [][]byte `json:"command"`
+			}
+			if err := json.Unmarshal(b, &payload); err != nil {
+				t.Fatalf("payload %s isn't a list of base64 packets: %v", b, err)
+			}
+			if len(payload.Command) != len(tt.want) {
+				t.Fatalf("payload has %d packets, want %d: %s", len(payload.Command), len(tt.want), b)
+			}
+			for i, packet := range payload.Command {
+				if string(packet) != string(tt.want[i]) {
+					t.Errorf("packet %d = % x, want % x", i, packet, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSetSegmentColors(t *testing.T) {
+	client, device := newTestClient(t)
+	colors := []RGBColor{{R: 255}, {G: 255}, {R: 255}}
+
+	// segments aren't among the capabilities of the test device, so callers fall back to a single color
+	if err := client.SetSegmentColors(testDeviceID, colors); !IsUnsupportedCommand(err) {
+		t.Fatalf("SetSegmentColors() without segment support = %v, want ErrUnsupportedCommand", err)
+	}
+	device.expectNothing()
+
+	client.SetCapabilityOverrides(map[string]Capabilities{testDeviceID: {Color: true, Segments: true}})
+	if err := client.SetSegmentColors(testDeviceID, colors); err != nil {
+		t.Fatalf("SetSegmentColors() returned error: %v", err)
+	}
+	msg := device.receive()
+	if msg.Command != "ptReal" {
+		t.Fatalf("device received %s, want ptReal", msg.Command)
+	}
+	var data PassThroughData
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := segmentColorData(colors)
+	if len(data.Command) != len(want.Command) || data.Command[0] != want.Command[0] ||
+		data.Command[1] != want.Command[1] {
+		t.Errorf("device received %v, want %v", data.Command, want.Command)
+	}
+	for _, packet := range data.Command {
+		if _, err := base64.StdEncoding.DecodeString(packet); err != nil {
+			t.Errorf("packet %q isn't base64: %v", packet, err)
+		}
+	}
+
+	// the same segment colors aren't sent again
+	if err := client.SetSegmentColors(testDeviceID, colors); err != nil {
+		t.Fatal(err)
+	}
+	device.expectNothing()
+}
@@ -0,0 +1,91 @@
+package govee
+
+import (
+	"time"
+)
+
+const (
+	defaultCommandTimeout   = time.Second
+	defaultFailureThreshold = 3
+)
+
+// DeviceSettings are user-configured settings of a single Govee device. Zero values fall back to the defaults.
+type DeviceSettings struct {
+	// ColorTolerance is the maximum per-channel delta to the last sent color that is treated as no change.
+	// Zero only skips colors identical to the last sent one.
+	ColorTolerance int
+	// CommandTimeout is the maximum time dialing and writing a single command may take
+	CommandTimeout time.Duration
+	// FailureThreshold is the number of consecutive failed commands after which the device is
+	// temporarily skipped
+	FailureThreshold int
+	// Retries is the number of times a command is resent after sending it failed, nil falls back to the default.
+	// Zero sends each command once.
+	Retries *int
+	// RetryDelay is the delay between two attempts to send a command
+	RetryDelay time.Duration
+	// ColorCorrection calibrates the colors sent to the device, nil sends colors unchanged
+	ColorCorrection *ColorCorrection
+}
+
+// SetDeviceSettings sets the default settings applied to all devices and the settings of individual devices.
+func (c *Client) SetDeviceSettings(defaults DeviceSettings, settings map[string]DeviceSettings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.defaultSettings = defaults
+	c.settings = settings
+}
+
+// deviceSettings returns the settings of the device with the given ID, merged with the defaults.
+func (c *Client) deviceSettings(deviceID string) DeviceSettings {
+	c.mu.RLock()
+	defaults, settings := c.defaultSettings, c.settings[deviceID]
+	c.mu.RUnlock()
+
+	if settings.ColorTolerance == 0 {
+		settings.ColorTolerance = defaults.ColorTolerance
+	}
+	if settings.CommandTimeout == 0 {
+		settings.CommandTimeout = defaults.CommandTimeout
+	}
+	if settings.CommandTimeout == 0 {
+		settings.CommandTimeout = defaultCommandTimeout
+	}
+	if settings.FailureThreshold == 0 {
+		settings.FailureThreshold = defaults.FailureThreshold
+	}
+	if settings.FailureThreshold == 0 {
+		settings.FailureThreshold = defaultFailureThreshold
+	}
+	if settings.Retries == nil {
+		settings.Retries = defaults.Retries
+	}
+	if settings.RetryDelay == 0 {
+		settings.RetryDelay = defaults.RetryDelay
+	}
+	if settings.ColorCorrection == nil {
+		settings.ColorCorrection = defaults.ColorCorrection
+	}
+	return settings
+}
+
+// retries returns the number of times a command is resent after sending it failed.
+func (s DeviceSettings) retries() int {
+	if s.Retries == nil {
+		return 0
+	}
+	return *s.Retries
+}
+
+// withinTolerance returns true if no channel of the two colors differs by more than tolerance.
+func withinTolerance(a, b RGBColor, tolerance int) bool {
+	return abs(a.R-b.R) <= tolerance && abs(a.G-b.G) <= tolerance && abs(a.B-b.B) <= tolerance
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,92 @@
+package govee
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// StatusData is the data structure of Govee devStatus responses
+type StatusData struct {
+	OnOff            int      `json:"onOff"`
+	Brightness       int      `json:"brightness"`
+	Color            RGBColor `json:"color"`
+	ColorTemInKelvin int      `json:"colorTemInKelvin"`
+}
+
+// IsOn returns whether the device reported to be turned on
+func (s StatusData) IsOn() bool {
+	return s.OnOff == 1
+}
+
+// parseStatus parses a devStatus response received on the response port
+func parseStatus(data json.RawMessage) (StatusData, error) {
+	var status StatusData
+	if err := json.Unmarshal(data, &status); err != nil {
+		return StatusData{}, fmt.Errorf("failed to unmarshal device status: %w", err)
+	}
+	if status.Brightness < 0 || status.Brightness > 100 {
+		return StatusData{}, fmt.Errorf("device status brightness %d out of range", status.Brightness)
+	}
+	return status, nil
+}
+
+// QueryStatus asks a Govee device for its current on/off state, brightness and color. The device answers on
+// the response port, so Discover must be running for the response to be received.
+func (c *Client) QueryStatus(ctx context.Context, deviceID string) (StatusData, error) {
+	ip, ok := c.deviceIP(deviceID)
+	if !ok {
+		return StatusData{}, ErrDeviceNotFound
+	}
+
+	response := make(chan StatusData, 1)
+	c.statusMu.Lock()
+	c.statusWaiters[ip] = append(c.statusWaiters[ip], response)
+	c.statusMu.Unlock()
+	defer c.removeStatusWaiter(ip, response)
+
+	if err := c.sendCommand(deviceID, "devStatus", struct{}{}); err != nil {
+		return StatusData{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.deviceSettings(deviceID).CommandTimeout)
+	defer cancel()
+
+	select {
+	case status := <-response:
+		return status, nil
+	case <-ctx.Done():
+		return StatusData{}, fmt.Errorf("no status response from device %s: %w", deviceID, ctx.Err())
+	}
+}
+
+// deliverStatus hands a devStatus response to everyone waiting for a status of the device with the given IP.
+func (c *Client) deliverStatus(ip string, status StatusData) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	for _, waiter := range c.statusWaiters[ip] {
+		select {
+		case waiter <- status:
+		default:
+		}
+	}
+	delete(c.statusWaiters, ip)
+}
+
+// removeStatusWaiter removes a waiter that is no longer interested in a status response.
+func (c *Client) removeStatusWaiter(ip string, response chan StatusData) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	waiters := c.statusWaiters[ip]
+	for i, waiter := range waiters {
+		if waiter == response {
+			c.statusWaiters[ip] = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(c.statusWaiters[ip]) == 0 {
+		delete(c.statusWaiters, ip)
+	}
+}
@@ -0,0 +1,123 @@
+package govee
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    StatusData
+		wantErr bool
+	}{
+		{
+			name: "color",
+			data: `{"onOff":1,"brightness":80,"color":{"r":255,"g":128,"b":0},"colorTemInKelvin":0}`,
+			want: StatusData{OnOff: 1, Brightness: 80, Color: RGBColor{R: 255, G: 128, B: 0}},
+		},
+		{
+			name: "color temperature",
+			data: `{"onOff":1,"brightness":100,"color":{"r":0,"g":0,"b":0},"colorTemInKelvin":2700}`,
+			want: StatusData{OnOff: 1, Brightness: 100, ColorTemInKelvin: 2700},
+		},
+		{
+			name: "off",
+			data: `{"onOff":0,"brightness":0,"color":{"r":0,"g":0,"b":0},"colorTemInKelvin":0}`,
+			want: StatusData{},
+		},
+		{name: "brightness above 100", data: `{"onOff":1,"brightness":101}`, wantErr: true},
+		{name: "negative brightness", data: `{"onOff":1,"brightness":-1}`, wantErr: true},
+		{name: "invalid", data: `{"onOff":"on"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStatus(json.RawMessage(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseStatus() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStatus() returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseStatus() = %+v, want %+v", got, tt.want)
+			}
+			if got.IsOn() != (tt.want.OnOff == 1) {
+				t.Errorf("IsOn() = %v, want %v", got.IsOn(), tt.want.OnOff == 1)
+			}
+		})
+	}
+}
+
+func TestQueryStatus(t *testing.T) {
+	client, device := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	responses, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer responses.Close()
+	go client.readResponses(ctx, responses, false)
+
+	type result struct {
+		status StatusData
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		status, err := client.QueryStatus(ctx, testDeviceID)
+		results <- result{status, err}
+	}()
+
+	if msg := device.receive(); msg.Command != "devStatus" {
+		t.Fatalf("device received %s, want devStatus", msg.Command)
+	}
+	reply := `{"msg":{"cmd":"devStatus","data":{"onOff":1,"brightness":42,"color":{"r":10,"g":20,"b":30},` +
+		`"colorTemInKelvin":0}}}`
+	if _, err := device.conn.WriteTo([]byte(reply), responses.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case res := <-results:
+		want := StatusData{OnOff: 1, Brightness: 42, Color: RGBColor{R: 10, G: 20, B: 30}}
+		if res.err != nil || res.status != want {
+			t.Errorf("QueryStatus() = %+v, %v, want %+v", res.status, res.err, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("QueryStatus() didn't return after the device replied")
+	}
+}
+
+func TestQueryStatusTimeout(t *testing.T) {
+	client, device := newTestClient(t)
+	client.SetDeviceSettings(DeviceSettings{CommandTimeout: 50 * time.Millisecond}, nil)
+
+	if _, err := client.QueryStatus(context.Background(), testDeviceID); err == nil {
+		t.Error("QueryStatus() without a reply = nil, want a timeout error")
+	}
+	device.receive()
+
+	client.statusMu.Lock()
+	waiters := len(client.statusWaiters)
+	client.statusMu.Unlock()
+	if waiters != 0 {
+		t.Errorf("client has %d status waiters after the timeout, want them removed", waiters)
+	}
+}
+
+func TestQueryStatusUnknownDevice(t *testing.T) {
+	client, _ := newTestClient(t)
+	if _, err := client.QueryStatus(context.Background(), "00:00:00:00:00:00:00:00"); !IsDeviceNotFound(err) {
+		t.Errorf("QueryStatus() of an unknown device = %v, want ErrDeviceNotFound", err)
+	}
+}
@@ -0,0 +1,82 @@
+package govee
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultDeviceTTL is how long a device is kept without responding to discovery by default
+const DefaultDeviceTTL = 60 * time.Second
+
+// SetDeviceTTL sets how long a device is kept without responding to discovery before it is removed.
+func (c *Client) SetDeviceTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deviceTTL = ttl
+}
+
+// LastSeen returns when the device last responded to discovery, false if it isn't known.
+func (c *Client) LastSeen(deviceID string) (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	lastSeen, ok := c.lastSeen[deviceID]
+	return lastSeen, ok
+}
+
+// deviceIP returns the IP of a device, false if it isn't known or didn't respond to discovery within the TTL.
+func (c *Client) deviceIP(deviceID string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ip, ok := c.devices[deviceID]
+	if !ok || c.expired(deviceID) {
+		return "", false
+	}
+	return ip, true
+}
+
+// expired returns whether a device didn't respond to discovery within the TTL. c.mu must be held.
+func (c *Client) expired(deviceID string) bool {
+	return c.now().Sub(c.lastSeen[deviceID]) > c.deviceTTL
+}
+
+// sweepExpiredDevices periodically removes devices that didn't respond to discovery within the TTL
+// until the context is done.
+func (c *Client) sweepExpiredDevices(ctx context.Context) {
+	for {
+		c.mu.RLock()
+		interval := c.deviceTTL / 4
+		c.mu.RUnlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		c.removeExpiredDevices()
+	}
+}
+
+// removeExpiredDevices removes all devices that didn't respond to discovery within the TTL.
+func (c *Client) removeExpiredDevices() {
+	c.mu.Lock()
+	removed := make(map[string]string)
+	for deviceID, ip := range c.devices {
+		if c.expired(deviceID) {
+			delete(c.devices, deviceID)
+			delete(c.lastSeen, deviceID)
+			delete(c.discovered, deviceID)
+			removed[deviceID] = ip
+		}
+	}
+	c.mu.Unlock()
+
+	for deviceID, ip := range removed {
+		c.ForceRefresh(deviceID)
+		c.closeControlConn(ip)
+		c.logger.Warn().Str("deviceId", deviceID).Msg("Govee device stopped responding to discovery, removing it")
+	}
+}
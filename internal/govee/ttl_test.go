@@ -0,0 +1,110 @@
+package govee
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock for Client.now that only moves when advanced.
+type fakeClock struct {
+	nanos atomic.Int64
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{}
+	c.nanos.Store(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	return c
+}
+
+func (c *fakeClock) now() time.Time {
+	return time.Unix(0, c.nanos.Load())
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.nanos.Add(int64(d))
+}
+
+// waitFor fails the test if the condition isn't met within a second.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDeviceExpiry(t *testing.T) {
+	client, device := newTestClient(t)
+	clock := newFakeClock()
+	client.now = clock.now
+	client.mu.Lock()
+	client.lastSeen[testDeviceID] = clock.now()
+	client.mu.Unlock()
+	// the sweeper runs every quarter of the TTL in real time, while expiry is judged by the fake clock
+	client.SetDeviceTTL(40 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.sweepExpiredDevices(ctx)
+
+	if err := client.SetColor(testDeviceID, 255, 0, 0); err != nil {
+		t.Fatalf("SetColor() = %v, want the device to be known", err)
+	}
+	device.receiveColor()
+
+	// within the TTL, the device is kept across several sweeps
+	clock.advance(40 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := client.LastSeen(testDeviceID); !ok {
+		t.Fatal("device was removed within the TTL, want it kept")
+	}
+
+	clock.advance(time.Millisecond)
+	if err := client.SetColor(testDeviceID, 0, 255, 0); !IsDeviceNotFound(err) {
+		t.Errorf("SetColor() after the TTL = %v, want ErrDeviceNotFound before the device is swept", err)
+	}
+	waitFor(t, "the expired device to be swept", func() bool { return client.DeviceCount() == 0 })
+	if _, ok := client.LastSeen(testDeviceID); ok {
+		t.Error("LastSeen() of the expired device = true, want it forgotten")
+	}
+	client.connMu.Lock()
+	conns := len(client.conns)
+	client.connMu.Unlock()
+	if conns != 0 {
+		t.Errorf("client has %d connections after the device expired, want it closed", conns)
+	}
+
+	// the device responds to discovery again
+	responses, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer responses.Close()
+	go client.readResponses(ctx, responses, false)
+	scan := `{"msg":{"cmd":"scan","data":{"device":"` + testDeviceID + `","ip":"127.0.0.1","sku":"H6159"}}}`
+	if _, err := device.conn.WriteTo([]byte(scan), responses.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the device to be discovered again", func() bool {
+		_, ok := client.LastSeen(testDeviceID)
+		return ok
+	})
+	if lastSeen, _ := client.LastSeen(testDeviceID); !lastSeen.Equal(clock.now()) {
+		t.Errorf("LastSeen() = %s, want the time of the discovery response %s", lastSeen, clock.now())
+	}
+
+	// the state sent before the device expired is forgotten, so the same color is sent again
+	if err := client.SetColor(testDeviceID, 255, 0, 0); err != nil {
+		t.Fatalf("SetColor() after the device reappeared = %v, want it to be sent", err)
+	}
+	if got := device.receiveColor(); got != (RGBColor{R: 255, G: 0, B: 0}) {
+		t.Errorf("device received color %v, want red", got)
+	}
+}
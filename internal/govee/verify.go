@@ -0,0 +1,74 @@
+package govee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// verifyTimeout is how long SetColorVerified waits for the device to report the requested color
+	verifyTimeout = 3 * time.Second
+	// verifyInterval is the interval between two status queries while verifying a color
+	verifyInterval = 200 * time.Millisecond
+	// verifyTolerance is the minimum per-channel delta accepted between the requested and the reported color,
+	// as devices may round colors they apply
+	verifyTolerance = 2
+)
+
+var (
+	// ErrColorNotApplied is returned by SetColorVerified if the device doesn't report the color it was sent
+	ErrColorNotApplied = errors.New("device didn't apply the color")
+)
+
+// IsColorNotApplied returns true if the error indicates the device didn't report the color it was sent
+func IsColorNotApplied(err error) bool {
+	return errors.Is(err, ErrColorNotApplied)
+}
+
+// SetColorVerified sets the color of a Govee device and reads the device status back until the device reports
+// the color, within the device's color tolerance. Returns ErrColorNotApplied if the device doesn't report the
+// color before verifyTimeout. The device answers on the response port, so Discover must be running.
+func (c *Client) SetColorVerified(deviceID string, r, g, b int) error {
+	// forget the cached color so the command is sent even if the color was sent before
+	c.rememberState(deviceID, func(state *deviceState) {
+		state.Color = nil
+	})
+	if err := c.SetColor(deviceID, r, g, b); err != nil {
+		return err
+	}
+
+	// the device reports the color it was sent, which includes the color correction
+	want := c.correctColor(deviceID, RGBColor{R: r, G: g, B: b})
+	tolerance := max(c.deviceSettings(deviceID).ColorTolerance, verifyTolerance)
+
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	var reported *RGBColor
+	for {
+		status, err := c.QueryStatus(ctx, deviceID)
+		if err == nil {
+			if withinTolerance(status.Color, want, tolerance) {
+				return nil
+			}
+			reported = &status.Color
+		} else if errors.Is(err, ErrDeviceNotFound) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			// the device's color is unknown, so the next color is sent even if it's the same
+			c.rememberState(deviceID, func(state *deviceState) {
+				state.Color = nil
+			})
+			if reported == nil {
+				return fmt.Errorf("%w: no status from device %s: %w", ErrColorNotApplied, deviceID, err)
+			}
+			return fmt.Errorf("%w: device %s reported %v instead of %v", ErrColorNotApplied, deviceID, *reported, want)
+		case <-time.After(verifyInterval):
+		}
+	}
+}
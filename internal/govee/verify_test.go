@@ -0,0 +1,80 @@
+package govee
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// replyToStatusQueries answers the status queries the device receives with the given color until the test ends,
+// sending the replies to the response listener of the client.
+func replyToStatusQueries(t *testing.T, client *Client, device *fakeDevice, color RGBColor) {
+	t.Helper()
+
+	responses, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		responses.Close()
+	})
+	go client.readResponses(ctx, responses, false)
+
+	reply := fmt.Sprintf(`{"msg":{"cmd":"devStatus","data":{"onOff":1,"brightness":100,`+
+		`"color":{"r":%d,"g":%d,"b":%d},"colorTemInKelvin":0}}}`, color.R, color.G, color.B)
+	go func() {
+		defer close(done)
+		buf := make([]byte, 1024)
+		for ctx.Err() == nil {
+			_ = device.conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+			n, err := device.conn.Read(buf)
+			if err == nil && bytes.Contains(buf[:n], []byte(`"devStatus"`)) {
+				_, _ = device.conn.WriteTo([]byte(reply), responses.LocalAddr())
+			}
+		}
+	}()
+}
+
+func TestSetColorVerified(t *testing.T) {
+	tests := []struct {
+		name     string
+		reported RGBColor
+		wantErr  bool
+	}{
+		{name: "matching", reported: RGBColor{R: 255, G: 128}},
+		{name: "within tolerance", reported: RGBColor{R: 253, G: 130}},
+		{name: "mismatching", reported: RGBColor{B: 255}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client, device := newTestClient(t)
+			replyToStatusQueries(t, client, device, tt.reported)
+
+			err := client.SetColorVerified(testDeviceID, 255, 128, 0)
+			if tt.wantErr {
+				if !IsColorNotApplied(err) {
+					t.Errorf("SetColorVerified() = %v, want ErrColorNotApplied", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("SetColorVerified() returned error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetColorVerifiedUnknownDevice(t *testing.T) {
+	client, _ := newTestClient(t)
+	if err := client.SetColorVerified("11:22:33:44:55:66:77:88", 255, 0, 0); !IsDeviceNotFound(err) {
+		t.Errorf("SetColorVerified() of an unknown device = %v, want ErrDeviceNotFound", err)
+	}
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Bridge reports the address of the discovered Hue bridge, empty if not discovered yet
+type Bridge interface {
+	BridgeAddress() string
+}
+
+// Devices reports the number of Govee devices that responded to discovery
+type Devices interface {
+	DeviceCount() int
+}
+
+// Status is the body of the health check responses
+type Status struct {
+	Ready         bool   `json:"ready"`
+	BridgeAddress string `json:"bridgeAddress"`
+	GoveeDevices  int    `json:"goveeDevices"`
+}
+
+// Handler returns the handler serving /healthz and /readyz.
+//
+// /healthz returns 200 as long as the process is up, /readyz only once the Hue bridge was discovered and at
+// least one Govee device responded to discovery.
+func Handler(bridge Bridge, devices Devices) http.Handler {
+	status := func() Status {
+		s := Status{
+			BridgeAddress: bridge.BridgeAddress(),
+			GoveeDevices:  devices.DeviceCount(),
+		}
+		s.Ready = s.BridgeAddress != "" && s.GoveeDevices > 0
+		return s
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeStatus(w, http.StatusOK, status())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		s := status()
+		code := http.StatusOK
+		if !s.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeStatus(w, code, s)
+	})
+	return mux
+}
+
+// Serve serves the health checks at the given address until the context is done.
+func Serve(ctx context.Context, addr string, bridge Bridge, devices Devices, logger zerolog.Logger) {
+	server := &http.Server{Addr: addr, Handler: Handler(bridge, devices)}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info().Str("addr", addr).Msg("Serving health checks")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error().Err(err).Msg("Health check server failed")
+	}
+}
+
+func writeStatus(w http.ResponseWriter, code int, status Status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
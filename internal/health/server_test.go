@@ -0,0 +1,72 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/hue"
+)
+
+// the clients passed to Serve by main
+var (
+	_ Bridge  = (*hue.Client)(nil)
+	_ Devices = (*govee.Client)(nil)
+)
+
+type fakeBridge string
+
+func (b fakeBridge) BridgeAddress() string {
+	return string(b)
+}
+
+type fakeDevices int
+
+func (d fakeDevices) DeviceCount() int {
+	return int(d)
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		bridge    fakeBridge
+		devices   fakeDevices
+		wantReady bool
+	}{
+		{name: "nothing discovered"},
+		{name: "bridge only", bridge: "192.168.1.2"},
+		{name: "devices only", devices: 2},
+		{name: "ready", bridge: "192.168.1.2", devices: 2, wantReady: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Handler(tt.bridge, tt.devices)
+			want := Status{Ready: tt.wantReady, BridgeAddress: string(tt.bridge), GoveeDevices: int(tt.devices)}
+
+			wantReadyCode := http.StatusServiceUnavailable
+			if tt.wantReady {
+				wantReadyCode = http.StatusOK
+			}
+			for path, wantCode := range map[string]int{"/healthz": http.StatusOK, "/readyz": wantReadyCode} {
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+				if recorder.Code != wantCode {
+					t.Errorf("GET %s = %d, want %d", path, recorder.Code, wantCode)
+				}
+				if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("Content-Type of %s = %q, want application/json", path, got)
+				}
+				var got Status
+				if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+					t.Fatalf("body of %s isn't a status: %v", path, err)
+				}
+				if got != want {
+					t.Errorf("body of %s = %+v, want %+v", path, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package hue
+
+// AverageLights combines several lights into a single light by averaging the XY coordinates and brightness
+// of all lights that are turned on. Lights that are off are excluded from the average, the result is only
+// turned off if all lights are off.
+//
+// The color temperature is averaged instead of the XY coordinates if all lit lights are in color temperature
+// mode, and dynamics are considered active if any of the lit lights has an active dynamic palette.
+func AverageLights(lights []*Light) *Light {
+	var lit []*Light
+	for _, light := range lights {
+		if light.On.On {
+			lit = append(lit, light)
+		}
+	}
+	if len(lit) == 0 {
+		return &Light{On: On{On: false}, ColorMode: ColorModeXY}
+	}
+
+	averaged := &Light{
+		On: On{On: true},
+		Color: Color{
+			Gamut:     lit[0].Color.Gamut,
+			GamutType: lit[0].Color.GamutType,
+		},
+		Dynamics: Dynamics{Status: DynamicsStatusInactive},
+	}
+
+	var brightness, x, y float64
+	mirek, allMirekValid := 0, true
+	for _, light := range lit {
+		brightness += light.Dimming.Brightness
+		x += light.Color.XY.X
+		y += light.Color.XY.Y
+		mirek += light.ColorTemperature.Mirek
+		allMirekValid = allMirekValid && light.ColorTemperature.MirekValid
+		if light.Dynamics.Status == DynamicsStatusActive {
+			averaged.Dynamics.Status = DynamicsStatusActive
+		}
+	}
+
+	n := float64(len(lit))
+	averaged.Dimming.Brightness = brightness / n
+	if allMirekValid {
+		averaged.ColorTemperature = ColorTemperature{Mirek: mirek / len(lit), MirekValid: true}
+		averaged.ColorMode = ColorModeCT
+	} else {
+		averaged.Color.XY = Coords{X: x / n, Y: y / n}
+		averaged.ColorMode = ColorModeXY
+	}
+	return averaged
+}
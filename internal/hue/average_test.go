@@ -0,0 +1,79 @@
+package hue
+
+import (
+	"testing"
+)
+
+func xyLight(on bool, brightness, x, y float64) *Light {
+	return &Light{On: On{On: on}, Dimming: Dimming{Brightness: brightness}, Color: Color{XY: Coords{X: x, Y: y}},
+		ColorMode: ColorModeXY}
+}
+
+func ctLight(on bool, brightness float64, mirek int) *Light {
+	return &Light{On: On{On: on}, Dimming: Dimming{Brightness: brightness},
+		ColorTemperature: ColorTemperature{Mirek: mirek, MirekValid: true}, ColorMode: ColorModeCT}
+}
+
+func TestAverageLights(t *testing.T) {
+	tests := []struct {
+		name   string
+		lights []*Light
+		want   Light
+	}{
+		{
+			name:   "all off",
+			lights: []*Light{xyLight(false, 80, 0.3, 0.3), ctLight(false, 50, 300)},
+			want:   Light{On: On{On: false}, ColorMode: ColorModeXY},
+		},
+		{
+			name:   "XY averaged",
+			lights: []*Light{xyLight(true, 80, 0.6, 0.3), xyLight(true, 40, 0.2, 0.5)},
+			want: Light{On: On{On: true}, Dimming: Dimming{Brightness: 60}, Color: Color{XY: Coords{X: 0.4, Y: 0.4}},
+				Dynamics: Dynamics{Status: DynamicsStatusInactive}, ColorMode: ColorModeXY},
+		},
+		{
+			name:   "lights that are off excluded",
+			lights: []*Light{xyLight(true, 80, 0.6, 0.3), xyLight(false, 10, 0.1, 0.1), xyLight(true, 40, 0.2, 0.5)},
+			want: Light{On: On{On: true}, Dimming: Dimming{Brightness: 60}, Color: Color{XY: Coords{X: 0.4, Y: 0.4}},
+				Dynamics: Dynamics{Status: DynamicsStatusInactive}, ColorMode: ColorModeXY},
+		},
+		{
+			name:   "color temperatures averaged",
+			lights: []*Light{ctLight(true, 100, 200), ctLight(true, 50, 400), xyLight(false, 10, 0.6, 0.3)},
+			want: Light{On: On{On: true}, Dimming: Dimming{Brightness: 75},
+				ColorTemperature: ColorTemperature{Mirek: 300, MirekValid: true},
+				Dynamics:         Dynamics{Status: DynamicsStatusInactive}, ColorMode: ColorModeCT},
+		},
+		{
+			name:   "mixed modes averaged as XY",
+			lights: []*Light{ctLight(true, 100, 200), xyLight(true, 50, 0.5, 0.4)},
+			want: Light{On: On{On: true}, Dimming: Dimming{Brightness: 75}, Color: Color{XY: Coords{X: 0.25, Y: 0.2}},
+				Dynamics: Dynamics{Status: DynamicsStatusInactive}, ColorMode: ColorModeXY},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AverageLights(tt.lights)
+			if got.On != tt.want.On || got.Dimming != tt.want.Dimming || got.ColorMode != tt.want.ColorMode ||
+				got.ColorTemperature != tt.want.ColorTemperature || got.Dynamics != tt.want.Dynamics ||
+				distance(got.Color.XY, tt.want.Color.XY) > coordsTolerance {
+				t.Errorf("AverageLights() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAverageLightsDynamicsAndGamut(t *testing.T) {
+	dynamic := xyLight(true, 50, 0.3, 0.3)
+	dynamic.Dynamics.Status = DynamicsStatusActive
+	static := xyLight(true, 50, 0.3, 0.3)
+	static.Color.Gamut, static.Color.GamutType = gamutC, GamutTypeC
+
+	averaged := AverageLights([]*Light{static, dynamic})
+	if averaged.Dynamics.Status != DynamicsStatusActive {
+		t.Errorf("Dynamics.Status = %q, want active if any lit light plays a dynamic scene", averaged.Dynamics.Status)
+	}
+	if averaged.Color.Gamut != gamutC || averaged.Color.GamutType != GamutTypeC {
+		t.Errorf("gamut = %+v %q, want the gamut of the first lit light", averaged.Color.Gamut, averaged.Color.GamutType)
+	}
+}
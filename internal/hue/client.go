@@ -1,7 +1,9 @@
 package hue
 
 import (
+	"bytes"
 	"context"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,8 +17,27 @@ import (
 	"github.com/hashicorp/mdns"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
 )
 
+// DefaultRequestTimeout is the default maximum duration of a single request to the bridge
+const DefaultRequestTimeout = 5 * time.Second
+
+// DefaultDiscoveryTimeout is how long the bridge is looked for on startup by default
+const DefaultDiscoveryTimeout = 60 * time.Second
+
+const (
+	// discoveryAttemptTimeout bounds a single attempt to discover the bridge
+	discoveryAttemptTimeout = 10 * time.Second
+	// discoveryMinBackoff is the delay before the first retry of a failed discovery
+	discoveryMinBackoff = time.Second
+	// discoveryMaxBackoff caps the delay between discovery retries
+	discoveryMaxBackoff = 15 * time.Second
+)
+
+// DefaultMaxConcurrentRequests is the default number of requests in flight to the bridge at the same time
+const DefaultMaxConcurrentRequests = 4
+
 // Client is a client for the Hue V2 API
 type Client struct {
 	hueBridgeID string
@@ -24,37 +45,139 @@ type Client struct {
 
 	lock          sync.Mutex // Mutex to protect bridgeAddress updates
 	bridgeAddress string
+	staticAddress bool // whether the bridge address is configured, skipping discovery
 
 	httpClient *http.Client
+	transport  *hueTransport
+
+	scenes *sceneCache // active scene per room
+
+	requestTimeout   time.Duration // maximum duration of a single request
+	inFlight         chan struct{} // one slot per request in flight, see SetMaxConcurrentRequests
+	cloudDiscovery   bool          // whether the bridge is discovered through the Hue cloud if mDNS fails
+	ipv6Discovery    bool          // whether mDNS discovery is retried over IPv6 if it fails over IPv4
+	discoveryTimeout time.Duration // how long StartAutoDiscovery retries to find the bridge
+	discoveryBackoff time.Duration // delay before the first retry of a failed discovery, replaceable for tests
+
+	// queryMDNS sends an mDNS query and passes the answers to the entries of the params, replaceable for tests
+	queryMDNS func(ctx context.Context, params *mdns.QueryParam) error
+}
+
+const (
+	// minBridgeIDLength is the length of the shortened bridge ID in the mDNS service name of the bridge
+	minBridgeIDLength = 6
+	// bridgeIDLength is the length of the full bridge ID, the common name of the bridge certificate
+	bridgeIDLength = 16
+)
+
+// ValidateBridgeID returns an error if the bridge ID isn't the 16 hex characters of a bridge ID or at least their
+// last 6 characters, which identify the bridge in mDNS discovery. An empty bridge ID matches any bridge.
+func ValidateBridgeID(bridgeID string) error {
+	if bridgeID == "" {
+		return nil
+	}
+	if len(bridgeID) < minBridgeIDLength || len(bridgeID) > bridgeIDLength {
+		return fmt.Errorf("hue bridge ID %q must be the 16 characters of the bridge ID, or at least its last %d, "+
+			"got %d characters", bridgeID, minBridgeIDLength, len(bridgeID))
+	}
+	if strings.Trim(strings.ToLower(bridgeID), "0123456789abcdef") != "" {
+		return fmt.Errorf("hue bridge ID %q must only contain hex characters", bridgeID)
+	}
+	return nil
 }
 
 // NewClient creates a new Client with the given hueBridgeID and hueUsername.
 func NewClient(hueBridgeID, hueUsername string, logger zerolog.Logger) *Client {
+	transport := newHueTransport(hueUsername)
 	client := &http.Client{
-		Transport: newHueTransport(hueUsername),
+		Transport: transport,
 	}
 
 	return &Client{
 		httpClient:  client,
+		transport:   transport,
 		hueBridgeID: hueBridgeID,
 		logger:      logger,
+		scenes:      newSceneCache(DefaultSceneCacheTTL),
+
+		requestTimeout:   DefaultRequestTimeout,
+		inFlight:         make(chan struct{}, DefaultMaxConcurrentRequests),
+		discoveryTimeout: DefaultDiscoveryTimeout,
+		discoveryBackoff: discoveryMinBackoff,
+		queryMDNS:        mdns.QueryContext,
+	}
+}
+
+// SetRequestsPerSecond sets the maximum rate of requests sent to the bridge, shared by all users of the client.
+func (c *Client) SetRequestsPerSecond(requestsPerSecond float64) {
+	c.transport.setLimit(rate.Limit(requestsPerSecond))
+}
+
+// SetDiscoveryTimeout sets how long StartAutoDiscovery retries to find the bridge before giving up. Must be
+// called before discovery is started.
+func (c *Client) SetDiscoveryTimeout(timeout time.Duration) {
+	c.discoveryTimeout = timeout
+}
+
+// SetMaxConcurrentRequests sets the maximum number of requests in flight to the bridge at the same time, shared
+// by all users of the client. Further requests wait for a free slot. Must be called before the client is used.
+func (c *Client) SetMaxConcurrentRequests(n int) {
+	c.inFlight = make(chan struct{}, n)
+}
+
+// VerifyCertificate makes the client only accept a certificate issued to the configured bridge ID, which Hue
+// bridges present as the common name of their certificate, and signed by one of the roots, e.g. the root CA of
+// Signify. Without roots, any certificate with the bridge ID as common name is accepted, which anyone can issue.
+// Requests to a bridge presenting another certificate fail with ErrCertificateMismatch. As the certificate is
+// issued to the full bridge ID, a shortened bridge ID can't be verified. Must be called before the client is used.
+func (c *Client) VerifyCertificate(roots *x509.CertPool) error {
+	if c.hueBridgeID == "" {
+		return fmt.Errorf("verifying the Hue bridge certificate requires a bridge ID")
+	}
+	if len(c.hueBridgeID) != bridgeIDLength {
+		return fmt.Errorf("verifying the Hue bridge certificate requires the full %d characters of the bridge ID, "+
+			"got %q", bridgeIDLength, c.hueBridgeID)
 	}
+	c.transport.verifyBridgeID(c.hueBridgeID, roots)
+	return nil
+}
+
+// SetRequestTimeout sets the maximum duration of a single request to the bridge, so that a hung connection
+// doesn't block the caller forever. Must be called before the client is used.
+func (c *Client) SetRequestTimeout(timeout time.Duration) {
+	c.requestTimeout = timeout
 }
 
-// StartAutoDiscovery starts the auto discovery process to find the Hue bridge.
+// BridgeAddress returns the address of the discovered Hue bridge, empty if it wasn't discovered yet.
+func (c *Client) BridgeAddress() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.bridgeAddress
+}
+
+// StartAutoDiscovery starts the auto discovery process to find the Hue bridge. With a static bridge address,
+// it only verifies that the bridge is reachable. Failed attempts are retried with an increasing backoff until the
+// discovery timeout, see SetDiscoveryTimeout, so the bridge is found even if the network comes up after startup.
 func (c *Client) StartAutoDiscovery(ctx context.Context) error {
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
+	if c.staticAddress {
+		return c.retryDiscovery(ctx, c.verifyReachable)
+	}
 
-	bridge, err := c.discoverBridge(ctx)
+	var bridge *DiscoveryResponse
+	err := c.retryDiscovery(ctx, func(ctx context.Context) error {
+		var err error
+		bridge, err = c.discoverBridge(ctx)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to discover Hue bridges: %w", err)
 	}
 
 	c.lock.Lock()
-	defer c.lock.Unlock()
 	c.bridgeAddress = bridge.Address
-	c.logger.Info().Str("bridgeID", c.hueBridgeID).Str("address", c.bridgeAddress).Msg("Found Hue bridge")
+	c.lock.Unlock()
+	c.logger.Info().Str("bridgeID", c.hueBridgeID).Str("address", bridge.Address).Msg("Found Hue bridge")
 
 	go func() {
 		for {
@@ -63,13 +186,13 @@ func (c *Client) StartAutoDiscovery(ctx context.Context) error {
 				return
 
 			case <-time.After(10 * time.Second):
-				ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+				attemptCtx, cancel := context.WithTimeout(ctx, discoveryAttemptTimeout)
 
-				bridge, err := c.discoverBridge(ctx)
+				bridge, err := c.discoverBridge(attemptCtx)
 				cancel()
 				if err != nil {
-					if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-						c.logger.Info().Msg("Discovery canceled or timed out")
+					if ctx.Err() != nil {
+						c.logger.Info().Msg("Discovery canceled")
 						return
 					}
 
@@ -77,14 +200,14 @@ func (c *Client) StartAutoDiscovery(ctx context.Context) error {
 					continue
 				}
 
-				if bridge.Address != "" && bridge.Address != c.bridgeAddress {
+				if bridge.Address != "" && bridge.Address != c.BridgeAddress() {
 					c.logger.Info().Str("bridgeID", c.hueBridgeID).Str("newAddress", bridge.Address).
 						Msg("Hue bridge address changed, updating")
 					c.lock.Lock()
 					c.bridgeAddress = bridge.Address
 					c.lock.Unlock()
 				} else {
-					c.logger.Info().Str("bridgeID", c.hueBridgeID).Msg("No change in Hue bridge address")
+					c.logger.Debug().Str("bridgeID", c.hueBridgeID).Msg("No change in Hue bridge address")
 				}
 			}
 		}
@@ -93,98 +216,297 @@ func (c *Client) StartAutoDiscovery(ctx context.Context) error {
 	return nil
 }
 
-// GetLight returns the light with the given ID.
-func (c *Client) GetLight(lightID string) (*Light, error) {
-	defer c.lock.Unlock()
+// retryDiscovery runs attempt with a timeout of discoveryAttemptTimeout each until it succeeds, waiting an
+// exponentially increasing backoff between attempts. No attempt runs past the discovery timeout, the last error
+// is returned once it's reached.
+func (c *Client) retryDiscovery(ctx context.Context, attempt func(ctx context.Context) error) error {
+	deadline := time.Now().Add(c.discoveryTimeout)
+	backoff := c.discoveryBackoff
+	for attempts := 1; ; attempts++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, min(discoveryAttemptTimeout, time.Until(deadline)))
+		err := attempt(attemptCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+		}
+
+		c.logger.Warn().Err(err).Int("attempt", attempts).Dur("backoff", backoff).
+			Msg("Hue bridge not found, retrying")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, discoveryMaxBackoff)
+	}
+}
+
+// Rediscover immediately discovers the Hue bridge and updates its address.
+func (c *Client) Rediscover(ctx context.Context) error {
+	if c.staticAddress {
+		return c.verifyReachable(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	bridge, err := c.discoverBridge(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to discover Hue bridges: %w", err)
+	}
+
 	c.lock.Lock()
-	url := fmt.Sprintf("https://%s/clip/v2/resource/light/%s", c.bridgeAddress, lightID)
-	resp, err := c.httpClient.Get(url)
+	defer c.lock.Unlock()
+	c.bridgeAddress = bridge.Address
+	c.logger.Info().Str("bridgeID", c.hueBridgeID).Str("address", c.bridgeAddress).Msg("Rediscovered Hue bridge")
+	return nil
+}
+
+// GetLight returns the light with the given ID.
+func (c *Client) GetLight(ctx context.Context, lightID string) (*Light, error) {
+	body, err := c.request(ctx, http.MethodGet, "/clip/v2/resource/light/"+lightID, nil)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s: %w", ErrLightNotFound, lightID, err)
+		}
+		return nil, fmt.Errorf("failed to get light info: %w", err)
+	}
+
+	var hueResp hueResponse[Light]
+	err = json.Unmarshal(body, &hueResp)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get light info: %s", resp.Status)
+	if len(hueResp.Data) == 0 {
+		if err := hueResp.apiError(); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrLightNotFound, lightID, err)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrLightNotFound, lightID)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	return &hueResp.Data[0], nil
+}
+
+// GetGroupedLight returns the grouped light with the given ID, the aggregated state of a room or zone.
+func (c *Client) GetGroupedLight(ctx context.Context, groupedLightID string) (*GroupedLight, error) {
+	body, err := c.request(ctx, http.MethodGet, "/clip/v2/resource/grouped_light/"+groupedLightID, nil)
 	if err != nil {
-		return nil, err
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: grouped light %s: %w", ErrLightNotFound, groupedLightID, err)
+		}
+		return nil, fmt.Errorf("failed to get grouped light info: %w", err)
 	}
 
-	var hueResp hueResponse[Light]
+	var hueResp hueResponse[GroupedLight]
 	err = json.Unmarshal(body, &hueResp)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(hueResp.Data) == 0 {
-		return nil, fmt.Errorf("no light found with ID %s", lightID)
+		if err := hueResp.apiError(); err != nil {
+			return nil, fmt.Errorf("%w: grouped light %s: %w", ErrLightNotFound, groupedLightID, err)
+		}
+		return nil, fmt.Errorf("%w: grouped light %s", ErrLightNotFound, groupedLightID)
 	}
 
 	return &hueResp.Data[0], nil
 }
 
-// GetActiveScene returns the active scene for the room with the given ID.
-func (c *Client) GetActiveScene(roomId string) (*Scene, error) {
-	defer c.lock.Unlock()
-	c.lock.Lock()
-	url := fmt.Sprintf("https://%s/clip/v2/resource/scene", c.bridgeAddress)
-	resp, err := c.httpClient.Get(url)
+// GetLightsByID fetches all lights with the given IDs, in the same order.
+func (c *Client) GetLightsByID(ctx context.Context, lightIDs []string) ([]*Light, error) {
+	lights := make([]*Light, 0, len(lightIDs))
+	for _, lightID := range lightIDs {
+		light, err := c.GetLight(ctx, lightID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get light %s: %w", lightID, err)
+		}
+		lights = append(lights, light)
+	}
+	return lights, nil
+}
+
+// SetLightState updates the state of the light with the given ID. Only the fields set in the update are changed.
+func (c *Client) SetLightState(ctx context.Context, lightID string, update LightStateUpdate) error {
+	body, err := json.Marshal(update)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to marshal light update: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get active scene: %s", resp.Status)
+	if _, err := c.request(ctx, http.MethodPut, "/clip/v2/resource/light/"+lightID, body); err != nil {
+		return fmt.Errorf("failed to update light %s: %w", lightID, err)
 	}
+	return nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// GetActiveScene returns the active dynamic scene for the room with the given ID. The scenes of all rooms are
+// cached for a short time, see SetSceneCacheTTL.
+func (c *Client) GetActiveScene(ctx context.Context, roomId string) (*Scene, error) {
+	active, err := c.scenes.activeScenes(func() ([]Scene, error) {
+		return c.listScenes(ctx)
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get active scene: %w", err)
+	}
+
+	scene, ok := active[roomId]
+	if !ok {
+		return nil, fmt.Errorf("%w: no active scene for room ID %s", ErrSceneNotFound, roomId)
+	}
+	// callers may modify the scene, e.g. to limit its palette
+	sceneCopy := *scene
+	return &sceneCopy, nil
+}
+
+// GetRecalledScene returns the scene that is currently recalled (static or dynamic) in the room with the
+// given ID, or nil if no scene is recalled. The scenes are cached like the ones of GetActiveScene.
+func (c *Client) GetRecalledScene(ctx context.Context, roomId string) (*Scene, error) {
+	scenes, err := c.scenes.allScenes(func() ([]Scene, error) {
+		return c.listScenes(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recalled scene: %w", err)
 	}
 
-	var hueResp hueResponse[Scene]
+	for _, scene := range scenes {
+		if scene.Group.ID == roomId && scene.Group.Type == "room" && scene.Status.Active != SceneStatusInactive {
+			// the loop variable is a copy, so callers may modify the scene
+			return &scene, nil
+		}
+	}
+	return nil, nil
+}
+
+// listScenes returns all scenes known to the bridge.
+func (c *Client) listScenes(ctx context.Context) ([]Scene, error) {
+	return getResources[Scene](ctx, c, "scene")
+}
+
+// GetLights returns all lights known to the bridge.
+func (c *Client) GetLights(ctx context.Context) ([]Light, error) {
+	lights, err := getResources[Light](ctx, c, "light")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lights: %w", err)
+	}
+	return lights, nil
+}
+
+// GetRooms returns all rooms known to the bridge.
+func (c *Client) GetRooms(ctx context.Context) ([]Room, error) {
+	rooms, err := getResources[Room](ctx, c, "room")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rooms: %w", err)
+	}
+	return rooms, nil
+}
+
+// getResources returns all resources of the given type known to the bridge.
+func getResources[T any](ctx context.Context, c *Client, resourceType string) ([]T, error) {
+	body, err := c.request(ctx, http.MethodGet, "/clip/v2/resource/"+resourceType, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s resources: %w", resourceType, err)
+	}
+
+	var hueResp hueResponse[T]
 	err = json.Unmarshal(body, &hueResp)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(hueResp.Data) == 0 {
-		return nil, fmt.Errorf("no active scene found for room ID %s", roomId)
+		if err := hueResp.apiError(); err != nil {
+			return nil, fmt.Errorf("failed to list %s resources: %w", resourceType, err)
+		}
 	}
+	return hueResp.Data, nil
+}
 
-	// Filter scenes by room ID
-	for _, scene := range hueResp.Data {
-		if scene.Group.ID == roomId && scene.Status.Active == "dynamic_palette" && scene.Group.Type == "room" {
-			return &scene, nil
+// request sends a request to the bridge and returns the response body. Requests fail with
+// ErrBridgeNotDiscovered before the bridge was discovered, ErrBridgeUnreachable if no response was received,
+// a RateLimitError on 429 and an APIError on any other unsuccessful status. Each request is aborted after the
+// request timeout, see SetRequestTimeout.
+func (c *Client) request(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	// only the address is read under the lock, so that requests of different synchronizations run concurrently
+	bridgeAddress := c.BridgeAddress()
+	if bridgeAddress == "" {
+		return nil, ErrBridgeNotDiscovered
+	}
+
+	// the timeout starts once a slot is free, so requests waiting for one don't time out
+	select {
+	case c.inFlight <- struct{}{}:
+		defer func() { <-c.inFlight }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, bridgeURL(bridgeAddress, path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if IsCertificateMismatch(err) || IsRateLimited(err) {
+			return nil, err
 		}
+		return nil, fmt.Errorf("%w: %w", ErrBridgeUnreachable, err)
 	}
+	defer resp.Body.Close()
 
-	return nil, fmt.Errorf("no active scene found for room ID %s", roomId)
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBridgeUnreachable, err)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, newRateLimitError(resp)
+	}
+	// 207 Multi-Status responses carry partial data next to errors, which are handled by the caller
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
+		return nil, newAPIError(resp.StatusCode, respBody)
+	}
+	return respBody, nil
 }
 
-// discoverBridge discovers the Hue bridge using mDNS.
-func (c *Client) discoverBridge(ctx context.Context) (*DiscoveryResponse, error) {
+// discoverBridgeMDNS discovers the Hue bridge using mDNS. Over IPv6, the IPv6 address of the bridge is preferred.
+func (c *Client) discoverBridgeMDNS(ctx context.Context, ipv6 bool) (*DiscoveryResponse, error) {
 	entriesCh := make(chan *mdns.ServiceEntry, 1)
 
 	params := mdns.DefaultParams("_hue._tcp")
 	params.Entries = entriesCh
-	params.DisableIPv6 = true // Disable IPv6 to avoid issues with some networks
-	params.Timeout = 10 * time.Second
+	params.DisableIPv6 = !ipv6 // IPv6 causes issues on some networks, so it's only used if enabled
+	params.Timeout = mdnsQueryTimeout
 	params.Logger = logger.Discard()
 
 	go func() {
 		defer close(entriesCh)
-		if err := mdns.QueryContext(ctx, params); err != nil {
+		if err := c.queryMDNS(ctx, params); err != nil {
 			log.Error().Err(err).Msg("mDNS query failed")
 		}
 	}()
 
-	// the service name consists of "Hue Bridge - " followed by the last 6 characters of the hueBridgeID
-	serviceName := fmt.Sprintf("Hue Bridge - %s", strings.ToUpper(c.hueBridgeID[len(c.hueBridgeID)-6:]))
+	// the service name consists of "Hue Bridge - " followed by the last 6 characters of the hueBridgeID,
+	// without a hueBridgeID the first bridge found is used
+	serviceName := "Hue Bridge - "
+	if len(c.hueBridgeID) >= minBridgeIDLength {
+		serviceName += strings.ToUpper(c.hueBridgeID[len(c.hueBridgeID)-minBridgeIDLength:])
+	}
 	log.Debug().Str("serviceName", serviceName).Msg("Starting mDNS query for service")
 
 	for {
@@ -201,9 +523,18 @@ func (c *Client) discoverBridge(ctx context.Context) (*DiscoveryResponse, error)
 				return nil, fmt.Errorf("service '%s' not found", serviceName)
 			}
 			entryName = entryName[:len(entryName)-len("._hue._tcp.local.")]
-			if strings.EqualFold(entryName, serviceName) {
+			if strings.EqualFold(entryName, serviceName) ||
+				(c.hueBridgeID == "" && strings.HasPrefix(strings.ToLower(entryName), strings.ToLower(serviceName))) {
+				address := entry.AddrV4
+				zone := ""
+				if (ipv6 || address == nil) && entry.AddrV6IPAddr != nil {
+					address, zone = entry.AddrV6IPAddr.IP, entry.AddrV6IPAddr.Zone
+				}
+				if address == nil {
+					return nil, fmt.Errorf("service '%s' has no address", serviceName)
+				}
 				serviceInfo := &DiscoveryResponse{
-					Address: entry.AddrV4.String(),
+					Address: hostAddress(address, zone),
 				}
 				return serviceInfo, nil
 			}
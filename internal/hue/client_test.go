@@ -0,0 +1,647 @@
+package hue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/metrics"
+	"github.com/hashicorp/mdns"
+	"github.com/rs/zerolog"
+	"golang.org/x/time/rate"
+)
+
+const testLightResponse = `{"errors":[],"data":[{"id":"light-1","on":{"on":true},"dimming":{"brightness":50}}]}`
+
+// newTestClient creates a Client sending requests to a TLS test server with the handler. The server's
+// certificate isn't verified, like that of a bridge without VerifyCertificate.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient("", "test-user", zerolog.Nop())
+	if err := client.SetBridgeAddress(strings.TrimPrefix(server.URL, "https://")); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// rateLimitedHandler responds to the first limitedRequests requests with 429 and the Retry-After header, and to
+// all following requests with a light. The number of handled requests is counted in requests.
+func rateLimitedHandler(limitedRequests int32, retryAfter string, requests *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("hue-application-key") != "test-user" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if requests.Add(1) <= limitedRequests {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(testLightResponse))
+	}
+}
+
+func TestClientRateLimited(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, rateLimitedHandler(1, "1", &requests))
+
+	_, err := client.GetLight(context.Background(), "light-1")
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("GetLight() = %v, want a RateLimitError", err)
+	}
+	if rateLimitErr.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %s, want 1s", rateLimitErr.RetryAfter)
+	}
+	if limit := client.transport.limiter.Limit(); limit != DefaultRequestsPerSecond/2 {
+		t.Errorf("rate after 429 = %v, want it halved to %v", limit, DefaultRequestsPerSecond/2)
+	}
+
+	start := time.Now()
+	light, err := client.GetLight(context.Background(), "light-1")
+	if err != nil {
+		t.Fatalf("GetLight() after Retry-After returned error: %v", err)
+	}
+	if light.ID != "light-1" || !light.On.On {
+		t.Errorf("GetLight() = %+v, want light-1 turned on", light)
+	}
+	// the Retry-After is counted from the 429 response, so allow for the time passed since
+	if waited := time.Since(start); waited < 900*time.Millisecond {
+		t.Errorf("GetLight() was sent after %s, want it to wait for the Retry-After of 1s", waited)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("bridge received %d requests, want 2", got)
+	}
+
+	// the rate recovers with every successful response
+	want := rate.Limit(DefaultRequestsPerSecond/2 + rateLimitRecoveryStep)
+	if limit := client.transport.limiter.Limit(); limit != want {
+		t.Errorf("rate after a successful response = %v, want %v", limit, want)
+	}
+}
+
+func TestClientRateLimitedBeyondTimeout(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, rateLimitedHandler(1, "60", &requests))
+	client.SetRequestTimeout(100 * time.Millisecond)
+
+	if _, err := client.GetLight(context.Background(), "light-1"); !IsRateLimited(err) {
+		t.Fatalf("GetLight() = %v, want ErrRateLimited", err)
+	}
+
+	// the pause outlasts the request timeout, so the request fails without being sent
+	start := time.Now()
+	_, err := client.GetLight(context.Background(), "light-1")
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter <= 0 {
+		t.Fatalf("GetLight() = %v, want a RateLimitError with the remaining pause", err)
+	}
+	if errors.Is(err, ErrBridgeUnreachable) {
+		t.Errorf("GetLight() = %v, want it not to be reported as unreachable", err)
+	}
+	if waited := time.Since(start); waited >= 100*time.Millisecond {
+		t.Errorf("GetLight() returned after %s, want it to fail right away", waited)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("bridge received %d requests, want 1", got)
+	}
+}
+
+func TestTransportRateLimitFloor(t *testing.T) {
+	transport := newHueTransport("test-user")
+	transport.setLimit(3)
+
+	for range 4 {
+		transport.slowDown(time.Nanosecond)
+	}
+	if limit := transport.limiter.Limit(); limit != minRequestsPerSecond {
+		t.Errorf("rate after repeated 429 = %v, want the floor of %v", limit, minRequestsPerSecond)
+	}
+
+	// the rate never recovers beyond the configured one
+	for range 50 {
+		transport.speedUp()
+	}
+	if limit := transport.limiter.Limit(); limit != 3 {
+		t.Errorf("rate after recovering = %v, want the configured 3", limit)
+	}
+}
+
+func TestSetLightState(t *testing.T) {
+	var method, path, body string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(data)
+		_, _ = w.Write([]byte(`{"errors":[],"data":[{"rid":"light-1","rtype":"light"}]}`))
+	})
+
+	tests := []struct {
+		name   string
+		update LightStateUpdate
+		want   string
+	}{
+		{
+			name: "color",
+			update: LightStateUpdate{On: &On{On: true}, Dimming: &Dimming{Brightness: 42.5},
+				Color: &ColorUpdate{XY: Coords{X: 0.675, Y: 0.322}}},
+			want: `{"on":{"on":true},"dimming":{"brightness":42.5},"color":{"xy":{"x":0.675,"y":0.322}}}`,
+		},
+		{
+			name:   "color temperature",
+			update: LightStateUpdate{ColorTemperature: &ColorTemperatureUpdate{Mirek: 366}},
+			want:   `{"color_temperature":{"mirek":366}}`,
+		},
+		{
+			name:   "off",
+			update: LightStateUpdate{On: &On{On: false}},
+			want:   `{"on":{"on":false}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := client.SetLightState(context.Background(), "light-1", tt.update); err != nil {
+				t.Fatalf("SetLightState() returned error: %v", err)
+			}
+			if method != http.MethodPut || path != "/clip/v2/resource/light/light-1" {
+				t.Errorf("request = %s %s, want PUT /clip/v2/resource/light/light-1", method, path)
+			}
+			if body != tt.want {
+				t.Errorf("body = %s, want %s", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientRequestsPerSecond(t *testing.T) {
+	const (
+		requestsPerSecond = 20
+		calls             = 11
+	)
+	var mu sync.Mutex
+	var received []time.Time
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, time.Now())
+		mu.Unlock()
+		_, _ = w.Write([]byte(testLightResponse))
+	})
+	client.SetRequestsPerSecond(requestsPerSecond)
+
+	var wg sync.WaitGroup
+	for range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetLight(context.Background(), "light-1"); err != nil {
+				t.Errorf("GetLight() returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// no window holds more requests than the rate allows, apart from the jitter of scheduling and opening the
+	// connections, which delays some requests on their way to the server and lets the next ones catch up
+	const jitter = 25 * time.Millisecond
+	slices.SortFunc(received, time.Time.Compare)
+	for window := 1; window < len(received); window++ {
+		want := time.Duration(window) * time.Second / requestsPerSecond
+		for i := 0; i+window < len(received); i++ {
+			if got := received[i+window].Sub(received[i]); got < want-jitter {
+				t.Fatalf("%d requests were sent within %s, want at most %d requests per second", window+1, got,
+					requestsPerSecond)
+			}
+		}
+	}
+}
+
+func TestRequestMetrics(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testLightResponse))
+	})
+	if _, err := client.GetLight(context.Background(), "light-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`hue2govee_hue_requests_total{status="200"}`,
+		"hue2govee_hue_request_duration_seconds_count",
+	} {
+		if !strings.Contains(recorder.Body.String(), want) {
+			t.Errorf("metrics don't contain %s:\n%s", want, recorder.Body)
+		}
+	}
+}
+
+func TestGetLightsAndRooms(t *testing.T) {
+	lights, rooms := fixtureHandler(t, "lights.json"), fixtureHandler(t, "rooms.json")
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/clip/v2/resource/light":
+			lights(w, r)
+		case "/clip/v2/resource/room":
+			rooms(w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	gotLights, err := client.GetLights(context.Background())
+	if err != nil {
+		t.Fatalf("GetLights() returned error: %v", err)
+	}
+	var names []string
+	for _, light := range gotLights {
+		names = append(names, light.ID+" "+light.Metadata.Name)
+	}
+	if want := []string{"light-1 Desk lamp", "light-2 Bookshelf", "light-3 Attic"}; !slices.Equal(names, want) {
+		t.Fatalf("GetLights() = %v, want %v", names, want)
+	}
+	gotRooms, err := client.GetRooms(context.Background())
+	if err != nil {
+		t.Fatalf("GetRooms() returned error: %v", err)
+	}
+	if len(gotRooms) != 1 || gotRooms[0].ID != "room-1" || gotRooms[0].Metadata.Name != "Office" {
+		t.Fatalf("GetRooms() = %+v, want the office", gotRooms)
+	}
+	// the rooms contain the devices owning the lights
+	for i, want := range []bool{true, true, false} {
+		if got := gotRooms[0].Contains(gotLights[i]); got != want {
+			t.Errorf("office contains %s = %v, want %v", gotLights[i].ID, got, want)
+		}
+	}
+}
+
+func TestGetGroupedLight(t *testing.T) {
+	groupedLight := fixtureHandler(t, "grouped_light.json")
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/clip/v2/resource/grouped_light/grouped-light-1":
+			groupedLight(w, r)
+		case "/clip/v2/resource/grouped_light/empty":
+			_, _ = w.Write([]byte(`{"errors":[],"data":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[{"description":"Not Found"}],"data":[]}`))
+		}
+	})
+
+	group, err := client.GetGroupedLight(context.Background(), "grouped-light-1")
+	if err != nil {
+		t.Fatalf("GetGroupedLight() returned error: %v", err)
+	}
+	if group.ID != "grouped-light-1" || group.Owner != (Group{ID: "room-1", Type: "room"}) || !group.On.On ||
+		group.Dimming.Brightness != 54.33 {
+		t.Errorf("GetGroupedLight() = %+v, want the office's grouped light", group)
+	}
+	if group.Color == nil || group.Color.XY != (Coords{X: 0.5612, Y: 0.4042}) {
+		t.Errorf("Color = %+v, want the aggregated xy color", group.Color)
+	}
+
+	// the grouped light is synchronized as a light in the aggregated color
+	light := group.Light()
+	if light.ID != "grouped-light-1" || light.ColorMode != ColorModeXY || light.Color.XY != group.Color.XY ||
+		light.Dimming.Brightness != 54.33 {
+		t.Errorf("Light() = %+v, want the grouped light's aggregated xy color and brightness", light)
+	}
+
+	for _, id := range []string{"unknown", "empty"} {
+		if _, err := client.GetGroupedLight(context.Background(), id); !IsLightNotFound(err) {
+			t.Errorf("GetGroupedLight(%q) error = %v, want a light not found error", id, err)
+		}
+	}
+}
+
+func TestConcurrentGetLight(t *testing.T) {
+	const requests = DefaultMaxConcurrentRequests
+	var arrived atomic.Int32
+	allArrived := make(chan struct{})
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// every request is held until all of them reached the bridge, which never happens if they're serialized
+		if arrived.Add(1) == requests {
+			close(allArrived)
+		}
+		select {
+		case <-allArrived:
+			_, _ = w.Write([]byte(testLightResponse))
+		case <-time.After(2 * time.Second):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	client.SetRequestsPerSecond(1000)
+	address := client.BridgeAddress()
+
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetLight(context.Background(), "light-1"); err != nil {
+				t.Errorf("GetLight() returned error: %v", err)
+			}
+		}()
+	}
+	// the address may be updated while requests are in flight
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 10 {
+			if err := client.SetBridgeAddress(address); err != nil {
+				t.Error(err)
+			}
+			_ = client.BridgeAddress()
+		}
+	}()
+	wg.Wait()
+
+	select {
+	case <-allArrived:
+	default:
+		t.Errorf("only %d of %d concurrent requests reached the bridge at the same time", arrived.Load(), requests)
+	}
+}
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			peak := maxInFlight.Load()
+			if n <= peak || maxInFlight.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(testLightResponse))
+	})
+	client.SetMaxConcurrentRequests(2)
+	client.SetRequestsPerSecond(1000)
+
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetLight(context.Background(), "light-1"); err != nil {
+				t.Errorf("GetLight() returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := maxInFlight.Load(); got != 2 {
+		t.Errorf("%d requests were in flight at the same time, want 2", got)
+	}
+}
+
+func TestMaxConcurrentRequestsUnderLoad(t *testing.T) {
+	scenes := fixtureHandler(t, "dynamic_scenes.json")
+	var inFlight, maxInFlight atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			peak := maxInFlight.Load()
+			if n <= peak || maxInFlight.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if strings.HasSuffix(r.URL.Path, "/scene") {
+			scenes(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(testLightResponse))
+	})
+	client.SetRequestsPerSecond(1000)
+	client.SetSceneCacheTTL(0)
+
+	// light and scene requests of many synchronizations share the limit
+	var wg sync.WaitGroup
+	for i := range 30 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if i%2 == 0 {
+				_, err = client.GetLight(context.Background(), "light-1")
+			} else {
+				_, err = client.GetActiveScene(context.Background(), "room-1")
+			}
+			if err != nil {
+				t.Errorf("request %d returned error: %v", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := maxInFlight.Load(); got > DefaultMaxConcurrentRequests {
+		t.Errorf("%d requests were in flight at the same time, want at most %d", got, DefaultMaxConcurrentRequests)
+	}
+}
+
+func TestMaxConcurrentRequestsCanceledWhileWaiting(t *testing.T) {
+	release := make(chan struct{})
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		_, _ = w.Write([]byte(testLightResponse))
+	})
+	client.SetMaxConcurrentRequests(1)
+	client.SetRequestsPerSecond(1000)
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := client.GetLight(context.Background(), "light-1")
+		first <- err
+	}()
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// a request waiting for a free slot gives up with its context and never reaches the bridge
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetLight(ctx, "light-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetLight() waiting for a slot = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+	if err := <-first; err != nil {
+		t.Errorf("GetLight() holding the slot returned error: %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("bridge received %d requests, want 1", got)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// the bridge hangs until the test is over
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	defer close(release)
+	client.SetRequestTimeout(50 * time.Millisecond)
+	client.SetRequestsPerSecond(1000)
+
+	calls := map[string]func(ctx context.Context) error{
+		"GetLight": func(ctx context.Context) error {
+			_, err := client.GetLight(ctx, "light-1")
+			return err
+		},
+		"GetActiveScene": func(ctx context.Context) error {
+			_, err := client.GetActiveScene(ctx, "room-1")
+			return err
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			err := call(context.Background())
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("%s() error = %v, want a deadline exceeded error", name, err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("%s() returned after %v, want it to give up after the request timeout", name, elapsed)
+			}
+
+			// canceling the context, e.g. on shutdown, aborts the request in flight
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+			if err := call(ctx); !errors.Is(err, context.Canceled) {
+				t.Errorf("%s() with a canceled context error = %v, want context.Canceled", name, err)
+			}
+		})
+	}
+}
+
+func TestValidateBridgeID(t *testing.T) {
+	tests := []struct {
+		name     string
+		bridgeID string
+		wantErr  string
+	}{
+		{name: "any bridge"},
+		{name: "full", bridgeID: testBridgeID},
+		{name: "shortened", bridgeID: "4A5B6C"},
+		{name: "too short", bridgeID: "5b6c", wantErr: "or at least its last 6, got 4 characters"},
+		{name: "too long", bridgeID: testBridgeID + "00", wantErr: "got 18 characters"},
+		{name: "not hex", bridgeID: "001788fffe4a5b6x", wantErr: "must only contain hex characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBridgeID(tt.bridgeID)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("ValidateBridgeID(%q) returned error: %v", tt.bridgeID, err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ValidateBridgeID(%q) = %v, want an error containing %q", tt.bridgeID, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyCertificateRequiresFullBridgeID(t *testing.T) {
+	for _, bridgeID := range []string{"", "4a5b6c", "5b6c"} {
+		client := NewClient(bridgeID, "test-user", zerolog.Nop())
+		if err := client.VerifyCertificate(nil); err == nil {
+			t.Errorf("VerifyCertificate() with bridge ID %q returned no error", bridgeID)
+		}
+	}
+
+	client := NewClient(testBridgeID, "test-user", zerolog.Nop())
+	if err := client.VerifyCertificate(nil); err != nil {
+		t.Errorf("VerifyCertificate() with the full bridge ID returned error: %v", err)
+	}
+}
+
+func TestDiscoverBridgeShortBridgeID(t *testing.T) {
+	// a bridge ID too short to name the mDNS service must not slice out of range
+	var queries atomic.Int32
+	client := NewClient("5b6c", "test-user", zerolog.Nop())
+	client.queryMDNS = answerMDNS(&queries, bridgeEntry(testBridgeID, "192.168.1.20"))
+
+	if bridge, err := client.discoverBridgeMDNS(context.Background(), false); err == nil {
+		t.Errorf("discoverBridgeMDNS() with a too short bridge ID = %+v, want the bridge not found", bridge)
+	}
+}
+
+func TestStartAutoDiscoveryRetries(t *testing.T) {
+	var logs bytes.Buffer
+	var queries atomic.Int32
+	client := NewClient(testBridgeID, "test-user", zerolog.New(&logs))
+	client.discoveryBackoff = 10 * time.Millisecond
+	answer := answerMDNS(&atomic.Int32{}, bridgeEntry(testBridgeID, "192.168.1.20"))
+	client.queryMDNS = func(ctx context.Context, params *mdns.QueryParam) error {
+		// the bridge only answers once the network is up
+		if queries.Add(1) < 3 {
+			return nil
+		}
+		return answer(ctx, params)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := client.StartAutoDiscovery(ctx); err != nil {
+		t.Fatalf("StartAutoDiscovery() returned error: %v", err)
+	}
+	if got := client.BridgeAddress(); got != "192.168.1.20" {
+		t.Errorf("BridgeAddress() = %q, want the address found by the third attempt", got)
+	}
+	if got := queries.Load(); got != 3 {
+		t.Errorf("sent %d mDNS queries, want 3", got)
+	}
+	if n := strings.Count(logs.String(), "Hue bridge not found, retrying"); n != 2 {
+		t.Errorf("logged %d retries, want 2:\n%s", n, logs.String())
+	}
+}
+
+func TestStartAutoDiscoveryGivesUp(t *testing.T) {
+	var queries atomic.Int32
+	client := NewClient(testBridgeID, "test-user", zerolog.Nop())
+	client.discoveryBackoff = 10 * time.Millisecond
+	client.SetDiscoveryTimeout(100 * time.Millisecond)
+	client.queryMDNS = answerMDNS(&queries)
+
+	err := client.StartAutoDiscovery(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "giving up after") {
+		t.Errorf("StartAutoDiscovery() without a bridge = %v, want it to give up after the discovery timeout", err)
+	}
+	if got := queries.Load(); got < 2 {
+		t.Errorf("sent %d mDNS queries, want the discovery retried", got)
+	}
+	if got := client.BridgeAddress(); got != "" {
+		t.Errorf("BridgeAddress() = %q, want none", got)
+	}
+}
+
+func TestStartAutoDiscoveryCanceled(t *testing.T) {
+	client := NewClient(testBridgeID, "test-user", zerolog.Nop())
+	client.queryMDNS = answerMDNS(&atomic.Int32{})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// canceling stops the retries without waiting for the backoff
+	if err := client.StartAutoDiscovery(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("StartAutoDiscovery() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
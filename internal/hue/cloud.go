@@ -0,0 +1,108 @@
+package hue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cloudDiscoveryURL is the Hue cloud endpoint listing the local addresses of the bridges in the caller's network
+var cloudDiscoveryURL = "https://discovery.meethue.com"
+
+const (
+	// cloudDiscoveryTimeout is how long the cloud discovery request may take
+	cloudDiscoveryTimeout = 5 * time.Second
+	// mdnsFallbackTimeout is how long mDNS discovery may take before falling back to cloud discovery
+	mdnsFallbackTimeout = 5 * time.Second
+)
+
+// cloudBridge is a bridge listed by the cloud discovery endpoint
+type cloudBridge struct {
+	ID                string `json:"id"`
+	InternalIPAddress string `json:"internalipaddress"`
+	Port              int    `json:"port"`
+}
+
+// SetCloudDiscovery enables discovering the bridge through the Hue cloud if mDNS doesn't find it, e.g. on
+// networks blocking multicast. Requires internet access. Must be called before discovery is started.
+func (c *Client) SetCloudDiscovery(enabled bool) {
+	c.cloudDiscovery = enabled
+}
+
+// discoverBridge discovers the Hue bridge using mDNS, falling back to the Hue cloud if enabled.
+func (c *Client) discoverBridge(ctx context.Context) (*DiscoveryResponse, error) {
+	if !c.cloudDiscovery {
+		return c.discoverBridgeLocal(ctx)
+	}
+
+	mdnsTimeout := mdnsFallbackTimeout
+	if c.ipv6Discovery {
+		// leave time for the IPv6 retry of mDNS discovery
+		mdnsTimeout *= 2
+	}
+	mdnsCtx, cancel := context.WithTimeout(ctx, mdnsTimeout)
+	bridge, err := c.discoverBridgeLocal(mdnsCtx)
+	cancel()
+	if err == nil {
+		return bridge, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	c.logger.Warn().Err(err).Msg("mDNS discovery failed, falling back to Hue cloud discovery")
+	bridge, cloudErr := c.discoverBridgeCloud(ctx)
+	if cloudErr != nil {
+		return nil, errors.Join(err, cloudErr)
+	}
+	return bridge, nil
+}
+
+// discoverBridgeCloud discovers the bridge by querying the Hue cloud, matching the bridge ID if set.
+func (c *Client) discoverBridgeCloud(ctx context.Context) (*DiscoveryResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, cloudDiscoveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cloudDiscoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Hue cloud discovery: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("hue cloud discovery returned status %d", resp.StatusCode)
+	}
+	var bridges []cloudBridge
+	if err := json.NewDecoder(resp.Body).Decode(&bridges); err != nil {
+		return nil, fmt.Errorf("failed to decode Hue cloud discovery response: %w", err)
+	}
+
+	for _, bridge := range bridges {
+		if bridge.InternalIPAddress == "" || !c.matchesBridgeID(bridge.ID) {
+			continue
+		}
+		address := bridge.InternalIPAddress
+		if bridge.Port != 0 && bridge.Port != 443 {
+			address = net.JoinHostPort(address, strconv.Itoa(bridge.Port))
+		}
+		return &DiscoveryResponse{Address: address}, nil
+	}
+	return nil, fmt.Errorf("bridge %q not found by Hue cloud discovery", c.hueBridgeID)
+}
+
+// matchesBridgeID returns whether the ID of a discovered bridge matches the configured bridge ID, which may be
+// shortened to its last characters like in the mDNS service name. Without a configured bridge ID, any bridge
+// matches.
+func (c *Client) matchesBridgeID(id string) bool {
+	return strings.HasSuffix(strings.ToLower(id), strings.ToLower(c.hueBridgeID))
+}
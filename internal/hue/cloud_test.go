@@ -0,0 +1,160 @@
+package hue
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/mdns"
+	"github.com/rs/zerolog"
+)
+
+const testBridgeID = "001788fffe4a5b6c"
+
+// answerMDNS returns an mDNS query answering with the entries, counting the queries sent.
+func answerMDNS(queries *atomic.Int32, entries ...*mdns.ServiceEntry) func(context.Context, *mdns.QueryParam) error {
+	return func(ctx context.Context, params *mdns.QueryParam) error {
+		queries.Add(1)
+		for _, entry := range entries {
+			select {
+			case params.Entries <- entry:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+// bridgeEntry returns the mDNS answer of the bridge with the given ID at the IPv4 address.
+func bridgeEntry(bridgeID, ip string) *mdns.ServiceEntry {
+	name := "Hue Bridge - " + strings.ToUpper(bridgeID[len(bridgeID)-minBridgeIDLength:])
+	return &mdns.ServiceEntry{Name: strings.ReplaceAll(name, " ", "\\ ") + "._hue._tcp.local.", AddrV4: net.ParseIP(ip)}
+}
+
+// serveCloudDiscovery serves the body as the response of the Hue cloud discovery endpoint, counting the requests.
+func serveCloudDiscovery(t *testing.T, status int, body string, requests *atomic.Int32) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	defaultURL := cloudDiscoveryURL
+	cloudDiscoveryURL = server.URL
+	t.Cleanup(func() { cloudDiscoveryURL = defaultURL })
+}
+
+const testCloudBridges = `[
+	{"id": "001788fffe000000", "internalipaddress": "192.168.1.10", "port": 443},
+	{"id": "001788FFFE4A5B6C", "internalipaddress": "192.168.1.20", "port": 443}
+]`
+
+func TestDiscoverBridgeCloudFallback(t *testing.T) {
+	var queries, requests atomic.Int32
+	serveCloudDiscovery(t, http.StatusOK, testCloudBridges, &requests)
+	client := NewClient(testBridgeID, "test-user", zerolog.Nop())
+	client.queryMDNS = answerMDNS(&queries) // multicast is blocked, mDNS finds nothing
+	client.SetCloudDiscovery(true)
+
+	bridge, err := client.discoverBridge(context.Background())
+	if err != nil {
+		t.Fatalf("discoverBridge() returned error: %v", err)
+	}
+	if bridge.Address != "192.168.1.20" {
+		t.Errorf("Address = %q, want the cloud listed address of the bridge with the configured ID", bridge.Address)
+	}
+	if queries.Load() != 1 || requests.Load() != 1 {
+		t.Errorf("sent %d mDNS queries and %d cloud requests, want one each", queries.Load(), requests.Load())
+	}
+}
+
+func TestDiscoverBridgeMDNSBeforeCloud(t *testing.T) {
+	var queries, requests atomic.Int32
+	serveCloudDiscovery(t, http.StatusOK, testCloudBridges, &requests)
+	client := NewClient(testBridgeID, "test-user", zerolog.Nop())
+	client.queryMDNS = answerMDNS(&queries, bridgeEntry("001788fffe000000", "192.168.1.10"),
+		bridgeEntry(testBridgeID, "192.168.1.30"))
+	client.SetCloudDiscovery(true)
+
+	bridge, err := client.discoverBridge(context.Background())
+	if err != nil {
+		t.Fatalf("discoverBridge() returned error: %v", err)
+	}
+	if bridge.Address != "192.168.1.30" {
+		t.Errorf("Address = %q, want the address found by mDNS", bridge.Address)
+	}
+	if requests.Load() != 0 {
+		t.Errorf("sent %d cloud requests, want none as mDNS found the bridge", requests.Load())
+	}
+}
+
+func TestDiscoverBridgeCloudDisabled(t *testing.T) {
+	var queries, requests atomic.Int32
+	serveCloudDiscovery(t, http.StatusOK, testCloudBridges, &requests)
+	client := NewClient(testBridgeID, "test-user", zerolog.Nop())
+	client.queryMDNS = answerMDNS(&queries)
+
+	if _, err := client.discoverBridge(context.Background()); err == nil {
+		t.Error("discoverBridge() without mDNS answers returned no error")
+	}
+	if requests.Load() != 0 {
+		t.Errorf("sent %d cloud requests, want none without hue_allow_cloud_discovery", requests.Load())
+	}
+}
+
+func TestDiscoverBridgeCloud(t *testing.T) {
+	tests := []struct {
+		name     string
+		bridgeID string
+		status   int
+		body     string
+		want     string
+		wantErr  string
+	}{
+		{name: "full bridge ID", bridgeID: testBridgeID, status: http.StatusOK, body: testCloudBridges,
+			want: "192.168.1.20"},
+		{name: "shortened bridge ID", bridgeID: "4a5b6c", status: http.StatusOK, body: testCloudBridges,
+			want: "192.168.1.20"},
+		{name: "any bridge", status: http.StatusOK, body: testCloudBridges, want: "192.168.1.10"},
+		{name: "custom port", bridgeID: testBridgeID, status: http.StatusOK,
+			body: `[{"id": "001788fffe4a5b6c", "internalipaddress": "192.168.1.20", "port": 8443}]`,
+			want: "192.168.1.20:8443"},
+		{name: "no address", bridgeID: testBridgeID, status: http.StatusOK,
+			body: `[{"id": "001788fffe4a5b6c", "port": 443}]`, wantErr: "not found by Hue cloud discovery"},
+		{name: "unknown bridge", bridgeID: "ffffff", status: http.StatusOK, body: testCloudBridges,
+			wantErr: "not found by Hue cloud discovery"},
+		{name: "rate limited", bridgeID: testBridgeID, status: http.StatusTooManyRequests, body: "[]",
+			wantErr: "returned status 429"},
+		{name: "invalid response", bridgeID: testBridgeID, status: http.StatusOK, body: "<html>",
+			wantErr: "failed to decode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			serveCloudDiscovery(t, tt.status, tt.body, &requests)
+			client := NewClient(tt.bridgeID, "test-user", zerolog.Nop())
+
+			bridge, err := client.discoverBridgeCloud(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("discoverBridgeCloud() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("discoverBridgeCloud() returned error: %v", err)
+			}
+			if bridge.Address != tt.want {
+				t.Errorf("Address = %q, want %q", bridge.Address, tt.want)
+			}
+		})
+	}
+}
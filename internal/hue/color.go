@@ -1,7 +1,17 @@
 package hue
 
 import (
+	"fmt"
 	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MirekMin is the coolest color temperature (in mireds) supported by Hue lights
+	MirekMin = 153
+	// MirekMax is the warmest color temperature (in mireds) supported by Hue lights
+	MirekMax = 500
 )
 
 var (
@@ -30,8 +40,88 @@ var (
 	}
 )
 
+// RGBColor is a color in sRGB with channels in the range 0-255
+type RGBColor struct {
+	R, G, B int
+}
+
+// ConversionOptions tweak how a Light is converted to RGB
+type ConversionOptions struct {
+	// ColorGamma encodes the linear RGB values converted from XY coordinates, sRGB by default
+	ColorGamma ColorGamma
+	// BrightnessConversion selects how the brightness is applied to colors converted from XY coordinates
+	BrightnessConversion BrightnessConversion
+}
+
+// BrightnessConversion selects how the brightness of a light is applied when converting its XY coordinates to RGB.
+type BrightnessConversion string
+
+const (
+	// BrightnessConversionLuminance scales the luminance before the conversion to sRGB, the default
+	BrightnessConversionLuminance BrightnessConversion = "luminance"
+	// BrightnessConversionMultiply converts the color at full luminance and multiplies the encoded channels by the
+	// brightness, so dim colors keep their hue and saturation. Channels beyond the sRGB range are scaled down evenly
+	// instead of being clipped.
+	BrightnessConversionMultiply BrightnessConversion = "multiply"
+)
+
+// ParseBrightnessConversion parses a brightness conversion in the form "luminance" or "multiply". An empty string
+// selects luminance.
+func ParseBrightnessConversion(s string) (BrightnessConversion, error) {
+	switch BrightnessConversion(strings.ToLower(s)) {
+	case "", BrightnessConversionLuminance:
+		return BrightnessConversionLuminance, nil
+	case BrightnessConversionMultiply:
+		return BrightnessConversionMultiply, nil
+	}
+	return "", fmt.Errorf("invalid brightness conversion %q, must be %s or %s", s, BrightnessConversionLuminance,
+		BrightnessConversionMultiply)
+}
+
+// ColorGamma is the exponent used to encode linear RGB values, each channel being raised to 1/ColorGamma.
+// Zero selects the piecewise sRGB curve.
+type ColorGamma float64
+
+const (
+	// ColorGammaSRGB encodes colors with the sRGB curve, the default
+	ColorGammaSRGB ColorGamma = 0
+	// ColorGammaLinear sends linear values, for devices that apply a gamma curve themselves
+	ColorGammaLinear ColorGamma = 1
+)
+
+// ParseColorGamma parses a color gamma in the form "srgb", "linear" or a positive exponent like "2.2".
+// An empty string selects sRGB.
+func ParseColorGamma(s string) (ColorGamma, error) {
+	switch strings.ToLower(s) {
+	case "", "srgb":
+		return ColorGammaSRGB, nil
+	case "linear":
+		return ColorGammaLinear, nil
+	}
+
+	exponent, err := strconv.ParseFloat(s, 64)
+	if err != nil || exponent <= 0 || math.IsInf(exponent, 0) {
+		return 0, fmt.Errorf("invalid color gamma %q, must be srgb, linear or a positive exponent", s)
+	}
+	return ColorGamma(exponent), nil
+}
+
+// encode encodes a linear channel value in [0, 1]
+func (g ColorGamma) encode(v float64) float64 {
+	if g == ColorGammaSRGB {
+		if v <= 0.0031308 {
+			return 12.92 * v
+		}
+		return 1.055*math.Pow(v, 1.0/2.4) - 0.055
+	}
+	if v <= 0 {
+		return 0
+	}
+	return math.Pow(v, 1/float64(g))
+}
+
 // ColorToRGB converts a Light to RGB with gamut correction
-func ColorToRGB(light *Light, fixedBrightness *int) (int, int, int) {
+func ColorToRGB(light *Light, fixedBrightness *int, opts ConversionOptions) (int, int, int) {
 	if !light.On.On {
 		return 0, 0, 0
 	}
@@ -41,7 +131,7 @@ func ColorToRGB(light *Light, fixedBrightness *int) (int, int, int) {
 		brightness = *fixedBrightness
 	}
 
-	if light.ColorTemperature.MirekValid {
+	if light.ColorMode == ColorModeCT {
 		// CT is in mireds, convert to Kelvin: 1000000/CT
 		kelvin := 1000000 / light.ColorTemperature.Mirek
 		return ctToRGB(kelvin, brightness)
@@ -54,29 +144,78 @@ func ColorToRGB(light *Light, fixedBrightness *int) (int, int, int) {
 			brightness,
 			light.Color.GamutType,
 			light.Color.Gamut,
+			opts.ColorGamma,
+			opts.BrightnessConversion,
 		)
 	}
 
-	brightnessValue := brightness * 255 / 100
+	brightnessValue := int(brightnessFactor(brightness) * 255)
 	return brightnessValue, brightnessValue, brightnessValue
 }
 
-// coordsToRGB converts XY coordinates and brightness to RGB with gamut correction
-func coordsToRGB(x, y float64, bri int, gamutType GamutType, gamut Gamut) (int, int, int) {
-	var colorGamut Gamut
+// ShiftMirek shifts a color temperature in mireds by the given offset and clamps the result to the
+// range supported by Hue lights. Positive offsets make the color warmer, negative offsets cooler.
+func ShiftMirek(mirek, offset int) int {
+	return int(clamp(float64(mirek+offset), MirekMin, MirekMax))
+}
+
+// RGBToXY converts an sRGB color to CIE XY coordinates within the gamut, ignoring its brightness. Colors outside
+// the gamut are moved to its closest edge, and a zero Gamut selects the default gamut. Black is mapped to the D65
+// white point since it has no chromaticity.
+func RGBToXY(r, g, b int, gamut Gamut) Coords {
+	toLinear := func(v int) float64 {
+		c := clamp(float64(v)/255, 0, 1)
+		if c <= 0.04045 {
+			return c / 12.92
+		}
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	rLin, gLin, bLin := toLinear(r), toLinear(g), toLinear(b)
+
+	X := rLin*0.4124 + gLin*0.3576 + bLin*0.1805
+	Y := rLin*0.2126 + gLin*0.7152 + bLin*0.0722
+	Z := rLin*0.0193 + gLin*0.1192 + bLin*0.9505
+
+	if !isValidGamut(gamut) {
+		gamut = defaultGamut
+	}
+	sum := X + Y + Z
+	if sum == 0 {
+		return CorrectToGamut(Coords{X: 0.3127, Y: 0.3290}, gamut)
+	}
+	return CorrectToGamut(Coords{X: X / sum, Y: Y / sum}, gamut)
+}
+
+// LightGamut returns the color gamut of the light, falling back to the gamut of its gamut type or the default
+// gamut if the light doesn't report its gamut.
+func LightGamut(light *Light) Gamut {
+	return resolveGamut(light.Color.GamutType, light.Color.Gamut)
+}
+
+// resolveGamut returns the gamut if it's set, otherwise the gamut of the gamut type or the default gamut
+func resolveGamut(gamutType GamutType, gamut Gamut) Gamut {
 	if isValidGamut(gamut) {
-		colorGamut = gamut
-	} else if g, ok := gamutMap[gamutType]; ok {
-		colorGamut = g
-	} else {
-		colorGamut = defaultGamut
+		return gamut
+	}
+	if g, ok := gamutMap[gamutType]; ok {
+		return g
 	}
+	return defaultGamut
+}
 
-	correctedCoords := correctToGamut(Coords{X: x, Y: y}, colorGamut)
+// coordsToRGB converts XY coordinates and brightness to RGB with gamut correction, encoding the channels with
+// the color gamma and applying the brightness as selected by the brightness conversion
+func coordsToRGB(x, y float64, bri int, gamutType GamutType, gamut Gamut, colorGamma ColorGamma,
+	conversion BrightnessConversion) (int, int, int) {
+	correctedCoords := CorrectToGamut(Coords{X: x, Y: y}, resolveGamut(gamutType, gamut))
 	x, y = correctedCoords.X, correctedCoords.Y
 
 	z := 1.0 - x - y
-	Y := float64(bri) / 100.0 // Brightness is 0-100 in the new API
+	brightness := brightnessFactor(bri) // Brightness is 0-100 in the new API
+	Y := brightness
+	if conversion == BrightnessConversionMultiply {
+		Y = 1
+	}
 	X := (Y / y) * x
 	Z := (Y / y) * z
 
@@ -85,15 +224,19 @@ func coordsToRGB(x, y float64, bri int, gamutType GamutType, gamut Gamut) (int,
 	gLin := X*-0.9689 + Y*1.8758 + Z*0.0415
 	bLin := X*0.0557 + Y*-0.2040 + Z*1.0570
 
-	toGamma := func(v float64) float64 {
-		if v <= 0.0031308 {
-			return 12.92 * v
+	if conversion == BrightnessConversionMultiply {
+		// clipping single channels would shift the hue, so all channels are scaled down evenly
+		if maxLin := max(rLin, gLin, bLin); maxLin > 1 {
+			rLin, gLin, bLin = rLin/maxLin, gLin/maxLin, bLin/maxLin
 		}
-		return 1.055*math.Pow(v, 1.0/2.4) - 0.055
+		return int(clampChannel(colorGamma.encode(rLin) * brightness * 255)),
+			int(clampChannel(colorGamma.encode(gLin) * brightness * 255)),
+			int(clampChannel(colorGamma.encode(bLin) * brightness * 255))
 	}
-	r, g, b := int(clamp(toGamma(rLin)*255, 0, 255)),
-		int(clamp(toGamma(gLin)*255, 0, 255)),
-		int(clamp(toGamma(bLin)*255, 0, 255))
+
+	r, g, b := int(clamp(colorGamma.encode(rLin)*255, 0, 255)),
+		int(clamp(colorGamma.encode(gLin)*255, 0, 255)),
+		int(clamp(colorGamma.encode(bLin)*255, 0, 255))
 
 	return r, g, b
 }
@@ -105,10 +248,17 @@ func isValidGamut(gamut Gamut) bool {
 		gamut.Blue.X == 0 && gamut.Blue.Y == 0)
 }
 
-// ctToRGB converts color temperature in Kelvin to RGB
+const (
+	// minKelvin and maxKelvin bound the color temperatures the approximation in ctToRGB is fitted for
+	minKelvin = 1000
+	maxKelvin = 40000
+)
+
+// ctToRGB converts color temperature in Kelvin to RGB. Temperatures outside 1000-40000K are clamped to that range,
+// as the approximation yields infinite or NaN channels for temperatures near zero.
 func ctToRGB(kelvin int, bri int) (int, int, int) {
 	// Algorithm based on https://tannerhelland.com/2012/09/18/convert-temperature-rgb-algorithm-code.html
-	temp := float64(kelvin) / 100.0
+	temp := clamp(float64(kelvin), minKelvin, maxKelvin) / 100.0
 
 	var r, g, b float64
 
@@ -158,31 +308,43 @@ func ctToRGB(kelvin int, bri int) (int, int, int) {
 	}
 
 	// Apply brightness
-	brightness := float64(bri) / 100.0
+	brightness := brightnessFactor(bri)
 	r = r * brightness
 	g = g * brightness
 	b = b * brightness
 
-	return int(r), int(g), int(b)
+	return int(clampChannel(r)), int(clampChannel(g)), int(clampChannel(b))
 }
 
-// isInGamut checks if point is inside triangle
-func isInGamut(point Coords, gamut Gamut) bool {
+// gamutEdgeTolerance is how far the barycentric coordinates of a point may be out of range for IsInGamut, so that
+// points on an edge aren't rejected due to rounding
+const gamutEdgeTolerance = 1e-9
+
+// IsInGamut reports whether the point lies inside the triangle spanned by the gamut, including its edges.
+// A degenerate gamut, whose corners lie on a line or coincide, contains no points.
+func IsInGamut(point Coords, gamut Gamut) bool {
 	v1x, v1y := gamut.Red.X, gamut.Red.Y
 	v2x, v2y := gamut.Green.X, gamut.Green.Y
 	v3x, v3y := gamut.Blue.X, gamut.Blue.Y
 
 	denominator := (v2y-v3y)*(v1x-v3x) + (v3x-v2x)*(v1y-v3y)
+	if math.Abs(denominator) < 1e-10 {
+		return false
+	}
 	a := ((v2y-v3y)*(point.X-v3x) + (v3x-v2x)*(point.Y-v3y)) / denominator
 	b := ((v3y-v1y)*(point.X-v3x) + (v1x-v3x)*(point.Y-v3y)) / denominator
 	c := 1 - a - b
 
-	return a >= 0 && a <= 1 && b >= 0 && b <= 1 && c >= 0 && c <= 1
+	inRange := func(v float64) bool {
+		return v >= -gamutEdgeTolerance && v <= 1+gamutEdgeTolerance
+	}
+	return inRange(a) && inRange(b) && inRange(c)
 }
 
-// correctToGamut projects point to gamut edge if outside the gamut
-func correctToGamut(point Coords, gamut Gamut) Coords {
-	if isInGamut(point, gamut) {
+// CorrectToGamut returns the point if it's inside the gamut, or else the closest point on the edges of the gamut.
+// Points outside a degenerate gamut are projected onto the line or point its corners collapse to.
+func CorrectToGamut(point Coords, gamut Gamut) Coords {
+	if IsInGamut(point, gamut) {
 		return point
 	}
 
@@ -232,6 +394,55 @@ func correctToGamut(point Coords, gamut Gamut) Coords {
 	return closestPoint
 }
 
+// ParseBrightnessCurve parses a brightness curve in the form "linear" or "gamma:N" with a positive exponent N,
+// returning the gamma to apply to the brightness. An empty string selects the linear curve.
+func ParseBrightnessCurve(s string) (float64, error) {
+	if s == "" || strings.EqualFold(s, "linear") {
+		return 1, nil
+	}
+
+	exponentStr, ok := strings.CutPrefix(strings.ToLower(s), "gamma:")
+	if !ok {
+		return 0, fmt.Errorf("invalid brightness curve %q, must be linear or gamma:N", s)
+	}
+	exponent, err := strconv.ParseFloat(exponentStr, 64)
+	if err != nil || exponent <= 0 || math.IsInf(exponent, 0) {
+		return 0, fmt.Errorf("invalid brightness curve exponent %q, must be positive", exponentStr)
+	}
+	return exponent, nil
+}
+
+// ApplyBrightnessGamma applies the gamma to a brightness in percent, returning a brightness in percent.
+// A gamma of zero is treated as linear (gamma 1).
+func ApplyBrightnessGamma(bri int, gamma float64) int {
+	factor := brightnessFactor(bri)
+	if gamma > 0 && gamma != 1 {
+		factor = math.Pow(factor, gamma)
+	}
+	return int(math.Round(factor * 100))
+}
+
+// RemapBrightness maps a brightness in percent linearly onto the range [floor, ceiling], so that the lowest
+// brightness of a light that is on becomes the floor instead of turning the device off.
+func RemapBrightness(bri, floor, ceiling int) int {
+	bri = int(clamp(float64(bri), 0, 100))
+	mapped := float64(floor) + float64(bri)*float64(ceiling-floor)/100
+	return int(clamp(math.Round(mapped), float64(floor), float64(ceiling)))
+}
+
+// brightnessFactor converts a brightness in percent to a factor in [0, 1]
+func brightnessFactor(bri int) float64 {
+	return clamp(float64(bri)/100.0, 0, 1)
+}
+
+// clampChannel clamps a channel value to 0-255, mapping NaN to 0 since converting it to int is undefined
+func clampChannel(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return clamp(v, 0, 255)
+}
+
 func clamp(x, minF, maxF float64) float64 {
 	if x < minF {
 		return minF
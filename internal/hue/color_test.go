@@ -0,0 +1,561 @@
+package hue
+
+import (
+	"math"
+	"testing"
+)
+
+// coordsTolerance is the maximum distance between XY coordinates considered equal
+const coordsTolerance = 1e-9
+
+func distance(a, b Coords) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
+
+func midpoint(a, b Coords) Coords {
+	return Coords{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}
+}
+
+func centroid(gamut Gamut) Coords {
+	return Coords{
+		X: (gamut.Red.X + gamut.Green.X + gamut.Blue.X) / 3,
+		Y: (gamut.Red.Y + gamut.Green.Y + gamut.Blue.Y) / 3,
+	}
+}
+
+// beyond returns the point at the given distance from the vertex, on the side facing away from the centroid
+func beyond(gamut Gamut, vertex Coords, dist float64) Coords {
+	c := centroid(gamut)
+	d := distance(vertex, c)
+	return Coords{X: vertex.X + (vertex.X-c.X)/d*dist, Y: vertex.Y + (vertex.Y-c.Y)/d*dist}
+}
+
+// outsideEdge returns the point at the given distance from the midpoint of the edge, perpendicular to it and on
+// the side facing away from the gamut
+func outsideEdge(gamut Gamut, p1, p2 Coords, dist float64) Coords {
+	m := midpoint(p1, p2)
+	nx, ny := -(p2.Y - p1.Y), p2.X-p1.X
+	n := math.Hypot(nx, ny)
+	nx, ny = nx/n, ny/n
+	if c := centroid(gamut); nx*(c.X-m.X)+ny*(c.Y-m.Y) > 0 {
+		nx, ny = -nx, -ny
+	}
+	return Coords{X: m.X + nx*dist, Y: m.Y + ny*dist}
+}
+
+// nearestOnEdges returns the distance of the point to the closest of densely sampled points on the gamut's edges
+func nearestOnEdges(point Coords, gamut Gamut) float64 {
+	const samples = 100000
+
+	nearest := math.Inf(1)
+	for _, edge := range [][2]Coords{{gamut.Red, gamut.Green}, {gamut.Green, gamut.Blue}, {gamut.Blue, gamut.Red}} {
+		for i := 0; i <= samples; i++ {
+			t := float64(i) / samples
+			sample := Coords{X: edge[0].X + (edge[1].X-edge[0].X)*t, Y: edge[0].Y + (edge[1].Y-edge[0].Y)*t}
+			nearest = math.Min(nearest, distance(point, sample))
+		}
+	}
+	return nearest
+}
+
+func TestIsInGamut(t *testing.T) {
+	for _, gamutType := range []GamutType{GamutTypeA, GamutTypeB, GamutTypeC} {
+		gamut := gamutMap[gamutType]
+		tests := []struct {
+			name  string
+			point Coords
+			want  bool
+		}{
+			{name: "centroid", point: centroid(gamut), want: true},
+			{name: "red vertex", point: gamut.Red, want: true},
+			{name: "green vertex", point: gamut.Green, want: true},
+			{name: "blue vertex", point: gamut.Blue, want: true},
+			{name: "red-green edge", point: midpoint(gamut.Red, gamut.Green), want: true},
+			{name: "green-blue edge", point: midpoint(gamut.Green, gamut.Blue), want: true},
+			{name: "blue-red edge", point: midpoint(gamut.Blue, gamut.Red), want: true},
+			{name: "just outside red-green edge", point: outsideEdge(gamut, gamut.Red, gamut.Green, 1e-6)},
+			{name: "just beyond red vertex", point: beyond(gamut, gamut.Red, 1e-6)},
+			{name: "origin", point: Coords{X: 0, Y: 0}},
+			{name: "far outside", point: Coords{X: 1, Y: 1}},
+		}
+		for _, tt := range tests {
+			t.Run(string(gamutType)+"/"+tt.name, func(t *testing.T) {
+				if got := IsInGamut(tt.point, gamut); got != tt.want {
+					t.Errorf("IsInGamut(%v) = %v, want %v", tt.point, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestCorrectToGamut(t *testing.T) {
+	for _, gamutType := range []GamutType{GamutTypeA, GamutTypeB, GamutTypeC} {
+		gamut := gamutMap[gamutType]
+		tests := []struct {
+			name  string
+			point Coords
+			want  *Coords // nil if the nearest point is only checked against sampled edges
+		}{
+			{name: "centroid", point: centroid(gamut), want: ptr(centroid(gamut))},
+			{name: "red vertex", point: gamut.Red, want: ptr(gamut.Red)},
+			{name: "green-blue edge", point: midpoint(gamut.Green, gamut.Blue), want: ptr(midpoint(gamut.Green, gamut.Blue))},
+			{name: "outside red-green edge", point: outsideEdge(gamut, gamut.Red, gamut.Green, 0.05),
+				want: ptr(midpoint(gamut.Red, gamut.Green))},
+			{name: "outside blue-red edge", point: outsideEdge(gamut, gamut.Blue, gamut.Red, 0.05),
+				want: ptr(midpoint(gamut.Blue, gamut.Red))},
+			{name: "beyond red vertex", point: beyond(gamut, gamut.Red, 0.2), want: ptr(gamut.Red)},
+			{name: "beyond green vertex", point: beyond(gamut, gamut.Green, 0.2), want: ptr(gamut.Green)},
+			{name: "beyond blue vertex", point: beyond(gamut, gamut.Blue, 0.2), want: ptr(gamut.Blue)},
+			{name: "origin", point: Coords{X: 0, Y: 0}},
+			{name: "far outside", point: Coords{X: 1, Y: 1}},
+			{name: "negative", point: Coords{X: -0.5, Y: 0.3}},
+		}
+		for _, tt := range tests {
+			t.Run(string(gamutType)+"/"+tt.name, func(t *testing.T) {
+				got := CorrectToGamut(tt.point, gamut)
+				if !IsInGamut(got, gamut) {
+					t.Errorf("CorrectToGamut(%v) = %v, which is outside the gamut", tt.point, got)
+				}
+				if tt.want != nil && distance(got, *tt.want) > coordsTolerance {
+					t.Errorf("CorrectToGamut(%v) = %v, want %v", tt.point, got, *tt.want)
+				}
+				// no point on the edges is closer than the corrected one, up to the sampling resolution
+				if !IsInGamut(tt.point, gamut) && distance(tt.point, got) > nearestOnEdges(tt.point, gamut)+coordsTolerance {
+					t.Errorf("CorrectToGamut(%v) = %v at distance %v, but the nearest edge point is at %v", tt.point,
+						got, distance(tt.point, got), nearestOnEdges(tt.point, gamut))
+				}
+			})
+		}
+	}
+}
+
+func TestCorrectToGamutDegenerate(t *testing.T) {
+	collinear := Gamut{
+		Red:   Coords{X: 0.5, Y: 0.5},
+		Green: Coords{X: 0.3, Y: 0.3},
+		Blue:  Coords{X: 0.1, Y: 0.1},
+	}
+	coincident := Gamut{
+		Red:   Coords{X: 0.3, Y: 0.3},
+		Green: Coords{X: 0.3, Y: 0.3},
+		Blue:  Coords{X: 0.3, Y: 0.3},
+	}
+
+	tests := []struct {
+		name  string
+		gamut Gamut
+		point Coords
+		want  Coords
+	}{
+		{name: "collinear, on the line", gamut: collinear, point: Coords{X: 0.2, Y: 0.2}, want: Coords{X: 0.2, Y: 0.2}},
+		{name: "collinear, beside the line", gamut: collinear, point: Coords{X: 0.2, Y: 0.4},
+			want: Coords{X: 0.3, Y: 0.3}},
+		{name: "collinear, beyond the end", gamut: collinear, point: Coords{X: 0.9, Y: 0.9},
+			want: Coords{X: 0.5, Y: 0.5}},
+		{name: "coincident", gamut: coincident, point: Coords{X: 0.6, Y: 0.1}, want: Coords{X: 0.3, Y: 0.3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if IsInGamut(tt.point, tt.gamut) {
+				t.Errorf("IsInGamut(%v) = true for a degenerate gamut, want false", tt.point)
+			}
+			if got := CorrectToGamut(tt.point, tt.gamut); distance(got, tt.want) > coordsTolerance {
+				t.Errorf("CorrectToGamut(%v) = %v, want %v", tt.point, got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestCtToRGB(t *testing.T) {
+	tests := []struct {
+		name   string
+		kelvin int
+		bri    int
+		sameAs int // kelvin expected to yield the same color, 0 to skip the comparison
+	}{
+		{name: "zero", kelvin: 0, bri: 100, sameAs: minKelvin},
+		{name: "below minimum", kelvin: 999, bri: 100, sameAs: minKelvin},
+		{name: "minimum", kelvin: minKelvin, bri: 100},
+		{name: "warm white", kelvin: 2700, bri: 100},
+		{name: "daylight", kelvin: 6500, bri: 100},
+		{name: "maximum", kelvin: maxKelvin, bri: 100},
+		{name: "above maximum", kelvin: 40001, bri: 100, sameAs: maxKelvin},
+		{name: "negative, log of a negative number is NaN", kelvin: -1000, bri: 100, sameAs: minKelvin},
+		{name: "smallest int", kelvin: math.MinInt, bri: 100, sameAs: minKelvin},
+		{name: "largest int", kelvin: math.MaxInt, bri: 100, sameAs: maxKelvin},
+		{name: "zero brightness", kelvin: 0, bri: 0},
+		{name: "negative brightness", kelvin: 6500, bri: -50},
+		{name: "brightness above 100", kelvin: 6500, bri: 150, sameAs: 6500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, g, b := ctToRGB(tt.kelvin, tt.bri)
+			for _, channel := range []int{r, g, b} {
+				if channel < 0 || channel > 255 {
+					t.Errorf("ctToRGB(%d, %d) = (%d, %d, %d), want channels within 0-255", tt.kelvin, tt.bri, r, g, b)
+				}
+			}
+			if tt.bri <= 0 && (r != 0 || g != 0 || b != 0) {
+				t.Errorf("ctToRGB(%d, %d) = (%d, %d, %d), want black", tt.kelvin, tt.bri, r, g, b)
+			}
+			if tt.sameAs == 0 {
+				return
+			}
+			wantR, wantG, wantB := ctToRGB(tt.sameAs, min(tt.bri, 100))
+			if r != wantR || g != wantG || b != wantB {
+				t.Errorf("ctToRGB(%d, %d) = (%d, %d, %d), want (%d, %d, %d) as for %dK", tt.kelvin, tt.bri, r, g, b,
+					wantR, wantG, wantB, tt.sameAs)
+			}
+		})
+	}
+}
+
+func TestClampChannel(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want float64
+	}{
+		{v: math.NaN(), want: 0},
+		{v: math.Inf(-1), want: 0},
+		{v: math.Inf(1), want: 255},
+		{v: -1, want: 0},
+		{v: 127.5, want: 127.5},
+		{v: 256, want: 255},
+	}
+	for _, tt := range tests {
+		if got := clampChannel(tt.v); got != tt.want {
+			t.Errorf("clampChannel(%v) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+// rgbHue returns the hue of an RGB color in degrees
+func rgbHue(r, g, b int) float64 {
+	rf, gf, bf := float64(r), float64(g), float64(b)
+	hue := math.Atan2(math.Sqrt(3)*(gf-bf), 2*rf-gf-bf) * 180 / math.Pi
+	if hue < 0 {
+		hue += 360
+	}
+	return hue
+}
+
+// hueDistance returns the angle between two hues in degrees
+func hueDistance(a, b float64) float64 {
+	d := math.Abs(a - b)
+	return math.Min(d, 360-d)
+}
+
+func TestRGBToXYRoundTrip(t *testing.T) {
+	gamut := gamutMap[GamutTypeA]
+
+	tests := []struct {
+		name        string
+		rgb         RGBColor
+		inGamut     bool    // whether the color lies within the gamut before correction
+		maxHueShift float64 // in degrees
+	}{
+		// the closest point within the gamut isn't on the line to the white point, so the hue shifts slightly
+		{name: "blue outside gamut", rgb: RGBColor{R: 0, G: 0, B: 255}, maxHueShift: 20},
+		{name: "red", rgb: RGBColor{R: 255, G: 0, B: 0}, inGamut: true, maxHueShift: 1},
+		{name: "green", rgb: RGBColor{R: 0, G: 255, B: 0}, inGamut: true, maxHueShift: 1},
+		{name: "cyan", rgb: RGBColor{R: 0, G: 255, B: 255}, inGamut: true, maxHueShift: 1},
+		{name: "magenta", rgb: RGBColor{R: 255, G: 0, B: 255}, inGamut: true, maxHueShift: 1},
+		{name: "orange", rgb: RGBColor{R: 255, G: 128, B: 0}, inGamut: true, maxHueShift: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wholePlane := Gamut{Red: Coords{X: 1}, Green: Coords{Y: 1}} // contains every valid XY color
+			if uncorrected := RGBToXY(tt.rgb.R, tt.rgb.G, tt.rgb.B, wholePlane); IsInGamut(uncorrected, gamut) != tt.inGamut {
+				t.Fatalf("RGBToXY(%v) = %v before correction, want in gamut A to be %v", tt.rgb, uncorrected, tt.inGamut)
+			}
+
+			xy := RGBToXY(tt.rgb.R, tt.rgb.G, tt.rgb.B, gamut)
+			if !IsInGamut(xy, gamut) {
+				t.Fatalf("RGBToXY(%v) = %v, which is outside gamut A", tt.rgb, xy)
+			}
+
+			r, g, b := coordsToRGB(xy.X, xy.Y, 100, "", gamut, ColorGammaSRGB, BrightnessConversionMultiply)
+			if shift := hueDistance(rgbHue(tt.rgb.R, tt.rgb.G, tt.rgb.B), rgbHue(r, g, b)); shift > tt.maxHueShift {
+				t.Errorf("round trip of %v returned (%d, %d, %d), shifting the hue by %.1f degrees", tt.rgb, r, g, b,
+					shift)
+			}
+		})
+	}
+}
+
+func TestParseBrightnessCurve(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{input: "", want: 1},
+		{input: "linear", want: 1},
+		{input: "Linear", want: 1},
+		{input: "gamma:0.6", want: 0.6},
+		{input: "GAMMA:2.2", want: 2.2},
+		{input: "gamma:1", want: 1},
+		{input: "gamma:0", wantErr: true},
+		{input: "gamma:-1", wantErr: true},
+		{input: "gamma:inf", wantErr: true},
+		{input: "gamma:", wantErr: true},
+		{input: "gamma", wantErr: true},
+		{input: "cubic", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseBrightnessCurve(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseBrightnessCurve(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBrightnessCurve(%q) returned error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBrightnessCurve(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyBrightnessCurve(t *testing.T) {
+	tests := []struct {
+		curve string
+		// want maps the brightness in percent to the brightness after the curve
+		want map[int]int
+	}{
+		{curve: "linear", want: map[int]int{0: 0, 50: 50, 100: 100}},
+		// 0.5^0.6 ≈ 0.660
+		{curve: "gamma:0.6", want: map[int]int{0: 0, 50: 66, 100: 100}},
+		// 0.5^2.2 ≈ 0.218
+		{curve: "gamma:2.2", want: map[int]int{0: 0, 50: 22, 100: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.curve, func(t *testing.T) {
+			gamma, err := ParseBrightnessCurve(tt.curve)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for bri, want := range tt.want {
+				if got := ApplyBrightnessGamma(bri, gamma); got != want {
+					t.Errorf("ApplyBrightnessGamma(%d, %v) = %d, want %d", bri, gamma, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestShiftMirek(t *testing.T) {
+	tests := []struct {
+		name          string
+		mirek, offset int
+		want          int
+	}{
+		{name: "no offset", mirek: 300, offset: 0, want: 300},
+		{name: "warmer", mirek: 300, offset: 50, want: 350},
+		{name: "cooler", mirek: 300, offset: -50, want: 250},
+		{name: "up to the warmest", mirek: 450, offset: 50, want: MirekMax},
+		{name: "clamped to the warmest", mirek: 450, offset: 51, want: MirekMax},
+		{name: "down to the coolest", mirek: 203, offset: -50, want: MirekMin},
+		{name: "clamped to the coolest", mirek: 203, offset: -51, want: MirekMin},
+		{name: "largest offset spans the range", mirek: MirekMin, offset: MirekMax - MirekMin, want: MirekMax},
+		{name: "largest negative offset spans the range", mirek: MirekMax, offset: MirekMin - MirekMax,
+			want: MirekMin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShiftMirek(tt.mirek, tt.offset); got != tt.want {
+				t.Errorf("ShiftMirek(%d, %d) = %d, want %d", tt.mirek, tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseColorGamma(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ColorGamma
+		wantErr bool
+	}{
+		{input: "", want: ColorGammaSRGB},
+		{input: "srgb", want: ColorGammaSRGB},
+		{input: "sRGB", want: ColorGammaSRGB},
+		{input: "linear", want: ColorGammaLinear},
+		{input: "2.2", want: 2.2},
+		{input: "1", want: ColorGammaLinear},
+		{input: "0", wantErr: true},
+		{input: "-2.2", wantErr: true},
+		{input: "inf", wantErr: true},
+		{input: "cubic", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseColorGamma(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseColorGamma(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseColorGamma(%q) returned error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseColorGamma(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorToRGBGamma(t *testing.T) {
+	tests := []struct {
+		name       string
+		gamma      ColorGamma
+		brightness float64
+		want       RGBColor
+	}{
+		{name: "srgb", gamma: ColorGammaSRGB, brightness: 100, want: RGBColor{R: 255, G: 232, B: 123}},
+		{name: "srgb dimmed", gamma: ColorGammaSRGB, brightness: 50, want: RGBColor{R: 250, G: 170, B: 89}},
+		// linear values are darker, as the midtones aren't lifted by a gamma curve
+		{name: "linear", gamma: ColorGammaLinear, brightness: 100, want: RGBColor{R: 255, G: 206, B: 51}},
+		{name: "linear dimmed", gamma: ColorGammaLinear, brightness: 50, want: RGBColor{R: 244, G: 103, B: 25}},
+		// an exponent of 2.2 approximates the sRGB curve
+		{name: "exponent", gamma: 2.2, brightness: 100, want: RGBColor{R: 255, G: 231, B: 122}},
+		{name: "exponent dimmed", gamma: 2.2, brightness: 50, want: RGBColor{R: 250, G: 168, B: 89}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			light := &Light{On: On{On: true}, Dimming: Dimming{Brightness: tt.brightness}, ColorMode: ColorModeXY,
+				Color: Color{XY: Coords{X: 0.4573, Y: 0.41}}}
+			r, g, b := ColorToRGB(light, nil, ConversionOptions{ColorGamma: tt.gamma})
+			if got := (RGBColor{R: r, G: g, B: b}); got != tt.want {
+				t.Errorf("ColorToRGB() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemapBrightness(t *testing.T) {
+	tests := []struct {
+		name           string
+		floor, ceiling int
+		// want maps the brightness in percent to the remapped brightness
+		want map[int]int
+	}{
+		{name: "full range", floor: 0, ceiling: 100, want: map[int]int{0: 0, 1: 1, 50: 50, 100: 100}},
+		// the lowest brightness of a light that is on becomes the floor instead of turning the device off
+		{name: "floor", floor: 10, ceiling: 100, want: map[int]int{0: 10, 1: 11, 50: 55, 100: 100}},
+		{name: "ceiling", floor: 0, ceiling: 60, want: map[int]int{0: 0, 1: 1, 50: 30, 99: 59, 100: 60}},
+		{name: "floor and ceiling", floor: 20, ceiling: 80, want: map[int]int{0: 20, 1: 21, 50: 50, 100: 80}},
+		{name: "out of range brightness is clamped", floor: 20, ceiling: 80, want: map[int]int{-5: 20, 150: 80}},
+		{name: "narrow range", floor: 49, ceiling: 50, want: map[int]int{0: 49, 49: 49, 51: 50, 100: 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for bri, want := range tt.want {
+				if got := RemapBrightness(bri, tt.floor, tt.ceiling); got != want {
+					t.Errorf("RemapBrightness(%d, %d, %d) = %d, want %d", bri, tt.floor, tt.ceiling, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBrightnessConversion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    BrightnessConversion
+		wantErr bool
+	}{
+		{input: "", want: BrightnessConversionLuminance},
+		{input: "luminance", want: BrightnessConversionLuminance},
+		{input: "Multiply", want: BrightnessConversionMultiply},
+		{input: "scale", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseBrightnessConversion(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBrightnessConversion(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBrightnessConversion(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// hueSaturation returns the HSV hue in degrees and the saturation of the color.
+func hueSaturation(r, g, b int) (float64, float64) {
+	maxC, minC := float64(max(r, g, b)), float64(min(r, g, b))
+	if maxC == 0 || maxC == minC {
+		return 0, 0
+	}
+	delta := maxC - minC
+	var h float64
+	switch maxC {
+	case float64(r):
+		h = math.Mod(float64(g-b)/delta, 6)
+	case float64(g):
+		h = float64(b-r)/delta + 2
+	default:
+		h = float64(r-g)/delta + 4
+	}
+	return math.Mod(h*60+360, 360), delta / maxC
+}
+
+func TestBrightnessConversionSaturation(t *testing.T) {
+	colors := map[string]Coords{
+		"orange":     {X: 0.5, Y: 0.44},
+		"warm white": {X: 0.4573, Y: 0.41},
+		"green":      {X: 0.2, Y: 0.6},
+		"blue":       {X: 0.167, Y: 0.04},
+	}
+	for name, xy := range colors {
+		t.Run(name, func(t *testing.T) {
+			// drift returns how far the hue and saturation of the dimmed color are off the color at full brightness
+			drift := func(conversion BrightnessConversion) (float64, float64) {
+				fullHue, fullSaturation := hueSaturation(coordsToRGB(xy.X, xy.Y, 100, "", Gamut{}, ColorGammaSRGB,
+					conversion))
+				dimHue, dimSaturation := hueSaturation(coordsToRGB(xy.X, xy.Y, 20, "", Gamut{}, ColorGammaSRGB,
+					conversion))
+				return math.Abs(dimHue - fullHue), math.Abs(dimSaturation - fullSaturation)
+			}
+
+			hueDrift, saturationDrift := drift(BrightnessConversionMultiply)
+			if hueDrift > 2 || saturationDrift > 0.05 {
+				t.Errorf("multiply shifted the dimmed color's hue by %.1f° and saturation by %.2f, want it kept",
+					hueDrift, saturationDrift)
+			}
+			// scaling the luminance clips channels at full brightness, so the dimmed color looks different
+			if luminanceHueDrift, _ := drift(BrightnessConversionLuminance); luminanceHueDrift <= hueDrift {
+				t.Errorf("luminance shifted the dimmed color's hue by %.1f°, want more than multiply's %.1f°",
+					luminanceHueDrift, hueDrift)
+			}
+		})
+	}
+}
+
+func TestBrightnessConversionMultiplyScalesEvenly(t *testing.T) {
+	// the channels of a dimmed color keep the ratio they have at full brightness
+	r, g, b := coordsToRGB(0.4573, 0.41, 100, "", Gamut{}, ColorGammaSRGB, BrightnessConversionMultiply)
+	dimR, dimG, dimB := coordsToRGB(0.4573, 0.41, 50, "", Gamut{}, ColorGammaSRGB, BrightnessConversionMultiply)
+	for _, c := range [][2]int{{r, dimR}, {g, dimG}, {b, dimB}} {
+		if want := c[0] / 2; c[1] < want-1 || c[1] > want+1 {
+			t.Errorf("dimmed color = (%d, %d, %d), want half of (%d, %d, %d)", dimR, dimG, dimB, r, g, b)
+			break
+		}
+	}
+
+	// black stays black
+	if r, g, b := coordsToRGB(0.4573, 0.41, 0, "", Gamut{}, ColorGammaSRGB, BrightnessConversionMultiply); r+g+b != 0 {
+		t.Errorf("color at zero brightness = (%d, %d, %d), want black", r, g, b)
+	}
+}
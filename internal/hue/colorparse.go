@@ -0,0 +1,52 @@
+package hue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// namedColors are the colors accepted by name by ParseColor
+var namedColors = map[string]RGBColor{
+	"black":   {R: 0, G: 0, B: 0},
+	"white":   {R: 255, G: 255, B: 255},
+	"red":     {R: 255, G: 0, B: 0},
+	"green":   {R: 0, G: 255, B: 0},
+	"blue":    {R: 0, G: 0, B: 255},
+	"yellow":  {R: 255, G: 255, B: 0},
+	"cyan":    {R: 0, G: 255, B: 255},
+	"magenta": {R: 255, G: 0, B: 255},
+	"orange":  {R: 255, G: 165, B: 0},
+	"purple":  {R: 128, G: 0, B: 128},
+	"pink":    {R: 255, G: 192, B: 203},
+	"warm":    {R: 255, G: 180, B: 107}, // roughly 3000K
+}
+
+// ParseColor parses a color given as a hex color (see ParseHexColor) or by name, e.g. "red" or "warm".
+func ParseColor(s string) (RGBColor, error) {
+	if color, ok := namedColors[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return color, nil
+	}
+	color, err := ParseHexColor(s)
+	if err != nil {
+		return RGBColor{}, fmt.Errorf("color %q must be a hex color like #FF8800 or a color name like red", s)
+	}
+	return color, nil
+}
+
+// ParseHexColor parses a hex color in the form "#RRGGBB" or "#RGB", the leading "#" being optional.
+func ParseHexColor(s string) (RGBColor, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return RGBColor{}, fmt.Errorf("color %q must be in the form #RRGGBB or #RGB", s)
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return RGBColor{}, fmt.Errorf("color %q is not a valid hex color", s)
+	}
+	return RGBColor{R: int(value >> 16 & 0xFF), G: int(value >> 8 & 0xFF), B: int(value & 0xFF)}, nil
+}
@@ -0,0 +1,53 @@
+package hue
+
+import "testing"
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    RGBColor
+		wantErr bool
+	}{
+		{input: "#aabbcc", want: RGBColor{R: 0xaa, G: 0xbb, B: 0xcc}},
+		{input: "aabbcc", want: RGBColor{R: 0xaa, G: 0xbb, B: 0xcc}},
+		{input: "#FF8800", want: RGBColor{R: 255, G: 136, B: 0}},
+		{input: "#abc", want: RGBColor{R: 0xaa, G: 0xbb, B: 0xcc}},
+		{input: "abc", want: RGBColor{R: 0xaa, G: 0xbb, B: 0xcc}},
+		{input: "#F80", want: RGBColor{R: 255, G: 136, B: 0}},
+		{input: " #000000 ", want: RGBColor{}},
+		{input: "red", want: RGBColor{R: 255, G: 0, B: 0}},
+		{input: "Red", want: RGBColor{R: 255, G: 0, B: 0}},
+		{input: "WARM", want: RGBColor{R: 255, G: 180, B: 107}},
+		{input: " bLuE ", want: RGBColor{R: 0, G: 0, B: 255}},
+		{input: "reddish", wantErr: true},
+		{input: "chartreuse", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "#", wantErr: true},
+		{input: "#ab", wantErr: true},
+		{input: "#abcd", wantErr: true},
+		{input: "#abcde", wantErr: true},
+		{input: "#aabbccd", wantErr: true},
+		{input: "#aabbccdd", wantErr: true},
+		{input: "#ggg", wantErr: true},
+		{input: "#12345g", wantErr: true},
+		{input: "+12345", wantErr: true},
+		{input: "##abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseColor(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseColor(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseColor(%q) returned error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseColor(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package hue
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/metrics"
+)
+
+// Effect is an effect played by a Hue light, approximated on Govee devices by modulating the color and brightness
+// of the light around its current state
+type Effect struct {
+	Name       string   // name of the Hue effect, e.g. candle
+	Color      RGBColor // color of the light at full brightness
+	Brightness int      // brightness of the light in percent (0-100)
+}
+
+// effectStep is a single state of a synthesized effect, held for the given duration
+type effectStep struct {
+	color      RGBColor
+	brightness int
+	hold       time.Duration
+}
+
+// SetEffect approximates a Hue light effect on a Govee device until it is stopped or replaced. Like dynamic
+// scenes, the effect counts as an active scene of the device.
+func (sc *SceneController) SetEffect(goveeDeviceID string, effect Effect) {
+	sc.StopScene(goveeDeviceID)
+
+	effectCtx, cancel := context.WithCancel(context.Background())
+
+	sc.mu.Lock()
+	sc.activeScenes[goveeDeviceID] = cancel
+	metrics.ActiveScenes.Set(float64(len(sc.activeScenes)))
+	sc.mu.Unlock()
+
+	go sc.runEffect(effectCtx, goveeDeviceID, effect)
+}
+
+// runEffect sends the steps of a synthesized effect to a Govee device until ctx is done.
+func (sc *SceneController) runEffect(ctx context.Context, goveeDeviceID string, effect Effect) {
+	sc.logger.Info().Str("deviceId", goveeDeviceID).Str("effect", effect.Name).Msg("Starting effect")
+
+	sc.setColor(goveeDeviceID, effect.Color)
+	current := effect.Color
+	for i := 0; ; i++ {
+		step := nextEffectStep(effect, i)
+		if step.color != current {
+			sc.setColor(goveeDeviceID, step.color)
+			current = step.color
+		}
+		if err := sc.goveeClient.SetBrightness(goveeDeviceID, step.brightness); err != nil {
+			sc.logger.Error().Err(err).Str("deviceId", goveeDeviceID).Msg("Failed to set Govee brightness")
+		}
+
+		select {
+		case <-ctx.Done():
+			sc.logger.Info().Str("deviceId", goveeDeviceID).Str("effect", effect.Name).Msg("Stopping effect")
+			return
+		case <-time.After(step.hold):
+		}
+	}
+}
+
+// nextEffectStep returns the i-th step of the effect. Candles flicker gently, fire flickers stronger towards red,
+// sparkle flashes at random and any other effect slowly breathes.
+func nextEffectStep(effect Effect, i int) effectStep {
+	dim := func(factor float64) int {
+		return int(math.Round(clamp(float64(effect.Brightness)*factor, 1, 100)))
+	}
+	randomHold := func(minMs, maxMs int) time.Duration {
+		return time.Duration(minMs+rand.IntN(maxMs-minMs+1)) * time.Millisecond
+	}
+
+	switch effect.Name {
+	case "candle":
+		return effectStep{color: effect.Color, brightness: dim(0.75 + 0.25*rand.Float64()), hold: randomHold(150, 400)}
+	case "fire":
+		ember := RGBColor{R: effect.Color.R, G: effect.Color.G / 3, B: 0}
+		return effectStep{
+			color:      lerpRGB(effect.Color, ember, rand.Float64()),
+			brightness: dim(0.6 + 0.4*rand.Float64()),
+			hold:       randomHold(100, 300),
+		}
+	case "sparkle":
+		if rand.Float64() < 0.2 {
+			return effectStep{color: effect.Color, brightness: 100, hold: 100 * time.Millisecond}
+		}
+		return effectStep{color: effect.Color, brightness: dim(0.6), hold: randomHold(150, 350)}
+	default:
+		// one breath takes 16 steps of 250ms
+		phase := float64(i%16) / 16 * 2 * math.Pi
+		return effectStep{color: effect.Color, brightness: dim(0.85 + 0.15*math.Cos(phase)), hold: 250 * time.Millisecond}
+	}
+}
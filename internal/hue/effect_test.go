@@ -0,0 +1,109 @@
+package hue
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/rs/zerolog"
+)
+
+func TestActiveEffect(t *testing.T) {
+	tests := []struct {
+		name    string
+		effects string
+		want    string
+		wantOK  bool
+	}{
+		{name: "not supported", effects: ""},
+		{name: "no effect", effects: `,"effects":{"status":"no_effect"}`},
+		{name: "candle", effects: `,"effects":{"status":"candle"}`, want: "candle", wantOK: true},
+		{name: "fire", effects: `,"effects":{"status":"fire","status_values":["no_effect","candle","fire"]}`,
+			want: "fire", wantOK: true},
+		{name: "sparkle", effects: `,"effects":{"status":"sparkle"}`, want: "sparkle", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var light Light
+			data := `{"id":"light-1","on":{"on":true},"color":{"xy":{"x":0.5,"y":0.4}}` + tt.effects + `}`
+			if err := json.Unmarshal([]byte(data), &light); err != nil {
+				t.Fatal(err)
+			}
+			got, ok := light.ActiveEffect()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ActiveEffect() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+			wantMode := ColorModeXY
+			if tt.wantOK {
+				wantMode = ColorModeEffect
+			}
+			if light.ColorMode != wantMode {
+				t.Errorf("ColorMode = %q, want %q", light.ColorMode, wantMode)
+			}
+		})
+	}
+}
+
+func TestNextEffectStep(t *testing.T) {
+	red := RGBColor{R: 255, G: 90, B: 30}
+	for _, name := range []string{"candle", "fire", "sparkle", "prism"} {
+		t.Run(name, func(t *testing.T) {
+			effect := Effect{Name: name, Color: red, Brightness: 80}
+			for i := range 100 {
+				step := nextEffectStep(effect, i)
+				if step.brightness < 1 || step.brightness > 100 {
+					t.Fatalf("step %d brightness = %d, want 1-100", i, step.brightness)
+				}
+				if step.hold <= 0 || step.hold > 500*time.Millisecond {
+					t.Fatalf("step %d hold = %v, want a short positive duration", i, step.hold)
+				}
+				// fire flickers towards a red ember, the other effects keep the light's color
+				if name == "fire" {
+					if step.color.R != red.R || step.color.G < red.G/3 || step.color.G > red.G || step.color.B > red.B {
+						t.Fatalf("step %d color = %+v, want between %+v and its ember", i, step.color, red)
+					}
+				} else if step.color != red {
+					t.Fatalf("step %d color = %+v, want %+v", i, step.color, red)
+				}
+			}
+		})
+	}
+
+	// effects without an approximation of their own breathe around the light's brightness
+	effect := Effect{Name: "prism", Color: red, Brightness: 80}
+	if first, full := nextEffectStep(effect, 0), nextEffectStep(effect, 16); first != full {
+		t.Errorf("breathing step 16 = %+v, want it to repeat step 0 %+v", full, first)
+	}
+	if got := nextEffectStep(effect, 0).brightness; got != 80 {
+		t.Errorf("breathing starts at brightness %d, want the light's 80", got)
+	}
+	if got := nextEffectStep(effect, 8).brightness; got != 56 {
+		t.Errorf("breathing is at brightness %d halfway, want 70%% of the light's brightness", got)
+	}
+}
+
+func TestSetEffect(t *testing.T) {
+	recorder := &colorRecorder{}
+	// the Govee device is unknown, so only the colors are recorded
+	sc := NewSceneController(govee.NewClient(zerolog.Nop(), govee.DefaultMulticastIP), zerolog.Nop())
+	sc.sendColor = recorder.sendColor
+
+	candle := Effect{Name: "candle", Color: RGBColor{R: 255, G: 147, B: 41}, Brightness: 60}
+	sc.SetEffect("AA:BB", candle)
+	if !sc.IsActive("AA:BB") {
+		t.Fatal("no scene is active after SetEffect(), want the effect counted as a scene")
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.sent()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if colors := recorder.sent(); len(colors) == 0 || colors[0] != candle.Color {
+		t.Errorf("sent %v, want the effect's color first", colors)
+	}
+
+	sc.StopScene("AA:BB")
+	if sc.IsActive("AA:BB") {
+		t.Error("effect is still active after StopScene()")
+	}
+}
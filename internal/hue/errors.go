@@ -0,0 +1,115 @@
+package hue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrLightNotFound is returned when the bridge doesn't know a light, usually because of a misconfigured ID
+	ErrLightNotFound = errors.New("light not found")
+	// ErrSceneNotFound is returned when no matching scene is found
+	ErrSceneNotFound = errors.New("scene not found")
+	// ErrBridgeNotDiscovered is returned when a request is sent before the bridge was discovered
+	ErrBridgeNotDiscovered = errors.New("hue bridge not discovered yet")
+	// ErrBridgeUnreachable is returned when the bridge didn't respond to a request, usually a transient network error
+	ErrBridgeUnreachable = errors.New("hue bridge unreachable")
+	// ErrCertificateMismatch is returned when the bridge presents a certificate not issued to the configured bridge ID
+	ErrCertificateMismatch = errors.New("hue bridge certificate mismatch")
+)
+
+// IsCertificateMismatch checks if the error is caused by a certificate not issued to the configured bridge
+func IsCertificateMismatch(err error) bool {
+	return errors.Is(err, ErrCertificateMismatch)
+}
+
+// IsLightNotFound checks if the error is caused by a light unknown to the bridge
+func IsLightNotFound(err error) bool {
+	return errors.Is(err, ErrLightNotFound)
+}
+
+// IsTransient checks if the error is caused by a temporary condition worth retrying, in contrast to a
+// misconfiguration
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrBridgeUnreachable) || errors.Is(err, ErrBridgeNotDiscovered) || IsRateLimited(err)
+}
+
+// APIErrorDetail is a single error reported in the errors array of a Hue API response
+type APIErrorDetail struct {
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description"`
+}
+
+// APIError is returned when the bridge responds with an unsuccessful status, or reports errors instead of data
+type APIError struct {
+	// StatusCode is the status of the response, zero if the errors were reported in a successful response
+	StatusCode int
+	Errors     []APIErrorDetail
+}
+
+func (e *APIError) Error() string {
+	descriptions := make([]string, 0, len(e.Errors))
+	for _, detail := range e.Errors {
+		descriptions = append(descriptions, detail.Description)
+	}
+
+	msg := "hue API error"
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(": %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if len(descriptions) > 0 {
+		msg += ": " + strings.Join(descriptions, "; ")
+	}
+	return msg
+}
+
+// newAPIError creates an APIError from an unsuccessful response, parsing the errors array of its body.
+func newAPIError(statusCode int, body []byte) *APIError {
+	var hueResp hueResponse[json.RawMessage]
+	_ = json.Unmarshal(body, &hueResp) // the details are optional, the status code is enough
+	return &APIError{StatusCode: statusCode, Errors: hueResp.Errors}
+}
+
+// ErrRateLimited is returned when the bridge rejects a request with 429 Too Many Requests
+var ErrRateLimited = errors.New("rate limited by Hue bridge")
+
+// RateLimitError is returned when the bridge rejects a request with 429 Too Many Requests. It matches
+// ErrRateLimited with errors.Is.
+type RateLimitError struct {
+	// RetryAfter is the delay requested by the bridge's Retry-After header, zero if absent
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s, retry after %s", ErrRateLimited, e.RetryAfter)
+	}
+	return ErrRateLimited.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// IsRateLimited checks if the error is caused by the bridge rate limiting requests
+func IsRateLimited(err error) bool {
+	return errors.Is(err, ErrRateLimited)
+}
+
+// newRateLimitError creates a RateLimitError from a 429 response, honoring its Retry-After header given
+// either in seconds or as an HTTP date.
+func newRateLimitError(resp *http.Response) *RateLimitError {
+	retryAfter := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+		return &RateLimitError{RetryAfter: time.Duration(seconds) * time.Second}
+	}
+	if date, err := http.ParseTime(retryAfter); err == nil {
+		return &RateLimitError{RetryAfter: max(time.Until(date), 0)}
+	}
+	return &RateLimitError{}
+}
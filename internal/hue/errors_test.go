@@ -0,0 +1,120 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantIs     error
+		wantStatus int // status of the wrapped APIError, zero if none is wrapped
+	}{
+		{name: "light not found", status: http.StatusNotFound,
+			body:   `{"errors":[{"description":"Not Found"}],"data":[]}`,
+			wantIs: ErrLightNotFound, wantStatus: http.StatusNotFound},
+		{name: "unauthorized", status: http.StatusForbidden,
+			body:       `{"errors":[{"description":"unauthorized user"}],"data":[]}`,
+			wantStatus: http.StatusForbidden},
+		{name: "server error", status: http.StatusInternalServerError, wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+
+			_, err := client.GetLight(context.Background(), "light-1")
+			if err == nil {
+				t.Fatal("GetLight() returned no error")
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("GetLight() = %v, want %v", err, tt.wantIs)
+			}
+			if tt.wantIs == nil && IsLightNotFound(err) {
+				t.Errorf("GetLight() = %v, want a permanent error other than a missing light", err)
+			}
+			if IsTransient(err) {
+				t.Errorf("GetLight() = %v, want a permanent error", err)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.wantStatus {
+				t.Errorf("GetLight() = %v, want an APIError with status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestClientTransientErrors(t *testing.T) {
+	notDiscovered := NewClient("", "test-user", zerolog.Nop())
+	if _, err := notDiscovered.GetLight(context.Background(), "light-1"); !errors.Is(err, ErrBridgeNotDiscovered) ||
+		!IsTransient(err) {
+		t.Errorf("GetLight() before discovery = %v, want a transient ErrBridgeNotDiscovered", err)
+	}
+
+	unreachable := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler) // drops the connection without a response
+	})
+	if _, err := unreachable.GetLight(context.Background(), "light-1"); !errors.Is(err, ErrBridgeUnreachable) ||
+		!IsTransient(err) {
+		t.Errorf("GetLight() without response = %v, want a transient ErrBridgeUnreachable", err)
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  APIError
+		want string
+	}{
+		{err: APIError{StatusCode: http.StatusForbidden}, want: "hue API error: 403 Forbidden"},
+		{err: APIError{Errors: []APIErrorDetail{{Description: "device is unreachable"}}},
+			want: "hue API error: device is unreachable"},
+		{err: APIError{StatusCode: http.StatusBadRequest,
+			Errors: []APIErrorDetail{{Description: "invalid body"}, {Description: "invalid value"}}},
+			want: "hue API error: 400 Bad Request: invalid body; invalid value"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestErrorDescriptions(t *testing.T) {
+	const errorsOnly = `{"errors":[{"description":"device (light) has communication issues"}],"data":[]}`
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(errorsOnly))
+	})
+
+	_, err := client.GetLight(context.Background(), "light-1")
+	if !IsLightNotFound(err) || !strings.Contains(err.Error(), "device (light) has communication issues") {
+		t.Errorf("GetLight() = %v, want ErrLightNotFound with the description of the bridge", err)
+	}
+	_, err = client.GetRooms(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "device (light) has communication issues") {
+		t.Errorf("GetRooms() = %v, want an error with the description of the bridge", err)
+	}
+}
+
+func TestPartialData(t *testing.T) {
+	// a 207 Multi-Status response with data next to errors is a successful response
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`{"errors":[{"description":"partial failure"}],"data":[{"id":"light-1"}]}`))
+	})
+
+	light, err := client.GetLight(context.Background(), "light-1")
+	if err != nil || light.ID != "light-1" {
+		t.Errorf("GetLight() = %+v, %v, want the light of the partial response", light, err)
+	}
+}
@@ -0,0 +1,169 @@
+package hue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// eventStreamMinBackoff is the delay before the first reconnect of a dropped event stream
+	eventStreamMinBackoff = time.Second
+	// eventStreamMaxBackoff caps the delay between reconnects of the event stream
+	eventStreamMaxBackoff = 30 * time.Second
+)
+
+// LightUpdate is a change of a light received from the event stream, nil fields didn't change
+type LightUpdate struct {
+	ID               string            `json:"id"`
+	On               *On               `json:"on,omitempty"`
+	Dimming          *Dimming          `json:"dimming,omitempty"`
+	ColorTemperature *ColorTemperature `json:"color_temperature,omitempty"`
+	Color            *ColorUpdate      `json:"color,omitempty"`
+	Dynamics         *Dynamics         `json:"dynamics,omitempty"`
+	Gradient         *Gradient         `json:"gradient,omitempty"`
+}
+
+// event is a single event of the Hue V2 event stream
+type event struct {
+	Type string            `json:"type"`
+	Data []json.RawMessage `json:"data"`
+}
+
+// eventResource is the common part of all resources contained in an event
+type eventResource struct {
+	Type string `json:"type"`
+}
+
+// StreamEvents opens the Hue V2 event stream and emits every light update until the context is done.
+// A dropped connection is reopened with an increasing backoff. An error is only returned if the initial
+// connection fails, e.g. because the bridge doesn't support the event stream.
+func (c *Client) StreamEvents(ctx context.Context) (<-chan LightUpdate, error) {
+	body, err := c.openEventStream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Hue event stream: %w", err)
+	}
+
+	updates := make(chan LightUpdate, 16)
+	go func() {
+		defer close(updates)
+
+		backoff := eventStreamMinBackoff
+		for {
+			err := parseEventStream(body, func(update LightUpdate) {
+				select {
+				case updates <- update:
+				case <-ctx.Done():
+				}
+			})
+			body.Close()
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Warn().Err(err).Dur("backoff", backoff).Msg("Hue event stream disconnected, reconnecting")
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, eventStreamMaxBackoff)
+
+				body, err = c.openEventStream(ctx)
+				if err == nil {
+					c.logger.Info().Msg("Reconnected to Hue event stream")
+					backoff = eventStreamMinBackoff
+					break
+				}
+				c.logger.Warn().Err(err).Dur("backoff", backoff).Msg("Failed to reconnect to Hue event stream")
+			}
+		}
+	}()
+	return updates, nil
+}
+
+// openEventStream connects to the event stream of the bridge and returns the response body.
+func (c *Client) openEventStream(ctx context.Context) (io.ReadCloser, error) {
+	url := bridgeURL(c.BridgeAddress(), "/eventstream/clip/v2")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// parseEventStream reads server-sent events from r and calls emit for every light update until r is
+// exhausted. Frames that aren't valid events are skipped.
+func parseEventStream(r io.Reader, emit func(LightUpdate)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case len(line) == 0:
+			if data.Len() > 0 {
+				for _, update := range parseEventData(data.Bytes()) {
+					emit(update)
+				}
+				data.Reset()
+			}
+		case bytes.HasPrefix(line, []byte("data:")):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" ")))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("event stream closed")
+}
+
+// parseEventData returns all light updates contained in the data of a single server-sent event.
+func parseEventData(data []byte) []LightUpdate {
+	var events []event
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil
+	}
+
+	var updates []LightUpdate
+	for _, e := range events {
+		if e.Type != "update" {
+			continue
+		}
+		for _, raw := range e.Data {
+			var resource eventResource
+			if err := json.Unmarshal(raw, &resource); err != nil || resource.Type != "light" {
+				continue
+			}
+			var update LightUpdate
+			if err := json.Unmarshal(raw, &update); err != nil {
+				continue
+			}
+			updates = append(updates, update)
+		}
+	}
+	return updates
+}
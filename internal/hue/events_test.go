@@ -0,0 +1,85 @@
+package hue
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEventStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []LightUpdate
+	}{
+		{
+			name: "single line data",
+			stream: "id: 1700000000:0\n" +
+				`data: [{"type":"update","data":[{"id":"light-1","type":"light","on":{"on":true},"dimming":{"brightness":42.5}}]}]` + "\n\n",
+			want: []LightUpdate{{ID: "light-1", On: &On{On: true}, Dimming: &Dimming{Brightness: 42.5}}},
+		},
+		{
+			name: "multi-line data",
+			stream: "data: [{\"type\":\"update\",\n" +
+				"data: \"data\":[{\"id\":\"light-1\",\"type\":\"light\",\n" +
+				"data:\"color\":{\"xy\":{\"x\":0.3,\"y\":0.6}}}]}]\n\n",
+			want: []LightUpdate{{ID: "light-1", Color: &ColorUpdate{XY: Coords{X: 0.3, Y: 0.6}}}},
+		},
+		{
+			name: "comment lines",
+			stream: ": hi\n\n" +
+				": keep-alive\n" +
+				`data: [{"type":"update","data":[{"id":"light-1","type":"light","on":{"on":false}}]}]` + "\n" +
+				": between data and the end of the event\n\n",
+			want: []LightUpdate{{ID: "light-1", On: &On{On: false}}},
+		},
+		{
+			name: "CRLF line endings",
+			stream: `data: [{"type":"update","data":[{"id":"light-1","type":"light","on":{"on":true}}]}]` + "\r\n\r\n" +
+				`data: [{"type":"update","data":[{"id":"light-2","type":"light","on":{"on":false}}]}]` + "\r\n\r\n",
+			want: []LightUpdate{{ID: "light-1", On: &On{On: true}}, {ID: "light-2", On: &On{On: false}}},
+		},
+		{
+			name: "multiple updates and resources in one event",
+			stream: `data: [{"type":"update","data":[` +
+				`{"id":"light-1","type":"light","color_temperature":{"mirek":300}},` +
+				`{"id":"group-1","type":"grouped_light","on":{"on":true}},` +
+				`{"id":"light-2","type":"light","dimming":{"brightness":10}}]},` +
+				`{"type":"add","data":[{"id":"light-3","type":"light","on":{"on":true}}]}]` + "\n\n",
+			want: []LightUpdate{
+				{ID: "light-1", ColorTemperature: &ColorTemperature{Mirek: 300}},
+				{ID: "light-2", Dimming: &Dimming{Brightness: 10}},
+			},
+		},
+		{
+			name: "invalid event skipped",
+			stream: "data: [{\"type\":\"update\",\n\n" +
+				`data: [{"type":"update","data":[{"id":"light-1","type":"light","on":{"on":true}}]}]` + "\n\n",
+			want: []LightUpdate{{ID: "light-1", On: &On{On: true}}},
+		},
+		{
+			name: "truncated frame",
+			stream: `data: [{"type":"update","data":[{"id":"light-1","type":"light","on":{"on":true}}]}]` + "\n\n" +
+				`data: [{"type":"update","data":[{"id":"light-2","type":"light","on":{"on":true}}]}]` + "\n",
+			want: []LightUpdate{{ID: "light-1", On: &On{On: true}}},
+		},
+		{
+			name:   "truncated data line",
+			stream: `data: [{"type":"update","data":[{"id":"light-1","type":"li`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []LightUpdate
+			err := parseEventStream(strings.NewReader(tt.stream), func(update LightUpdate) {
+				got = append(got, update)
+			})
+			if err == nil {
+				t.Error("parseEventStream() returned nil, want an error once the stream is exhausted")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEventStream() emitted %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package hue
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GradientPointMode selects which point of a gradient drives a single-color device
+type GradientPointMode string
+
+const (
+	GradientPointAverage  GradientPointMode = "average"
+	GradientPointDominant GradientPointMode = "dominant"
+	GradientPointFirst    GradientPointMode = "first"
+	GradientPointLast     GradientPointMode = "last"
+	GradientPointIndex    GradientPointMode = "index"
+)
+
+// dominantDistance is the maximum xy distance for two gradient points to be considered the same color
+const dominantDistance = 0.05
+
+// GradientPointSelector selects a single point of a gradient
+type GradientPointSelector struct {
+	Mode  GradientPointMode
+	Index int // only used with GradientPointIndex
+}
+
+// ParseGradientPointSelector parses a selector in the form "average", "dominant", "first", "last" or "index:N".
+// An empty string selects the average.
+func ParseGradientPointSelector(s string) (GradientPointSelector, error) {
+	switch mode := GradientPointMode(strings.ToLower(s)); mode {
+	case "":
+		return GradientPointSelector{Mode: GradientPointAverage}, nil
+	case GradientPointAverage, GradientPointDominant, GradientPointFirst, GradientPointLast:
+		return GradientPointSelector{Mode: mode}, nil
+	}
+
+	indexStr, ok := strings.CutPrefix(strings.ToLower(s), string(GradientPointIndex)+":")
+	if !ok {
+		return GradientPointSelector{}, fmt.Errorf("invalid gradient point %q, must be one of average, dominant, "+
+			"first, last or index:N", s)
+	}
+	index, err := strconv.Atoi(indexStr)
+	if err != nil || index < 0 {
+		return GradientPointSelector{}, fmt.Errorf("invalid gradient point index %q", indexStr)
+	}
+	return GradientPointSelector{Mode: GradientPointIndex, Index: index}, nil
+}
+
+// Select returns the coordinates of the selected gradient point. It returns false if the gradient has no points.
+// An index beyond the number of points selects the last point.
+func (s GradientPointSelector) Select(points []GradientPoint) (Coords, bool) {
+	if len(points) == 0 {
+		return Coords{}, false
+	}
+
+	switch s.Mode {
+	case GradientPointFirst:
+		return points[0].Color.XY, true
+	case GradientPointLast:
+		return points[len(points)-1].Color.XY, true
+	case GradientPointIndex:
+		return points[min(s.Index, len(points)-1)].Color.XY, true
+	case GradientPointDominant:
+		return dominantGradientPoint(points), true
+	default:
+		return averageGradientPoint(points), true
+	}
+}
+
+// averageGradientPoint returns the average coordinates of all gradient points.
+func averageGradientPoint(points []GradientPoint) Coords {
+	var avg Coords
+	for _, point := range points {
+		avg.X += point.Color.XY.X
+		avg.Y += point.Color.XY.Y
+	}
+	avg.X /= float64(len(points))
+	avg.Y /= float64(len(points))
+	return avg
+}
+
+// dominantGradientPoint returns the point sharing its color with the most other points, the first one on ties.
+func dominantGradientPoint(points []GradientPoint) Coords {
+	best, bestCount := 0, -1
+	for i, point := range points {
+		count := 0
+		for _, other := range points {
+			if math.Hypot(point.Color.XY.X-other.Color.XY.X, point.Color.XY.Y-other.Color.XY.Y) <= dominantDistance {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+	return points[best].Color.XY
+}
+
+// GradientToRGBs converts every point of a gradient light to RGB, in the order of the gradient. Lights
+// without a gradient result in their single color.
+func GradientToRGBs(light *Light, fixedBrightness *int, opts ConversionOptions) []RGBColor {
+	if light.Gradient == nil || len(light.Gradient.Points) == 0 || light.ColorMode == ColorModeCT {
+		r, g, b := ColorToRGB(light, fixedBrightness, opts)
+		return []RGBColor{{R: r, G: g, B: b}}
+	}
+
+	colors := make([]RGBColor, len(light.Gradient.Points))
+	for i, point := range light.Gradient.Points {
+		pointLight := *light
+		pointLight.Color.XY = point.Color.XY
+		r, g, b := ColorToRGB(&pointLight, fixedBrightness, opts)
+		colors[i] = RGBColor{R: r, G: g, B: b}
+	}
+	return colors
+}
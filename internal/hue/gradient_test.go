@@ -0,0 +1,135 @@
+package hue
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+// gradientPoints returns gradient points with the given coordinates.
+func gradientPoints(coords ...Coords) []GradientPoint {
+	points := make([]GradientPoint, len(coords))
+	for i, xy := range coords {
+		points[i].Color.XY = xy
+	}
+	return points
+}
+
+func TestParseGradientPointSelector(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    GradientPointSelector
+		wantErr bool
+	}{
+		{s: "", want: GradientPointSelector{Mode: GradientPointAverage}},
+		{s: "average", want: GradientPointSelector{Mode: GradientPointAverage}},
+		{s: "Dominant", want: GradientPointSelector{Mode: GradientPointDominant}},
+		{s: "first", want: GradientPointSelector{Mode: GradientPointFirst}},
+		{s: "last", want: GradientPointSelector{Mode: GradientPointLast}},
+		{s: "index:0", want: GradientPointSelector{Mode: GradientPointIndex, Index: 0}},
+		{s: "INDEX:3", want: GradientPointSelector{Mode: GradientPointIndex, Index: 3}},
+		{s: "index:-1", wantErr: true},
+		{s: "index:two", wantErr: true},
+		{s: "index", wantErr: true},
+		{s: "middle", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseGradientPointSelector(tt.s)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseGradientPointSelector(%q) = %+v, want an error", tt.s, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseGradientPointSelector(%q) = %+v, %v, want %+v", tt.s, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGradientPointSelectorSelect(t *testing.T) {
+	red, green, blue := Coords{X: 0.675, Y: 0.322}, Coords{X: 0.409, Y: 0.518}, Coords{X: 0.167, Y: 0.04}
+	nearBlue := Coords{X: 0.17, Y: 0.05}
+	points := gradientPoints(red, blue, green, nearBlue)
+
+	tests := []struct {
+		name     string
+		selector GradientPointSelector
+		points   []GradientPoint
+		want     Coords
+	}{
+		{name: "average", selector: GradientPointSelector{Mode: GradientPointAverage}, points: points,
+			want: Coords{X: (0.675 + 0.167 + 0.409 + 0.17) / 4, Y: (0.322 + 0.04 + 0.518 + 0.05) / 4}},
+		{name: "first", selector: GradientPointSelector{Mode: GradientPointFirst}, points: points, want: red},
+		{name: "last", selector: GradientPointSelector{Mode: GradientPointLast}, points: points, want: nearBlue},
+		{name: "index", selector: GradientPointSelector{Mode: GradientPointIndex, Index: 2}, points: points,
+			want: green},
+		{name: "index beyond the points selects the last",
+			selector: GradientPointSelector{Mode: GradientPointIndex, Index: 9}, points: points, want: nearBlue},
+		{name: "dominant", selector: GradientPointSelector{Mode: GradientPointDominant}, points: points,
+			want: blue},
+		{name: "dominant tie selects the first",
+			selector: GradientPointSelector{Mode: GradientPointDominant}, points: gradientPoints(green, red),
+			want: green},
+		{name: "single point", selector: GradientPointSelector{Mode: GradientPointAverage},
+			points: gradientPoints(red), want: red},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.selector.Select(tt.points)
+			if !ok || distance(got, tt.want) > coordsTolerance {
+				t.Errorf("Select() = %+v, %v, want %+v", got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := (GradientPointSelector{Mode: GradientPointFirst}).Select(nil); ok {
+		t.Error("Select() of an empty gradient = true, want false")
+	}
+}
+
+func TestGradientToRGBs(t *testing.T) {
+	const gradientLight = `{"id":"light-1","on":{"on":true},"dimming":{"brightness":100},` +
+		`"color":{"xy":{"x":0.4,"y":0.4},"gamut_type":"C"},` +
+		`"gradient":{"points":[{"color":{"xy":{"x":0.675,"y":0.322}}},{"color":{"xy":{"x":0.17,"y":0.7}}},` +
+		`{"color":{"xy":{"x":0.1532,"y":0.0475}}}],"points_capable":5}}`
+	var resp hueResponse[Light]
+	if err := json.Unmarshal([]byte(`{"errors":[],"data":[`+gradientLight+`]}`), &resp); err != nil {
+		t.Fatal(err)
+	}
+	light := resp.Data[0]
+	fullBrightness := 100
+	var opts ConversionOptions
+
+	// rgbAt converts the light with the given color to RGB
+	rgbAt := func(light Light, xy Coords) RGBColor {
+		light.Color.XY = xy
+		r, g, b := ColorToRGB(&light, &fullBrightness, opts)
+		return RGBColor{R: r, G: g, B: b}
+	}
+
+	var want []RGBColor
+	for _, point := range light.Gradient.Points {
+		want = append(want, rgbAt(light, point.Color.XY))
+	}
+	if got := GradientToRGBs(&light, &fullBrightness, opts); !slices.Equal(got, want) {
+		t.Errorf("GradientToRGBs() = %v, want one color per gradient point %v", got, want)
+	}
+
+	// a light without gradient and a light showing a color temperature fall back to their single color
+	plain := light
+	plain.Gradient = nil
+	single := []RGBColor{rgbAt(plain, plain.Color.XY)}
+	if got := GradientToRGBs(&plain, &fullBrightness, opts); !slices.Equal(got, single) {
+		t.Errorf("GradientToRGBs() of a light without gradient = %v, want its single color", got)
+	}
+	ct := light
+	ct.ColorMode = ColorModeCT
+	ct.ColorTemperature = ColorTemperature{Mirek: 366, MirekValid: true}
+	r, g, b := ColorToRGB(&ct, &fullBrightness, opts)
+	if got := GradientToRGBs(&ct, &fullBrightness, opts); !slices.Equal(got, []RGBColor{{R: r, G: g, B: b}}) {
+		t.Errorf("GradientToRGBs() of a light showing a color temperature = %v, want its single color", got)
+	}
+}
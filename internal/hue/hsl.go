@@ -0,0 +1,78 @@
+package hue
+
+import (
+	"math"
+)
+
+// RGBToHSL converts an RGB color (0-255 per channel) to HSL, with hue in degrees [0, 360) and
+// saturation and lightness in the range [0, 1].
+func RGBToHSL(r, g, b int) (float64, float64, float64) {
+	rf, gf, bf := float64(r)/255.0, float64(g)/255.0, float64(b)/255.0
+
+	maxC := math.Max(rf, math.Max(gf, bf))
+	minC := math.Min(rf, math.Min(gf, bf))
+	l := (maxC + minC) / 2
+
+	delta := maxC - minC
+	if delta == 0 {
+		return 0, 0, l
+	}
+
+	s := delta / (1 - math.Abs(2*l-1))
+
+	var h float64
+	switch maxC {
+	case rf:
+		h = math.Mod((gf-bf)/delta, 6)
+	case gf:
+		h = (bf-rf)/delta + 2
+	default:
+		h = (rf-gf)/delta + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+
+	return h, s, l
+}
+
+// HSLToRGB converts an HSL color to RGB (0-255 per channel). Hue is in degrees and wrapped into [0, 360),
+// saturation and lightness are clamped to [0, 1].
+func HSLToRGB(h, s, l float64) (int, int, int) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	s = clamp(s, 0, 1)
+	l = clamp(l, 0, 1)
+
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var rf, gf, bf float64
+	switch {
+	case h < 60:
+		rf, gf, bf = c, x, 0
+	case h < 120:
+		rf, gf, bf = x, c, 0
+	case h < 180:
+		rf, gf, bf = 0, c, x
+	case h < 240:
+		rf, gf, bf = 0, x, c
+	case h < 300:
+		rf, gf, bf = x, 0, c
+	default:
+		rf, gf, bf = c, 0, x
+	}
+
+	return int(math.Round((rf + m) * 255)), int(math.Round((gf + m) * 255)), int(math.Round((bf + m) * 255))
+}
+
+// DeriveColor derives an accent color from an RGB color by shifting its hue (in degrees) and scaling its
+// saturation and lightness in HSL space.
+func DeriveColor(r, g, b int, hueShift, saturationScale, lightnessScale float64) (int, int, int) {
+	h, s, l := RGBToHSL(r, g, b)
+	return HSLToRGB(h+hueShift, s*saturationScale, l*lightnessScale)
+}
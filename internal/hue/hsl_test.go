@@ -0,0 +1,102 @@
+package hue
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRGBToHSL(t *testing.T) {
+	tests := []struct {
+		name    string
+		r, g, b int
+		h, s, l float64
+	}{
+		{name: "black", r: 0, g: 0, b: 0, h: 0, s: 0, l: 0},
+		{name: "white", r: 255, g: 255, b: 255, h: 0, s: 0, l: 1},
+		{name: "gray", r: 128, g: 128, b: 128, h: 0, s: 0, l: 128.0 / 255},
+		{name: "red", r: 255, g: 0, b: 0, h: 0, s: 1, l: 0.5},
+		{name: "green", r: 0, g: 255, b: 0, h: 120, s: 1, l: 0.5},
+		{name: "blue", r: 0, g: 0, b: 255, h: 240, s: 1, l: 0.5},
+		{name: "magenta wraps below 360", r: 255, g: 0, b: 128, h: 330 - 0.118, s: 1, l: 0.5},
+		{name: "dark orange", r: 128, g: 64, b: 0, h: 30, s: 1, l: 64.0 / 255},
+		{name: "pastel cyan", r: 128, g: 255, b: 255, h: 180, s: 1, l: 383.0 / 510},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, s, l := RGBToHSL(tt.r, tt.g, tt.b)
+			if math.Abs(h-tt.h) > 0.01 || math.Abs(s-tt.s) > 0.001 || math.Abs(l-tt.l) > 0.001 {
+				t.Errorf("RGBToHSL(%d, %d, %d) = (%.3f, %.3f, %.3f), want (%.3f, %.3f, %.3f)", tt.r, tt.g, tt.b,
+					h, s, l, tt.h, tt.s, tt.l)
+			}
+		})
+	}
+}
+
+func TestHSLToRGB(t *testing.T) {
+	tests := []struct {
+		name    string
+		h, s, l float64
+		r, g, b int
+	}{
+		{name: "red", h: 0, s: 1, l: 0.5, r: 255},
+		{name: "yellow", h: 60, s: 1, l: 0.5, r: 255, g: 255},
+		{name: "cyan", h: 180, s: 1, l: 0.5, g: 255, b: 255},
+		{name: "hue wraps around", h: 480, s: 1, l: 0.5, g: 255},
+		{name: "negative hue wraps around", h: -120, s: 1, l: 0.5, b: 255},
+		{name: "saturation clamped", h: 0, s: 2, l: 0.5, r: 255},
+		{name: "lightness clamped", h: 0, s: 1, l: 1.5, r: 255, g: 255, b: 255},
+		{name: "no saturation is gray", h: 200, s: 0, l: 0.5, r: 128, g: 128, b: 128},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if r, g, b := HSLToRGB(tt.h, tt.s, tt.l); r != tt.r || g != tt.g || b != tt.b {
+				t.Errorf("HSLToRGB(%v, %v, %v) = (%d, %d, %d), want (%d, %d, %d)", tt.h, tt.s, tt.l, r, g, b,
+					tt.r, tt.g, tt.b)
+			}
+		})
+	}
+}
+
+func TestHSLRoundTrip(t *testing.T) {
+	for r := 0; r <= 255; r += 15 {
+		for g := 0; g <= 255; g += 15 {
+			for b := 0; b <= 255; b += 15 {
+				if gotR, gotG, gotB := HSLToRGB(RGBToHSL(r, g, b)); gotR != r || gotG != g || gotB != b {
+					t.Fatalf("HSLToRGB(RGBToHSL(%d, %d, %d)) = (%d, %d, %d), want the color unchanged", r, g, b,
+						gotR, gotG, gotB)
+				}
+			}
+		}
+	}
+}
+
+func TestDeriveColor(t *testing.T) {
+	tests := []struct {
+		name                                      string
+		r, g, b                                   int
+		hueShift, saturationScale, lightnessScale float64
+		wantR, wantG, wantB                       int
+	}{
+		{name: "identity", r: 200, g: 100, b: 50, saturationScale: 1, lightnessScale: 1,
+			wantR: 200, wantG: 100, wantB: 50},
+		{name: "complementary", r: 255, g: 0, b: 0, hueShift: 180, saturationScale: 1, lightnessScale: 1,
+			wantG: 255, wantB: 255},
+		{name: "negative shift", r: 0, g: 0, b: 255, hueShift: -120, saturationScale: 1, lightnessScale: 1,
+			wantG: 255},
+		{name: "desaturated", r: 255, g: 0, b: 0, saturationScale: 0.5, lightnessScale: 1,
+			wantR: 191, wantG: 64, wantB: 64},
+		{name: "fully desaturated", r: 255, g: 0, b: 0, saturationScale: 0, lightnessScale: 1,
+			wantR: 128, wantG: 128, wantB: 128},
+		{name: "darker", r: 255, g: 0, b: 0, saturationScale: 1, lightnessScale: 0.5, wantR: 128},
+		{name: "lightness capped at white", r: 255, g: 0, b: 0, saturationScale: 1, lightnessScale: 3,
+			wantR: 255, wantG: 255, wantB: 255},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, g, b := DeriveColor(tt.r, tt.g, tt.b, tt.hueShift, tt.saturationScale, tt.lightnessScale)
+			if r != tt.wantR || g != tt.wantG || b != tt.wantB {
+				t.Errorf("DeriveColor() = (%d, %d, %d), want (%d, %d, %d)", r, g, b, tt.wantR, tt.wantG, tt.wantB)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package hue
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"time"
+)
+
+// SetIPv6Discovery makes mDNS discovery retry over IPv6 if no bridge is found over IPv4, for IPv6-only networks.
+// Must be called before discovery is started.
+func (c *Client) SetIPv6Discovery(enabled bool) {
+	c.ipv6Discovery = enabled
+}
+
+// discoverBridgeLocal discovers the bridge using mDNS over IPv4, retrying over IPv6 if enabled.
+func (c *Client) discoverBridgeLocal(ctx context.Context) (*DiscoveryResponse, error) {
+	if !c.ipv6Discovery {
+		return c.discoverBridgeMDNS(ctx, false)
+	}
+
+	// the IPv4 query must leave time for the IPv6 retry
+	ipv4Ctx, cancel := context.WithTimeout(ctx, mdnsFallbackTimeout)
+	bridge, err := c.discoverBridgeMDNS(ipv4Ctx, false)
+	cancel()
+	if err == nil || ctx.Err() != nil {
+		return bridge, err
+	}
+
+	c.logger.Debug().Err(err).Msg("No Hue bridge found over IPv4, retrying mDNS discovery over IPv6")
+	return c.discoverBridgeMDNS(ctx, true)
+}
+
+// mdnsQueryTimeout is how long a single mDNS query waits for answers
+const mdnsQueryTimeout = 10 * time.Second
+
+// hostAddress returns the IP as host of a bridge address, bracketing IPv6 addresses along with their zone.
+func hostAddress(ip net.IP, zone string) string {
+	if ip.To4() != nil {
+		return ip.String()
+	}
+	if zone != "" {
+		return "[" + ip.String() + "%" + zone + "]"
+	}
+	return "[" + ip.String() + "]"
+}
+
+// bridgeURL returns the HTTPS URL of the path on the bridge with the given address, a host with an optional port.
+// Zones of IPv6 addresses are escaped as required in URLs.
+func bridgeURL(address, path string) string {
+	u := url.URL{Scheme: "https", Host: address, Path: path}
+	return u.String()
+}
@@ -0,0 +1,87 @@
+package hue
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/mdns"
+	"github.com/rs/zerolog"
+)
+
+func TestBridgeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   net.IP
+		zone string
+		want string
+	}{
+		{name: "IPv4", ip: net.ParseIP("192.168.1.20"), want: "https://192.168.1.20/clip/v2/resource/light"},
+		{name: "IPv6", ip: net.ParseIP("fd00::20"), want: "https://[fd00::20]/clip/v2/resource/light"},
+		{name: "IPv6 with zone", ip: net.ParseIP("fe80::20"), zone: "eth0",
+			want: "https://[fe80::20%25eth0]/clip/v2/resource/light"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bridgeURL(hostAddress(tt.ip, tt.zone), "/clip/v2/resource/light"); got != tt.want {
+				t.Errorf("bridgeURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// configured addresses may carry a port
+	if got := bridgeURL("[fd00::20]:8443", "/api"); got != "https://[fd00::20]:8443/api" {
+		t.Errorf("bridgeURL() with port = %q, want https://[fd00::20]:8443/api", got)
+	}
+}
+
+// answerMDNSOverIPv6 answers mDNS queries with the entry only if they are sent over IPv6.
+func answerMDNSOverIPv6(queries *atomic.Int32, entry *mdns.ServiceEntry) func(context.Context, *mdns.QueryParam) error {
+	return func(ctx context.Context, params *mdns.QueryParam) error {
+		queries.Add(1)
+		if params.DisableIPv6 {
+			return nil
+		}
+		return answerMDNS(&atomic.Int32{}, entry)(ctx, params)
+	}
+}
+
+func TestDiscoverBridgeIPv6Fallback(t *testing.T) {
+	entry := bridgeEntry(testBridgeID, "")
+	entry.AddrV6IPAddr = &net.IPAddr{IP: net.ParseIP("fe80::20"), Zone: "eth0"}
+
+	var queries atomic.Int32
+	client := NewClient(testBridgeID, "test-user", zerolog.Nop())
+	client.queryMDNS = answerMDNSOverIPv6(&queries, entry)
+	client.SetIPv6Discovery(true)
+
+	bridge, err := client.discoverBridgeLocal(context.Background())
+	if err != nil {
+		t.Fatalf("discoverBridgeLocal() returned error: %v", err)
+	}
+	if bridge.Address != "[fe80::20%eth0]" {
+		t.Errorf("Address = %q, want the bracketed IPv6 address with zone", bridge.Address)
+	}
+	if queries.Load() != 2 {
+		t.Errorf("sent %d mDNS queries, want one over IPv4 and one over IPv6", queries.Load())
+	}
+}
+
+func TestDiscoverBridgeIPv6Disabled(t *testing.T) {
+	entry := bridgeEntry(testBridgeID, "")
+	entry.AddrV6IPAddr = &net.IPAddr{IP: net.ParseIP("fd00::20")}
+
+	var queries atomic.Int32
+	client := NewClient(testBridgeID, "test-user", zerolog.Nop())
+	client.queryMDNS = answerMDNSOverIPv6(&queries, entry)
+
+	_, err := client.discoverBridgeLocal(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("discoverBridgeLocal() without hue_enable_ipv6 = %v, want the bridge not found", err)
+	}
+	if queries.Load() != 1 {
+		t.Errorf("sent %d mDNS queries, want only the IPv4 one", queries.Load())
+	}
+}
@@ -0,0 +1,92 @@
+package hue
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// linkButtonNotPressedType is the error type returned by the bridge while its link button wasn't pressed
+const linkButtonNotPressedType = 101
+
+// ErrLinkButtonNotPressed is returned by Pair until the link button of the bridge is pressed
+var ErrLinkButtonNotPressed = errors.New("link button not pressed")
+
+// IsLinkButtonNotPressed checks if the error is caused by the link button of the bridge not being pressed
+func IsLinkButtonNotPressed(err error) bool {
+	return errors.Is(err, ErrLinkButtonNotPressed)
+}
+
+// Credentials are the credentials of an application registered with the bridge
+type Credentials struct {
+	// Username is the application key sent in the hue-application-key header
+	Username  string `json:"username"`
+	ClientKey string `json:"clientkey"`
+}
+
+// pairRequest is the body of an application registration request
+type pairRequest struct {
+	DeviceType        string `json:"devicetype"`
+	GenerateClientKey bool   `json:"generateclientkey"`
+}
+
+// pairResponse is a single entry of the response to an application registration request
+type pairResponse struct {
+	Success *Credentials `json:"success"`
+	Error   *struct {
+		Type        int    `json:"type"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// Pair registers a new application with the bridge and returns its credentials. The link button of the
+// bridge must have been pressed shortly before, otherwise ErrLinkButtonNotPressed is returned.
+func (c *Client) Pair(deviceType string) (*Credentials, error) {
+	body, err := json.Marshal(pairRequest{DeviceType: deviceType, GenerateClientKey: true})
+	if err != nil {
+		return nil, err
+	}
+
+	url := bridgeURL(c.BridgeAddress(), "/api")
+	resp, err := c.httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to pair with bridge: %s", resp.Status)
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parsePairResponse(respBody)
+}
+
+// parsePairResponse returns the credentials of a registration response, or the error the bridge returned.
+func parsePairResponse(body []byte) (*Credentials, error) {
+	var responses []pairResponse
+	if err := json.Unmarshal(body, &responses); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pairing response: %w", err)
+	}
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("empty pairing response")
+	}
+
+	response := responses[0]
+	switch {
+	case response.Success != nil:
+		return response.Success, nil
+	case response.Error != nil && response.Error.Type == linkButtonNotPressedType:
+		return nil, ErrLinkButtonNotPressed
+	case response.Error != nil:
+		return nil, fmt.Errorf("failed to pair with bridge: %s", response.Error.Description)
+	default:
+		return nil, fmt.Errorf("unexpected pairing response: %s", body)
+	}
+}
@@ -0,0 +1,65 @@
+package hue
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPair(t *testing.T) {
+	var request pairRequest
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("invalid pairing request %s: %v", body, err)
+		}
+		_, _ = w.Write([]byte(`[{"success":{"username":"new-user","clientkey":"client-key"}}]`))
+	})
+
+	credentials, err := client.Pair("hue2govee#test")
+	if err != nil {
+		t.Fatalf("Pair() returned error: %v", err)
+	}
+	if credentials.Username != "new-user" || credentials.ClientKey != "client-key" {
+		t.Errorf("Pair() = %+v, want the credentials of the response", credentials)
+	}
+	if request.DeviceType != "hue2govee#test" || !request.GenerateClientKey {
+		t.Errorf("pairing request = %+v, want the device type and a client key", request)
+	}
+}
+
+func TestParsePairResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{name: "success", body: `[{"success":{"username":"new-user","clientkey":"client-key"}}]`},
+		{name: "link button not pressed",
+			body:    `[{"error":{"type":101,"address":"","description":"link button not pressed"}}]`,
+			wantErr: ErrLinkButtonNotPressed.Error()},
+		{name: "other error", body: `[{"error":{"type":7,"address":"","description":"invalid value"}}]`,
+			wantErr: "invalid value"},
+		{name: "empty", body: `[]`, wantErr: "empty pairing response"},
+		{name: "invalid", body: `{`, wantErr: "failed to unmarshal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credentials, err := parsePairResponse([]byte(tt.body))
+			if tt.wantErr == "" {
+				if err != nil || credentials.Username != "new-user" {
+					t.Errorf("parsePairResponse() = %+v, %v, want the credentials", credentials, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parsePairResponse() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := parsePairResponse([]byte(`[{"error":{"type":101}}]`)); !IsLinkButtonNotPressed(err) {
+		t.Errorf("parsePairResponse() = %v, want ErrLinkButtonNotPressed", err)
+	}
+}
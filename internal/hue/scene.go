@@ -2,13 +2,50 @@ package hue
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/cedrickring/hue-to-govee/internal/govee"
+	"github.com/cedrickring/hue-to-govee/internal/metrics"
 	"github.com/rs/zerolog"
 )
 
+// ConflictPolicy decides what happens when a manual command or static rule targets a device with an active scene
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyStopScene stops the active scene before the command is applied
+	ConflictPolicyStopScene ConflictPolicy = "stop_scene"
+	// ConflictPolicyReject rejects the command while a scene is active
+	ConflictPolicyReject ConflictPolicy = "reject"
+)
+
+var (
+	// ErrSceneActive is returned when a command is rejected because a dynamic scene is active on the device
+	ErrSceneActive = errors.New("a dynamic scene is active on the device")
+)
+
+// IsSceneActive checks if the error is caused by a command rejected in favor of an active scene
+func IsSceneActive(err error) bool {
+	return errors.Is(err, ErrSceneActive)
+}
+
+// ParseConflictPolicy parses a conflict policy, defaulting to ConflictPolicyStopScene if empty.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(s); policy {
+	case "":
+		return ConflictPolicyStopScene, nil
+	case ConflictPolicyStopScene, ConflictPolicyReject:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid scene conflict policy %q, must be one of %s or %s", s,
+			ConflictPolicyStopScene, ConflictPolicyReject)
+	}
+}
+
 // SceneController manages dynamic scenes for Govee devices
 type SceneController struct {
 	mu           sync.Mutex // Mutex to protect activeScenes updates
@@ -16,6 +53,8 @@ type SceneController struct {
 
 	logger      zerolog.Logger
 	goveeClient *govee.Client
+	// sendColor sets the color of a Govee device while a scene plays, replaceable for tests
+	sendColor func(goveeDeviceID string, r, g, b int) error
 }
 
 // NewSceneController creates a new SceneController
@@ -23,21 +62,60 @@ func NewSceneController(goveeClient *govee.Client, logger zerolog.Logger) *Scene
 	return &SceneController{
 		activeScenes: make(map[string]context.CancelFunc),
 		goveeClient:  goveeClient,
-		logger:       logger.With().Str("component", "sceneController").Logger(),
+		logger:       logger,
+		sendColor:    goveeClient.SetColor,
 	}
 }
 
-// SetScene sets a dynamic scene for a Govee device
-func (sc *SceneController) SetScene(goveeLightId string, scene Scene) {
+// SetScene sets a dynamic scene for a Govee device, converting its palette with the options within the gamut of
+// the Hue light that plays the scene
+func (sc *SceneController) SetScene(goveeLightId string, scene Scene, opts ConversionOptions, gamut Gamut) {
 	sc.StopScene(goveeLightId)
 
 	sceneCtx, cancel := context.WithCancel(context.Background())
 
 	sc.mu.Lock()
 	sc.activeScenes[goveeLightId] = cancel
+	metrics.ActiveScenes.Set(float64(len(sc.activeScenes)))
 	sc.mu.Unlock()
 
-	go sc.runDynamicScene(sceneCtx, goveeLightId, scene)
+	go sc.runDynamicScene(sceneCtx, goveeLightId, scene, opts, gamut)
+}
+
+// SetDIYScene activates a Govee DIY scene on a Govee device in place of a dynamic scene. The scene is
+// considered active until it is stopped or replaced.
+func (sc *SceneController) SetDIYScene(goveeDeviceID string, code int) error {
+	sc.StopScene(goveeDeviceID)
+
+	if err := sc.goveeClient.SetDIYScene(goveeDeviceID, code); err != nil {
+		return err
+	}
+
+	sc.mu.Lock()
+	sc.activeScenes[goveeDeviceID] = func() {}
+	metrics.ActiveScenes.Set(float64(len(sc.activeScenes)))
+	sc.mu.Unlock()
+
+	sc.logger.Info().Str("deviceId", goveeDeviceID).Int("code", code).Msg("Activated Govee DIY scene")
+	return nil
+}
+
+// SetDeviceScene activates a built-in scene of a Govee device in place of a dynamic scene. The scene is
+// considered active until it is stopped or replaced.
+func (sc *SceneController) SetDeviceScene(goveeDeviceID string, sceneCode int) error {
+	sc.StopScene(goveeDeviceID)
+
+	if err := sc.goveeClient.SetDeviceScene(goveeDeviceID, sceneCode); err != nil {
+		return err
+	}
+
+	sc.mu.Lock()
+	sc.activeScenes[goveeDeviceID] = func() {}
+	metrics.ActiveScenes.Set(float64(len(sc.activeScenes)))
+	sc.mu.Unlock()
+
+	sc.logger.Info().Str("deviceId", goveeDeviceID).Int("sceneCode", sceneCode).Msg("Activated Govee scene")
+	return nil
 }
 
 // IsActive returns true if a dynamic scene is currently active for a Govee device
@@ -49,6 +127,22 @@ func (sc *SceneController) IsActive(goveeLightID string) bool {
 	return ok
 }
 
+// AcquireDevice makes sure no scene writes to a Govee device before another writer takes it over.
+// Depending on the policy, an active scene is stopped or ErrSceneActive is returned.
+func (sc *SceneController) AcquireDevice(goveeDeviceID string, policy ConflictPolicy) error {
+	if !sc.IsActive(goveeDeviceID) {
+		return nil
+	}
+
+	if policy == ConflictPolicyReject {
+		return fmt.Errorf("device %s: %w", goveeDeviceID, ErrSceneActive)
+	}
+
+	sc.StopScene(goveeDeviceID)
+	sc.logger.Info().Str("deviceId", goveeDeviceID).Msg("Stopped dynamic scene in favor of another command")
+	return nil
+}
+
 // StopScene stops a dynamic scene for a Govee device
 func (sc *SceneController) StopScene(goveeDeviceID string) {
 	sc.mu.Lock()
@@ -57,20 +151,97 @@ func (sc *SceneController) StopScene(goveeDeviceID string) {
 	if cancelFunc, exists := sc.activeScenes[goveeDeviceID]; exists {
 		cancelFunc()
 		delete(sc.activeScenes, goveeDeviceID)
+		metrics.ActiveScenes.Set(float64(len(sc.activeScenes)))
+	}
+}
+
+// LimitPalette returns at most maxColors colors of the palette, evenly spaced across the palette so
+// the subset stays representative of the whole scene. A maxColors of zero or less means no limit.
+func LimitPalette[T any](colors []T, maxColors int) []T {
+	if maxColors <= 0 || len(colors) <= maxColors {
+		return colors
+	}
+
+	subset := make([]T, maxColors)
+	step := float64(len(colors)) / float64(maxColors)
+	for i := range subset {
+		subset[i] = colors[int(float64(i)*step)]
 	}
+	return subset
+}
+
+// sceneTransitionSteps is the number of intermediate colors sent while crossfading between palette colors
+const sceneTransitionSteps = 10
+
+// defaultSceneBrightness is used for scenes without actions to take the brightness from
+const defaultSceneBrightness = 100
+
+// sceneBrightness returns the average brightness of the scene actions as a percentage (0-100), the
+// unit used by both the Hue V2 API and Govee. Scenes without actions use defaultSceneBrightness.
+func sceneBrightness(actions []SceneAction) int {
+	if len(actions) == 0 {
+		return defaultSceneBrightness
+	}
+
+	brightness := 0.0
+	for _, action := range actions {
+		brightness += action.Action.Dimming.Brightness
+	}
+	brightness /= float64(len(actions))
+	return int(math.Round(clamp(brightness, 0, 100)))
+}
+
+// minSceneSpeed is the slowest speed dynamic scenes are cycled at, so a speed of zero doesn't stall the scene
+const minSceneSpeed = 0.05
+
+// sceneSpeed returns the speed of a scene clamped to [minSceneSpeed, 1], the range of the Hue V2 API.
+func sceneSpeed(speed float64) float64 {
+	if math.IsNaN(speed) {
+		return minSceneSpeed
+	}
+	return clamp(speed, minSceneSpeed, 1)
+}
+
+// paletteColors converts the palette of a scene to RGB at full brightness with the options, keeping the XY colors
+// within the gamut. The scene's brightness is set on the device separately. XY colors and color temperatures are
+// interleaved in palette order, so warm-to-cool white palettes are cycled as well.
+func paletteColors(palette Palette, opts ConversionOptions, gamut Gamut) []RGBColor {
+	const fullBrightness = 100
+
+	colors := make([]RGBColor, 0, len(palette.Color)+len(palette.ColorTemperature))
+	for i := 0; i < max(len(palette.Color), len(palette.ColorTemperature)); i++ {
+		if i < len(palette.Color) {
+			xy := palette.Color[i].Color.XY
+			r, g, b := coordsToRGB(xy.X, xy.Y, fullBrightness, "", gamut, opts.ColorGamma,
+				opts.BrightnessConversion)
+			colors = append(colors, RGBColor{R: r, G: g, B: b})
+		}
+		if i < len(palette.ColorTemperature) {
+			mirek := palette.ColorTemperature[i].ColorTemperature.Mirek
+			if mirek <= 0 {
+				continue
+			}
+			r, g, b := ctToRGB(1000000/mirek, fullBrightness)
+			colors = append(colors, RGBColor{R: r, G: g, B: b})
+		}
+	}
+	return colors
 }
 
 // runDynamicScene runs a dynamic scene for a Govee device
-func (sc *SceneController) runDynamicScene(ctx context.Context, goveeDeviceID string, scene Scene) {
-	if len(scene.Palette.Color) == 0 {
+func (sc *SceneController) runDynamicScene(ctx context.Context, goveeDeviceID string, scene Scene,
+	opts ConversionOptions, gamut Gamut) {
+	brightness := sceneBrightness(scene.Actions)
+	colors := paletteColors(scene.Palette, opts, gamut)
+	if len(colors) == 0 {
 		sc.logger.Warn().Str("deviceId", goveeDeviceID).Msg("Scene has no colors in palette")
 		return
 	}
 
 	baseCycleTime := 20.0
-	adjustedCycleTime := baseCycleTime / scene.Speed
-	colorsInPalette := len(scene.Palette.Color)
-	timePerColor := time.Duration(adjustedCycleTime/float64(colorsInPalette)) * time.Second
+	adjustedCycleTime := baseCycleTime / sceneSpeed(scene.Speed)
+	colorsInPalette := len(colors)
+	timePerColor := time.Duration(adjustedCycleTime / float64(colorsInPalette) * float64(time.Second))
 	transitionTime := timePerColor / 3
 
 	sc.logger.Info().
@@ -80,52 +251,67 @@ func (sc *SceneController) runDynamicScene(ctx context.Context, goveeDeviceID st
 		Int("colorsInPalette", colorsInPalette).
 		Msg("Starting dynamic scene")
 
+	if err := sc.goveeClient.SetBrightness(goveeDeviceID, brightness); err != nil {
+		sc.logger.Error().Err(err).Str("deviceId", goveeDeviceID).Msg("Failed to set Govee brightness")
+	}
+
+	var current *RGBColor
 	for {
-		select {
-		case <-ctx.Done():
-			sc.logger.Info().Str("deviceId", goveeDeviceID).Msg("Stopping dynamic scene")
-			return
-		default:
-			for i, paletteColor := range scene.Palette.Color {
-				select {
-				case <-ctx.Done():
+		for i, color := range colors {
+			sc.logger.Debug().
+				Int("paletteIndex", i).
+				Int("brightness", brightness).
+				Int("r", color.R).Int("g", color.G).Int("b", color.B).
+				Msg("Applying palette color")
+
+			hold := timePerColor
+			if current != nil && *current != color {
+				if !sc.crossfade(ctx, goveeDeviceID, *current, color, transitionTime) {
+					sc.logger.Info().Str("deviceId", goveeDeviceID).Msg("Stopping dynamic scene")
 					return
-				default:
-					x := paletteColor.Color.XY.X
-					y := paletteColor.Color.XY.Y
-
-					brightness := 0.0
-					for _, action := range scene.Actions {
-						brightness += action.Action.Dimming.Brightness
-					}
-					brightness /= float64(len(scene.Actions)) // Average brightness across actions
-
-					r, g, b := coordsToRGB(x, y, int(brightness), GamutTypeC, Gamut{})
-
-					sc.logger.Debug().
-						Int("paletteIndex", i).
-						Float64("x", x).
-						Float64("y", y).
-						Float64("brightness", brightness).
-						Int("r", r).Int("g", g).Int("b", b).
-						Msg("Applying palette color")
-
-					if err := sc.goveeClient.SetColor(goveeDeviceID, r, g, b); err != nil {
-						sc.logger.Error().Err(err).Str("deviceId", goveeDeviceID).Msg("Failed to set Govee color")
-					}
-
-					briByte := int(brightness)
-					if err := sc.goveeClient.SetBrightness(goveeDeviceID, briByte); err != nil {
-						sc.logger.Error().Err(err).Str("deviceId", goveeDeviceID).Msg("Failed to set Govee brightness")
-					}
-
-					select {
-					case <-ctx.Done():
-						return
-					case <-time.After(timePerColor):
-					}
 				}
+				hold -= transitionTime
+			} else {
+				sc.setColor(goveeDeviceID, color)
 			}
+			current = &color
+
+			select {
+			case <-ctx.Done():
+				sc.logger.Info().Str("deviceId", goveeDeviceID).Msg("Stopping dynamic scene")
+				return
+			case <-time.After(hold):
+			}
+		}
+	}
+}
+
+// crossfade interpolates the color of a Govee device from one color to another in sceneTransitionSteps
+// steps over the given duration, ending on the target color. It returns false if ctx is done before.
+func (sc *SceneController) crossfade(ctx context.Context, goveeDeviceID string, from, to RGBColor, duration time.Duration) bool {
+	interval := duration / sceneTransitionSteps
+	for step := 1; step <= sceneTransitionSteps; step++ {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(interval):
 		}
+		sc.setColor(goveeDeviceID, lerpRGB(from, to, float64(step)/sceneTransitionSteps))
+	}
+	return true
+}
+
+// setColor sets the color of a Govee device, logging failures since scenes keep running regardless
+func (sc *SceneController) setColor(goveeDeviceID string, color RGBColor) {
+	if err := sc.sendColor(goveeDeviceID, color.R, color.G, color.B); err != nil {
+		sc.logger.Error().Err(err).Str("deviceId", goveeDeviceID).Msg("Failed to set Govee color")
+	}
+}
+
+// lerpRGB linearly interpolates between two colors, t ranges from 0 (from) to 1 (to)
+func lerpRGB(from, to RGBColor, t float64) RGBColor {
+	lerp := func(a, b int) int {
+		return int(math.Round(float64(a) + (float64(b)-float64(a))*t))
 	}
+	return RGBColor{R: lerp(from.R, to.R), G: lerp(from.G, to.G), B: lerp(from.B, to.B)}
 }
@@ -0,0 +1,309 @@
+package hue
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// gamutC is the gamut of current Hue color lights
+var gamutC = Gamut{
+	Red:   Coords{X: 0.6915, Y: 0.3083},
+	Green: Coords{X: 0.17, Y: 0.7},
+	Blue:  Coords{X: 0.1532, Y: 0.0475},
+}
+
+func ctPaletteColor(mirek int) PaletteColorTemp {
+	return PaletteColorTemp{ColorTemperature: ColorTemperature{Mirek: mirek, MirekValid: true}}
+}
+
+func xyPaletteColor(x, y float64) PaletteColor {
+	var color PaletteColor
+	color.Color.XY = Coords{X: x, Y: y}
+	return color
+}
+
+// ctRGB returns the color of the color temperature in Kelvin at full brightness
+func ctRGB(kelvin int) RGBColor {
+	r, g, b := ctToRGB(kelvin, 100)
+	return RGBColor{R: r, G: g, B: b}
+}
+
+func TestPaletteColorsColorTemperatureOnly(t *testing.T) {
+	palette := Palette{ColorTemperature: []PaletteColorTemp{ctPaletteColor(153), ctPaletteColor(366),
+		ctPaletteColor(500)}}
+
+	colors := paletteColors(palette, ConversionOptions{}, gamutC)
+	if len(colors) != 3 {
+		t.Fatalf("paletteColors() = %v, want a color for every color temperature", colors)
+	}
+	for i, color := range colors {
+		if color.R == 0 && color.G == 0 && color.B == 0 {
+			t.Errorf("color %d of the palette is black, want the color temperature at full brightness", i)
+		}
+		if want := ctRGB(1000000 / palette.ColorTemperature[i].ColorTemperature.Mirek); color != want {
+			t.Errorf("color %d = %v, want %v", i, color, want)
+		}
+	}
+	// warmer color temperatures have less blue
+	if !(colors[0].B > colors[1].B && colors[1].B > colors[2].B) {
+		t.Errorf("blue of the colors from cool to warm = %d, %d, %d, want it decreasing", colors[0].B, colors[1].B,
+			colors[2].B)
+	}
+}
+
+func TestPaletteColorsInterleaved(t *testing.T) {
+	palette := Palette{
+		Color:            []PaletteColor{xyPaletteColor(0.6915, 0.3083), xyPaletteColor(0.17, 0.7)},
+		ColorTemperature: []PaletteColorTemp{ctPaletteColor(153), ctPaletteColor(0), ctPaletteColor(500)},
+	}
+
+	colors := paletteColors(palette, ConversionOptions{}, gamutC)
+	warm, cool := ctRGB(1000000/500), ctRGB(1000000/153)
+	// red, cool white, green, the invalid color temperature skipped, warm white
+	if len(colors) != 4 {
+		t.Fatalf("paletteColors() = %v, want 4 colors", colors)
+	}
+	if colors[0].R <= colors[0].G || colors[2].G <= colors[2].R {
+		t.Errorf("XY colors = %v, %v, want red then green", colors[0], colors[2])
+	}
+	if colors[1] != cool || colors[3] != warm {
+		t.Errorf("color temperatures = %v, %v, want %v, %v", colors[1], colors[3], cool, warm)
+	}
+}
+
+func sceneActions(brightness ...float64) []SceneAction {
+	actions := make([]SceneAction, len(brightness))
+	for i, bri := range brightness {
+		actions[i].Action.On.On = true
+		actions[i].Action.Dimming.Brightness = bri
+	}
+	return actions
+}
+
+func TestSceneBrightness(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []SceneAction
+		want    int
+	}{
+		{name: "no actions", actions: nil, want: defaultSceneBrightness},
+		{name: "single action", actions: sceneActions(42.4), want: 42},
+		{name: "multiple actions averaged", actions: sceneActions(40, 60, 81), want: 60},
+		{name: "average rounded", actions: sceneActions(50, 51), want: 51},
+		{name: "out of range clamped", actions: sceneActions(150, 130), want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sceneBrightness(tt.actions); got != tt.want {
+				t.Errorf("sceneBrightness() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSceneSpeed(t *testing.T) {
+	tests := []struct {
+		speed float64
+		want  float64
+	}{
+		{speed: 0.5, want: 0.5},
+		{speed: 1, want: 1},
+		{speed: minSceneSpeed, want: minSceneSpeed},
+		{speed: 0, want: minSceneSpeed},
+		{speed: -1, want: minSceneSpeed},
+		{speed: 2, want: 1},
+		{speed: math.Inf(1), want: 1},
+		{speed: math.NaN(), want: minSceneSpeed},
+	}
+	for _, tt := range tests {
+		if got := sceneSpeed(tt.speed); got != tt.want {
+			t.Errorf("sceneSpeed(%v) = %v, want %v", tt.speed, got, tt.want)
+		}
+	}
+}
+
+// colorRecorder records the colors a SceneController sends.
+type colorRecorder struct {
+	mu     sync.Mutex
+	colors []RGBColor
+}
+
+func (r *colorRecorder) sendColor(_ string, red, green, blue int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.colors = append(r.colors, RGBColor{R: red, G: green, B: blue})
+	return nil
+}
+
+func (r *colorRecorder) sent() []RGBColor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.colors)
+}
+
+// newRecordingSceneController creates a SceneController whose colors are recorded instead of sent.
+func newRecordingSceneController() (*SceneController, *colorRecorder) {
+	recorder := &colorRecorder{}
+	sc := NewSceneController(nil, zerolog.Nop())
+	sc.sendColor = recorder.sendColor
+	return sc, recorder
+}
+
+func TestCrossfade(t *testing.T) {
+	sc, recorder := newRecordingSceneController()
+	from, to := RGBColor{R: 255, G: 0, B: 0}, RGBColor{R: 0, G: 0, B: 255}
+
+	if !sc.crossfade(context.Background(), "AA:BB", from, to, 50*time.Millisecond) {
+		t.Fatal("crossfade() = false, want the fade completed")
+	}
+
+	colors := recorder.sent()
+	if len(colors) != sceneTransitionSteps {
+		t.Fatalf("crossfade() sent %d colors, want %d steps", len(colors), sceneTransitionSteps)
+	}
+	for i, color := range colors {
+		want := lerpRGB(from, to, float64(i+1)/sceneTransitionSteps)
+		if color != want {
+			t.Errorf("step %d = %v, want %v", i+1, color, want)
+		}
+	}
+	if colors[len(colors)-1] != to {
+		t.Errorf("last step = %v, want the target color %v", colors[len(colors)-1], to)
+	}
+}
+
+func TestCrossfadeCanceled(t *testing.T) {
+	sc, recorder := newRecordingSceneController()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// each of the steps of the fade takes a second, the fade is canceled during the first one
+	done := make(chan bool)
+	go func() {
+		done <- sc.crossfade(ctx, "AA:BB", RGBColor{R: 255}, RGBColor{B: 255}, sceneTransitionSteps*time.Second)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	canceled := time.Now()
+	cancel()
+
+	select {
+	case completed := <-done:
+		if completed {
+			t.Error("crossfade() = true, want false after the context was canceled")
+		}
+		if elapsed := time.Since(canceled); elapsed > 100*time.Millisecond {
+			t.Errorf("crossfade() returned %s after it was canceled, want it to stop promptly", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("crossfade() didn't return after it was canceled")
+	}
+	if colors := recorder.sent(); len(colors) != 0 {
+		t.Errorf("crossfade() sent %v after it was canceled, want no more steps", colors)
+	}
+}
+
+func TestLimitPalette(t *testing.T) {
+	palette := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	tests := []struct {
+		name      string
+		maxColors int
+		want      []int
+	}{
+		{name: "no limit", maxColors: 0, want: palette},
+		{name: "negative limit", maxColors: -1, want: palette},
+		{name: "limit above palette size", maxColors: 12, want: palette},
+		{name: "limit equal to palette size", maxColors: 10, want: palette},
+		{name: "evenly spaced", maxColors: 5, want: []int{0, 2, 4, 6, 8}},
+		{name: "uneven spacing", maxColors: 3, want: []int{0, 3, 6}},
+		{name: "single color", maxColors: 1, want: []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LimitPalette(palette, tt.maxColors); !slices.Equal(got, tt.want) {
+				t.Errorf("LimitPalette(%d) = %v, want %v", tt.maxColors, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	tests := map[string]ConflictPolicy{"": ConflictPolicyStopScene, "stop_scene": ConflictPolicyStopScene,
+		"reject": ConflictPolicyReject}
+	for s, want := range tests {
+		if got, err := ParseConflictPolicy(s); err != nil || got != want {
+			t.Errorf("ParseConflictPolicy(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+	if _, err := ParseConflictPolicy("ignore"); err == nil {
+		t.Error("ParseConflictPolicy(\"ignore\") returned no error, want an invalid policy")
+	}
+}
+
+func TestAcquireDevice(t *testing.T) {
+	// startScene marks a scene active on the device, returning whether it was stopped since
+	startScene := func(sc *SceneController) func() bool {
+		var stopped bool
+		sc.mu.Lock()
+		sc.activeScenes["AA:BB"] = func() { stopped = true }
+		sc.mu.Unlock()
+		return func() bool { return stopped }
+	}
+
+	t.Run("no active scene", func(t *testing.T) {
+		sc, _ := newRecordingSceneController()
+		for _, policy := range []ConflictPolicy{ConflictPolicyStopScene, ConflictPolicyReject} {
+			if err := sc.AcquireDevice("AA:BB", policy); err != nil {
+				t.Errorf("AcquireDevice() with policy %s returned error: %v", policy, err)
+			}
+		}
+	})
+
+	t.Run("stop scene", func(t *testing.T) {
+		sc, _ := newRecordingSceneController()
+		stopped := startScene(sc)
+		if err := sc.AcquireDevice("AA:BB", ConflictPolicyStopScene); err != nil {
+			t.Fatalf("AcquireDevice() returned error: %v", err)
+		}
+		if !stopped() || sc.IsActive("AA:BB") {
+			t.Error("the scene is still active, want it stopped in favor of the command")
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		sc, _ := newRecordingSceneController()
+		stopped := startScene(sc)
+		err := sc.AcquireDevice("AA:BB", ConflictPolicyReject)
+		if !IsSceneActive(err) {
+			t.Fatalf("AcquireDevice() = %v, want ErrSceneActive", err)
+		}
+		if stopped() || !sc.IsActive("AA:BB") {
+			t.Error("the scene was stopped, want it to keep playing")
+		}
+		// other devices aren't affected by the scene
+		if err := sc.AcquireDevice("CC:DD", ConflictPolicyReject); err != nil {
+			t.Errorf("AcquireDevice() of another device returned error: %v", err)
+		}
+	})
+}
+
+func TestPaletteColorsConversionOptions(t *testing.T) {
+	palette := Palette{Color: []PaletteColor{xyPaletteColor(0.4573, 0.41)}}
+	opts := ConversionOptions{ColorGamma: ColorGammaLinear, BrightnessConversion: BrightnessConversionMultiply}
+
+	// the palette is converted like the light's own color, within the light's gamut
+	colors := paletteColors(palette, opts, gamutC)
+	r, g, b := coordsToRGB(0.4573, 0.41, 100, "", gamutC, ColorGammaLinear, BrightnessConversionMultiply)
+	if want := (RGBColor{R: r, G: g, B: b}); len(colors) != 1 || colors[0] != want {
+		t.Errorf("paletteColors() = %v, want [%v]", colors, want)
+	}
+	if defaults := paletteColors(palette, ConversionOptions{}, gamutC); defaults[0] == colors[0] {
+		t.Errorf("paletteColors() with and without options = %v, want the options to change the color", colors[0])
+	}
+}
@@ -0,0 +1,115 @@
+package hue
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultSceneCacheTTL is how long the active scenes are cached by default
+const DefaultSceneCacheTTL = 2 * time.Second
+
+// sceneCache caches all scenes and the active dynamic scene of every room as of a single fetch of all scenes, so
+// that polling synchronizations of any number of rooms share one request to the bridge.
+type sceneCache struct {
+	fetchMu sync.Mutex // Mutex to let only one caller fetch the scenes while the others wait for its result
+
+	mu         sync.Mutex // Mutex to protect ttl, scenes, active, fetchedAt and generation updates
+	ttl        time.Duration
+	scenes     []Scene           // all scenes as of the last fetch
+	active     map[string]*Scene // active dynamic scene by room ID, nil if not fetched yet
+	fetchedAt  time.Time
+	generation uint64 // incremented on invalidation, so that a fetch in flight isn't cached
+}
+
+// newSceneCache creates a new, empty sceneCache
+func newSceneCache(ttl time.Duration) *sceneCache {
+	return &sceneCache{ttl: ttl}
+}
+
+// activeScenes returns the active dynamic scene by room ID, calling list to fetch all scenes if the cached
+// ones are stale.
+func (c *sceneCache) activeScenes(list func() ([]Scene, error)) (map[string]*Scene, error) {
+	_, active, err := c.snapshot(list)
+	return active, err
+}
+
+// allScenes returns all scenes, calling list to fetch them if the cached ones are stale. The scenes are shared
+// with other callers and must not be modified.
+func (c *sceneCache) allScenes(list func() ([]Scene, error)) ([]Scene, error) {
+	scenes, _, err := c.snapshot(list)
+	return scenes, err
+}
+
+// snapshot returns all scenes and the active dynamic scene by room ID, calling list to fetch all scenes if the
+// cached ones are stale.
+func (c *sceneCache) snapshot(list func() ([]Scene, error)) ([]Scene, map[string]*Scene, error) {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	c.mu.Lock()
+	if c.active != nil && time.Since(c.fetchedAt) < c.ttl {
+		scenes, active := c.scenes, c.active
+		c.mu.Unlock()
+		return scenes, active, nil
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	scenes, err := list()
+	if err != nil {
+		return nil, nil, err
+	}
+	active := indexActiveScenes(scenes)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl > 0 && c.generation == generation {
+		c.scenes, c.active, c.fetchedAt = scenes, active, time.Now()
+	}
+	return scenes, active, nil
+}
+
+// indexActiveScenes maps the ID of each room to its active dynamic scene.
+func indexActiveScenes(scenes []Scene) map[string]*Scene {
+	active := make(map[string]*Scene)
+	for _, scene := range scenes {
+		if scene.Group.Type != "room" || scene.Status.Active != SceneStatusDynamicPalette {
+			continue
+		}
+		if _, ok := active[scene.Group.ID]; !ok {
+			active[scene.Group.ID] = &scene
+		}
+	}
+	return active
+}
+
+// invalidate drops the cached scenes.
+func (c *sceneCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scenes, c.active = nil, nil
+	c.generation++
+}
+
+// setTTL sets how long the active scenes are cached, dropping the cached scenes.
+func (c *sceneCache) setTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = ttl
+	c.scenes, c.active = nil, nil
+	c.generation++
+}
+
+// SetSceneCacheTTL sets how long the active scenes are cached, 0 disables caching.
+func (c *Client) SetSceneCacheTTL(ttl time.Duration) {
+	c.scenes.setTTL(ttl)
+}
+
+// InvalidateActiveScene drops the cached active scenes, so that the next call to GetActiveScene fetches them
+// from the bridge, e.g. because a light of the room started or stopped a dynamic scene. As all rooms share a
+// single fetch, the scenes of all rooms are refetched.
+func (c *Client) InvalidateActiveScene(roomID string) {
+	c.scenes.invalidate()
+}
@@ -0,0 +1,235 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fixtureHandler responds to every request with the contents of the file in testdata.
+func fixtureHandler(t *testing.T, name string) http.HandlerFunc {
+	t.Helper()
+
+	fixture, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(fixture)
+	}
+}
+
+func TestGetRecalledScene(t *testing.T) {
+	client := newTestClient(t, fixtureHandler(t, "scenes.json"))
+
+	scene, err := client.GetRecalledScene(context.Background(), "room-1")
+	if err != nil {
+		t.Fatalf("GetRecalledScene() returned error: %v", err)
+	}
+	if scene == nil || scene.ID != "scene-tropical-twilight" {
+		t.Fatalf("GetRecalledScene() = %+v, want the recalled scene-tropical-twilight", scene)
+	}
+	if !scene.AutoDynamic {
+		t.Error("AutoDynamic = false, want the scene's auto_dynamic flag")
+	}
+	if scene.Status.Active != SceneStatusStatic || scene.Status.LastRecall != "2024-05-01T18:03:12.345Z" {
+		t.Errorf("Status = %+v, want a static scene with its last recall", scene.Status)
+	}
+	if len(scene.Palette.Color) != 2 || scene.Palette.Color[1].Color.XY != (Coords{X: 0.2185, Y: 0.1377}) {
+		t.Errorf("Palette.Color = %+v, want both colors of the palette", scene.Palette.Color)
+	}
+
+	// scenes recalled in zones and rooms without a recalled scene have no recalled room scene
+	for _, roomID := range []string{"zone-1", "room-2"} {
+		scene, err := client.GetRecalledScene(context.Background(), roomID)
+		if err != nil || scene != nil {
+			t.Errorf("GetRecalledScene(%q) = %+v, %v, want no scene", roomID, scene, err)
+		}
+	}
+}
+
+// countingHandler responds to every request with the contents of the file in testdata and counts the requests.
+func countingHandler(t *testing.T, name string, requests *atomic.Int32) http.HandlerFunc {
+	t.Helper()
+
+	fixture := fixtureHandler(t, name)
+	return func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fixture(w, r)
+	}
+}
+
+func TestGetActiveSceneCached(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, countingHandler(t, "dynamic_scenes.json", &requests))
+	client.SetSceneCacheTTL(100 * time.Millisecond)
+
+	getActiveScene := func() {
+		t.Helper()
+
+		scene, err := client.GetActiveScene(context.Background(), "room-1")
+		if err != nil {
+			t.Fatalf("GetActiveScene() returned error: %v", err)
+		}
+		if scene.ID != "scene-tropical-twilight" {
+			t.Fatalf("GetActiveScene() = %s, want scene-tropical-twilight", scene.ID)
+		}
+		// the scene is a copy, modifying it doesn't change the cached scene
+		scene.Palette.Color = nil
+	}
+
+	for range 5 {
+		getActiveScene()
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("bridge was requested %d times within the TTL, want once", got)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	getActiveScene()
+	if got := requests.Load(); got != 2 {
+		t.Errorf("bridge was requested %d times after the TTL expired, want twice", got)
+	}
+
+	// a light's dynamics status flipped, so the scenes are fetched again
+	client.InvalidateActiveScene("room-1")
+	getActiveScene()
+	if got := requests.Load(); got != 3 {
+		t.Errorf("bridge was requested %d times after invalidating, want 3 times", got)
+	}
+}
+
+func TestGetActiveSceneConcurrent(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, countingHandler(t, "dynamic_scenes.json", &requests))
+	client.SetSceneCacheTTL(time.Minute)
+
+	// synchronizations polling concurrently share a single fetch
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetActiveScene(context.Background(), "room-1"); err != nil {
+				t.Errorf("GetActiveScene() returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := requests.Load(); got != 1 {
+		t.Errorf("bridge was requested %d times by concurrent callers, want once", got)
+	}
+}
+
+func TestGetActiveSceneWithoutCache(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, countingHandler(t, "dynamic_scenes.json", &requests))
+	client.SetSceneCacheTTL(0)
+
+	for range 3 {
+		if _, err := client.GetActiveScene(context.Background(), "room-1"); err != nil {
+			t.Fatalf("GetActiveScene() returned error: %v", err)
+		}
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("bridge was requested %d times with caching disabled, want 3 times", got)
+	}
+}
+
+func TestGetActiveSceneErrorNotCached(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fixtureHandler(t, "dynamic_scenes.json")(w, r)
+	})
+
+	if _, err := client.GetActiveScene(context.Background(), "room-1"); err == nil {
+		t.Fatal("GetActiveScene() returned no error for a failing bridge")
+	}
+	if _, err := client.GetActiveScene(context.Background(), "room-1"); err != nil {
+		t.Errorf("GetActiveScene() after a failed fetch returned error: %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("bridge was requested %d times, want the failed fetch retried", got)
+	}
+}
+
+func TestGetActiveSceneNotFound(t *testing.T) {
+	client := newTestClient(t, fixtureHandler(t, "dynamic_scenes.json"))
+
+	// rooms without a dynamic scene and zones have no active scene
+	for _, roomID := range []string{"room-3", "zone-1"} {
+		if _, err := client.GetActiveScene(context.Background(), roomID); !errors.Is(err, ErrSceneNotFound) {
+			t.Errorf("GetActiveScene(%q) error = %v, want ErrSceneNotFound", roomID, err)
+		}
+	}
+}
+
+func TestGetActiveSceneOfSeveralRooms(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, countingHandler(t, "dynamic_scenes.json", &requests))
+
+	want := map[string]string{"room-1": "scene-tropical-twilight", "room-2": "scene-arctic-aurora"}
+	for roomID, sceneID := range want {
+		scene, err := client.GetActiveScene(context.Background(), roomID)
+		if err != nil {
+			t.Fatalf("GetActiveScene(%q) returned error: %v", roomID, err)
+		}
+		if scene.ID != sceneID {
+			t.Errorf("GetActiveScene(%q) = %s, want %s", roomID, scene.ID, sceneID)
+		}
+	}
+	if _, err := client.GetRecalledScene(context.Background(), "room-1"); err != nil {
+		t.Fatalf("GetRecalledScene() returned error: %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("bridge was requested %d times for several rooms, want a single listing of the scenes", got)
+	}
+}
+
+func TestSceneCacheSharesListing(t *testing.T) {
+	scenes := []Scene{
+		{ID: "static", Group: Group{ID: "room-1", Type: "room"}, Status: SceneStatus{Active: SceneStatusStatic}},
+		{ID: "first", Group: Group{ID: "room-1", Type: "room"}, Status: SceneStatus{Active: SceneStatusDynamicPalette}},
+		{ID: "second", Group: Group{ID: "room-1", Type: "room"},
+			Status: SceneStatus{Active: SceneStatusDynamicPalette}},
+		{ID: "other", Group: Group{ID: "room-2", Type: "room"}, Status: SceneStatus{Active: SceneStatusDynamicPalette}},
+		{ID: "zone", Group: Group{ID: "zone-1", Type: "zone"}, Status: SceneStatus{Active: SceneStatusDynamicPalette}},
+	}
+	calls := 0
+	list := func() ([]Scene, error) {
+		calls++
+		return scenes, nil
+	}
+	cache := newSceneCache(time.Minute)
+
+	// the first dynamic scene of each room is active, scenes of zones aren't indexed
+	got := make(map[string]string)
+	for _, roomID := range []string{"room-1", "room-2", "zone-1", "room-3"} {
+		active, err := cache.activeScenes(list)
+		if err != nil {
+			t.Fatalf("activeScenes() for %s returned error: %v", roomID, err)
+		}
+		if scene, ok := active[roomID]; ok {
+			got[roomID] = scene.ID
+		}
+	}
+	if calls != 1 {
+		t.Errorf("list was called %d times, want once for all rooms", calls)
+	}
+	if len(got) != 2 || got["room-1"] != "first" || got["room-2"] != "other" {
+		t.Errorf("activeScenes() = %v, want first for room-1 and other for room-2", got)
+	}
+	if all, _ := cache.allScenes(list); len(all) != len(scenes) || calls != 1 {
+		t.Errorf("allScenes() = %d scenes after %d listings, want all scenes of the cached listing", len(all),
+			calls)
+	}
+}
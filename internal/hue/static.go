@@ -0,0 +1,48 @@
+package hue
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// reachabilityTimeout is how long connecting to a statically configured bridge may take
+const reachabilityTimeout = 5 * time.Second
+
+// SetBridgeAddress sets a static address of the bridge, a host with an optional port. Discovery is skipped
+// for a static address, StartAutoDiscovery and Rediscover only verify that the bridge is reachable.
+// Must be called before discovery is started.
+func (c *Client) SetBridgeAddress(address string) error {
+	u, err := url.Parse("https://" + address)
+	if err != nil || u.Host != address || u.Hostname() == "" {
+		return fmt.Errorf("invalid Hue bridge address %q, must be a host with an optional port", address)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.staticAddress = true
+	c.bridgeAddress = address
+	return nil
+}
+
+// verifyReachable verifies that a TCP connection to the statically configured bridge can be established.
+func (c *Client) verifyReachable(ctx context.Context) error {
+	address := c.BridgeAddress()
+	u := url.URL{Host: address}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+
+	dialer := net.Dialer{Timeout: reachabilityTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBridgeUnreachable, err)
+	}
+	conn.Close()
+
+	c.logger.Info().Str("address", address).Msg("Using static Hue bridge address")
+	return nil
+}
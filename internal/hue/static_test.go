@@ -0,0 +1,105 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSetBridgeAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "192.168.1.20"},
+		{address: "192.168.1.20:8443"},
+		{address: "hue-bridge.local"},
+		{address: "[fe80::1]:443"},
+		{address: "", wantErr: true},
+		{address: ":443", wantErr: true},
+		{address: "https://192.168.1.20", wantErr: true},
+		{address: "192.168.1.20/api", wantErr: true},
+		{address: "user@192.168.1.20", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			client := NewClient("", "test-user", zerolog.Nop())
+			err := client.SetBridgeAddress(tt.address)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SetBridgeAddress(%q) returned no error", tt.address)
+				}
+				if client.BridgeAddress() != "" {
+					t.Errorf("BridgeAddress() = %q after an invalid address, want none", client.BridgeAddress())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetBridgeAddress(%q) returned error: %v", tt.address, err)
+			}
+			if got := client.BridgeAddress(); got != tt.address {
+				t.Errorf("BridgeAddress() = %q, want %q", got, tt.address)
+			}
+		})
+	}
+}
+
+func TestStaticAddressSkipsDiscovery(t *testing.T) {
+	var host atomic.Value
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		host.Store(r.Host)
+		_, _ = w.Write([]byte(testLightResponse))
+	})
+	var queries atomic.Int32
+	client.queryMDNS = answerMDNS(&queries)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := client.StartAutoDiscovery(ctx); err != nil {
+		t.Fatalf("StartAutoDiscovery() with a reachable static address returned error: %v", err)
+	}
+	if err := client.Rediscover(ctx); err != nil {
+		t.Fatalf("Rediscover() with a reachable static address returned error: %v", err)
+	}
+	if queries.Load() != 0 {
+		t.Errorf("sent %d mDNS queries, want none with a static address", queries.Load())
+	}
+
+	if _, err := client.GetLight(ctx, "light-1"); err != nil {
+		t.Fatalf("GetLight() returned error: %v", err)
+	}
+	if got := host.Load(); got != client.BridgeAddress() {
+		t.Errorf("GetLight() requested host %v, want the configured %s", got, client.BridgeAddress())
+	}
+}
+
+func TestStaticAddressUnreachable(t *testing.T) {
+	// nothing listens on the address of a closed listener
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	client := NewClient("", "test-user", zerolog.Nop())
+	if err := client.SetBridgeAddress(address); err != nil {
+		t.Fatal(err)
+	}
+	var queries atomic.Int32
+	client.queryMDNS = answerMDNS(&queries)
+	client.SetDiscoveryTimeout(100 * time.Millisecond)
+
+	if err := client.StartAutoDiscovery(context.Background()); !errors.Is(err, ErrBridgeUnreachable) {
+		t.Errorf("StartAutoDiscovery() error = %v, want ErrBridgeUnreachable", err)
+	}
+	if queries.Load() != 0 {
+		t.Errorf("sent %d mDNS queries, want none with a static address", queries.Load())
+	}
+}
@@ -1,30 +1,191 @@
 package hue
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/metrics"
+	"golang.org/x/time/rate"
+)
+
+// DefaultRequestsPerSecond is the default rate of requests sent to the bridge, matching its documented limit
+const DefaultRequestsPerSecond = 10
+
+const (
+	// minRequestsPerSecond is the lowest rate requests are slowed down to while the bridge rate limits them
+	minRequestsPerSecond = 1
+	// rateLimitRecoveryStep is how much the rate of requests increases with every successful response until it's
+	// back at the configured rate
+	rateLimitRecoveryStep = 0.1
+	// defaultRateLimitPause is how long requests are paused after a 429 response without a Retry-After header
+	defaultRateLimitPause = time.Second
 )
 
-// hueTransport is a http.RoundTripper that adds the Hue application key to the request headers.
+// hueTransport is a http.RoundTripper that adds the Hue application key to the request headers and limits
+// the rate of requests sent to the bridge. When the bridge rate limits a request, further requests are paused
+// for the Retry-After of the response and sent at half the rate, which recovers with every successful response.
 type hueTransport struct {
 	hueUsername string
+	limiter     *rate.Limiter
+	tlsConfig   *tls.Config
+
+	mu          sync.Mutex // Mutex to protect limit and pausedUntil updates
+	limit       rate.Limit // configured rate of requests, the limiter's rate is lower while recovering from a 429
+	pausedUntil time.Time  // no request is sent before this time
 
 	T http.RoundTripper
 }
 
 // newHueTransport creates a new hueTransport with the given hueUsername.
 func newHueTransport(hueUsername string) *hueTransport {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true, // Skip TLS verification for local bridge, see verifyBridgeID
+	}
 	return &hueTransport{
 		hueUsername: hueUsername,
+		limiter:     rate.NewLimiter(DefaultRequestsPerSecond, 1),
+		tlsConfig:   tlsConfig,
+		limit:       DefaultRequestsPerSecond,
 		T: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true, // Skip TLS verification for local bridge
-			},
+			TLSClientConfig: tlsConfig,
 		},
 	}
 }
 
+// verifyBridgeID makes the transport only accept a certificate whose common name is the given bridge ID. Bridges
+// don't present a certificate for their host name, so the chain is verified against roots without a host name
+// and the bridge is identified by the common name instead. Without roots, only the common name is verified.
+func (t *hueTransport) verifyBridgeID(bridgeID string, roots *x509.CertPool) {
+	t.tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		return verifyBridgeCertificate(rawCerts, bridgeID, roots)
+	}
+}
+
+// verifyBridgeCertificate verifies that the leaf certificate chains up to one of the roots, if any, and that its
+// common name equals the bridge ID.
+func verifyBridgeCertificate(rawCerts [][]byte, bridgeID string, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("%w: no certificate presented", ErrCertificateMismatch)
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, rawCert := range rawCerts {
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCertificateMismatch, err)
+		}
+		certs = append(certs, cert)
+	}
+	leaf := certs[0]
+
+	if roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCertificateMismatch, err)
+		}
+	}
+
+	if !strings.EqualFold(leaf.Subject.CommonName, bridgeID) {
+		return fmt.Errorf("%w: certificate is issued to %q instead of bridge %q", ErrCertificateMismatch,
+			leaf.Subject.CommonName, bridgeID)
+	}
+	return nil
+}
+
+// setLimit sets the configured rate of requests.
+func (t *hueTransport) setLimit(limit rate.Limit) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.limit = limit
+	t.limiter.SetLimit(limit)
+}
+
+// waitUntilResumed waits until requests are no longer paused after a 429 response. If the pause outlasts the
+// deadline of ctx, a RateLimitError is returned right away instead.
+func (t *hueTransport) waitUntilResumed(ctx context.Context) error {
+	t.mu.Lock()
+	pause := time.Until(t.pausedUntil)
+	t.mu.Unlock()
+
+	if pause <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < pause {
+		return &RateLimitError{RetryAfter: pause}
+	}
+
+	timer := time.NewTimer(pause)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// slowDown pauses requests for retryAfter, or defaultRateLimitPause if zero, and halves the rate of requests.
+func (t *hueTransport) slowDown(retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = defaultRateLimitPause
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if until := time.Now().Add(retryAfter); until.After(t.pausedUntil) {
+		t.pausedUntil = until
+	}
+	t.limiter.SetLimit(max(t.limiter.Limit()/2, min(minRequestsPerSecond, t.limit)))
+}
+
+// speedUp increases the rate of requests by rateLimitRecoveryStep, up to the configured rate.
+func (t *hueTransport) speedUp() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if current := t.limiter.Limit(); current < t.limit {
+		t.limiter.SetLimit(min(current+rateLimitRecoveryStep, t.limit))
+	}
+}
+
 func (t *hueTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.waitUntilResumed(req.Context()); err != nil {
+		return nil, err
+	}
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
 	req.Header.Set("hue-application-key", t.hueUsername)
-	return t.T.RoundTrip(req)
+
+	start := time.Now()
+	resp, err := t.T.RoundTrip(req)
+	metrics.HueRequestDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.HueRequests.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	metrics.HueRequests.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		t.slowDown(newRateLimitError(resp).RetryAfter)
+	} else if resp.StatusCode < http.StatusBadRequest {
+		t.speedUp()
+	}
+	return resp, nil
 }
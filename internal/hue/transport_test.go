@@ -0,0 +1,91 @@
+package hue
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newTestCertificate creates a certificate with the common name, signed by the parent or self-signed if parent is
+// nil, and returns it with its DER encoding and private key.
+func newTestCertificate(t *testing.T, commonName string, isCA bool, parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey) (*x509.Certificate, []byte, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, der, key
+}
+
+func TestVerifyBridgeCertificate(t *testing.T) {
+	const bridgeID = "001788fffe4a1b2c"
+
+	root, _, rootKey := newTestCertificate(t, "root-bridge", true, nil, nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	_, signed, _ := newTestCertificate(t, bridgeID, false, root, rootKey)
+	_, signedUpperCase, _ := newTestCertificate(t, "001788FFFE4A1B2C", false, root, rootKey)
+	intermediate, intermediateDER, intermediateKey := newTestCertificate(t, "intermediate", true, root, rootKey)
+	_, signedByIntermediate, _ := newTestCertificate(t, bridgeID, false, intermediate, intermediateKey)
+	_, signedOtherID, _ := newTestCertificate(t, "001788fffe000000", false, root, rootKey)
+	_, selfSigned, _ := newTestCertificate(t, bridgeID, false, nil, nil)
+
+	tests := []struct {
+		name     string
+		rawCerts [][]byte
+		roots    *x509.CertPool
+		wantErr  bool
+	}{
+		{name: "signed by root", rawCerts: [][]byte{signed}, roots: roots},
+		{name: "signed by root, upper case common name", rawCerts: [][]byte{signedUpperCase}, roots: roots},
+		{name: "signed by intermediate", rawCerts: [][]byte{signedByIntermediate, intermediateDER}, roots: roots},
+		{name: "intermediate missing", rawCerts: [][]byte{signedByIntermediate}, roots: roots, wantErr: true},
+		{name: "signed by root for another bridge", rawCerts: [][]byte{signedOtherID}, roots: roots, wantErr: true},
+		{name: "self-signed with bridge ID", rawCerts: [][]byte{selfSigned}, roots: roots, wantErr: true},
+		{name: "self-signed without roots", rawCerts: [][]byte{selfSigned}},
+		{name: "another bridge without roots", rawCerts: [][]byte{signedOtherID}, wantErr: true},
+		{name: "no certificate", roots: roots, wantErr: true},
+		{name: "malformed certificate", rawCerts: [][]byte{[]byte("not a certificate")}, roots: roots, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyBridgeCertificate(tt.rawCerts, bridgeID, tt.roots)
+			if tt.wantErr {
+				if !errors.Is(err, ErrCertificateMismatch) {
+					t.Errorf("verifyBridgeCertificate() = %v, want ErrCertificateMismatch", err)
+				}
+			} else if err != nil {
+				t.Errorf("verifyBridgeCertificate() = %v, want nil", err)
+			}
+		})
+	}
+}
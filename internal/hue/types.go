@@ -1,11 +1,22 @@
 package hue
 
+import (
+	"encoding/json"
+	"math"
+)
+
 // hueResponse is a generic response from the Hue API
 type hueResponse[T any] struct {
-	Errors []struct {
-		Type string `json:"type"`
+	Errors []APIErrorDetail `json:"errors"`
+	Data   []T              `json:"data"`
+}
+
+// apiError returns the errors reported in the response, nil if there are none
+func (r hueResponse[T]) apiError() error {
+	if len(r.Errors) == 0 {
+		return nil
 	}
-	Data []T `json:"data"`
+	return &APIError{Errors: r.Errors}
 }
 
 // Coords represents a coordinate pair
@@ -21,7 +32,13 @@ type On struct {
 
 // Dimming represents the brightness of a light
 type Dimming struct {
-	Brightness float64 `json:"brightness"`
+	Brightness float64 `json:"brightness"` // percentage (0-100)
+}
+
+// Percent returns the brightness rounded to a whole percent. Unlike the 0-254 brightness of the V1 API, the V2
+// API reports the brightness in percent already, so it must not be scaled.
+func (d Dimming) Percent() int {
+	return int(math.Round(d.Brightness))
 }
 
 // ColorTemperature represents the color temperature of a light
@@ -66,13 +83,112 @@ type Dynamics struct {
 	Status DynamicsStatus `json:"status"`
 }
 
+// EffectStatusNone is the effect status of a light without an active effect
+const EffectStatusNone = "no_effect"
+
+// Effects contains the status of the effect of a light, e.g. candle or fire
+type Effects struct {
+	Status string `json:"status"`
+}
+
+// ColorMode is the mode that determines the color of a light
+type ColorMode string
+
+const (
+	ColorModeXY     ColorMode = "xy"     // the color is set by its XY coordinates
+	ColorModeCT     ColorMode = "ct"     // the color is set by its color temperature
+	ColorModeEffect ColorMode = "effect" // an effect like candle or fire plays with the XY color
+)
+
+// GradientPoint represents a single color point of a gradient
+type GradientPoint struct {
+	Color struct {
+		XY Coords `json:"xy"`
+	} `json:"color"`
+}
+
+// Gradient represents the gradient of a gradient light
+type Gradient struct {
+	Points        []GradientPoint `json:"points"`
+	PointsCapable int             `json:"points_capable"`
+}
+
 // Light represents a Hue light
 type Light struct {
+	ID               string           `json:"id"`
+	Owner            Group            `json:"owner"`
+	Metadata         Metadata         `json:"metadata"`
 	On               On               `json:"on"`
 	Dimming          Dimming          `json:"dimming"`
 	ColorTemperature ColorTemperature `json:"color_temperature"`
 	Color            Color            `json:"color"`
 	Dynamics         Dynamics         `json:"dynamics"`
+	Effects          *Effects         `json:"effects,omitempty"`
+	Gradient         *Gradient        `json:"gradient,omitempty"`
+
+	// ColorMode is the mode that determines the color of the light, set when the light is decoded
+	ColorMode ColorMode `json:"-"`
+}
+
+// UnmarshalJSON decodes the light and sets its ColorMode. Unlike the V1 API, the V2 light resource has no color
+// mode, but the bridge reports the color temperature as invalid once an XY color is set, and vice versa, so
+// mirek_valid tells which of the two reported colors is current.
+func (l *Light) UnmarshalJSON(data []byte) error {
+	type light Light // without this method
+	if err := json.Unmarshal(data, (*light)(l)); err != nil {
+		return err
+	}
+	l.ColorMode = l.detectColorMode()
+	return nil
+}
+
+// ActiveEffect returns the name of the effect the light plays, e.g. candle, and false if it plays none.
+func (l *Light) ActiveEffect() (string, bool) {
+	if l.Effects == nil || l.Effects.Status == "" || l.Effects.Status == EffectStatusNone {
+		return "", false
+	}
+	return l.Effects.Status, true
+}
+
+// detectColorMode returns the mode that determines the color of the light, an effect taking precedence over the
+// color temperature, which takes precedence over a stale XY color.
+func (l *Light) detectColorMode() ColorMode {
+	if _, ok := l.ActiveEffect(); ok {
+		return ColorModeEffect
+	}
+	if l.ColorTemperature.MirekValid && l.ColorTemperature.Mirek > 0 {
+		return ColorModeCT
+	}
+	return ColorModeXY
+}
+
+// GroupedLight represents the aggregated state of all lights in a Hue room or zone
+type GroupedLight struct {
+	ID               string            `json:"id"`
+	Owner            Group             `json:"owner"`
+	On               On                `json:"on"`
+	Dimming          Dimming           `json:"dimming"`
+	ColorTemperature *ColorTemperature `json:"color_temperature,omitempty"`
+	Color            *Color            `json:"color,omitempty"`
+}
+
+// Light returns the grouped light as a single light with the aggregated state, so it can be synchronized like
+// any other light. The light has no gradient and no dynamics.
+func (g GroupedLight) Light() Light {
+	light := Light{
+		ID:      g.ID,
+		Owner:   g.Owner,
+		On:      g.On,
+		Dimming: g.Dimming,
+	}
+	if g.ColorTemperature != nil {
+		light.ColorTemperature = *g.ColorTemperature
+	}
+	if g.Color != nil {
+		light.Color = *g.Color
+	}
+	light.ColorMode = light.detectColorMode()
+	return light
 }
 
 // Group represents a Hue group
@@ -81,14 +197,38 @@ type Group struct {
 	Type string `json:"rtype"`
 }
 
+// Room represents a Hue room
+type Room struct {
+	ID       string   `json:"id"`
+	Metadata Metadata `json:"metadata"`
+	Children []Group  `json:"children"`
+}
+
+// Contains returns true if the light belongs to the room
+func (r Room) Contains(light Light) bool {
+	for _, child := range r.Children {
+		if child.ID == light.ID || child.ID == light.Owner.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// Metadata contains the human-readable metadata of a Hue resource
+type Metadata struct {
+	Name string `json:"name"`
+}
+
 // Scene represents a Hue scene
 type Scene struct {
-	ID      string        `json:"id"`
-	Palette Palette       `json:"palette"`
-	Speed   float64       `json:"speed"`
-	Status  SceneStatus   `json:"status"`
-	Group   Group         `json:"group"`
-	Actions []SceneAction `json:"actions"`
+	ID          string        `json:"id"`
+	Metadata    Metadata      `json:"metadata"`
+	Palette     Palette       `json:"palette"`
+	Speed       float64       `json:"speed"`
+	AutoDynamic bool          `json:"auto_dynamic"`
+	Status      SceneStatus   `json:"status"`
+	Group       Group         `json:"group"`
+	Actions     []SceneAction `json:"actions"`
 }
 
 // SceneAction represents a single action in a scene
@@ -120,13 +260,40 @@ type PaletteColorTemp struct {
 	Dimming          Dimming          `json:"dimming"`
 }
 
+// SceneStatusActive represents whether a scene is recalled and how
+type SceneStatusActive string
+
+const (
+	SceneStatusInactive       SceneStatusActive = "inactive"
+	SceneStatusStatic         SceneStatusActive = "static"
+	SceneStatusDynamicPalette SceneStatusActive = "dynamic_palette"
+)
+
 // SceneStatus represents the status of a scene
 type SceneStatus struct {
-	Active     string `json:"active"`
-	LastRecall string `json:"last_recall,omitempty"`
+	Active     SceneStatusActive `json:"active"`
+	LastRecall string            `json:"last_recall,omitempty"`
 }
 
 // DiscoveryResponse represents the response from the Hue bridge discovery endpoint
 type DiscoveryResponse struct {
 	Address string
 }
+
+// LightStateUpdate is the body of a light update request, nil fields are left unchanged
+type LightStateUpdate struct {
+	On               *On                     `json:"on,omitempty"`
+	Dimming          *Dimming                `json:"dimming,omitempty"`
+	Color            *ColorUpdate            `json:"color,omitempty"`
+	ColorTemperature *ColorTemperatureUpdate `json:"color_temperature,omitempty"`
+}
+
+// ColorUpdate sets the color of a light
+type ColorUpdate struct {
+	XY Coords `json:"xy"`
+}
+
+// ColorTemperatureUpdate sets the color temperature of a light
+type ColorTemperatureUpdate struct {
+	Mirek int `json:"mirek"`
+}
@@ -0,0 +1,107 @@
+package hue
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLightColorMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		light string
+		want  ColorMode
+	}{
+		{
+			name: "ct with stale xy",
+			light: `{"id":"light-1","on":{"on":true},"dimming":{"brightness":100},` +
+				`"color_temperature":{"mirek":366,"mirek_valid":true},"color":{"xy":{"x":0.675,"y":0.322}},` +
+				`"effects":{"status":"no_effect"}}`,
+			want: ColorModeCT,
+		},
+		{
+			name: "xy with stale ct",
+			light: `{"id":"light-1","on":{"on":true},"dimming":{"brightness":100},` +
+				`"color_temperature":{"mirek":366,"mirek_valid":false},"color":{"xy":{"x":0.675,"y":0.322}},` +
+				`"effects":{"status":"no_effect"}}`,
+			want: ColorModeXY,
+		},
+		{
+			name: "xy without color temperature support",
+			light: `{"id":"light-1","on":{"on":true},"dimming":{"brightness":100},` +
+				`"color":{"xy":{"x":0.675,"y":0.322}}}`,
+			want: ColorModeXY,
+		},
+		{
+			name: "effect",
+			light: `{"id":"light-1","on":{"on":true},"dimming":{"brightness":100},` +
+				`"color_temperature":{"mirek":366,"mirek_valid":true},"color":{"xy":{"x":0.675,"y":0.322}},` +
+				`"effects":{"status":"candle"}}`,
+			want: ColorModeEffect,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp hueResponse[Light]
+			if err := json.Unmarshal([]byte(`{"errors":[],"data":[`+tt.light+`]}`), &resp); err != nil {
+				t.Fatal(err)
+			}
+			light := resp.Data[0]
+			if light.ColorMode != tt.want {
+				t.Fatalf("ColorMode = %q, want %q", light.ColorMode, tt.want)
+			}
+
+			// the color is converted from the color the mode selects, ignoring the stale one
+			var opts ConversionOptions
+			var wantR, wantG, wantB int
+			if tt.want == ColorModeCT {
+				wantR, wantG, wantB = ctToRGB(1000000/366, 100)
+			} else {
+				wantR, wantG, wantB = coordsToRGB(0.675, 0.322, 100, light.Color.GamutType, light.Color.Gamut,
+					opts.ColorGamma, opts.BrightnessConversion)
+			}
+			r, g, b := ColorToRGB(&light, nil, opts)
+			if r != wantR || g != wantG || b != wantB {
+				t.Errorf("ColorToRGB() = (%d, %d, %d), want (%d, %d, %d)", r, g, b, wantR, wantG, wantB)
+			}
+		})
+	}
+}
+
+func TestGroupedLightColorMode(t *testing.T) {
+	ct := GroupedLight{On: On{On: true}, ColorTemperature: &ColorTemperature{Mirek: 300, MirekValid: true}}
+	if light := ct.Light(); light.ColorMode != ColorModeCT {
+		t.Errorf("ColorMode of grouped light with valid color temperature = %q, want %q", light.ColorMode,
+			ColorModeCT)
+	}
+	xy := GroupedLight{On: On{On: true}, Color: &Color{XY: Coords{X: 0.3, Y: 0.3}}}
+	if light := xy.Light(); light.ColorMode != ColorModeXY {
+		t.Errorf("ColorMode of grouped light with XY color = %q, want %q", light.ColorMode, ColorModeXY)
+	}
+}
+
+func TestDimmingPercent(t *testing.T) {
+	// the V2 brightness is a percent, scaling it like the V1 brightness of 0-254 would dim full brightness to 39%
+	tests := map[float64]int{0: 0, 0.4: 0, 1: 1, 50: 50, 50.8: 51, 99.6: 100, 100: 100}
+	for brightness, want := range tests {
+		if got := (Dimming{Brightness: brightness}).Percent(); got != want {
+			t.Errorf("Percent() of brightness %v = %d, want %d", brightness, got, want)
+		}
+	}
+}
+
+func TestRoomContains(t *testing.T) {
+	room := Room{Children: []Group{{ID: "device-1", Type: "device"}, {ID: "light-2", Type: "light"}}}
+	tests := []struct {
+		light Light
+		want  bool
+	}{
+		{light: Light{ID: "light-1", Owner: Group{ID: "device-1"}}, want: true},
+		{light: Light{ID: "light-2", Owner: Group{ID: "device-2"}}, want: true},
+		{light: Light{ID: "light-3", Owner: Group{ID: "device-3"}}, want: false},
+	}
+	for _, tt := range tests {
+		if got := room.Contains(tt.light); got != tt.want {
+			t.Errorf("Contains(%s) = %v, want %v", tt.light.ID, got, tt.want)
+		}
+	}
+}
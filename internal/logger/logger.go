@@ -1,20 +1,84 @@
 package logger
 
 import (
+	"io"
+	"os"
+	"strings"
+
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 )
 
-// Default returns a new logger with the configured log level
+const (
+	// FormatConsole writes human-friendly log lines, the default
+	FormatConsole = "console"
+	// FormatJSON writes one JSON object per log line for log shippers
+	FormatJSON = "json"
+)
+
+// componentLevels are the log levels of the components configured in component_log_levels, set by Default
+var componentLevels map[string]zerolog.Level
+
+// Default returns a new logger with the configured log level, format and output
 func Default() zerolog.Logger {
-	logger := zerolog.New(zerolog.NewConsoleWriter())
+	var out io.Writer = os.Stdout
+	var fileErr error
+	path := viper.GetString("log_file")
+	if path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			fileErr = err
+		} else {
+			out = file
+		}
+	}
+
+	var logger zerolog.Logger
+	format := strings.ToLower(viper.GetString("log_format"))
+	switch format {
+	case FormatJSON:
+		logger = zerolog.New(out).With().Timestamp().Logger()
+	default:
+		logger = zerolog.New(zerolog.ConsoleWriter{Out: out, NoColor: out != os.Stdout})
+	}
+
+	if format != "" && format != FormatConsole && format != FormatJSON {
+		logger.Error().Str("format", format).Msg("Invalid log format in config, defaulting to console format")
+	}
+	if fileErr != nil {
+		logger.Error().Err(fileErr).Str("path", path).Msg("Failed to open log file, logging to stdout")
+	}
 
 	level, err := zerolog.ParseLevel(viper.GetString("log_level"))
 	if err != nil {
 		logger.Error().Err(err).Msg("Invalid log level in config, defaulting to Info level")
 		level = zerolog.InfoLevel
 	}
-	zerolog.SetGlobalLevel(level)
 
+	// the global level lets the most verbose component through, each logger filters by its own level
+	globalLevel := level
+	componentLevels = make(map[string]zerolog.Level)
+	for component, value := range viper.GetStringMapString("component_log_levels") {
+		componentLevel, err := zerolog.ParseLevel(value)
+		if err != nil {
+			logger.Error().Err(err).Str("component", component).
+				Msg("Invalid component log level in config, using the global level")
+			continue
+		}
+		componentLevels[strings.ToLower(component)] = componentLevel
+		globalLevel = min(globalLevel, componentLevel)
+	}
+	zerolog.SetGlobalLevel(globalLevel)
+
+	return logger.Level(level)
+}
+
+// Component returns a logger for the component, logging at the level configured for the component in
+// component_log_levels or at the level of the base logger otherwise.
+func Component(base zerolog.Logger, component string) zerolog.Logger {
+	logger := base.With().Str("component", component).Logger()
+	if level, ok := componentLevels[strings.ToLower(component)]; ok {
+		logger = logger.Level(level)
+	}
 	return logger
 }
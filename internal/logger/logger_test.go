@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// loadTestLogger configures the logger with the given config keys, logging at info level to a file whose path is
+// returned. Viper and the global log level are reset after the test.
+func loadTestLogger(t *testing.T, keys map[string]any) string {
+	t.Helper()
+
+	viper.Reset()
+	globalLevel := zerolog.GlobalLevel()
+	t.Cleanup(func() {
+		viper.Reset()
+		zerolog.SetGlobalLevel(globalLevel)
+	})
+
+	path := filepath.Join(t.TempDir(), "hue2govee.log")
+	viper.Set("log_file", path)
+	viper.Set("log_level", "info")
+	for key, value := range keys {
+		viper.Set(key, value)
+	}
+	return path
+}
+
+// readLines returns the lines written to the log file.
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func TestDefaultJSONFormat(t *testing.T) {
+	path := loadTestLogger(t, map[string]any{"log_format": "JSON"})
+
+	log := Default()
+	log.Info().Str("deviceId", "AA:BB").Msg("Found Govee device")
+	log.Warn().Int("status", 503).Msg("Hue bridge temporarily unavailable")
+
+	lines := readLines(t, path)
+	if len(lines) != 2 {
+		t.Fatalf("log file has %d lines, want 2:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	for _, line := range lines {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q isn't valid JSON: %v", line, err)
+		}
+		if entry["level"] == nil || entry["message"] == nil || entry["time"] == nil {
+			t.Errorf("log line %q is missing the level, message or time", line)
+		}
+	}
+}
+
+func TestDefaultConsoleFormat(t *testing.T) {
+	path := loadTestLogger(t, nil)
+
+	log := Default()
+	log.Info().Msg("Found Govee device")
+
+	lines := readLines(t, path)
+	if len(lines) != 1 || json.Valid([]byte(lines[0])) || !strings.Contains(lines[0], "Found Govee device") {
+		t.Errorf("log file = %q, want a single human-friendly line", lines)
+	}
+}
+
+func TestComponentLevels(t *testing.T) {
+	path := loadTestLogger(t, map[string]any{
+		"log_format":           "json",
+		"component_log_levels": map[string]string{"govee": "debug", "hue": "warn"},
+	})
+
+	base := Default()
+	goveeLog, hueLog, syncLog := Component(base, "govee"), Component(base, "hue"), Component(base, "sync")
+	goveeLog.Debug().Msg("govee debug")
+	hueLog.Info().Msg("hue info")
+	hueLog.Warn().Msg("hue warn")
+	syncLog.Debug().Msg("sync debug")
+	syncLog.Info().Msg("sync info")
+	base.Debug().Msg("base debug")
+
+	var messages []string
+	for _, line := range readLines(t, path) {
+		var entry struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, entry.Message)
+	}
+	// components without a level of their own log at the global level
+	want := []string{"govee debug", "hue warn", "sync info"}
+	if strings.Join(messages, ",") != strings.Join(want, ",") {
+		t.Errorf("logged messages = %q, want %q", messages, want)
+	}
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// GoveeCommands counts the commands sent to Govee devices by command and result
+	GoveeCommands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hue2govee_govee_commands_total",
+		Help: "Number of commands sent to Govee devices.",
+	}, []string{"command", "result"})
+
+	// HueRequests counts the requests sent to the Hue bridge by status code, "error" if no response was received
+	HueRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hue2govee_hue_requests_total",
+		Help: "Number of requests sent to the Hue bridge.",
+	}, []string{"status"})
+
+	// HueRequestDuration observes the latency of requests sent to the Hue bridge
+	HueRequestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "hue2govee_hue_request_duration_seconds",
+		Help:    "Latency of requests sent to the Hue bridge.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// ActiveScenes is the number of dynamic scenes currently played on Govee devices
+	ActiveScenes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "hue2govee_active_dynamic_scenes",
+		Help: "Number of dynamic scenes currently played on Govee devices.",
+	})
+)
+
+// registry holds all metrics of the bridge
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(GoveeCommands, HueRequests, HueRequestDuration, ActiveScenes)
+}
+
+// Result returns the result label of an operation that failed with err.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// Handler returns the handler serving the metrics on /metrics.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// Serve exposes the metrics on /metrics at the given address until the context is done.
+func Serve(ctx context.Context, addr string, logger zerolog.Logger) {
+	server := &http.Server{Addr: addr, Handler: Handler()}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info().Str("addr", addr).Msg("Serving metrics")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error().Err(err).Msg("Metrics server failed")
+	}
+}
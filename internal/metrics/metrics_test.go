@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// scrape returns the metrics served at the URL in the Prometheus text format.
+func scrape(t *testing.T, url string) string {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s = %d, want 200", url, resp.StatusCode)
+	}
+	return string(body)
+}
+
+func TestServe(t *testing.T) {
+	// reserve a free port for the server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Serve(ctx, addr, zerolog.Nop())
+		close(done)
+	}()
+
+	GoveeCommands.WithLabelValues("colorwc", Result(nil)).Inc()
+	HueRequests.WithLabelValues("200").Inc()
+	HueRequestDuration.Observe(0.05)
+	ActiveScenes.Set(2)
+
+	// wait for the server to listen
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			break
+		}
+	}
+	body := scrape(t, "http://"+addr+"/metrics")
+	for _, want := range []string{
+		`hue2govee_govee_commands_total{command="colorwc",result="ok"}`,
+		`hue2govee_hue_requests_total{status="200"}`,
+		"hue2govee_hue_request_duration_seconds_bucket",
+		"hue2govee_active_dynamic_scenes 2",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics don't contain %s:\n%s", want, body)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Serve() didn't return after the context was cancelled")
+	}
+	if _, err := http.Get("http://" + addr + "/metrics"); err == nil {
+		t.Error("server still serves metrics after the context was cancelled")
+	}
+}
+
+func TestResult(t *testing.T) {
+	if got := Result(nil); got != "ok" {
+		t.Errorf("Result(nil) = %q, want ok", got)
+	}
+	if got := Result(errors.New("failed")); got != "error" {
+		t.Errorf("Result(err) = %q, want error", got)
+	}
+}
@@ -0,0 +1,181 @@
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/rs/zerolog"
+)
+
+// DiscoveryPrefix is the topic prefix Home Assistant listens on for MQTT discovery
+const DiscoveryPrefix = "homeassistant"
+
+const (
+	availabilityOnline  = "online"
+	availabilityOffline = "offline"
+)
+
+// Client publishes retained messages to an MQTT broker
+type Client interface {
+	Publish(topic string, payload []byte) error
+}
+
+// StatePayload is the state of a synchronization published to its state topic
+type StatePayload struct {
+	State            string          `json:"state"` // ON or OFF
+	Color            *state.RGBColor `json:"color,omitempty"`
+	ColorTemperature int             `json:"color_temp_kelvin,omitempty"`
+	Brightness       int             `json:"brightness"`
+	Paused           bool            `json:"paused"`
+}
+
+// discoveryPayload announces a synchronization as a Home Assistant sensor
+type discoveryPayload struct {
+	Name                string `json:"name"`
+	UniqueID            string `json:"unique_id"`
+	StateTopic          string `json:"state_topic"`
+	ValueTemplate       string `json:"value_template"`
+	JSONAttributesTopic string `json:"json_attributes_topic"`
+	AvailabilityTopic   string `json:"availability_topic"`
+}
+
+// Publisher publishes the state pushed to each Govee device whenever it changes, along with Home Assistant
+// discovery and availability topics.
+type Publisher struct {
+	client Client
+	prefix string
+	logger zerolog.Logger
+
+	mu        sync.Mutex        // Mutex to protect announced and published updates
+	announced map[string]bool   // synchronizations announced to Home Assistant
+	published map[string][]byte // last state payload published per synchronization
+}
+
+// NewPublisher creates a Publisher publishing below the given topic prefix.
+func NewPublisher(client Client, prefix string, logger zerolog.Logger) *Publisher {
+	return &Publisher{
+		client:    client,
+		prefix:    strings.TrimSuffix(prefix, "/"),
+		logger:    logger,
+		announced: make(map[string]bool),
+		published: make(map[string][]byte),
+	}
+}
+
+// AvailabilityTopic returns the topic the availability of the bridge is published to.
+func (p *Publisher) AvailabilityTopic() string {
+	return p.prefix + "/availability"
+}
+
+// StateTopic returns the topic the state of the synchronization with the given ID is published to.
+func (p *Publisher) StateTopic(syncID string) string {
+	return fmt.Sprintf("%s/%s/state", p.prefix, topicID(syncID))
+}
+
+// DiscoveryTopic returns the Home Assistant discovery topic of the synchronization with the given ID.
+func (p *Publisher) DiscoveryTopic(syncID string) string {
+	return fmt.Sprintf("%s/sensor/%s_%s/config", DiscoveryPrefix, topicID(p.prefix), topicID(syncID))
+}
+
+// Run publishes every change of a synchronization state until the context is done.
+func (p *Publisher) Run(ctx context.Context, store *state.Store) {
+	updates, unsubscribe := store.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case st := <-updates:
+			if err := p.Publish(st); err != nil {
+				p.logger.Error().Err(err).Str("syncId", st.ID).Msg("Failed to publish synchronization state")
+			}
+		}
+	}
+}
+
+// PublishAll publishes availability and the current state of all synchronizations, e.g. after (re)connecting.
+func (p *Publisher) PublishAll(store *state.Store) {
+	p.mu.Lock()
+	clear(p.announced)
+	clear(p.published)
+	p.mu.Unlock()
+
+	if err := p.client.Publish(p.AvailabilityTopic(), []byte(availabilityOnline)); err != nil {
+		p.logger.Error().Err(err).Msg("Failed to publish availability")
+	}
+	states, _ := store.Snapshot()
+	for _, st := range states {
+		if err := p.Publish(st); err != nil {
+			p.logger.Error().Err(err).Str("syncId", st.ID).Msg("Failed to publish synchronization state")
+		}
+	}
+}
+
+// Publish announces the synchronization to Home Assistant if it wasn't yet and publishes its state if it
+// changed since it was last published.
+func (p *Publisher) Publish(st state.SyncState) error {
+	payload, err := json.Marshal(statePayload(st))
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.announced[st.ID] {
+		discovery, err := json.Marshal(discoveryPayload{
+			Name:                st.ID,
+			UniqueID:            topicID(p.prefix) + "_" + topicID(st.ID),
+			StateTopic:          p.StateTopic(st.ID),
+			ValueTemplate:       "{{ value_json.state }}",
+			JSONAttributesTopic: p.StateTopic(st.ID),
+			AvailabilityTopic:   p.AvailabilityTopic(),
+		})
+		if err != nil {
+			return err
+		}
+		if err := p.client.Publish(p.DiscoveryTopic(st.ID), discovery); err != nil {
+			return fmt.Errorf("failed to publish discovery: %w", err)
+		}
+		p.announced[st.ID] = true
+	}
+
+	if bytes.Equal(p.published[st.ID], payload) {
+		return nil
+	}
+	if err := p.client.Publish(p.StateTopic(st.ID), payload); err != nil {
+		return fmt.Errorf("failed to publish state: %w", err)
+	}
+	p.published[st.ID] = payload
+	return nil
+}
+
+// statePayload returns the state payload of a synchronization from the last command sent to its device.
+func statePayload(st state.SyncState) StatePayload {
+	payload := StatePayload{State: "OFF", Paused: st.Paused}
+	if cmd := st.LastCommand; cmd != nil && cmd.On {
+		payload.State = "ON"
+		payload.Brightness = cmd.Brightness
+		if cmd.ColorTemperature > 0 {
+			payload.ColorTemperature = cmd.ColorTemperature
+		} else {
+			color := cmd.Color
+			payload.Color = &color
+		}
+	}
+	return payload
+}
+
+var invalidTopicChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// topicID turns an ID into a topic level and Home Assistant object ID, replacing unsupported characters.
+func topicID(id string) string {
+	return invalidTopicChars.ReplaceAllString(id, "_")
+}
@@ -0,0 +1,199 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	"github.com/rs/zerolog"
+)
+
+// message is a message published to the mockClient
+type message struct {
+	topic   string
+	payload string
+}
+
+// mockClient records the published messages instead of sending them to a broker.
+type mockClient struct {
+	mu       sync.Mutex
+	messages []message
+}
+
+func (c *mockClient) Publish(topic string, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messages = append(c.messages, message{topic: topic, payload: string(payload)})
+	return nil
+}
+
+// take returns the messages published since the last call.
+func (c *mockClient) take() []message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	messages := c.messages
+	c.messages = nil
+	return messages
+}
+
+func onState(r, g, b, brightness int) state.SyncState {
+	return state.SyncState{ID: "desk", LastCommand: &state.Command{On: true,
+		Color: state.RGBColor{R: r, G: g, B: b}, Brightness: brightness}}
+}
+
+func TestPublish(t *testing.T) {
+	client := &mockClient{}
+	p := NewPublisher(client, "hue2govee/", zerolog.Nop())
+
+	if err := p.Publish(onState(255, 128, 0, 80)); err != nil {
+		t.Fatal(err)
+	}
+	messages := client.take()
+	if len(messages) != 2 {
+		t.Fatalf("published %v, want the discovery and the state", messages)
+	}
+	if messages[0].topic != "homeassistant/sensor/hue2govee_desk/config" {
+		t.Errorf("discovery topic = %q, want homeassistant/sensor/hue2govee_desk/config", messages[0].topic)
+	}
+	var discovery discoveryPayload
+	if err := json.Unmarshal([]byte(messages[0].payload), &discovery); err != nil {
+		t.Fatal(err)
+	}
+	want := discoveryPayload{Name: "desk", UniqueID: "hue2govee_desk", StateTopic: "hue2govee/desk/state",
+		ValueTemplate: "{{ value_json.state }}", JSONAttributesTopic: "hue2govee/desk/state",
+		AvailabilityTopic: "hue2govee/availability"}
+	if discovery != want {
+		t.Errorf("discovery = %+v, want %+v", discovery, want)
+	}
+	wantState := message{topic: "hue2govee/desk/state",
+		payload: `{"state":"ON","color":{"r":255,"g":128,"b":0},"brightness":80,"paused":false}`}
+	if messages[1] != wantState {
+		t.Errorf("state message = %+v, want %+v", messages[1], wantState)
+	}
+
+	// unchanged states aren't published again, the discovery is only published once
+	if err := p.Publish(onState(255, 128, 0, 80)); err != nil {
+		t.Fatal(err)
+	}
+	if messages := client.take(); len(messages) != 0 {
+		t.Errorf("published %v for an unchanged state, want nothing", messages)
+	}
+	if err := p.Publish(onState(255, 128, 0, 40)); err != nil {
+		t.Fatal(err)
+	}
+	if messages := client.take(); len(messages) != 1 || messages[0].topic != "hue2govee/desk/state" {
+		t.Errorf("published %v for a changed state, want only the state", messages)
+	}
+}
+
+func TestStatePayload(t *testing.T) {
+	tests := []struct {
+		name string
+		st   state.SyncState
+		want string
+	}{
+		{name: "nothing sent", st: state.SyncState{}, want: `{"state":"OFF","brightness":0,"paused":false}`},
+		{name: "off", st: state.SyncState{LastCommand: &state.Command{Brightness: 80}},
+			want: `{"state":"OFF","brightness":0,"paused":false}`},
+		{name: "paused", st: state.SyncState{Paused: true}, want: `{"state":"OFF","brightness":0,"paused":true}`},
+		{name: "color temperature",
+			st:   state.SyncState{LastCommand: &state.Command{On: true, ColorTemperature: 2700, Brightness: 50}},
+			want: `{"state":"ON","color_temp_kelvin":2700,"brightness":50,"paused":false}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(statePayload(tt.st))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("statePayload() = %s, want %s", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopicID(t *testing.T) {
+	tests := map[string]string{"desk": "desk", "light-1 → AA:BB": "light-1_AA_BB", "living room/tv": "living_room_tv"}
+	for id, want := range tests {
+		if got := topicID(id); got != want {
+			t.Errorf("topicID(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestPublishAll(t *testing.T) {
+	client := &mockClient{}
+	p := NewPublisher(client, "hue2govee", zerolog.Nop())
+	store := state.NewStore()
+	store.Update("desk", func(st *state.SyncState) {
+		st.LastCommand = &state.Command{On: true, Brightness: 80}
+	})
+
+	// after reconnecting, everything is published again, even if it was published before
+	for range 2 {
+		p.PublishAll(store)
+		messages := client.take()
+		topics := make([]string, len(messages))
+		for i, msg := range messages {
+			topics[i] = msg.topic
+		}
+		want := []string{"hue2govee/availability", "homeassistant/sensor/hue2govee_desk/config",
+			"hue2govee/desk/state"}
+		if len(topics) != len(want) || topics[0] != want[0] || topics[1] != want[1] || topics[2] != want[2] {
+			t.Errorf("PublishAll() published to %v, want %v", topics, want)
+		}
+		if messages[0].payload != availabilityOnline {
+			t.Errorf("availability = %q, want %q", messages[0].payload, availabilityOnline)
+		}
+	}
+}
+
+func TestRunPublishesChanges(t *testing.T) {
+	client := &mockClient{}
+	p := NewPublisher(client, "hue2govee", zerolog.Nop())
+	store := state.NewStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Run(ctx, store)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// updateUntilPublished applies update until the state of the desk is published with the given payload. The
+	// subscription may not be set up right away, so the update is repeated, unchanged states aren't republished.
+	updateUntilPublished := func(update func(st *state.SyncState), payload string) {
+		t.Helper()
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			store.Update("desk", update)
+			time.Sleep(5 * time.Millisecond)
+			for _, msg := range client.take() {
+				if msg.topic == "hue2govee/desk/state" && msg.payload == payload {
+					return
+				}
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("state %s wasn't published", payload)
+			}
+		}
+	}
+
+	updateUntilPublished(func(st *state.SyncState) {
+		st.LastCommand = &state.Command{On: true, Brightness: 80}
+	}, `{"state":"ON","color":{"r":0,"g":0,"b":0},"brightness":80,"paused":false}`)
+	updateUntilPublished(func(st *state.SyncState) {
+		st.Paused = true
+	}, `{"state":"ON","color":{"r":0,"g":0,"b":0},"brightness":80,"paused":true}`)
+}
@@ -0,0 +1,79 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cedrickring/hue-to-govee/internal/state"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rs/zerolog"
+)
+
+// DefaultTopicPrefix is the topic prefix used if none is configured
+const DefaultTopicPrefix = "hue2govee"
+
+// publishTimeout is how long publishing a single message may take
+const publishTimeout = 5 * time.Second
+
+// Options configure the connection to the MQTT broker
+type Options struct {
+	Broker      string // e.g. tcp://localhost:1883
+	Username    string
+	Password    string
+	TopicPrefix string
+}
+
+// pahoClient publishes retained messages with a paho client
+type pahoClient struct {
+	client paho.Client
+}
+
+func (c pahoClient) Publish(topic string, payload []byte) error {
+	token := c.client.Publish(topic, 1, true, payload)
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("timed out publishing to %s", topic)
+	}
+	return token.Error()
+}
+
+// Serve publishes the synchronization states to the MQTT broker until the context is done. The bridge is marked
+// offline when it disconnects, and everything is published again after reconnecting.
+func Serve(ctx context.Context, opts Options, store *state.Store, logger zerolog.Logger) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	// the current states are published by the connect handler, every time the client (re)connects
+	var publisher *Publisher
+	clientOpts := paho.NewClientOptions().
+		AddBroker(opts.Broker).
+		SetClientID("hue2govee-"+hostname).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetWill(strings.TrimSuffix(opts.TopicPrefix, "/")+"/availability", availabilityOffline, 1, true).
+		SetOnConnectHandler(func(paho.Client) {
+			logger.Info().Str("broker", opts.Broker).Msg("Connected to MQTT broker")
+			go publisher.PublishAll(store)
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			logger.Warn().Err(err).Msg("Lost connection to MQTT broker, reconnecting")
+		})
+
+	client := paho.NewClient(clientOpts)
+	publisher = NewPublisher(pahoClient{client: client}, opts.TopicPrefix, logger)
+
+	// with connect retry, the client keeps connecting in the background
+	client.Connect()
+	defer func() {
+		client.Publish(publisher.AvailabilityTopic(), 1, true, availabilityOffline).WaitTimeout(publishTimeout)
+		client.Disconnect(250)
+	}()
+
+	publisher.Run(ctx, store)
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// fileContent is the content of the state file
+type fileContent struct {
+	UpdatedAt        time.Time   `json:"updatedAt"`
+	Synchronizations []SyncState `json:"synchronizations"`
+}
+
+// WriteFilePeriodically writes the store's state to path every interval if it changed, until the context is done.
+func WriteFilePeriodically(ctx context.Context, logger zerolog.Logger, store *Store, path string, interval time.Duration) {
+	var lastVersion uint64
+	written := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+			states, version := store.Snapshot()
+			if written && version == lastVersion {
+				continue
+			}
+
+			if err := WriteFile(path, states); err != nil {
+				logger.Error().Err(err).Str("path", path).Msg("Failed to write state file")
+				continue
+			}
+			lastVersion, written = version, true
+		}
+	}
+}
+
+// WriteFile atomically writes the given states to path by writing a temp file and renaming it.
+func WriteFile(path string, states []SyncState) error {
+	b, err := json.MarshalIndent(fileContent{UpdatedAt: time.Now(), Synchronizations: states}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// readFile reads the state file, failing the test if it isn't valid.
+func readFile(t *testing.T, path string) fileContent {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content fileContent
+	if err := json.Unmarshal(b, &content); err != nil {
+		t.Fatalf("state file isn't valid JSON: %v\n%s", err, b)
+	}
+	return content
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	states := []SyncState{{
+		ID:            "desk",
+		HueLightID:    "light-1",
+		GoveeDeviceID: "AA:BB:CC:DD:EE:FF:00:11",
+		Hue:           &HueState{On: true, Brightness: 50},
+		LastCommand:   &Command{On: true, Color: RGBColor{R: 255}, Brightness: 50},
+		ActiveScene:   "scene-1",
+		Healthy:       true,
+	}}
+
+	if err := WriteFile(path, states); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+	content := readFile(t, path)
+	if len(content.Synchronizations) != 1 {
+		t.Fatalf("state file has %d synchronizations, want 1", len(content.Synchronizations))
+	}
+	got := content.Synchronizations[0]
+	if got.ID != "desk" || *got.Hue != *states[0].Hue || got.LastCommand.Color != states[0].LastCommand.Color ||
+		got.ActiveScene != "scene-1" || !got.Healthy {
+		t.Errorf("state file has synchronization %+v, want %+v", got, states[0])
+	}
+	if content.UpdatedAt.IsZero() {
+		t.Error("updatedAt of the state file isn't set")
+	}
+
+	// the file is replaced without leaving temp files behind
+	if err := WriteFile(path, nil); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+	if content := readFile(t, path); len(content.Synchronizations) != 0 {
+		t.Errorf("state file has %d synchronizations after rewriting it, want 0", len(content.Synchronizations))
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d files, want only the state file", len(entries))
+	}
+}
+
+func TestWriteFileMissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "state.json")
+	if err := WriteFile(path, nil); err == nil {
+		t.Error("WriteFile() into a missing directory = nil, want an error")
+	}
+}
+
+func TestWriteFilePeriodically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := NewStore()
+	store.Update("desk", func(st *SyncState) { st.Healthy = true })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go WriteFilePeriodically(ctx, zerolog.Nop(), store, path, 10*time.Millisecond)
+
+	waitForFile := func(what string, cond func(fileContent) bool) fileContent {
+		t.Helper()
+
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			if content := readFile(t, path); cond(content) {
+				return content
+			}
+		}
+		t.Fatalf("state file wasn't written %s", what)
+		return fileContent{}
+	}
+
+	first := waitForFile("initially", func(c fileContent) bool { return len(c.Synchronizations) == 1 })
+
+	// the file isn't rewritten while the state doesn't change
+	time.Sleep(50 * time.Millisecond)
+	if content := readFile(t, path); !content.UpdatedAt.Equal(first.UpdatedAt) {
+		t.Error("state file was rewritten without a change")
+	}
+
+	store.Update("shelf", func(st *SyncState) {})
+	waitForFile("after a change", func(c fileContent) bool { return len(c.Synchronizations) == 2 })
+}
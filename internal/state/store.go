@@ -0,0 +1,142 @@
+package state
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// RGBColor is a representation of an RGB color
+type RGBColor struct {
+	R int `json:"r"`
+	G int `json:"g"`
+	B int `json:"b"`
+}
+
+// HueState is the last observed state of the Hue light of a synchronization
+type HueState struct {
+	On         bool    `json:"on"`
+	Brightness float64 `json:"brightness"`
+	Dynamic    bool    `json:"dynamic"`
+}
+
+// Command is the last command sent to the Govee device of a synchronization
+type Command struct {
+	On               bool      `json:"on"`
+	Color            RGBColor  `json:"color"`
+	ColorTemperature int       `json:"colorTemperature,omitempty"` // in Kelvin, set instead of Color for whites
+	Brightness       int       `json:"brightness"`
+	SentAt           time.Time `json:"sentAt"`
+}
+
+// SameState returns whether both commands set the same device state, ignoring when they were sent
+func (c Command) SameState(other Command) bool {
+	c.SentAt, other.SentAt = time.Time{}, time.Time{}
+	return c == other
+}
+
+// SyncState is the current state of a single synchronization
+type SyncState struct {
+	ID            string    `json:"id"`
+	HueLightID    string    `json:"hueLightId"`
+	GoveeDeviceID string    `json:"goveeDeviceId"`
+	Hue           *HueState `json:"hue,omitempty"`
+	LastCommand   *Command  `json:"lastCommand,omitempty"`
+	ActiveScene   string    `json:"activeScene,omitempty"`
+	Paused        bool      `json:"paused"`
+	Healthy       bool      `json:"healthy"`
+	LastError     string    `json:"lastError,omitempty"`
+	// DeviceFailedUntil is set while commands to the Govee device are skipped after repeated failures
+	DeviceFailedUntil *time.Time `json:"deviceFailedUntil,omitempty"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+// Store holds the current state of all synchronizations and notifies subscribers about changes.
+type Store struct {
+	mu          sync.RWMutex // Mutex to protect syncs and subscribers updates
+	syncs       map[string]SyncState
+	version     uint64
+	subscribers map[chan SyncState]struct{}
+}
+
+// NewStore creates a new, empty Store
+func NewStore() *Store {
+	return &Store{
+		syncs:       make(map[string]SyncState),
+		subscribers: make(map[chan SyncState]struct{}),
+	}
+}
+
+// Update applies fn to the state of the synchronization with the given ID and notifies subscribers.
+// Subscribers that aren't keeping up miss the update rather than blocking the caller.
+func (s *Store) Update(id string, fn func(state *SyncState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.syncs[id]
+	state.ID = id
+	fn(&state)
+	state.UpdatedAt = time.Now()
+	s.syncs[id] = state
+	s.version++
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- state:
+		default:
+		}
+	}
+}
+
+// Remove removes the state of the synchronization with the given ID.
+func (s *Store) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.syncs, id)
+	s.version++
+}
+
+// Get returns the state of the synchronization with the given ID.
+func (s *Store) Get(id string) (SyncState, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, ok := s.syncs[id]
+	return state, ok
+}
+
+// Snapshot returns the states of all synchronizations sorted by ID, along with a version that
+// changes whenever the state changes.
+func (s *Store) Snapshot() ([]SyncState, uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	states := make([]SyncState, 0, len(s.syncs))
+	for _, state := range s.syncs {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ID < states[j].ID
+	})
+	return states, s.version
+}
+
+// Subscribe returns a channel receiving every state update and a function to unsubscribe.
+func (s *Store) Subscribe() (<-chan SyncState, func()) {
+	ch := make(chan SyncState, 16)
+
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	store := NewStore()
+	updates, unsubscribe := store.Subscribe()
+
+	store.Update("desk", func(st *SyncState) { st.Paused = true })
+	select {
+	case st := <-updates:
+		if st.ID != "desk" || !st.Paused {
+			t.Errorf("update = %+v, want desk paused", st)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("received no update")
+	}
+
+	unsubscribe()
+	if _, ok := <-updates; ok {
+		t.Error("updates channel is still open after unsubscribing")
+	}
+	unsubscribe() // unsubscribing twice is harmless
+	store.Update("desk", func(st *SyncState) { st.Paused = false })
+}
+
+func TestUpdateDropsForSlowSubscribers(t *testing.T) {
+	store := NewStore()
+	updates, unsubscribe := store.Subscribe()
+	defer unsubscribe()
+
+	// nobody reads the updates, but updating never blocks
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 100 {
+			store.Update("desk", func(st *SyncState) {
+				st.LastCommand = &Command{Brightness: i}
+			})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Update() blocked on a subscriber that doesn't keep up")
+	}
+
+	if len(updates) != cap(updates) {
+		t.Errorf("%d updates buffered, want the first %d", len(updates), cap(updates))
+	}
+	if st, _ := store.Get("desk"); st.LastCommand.Brightness != 99 {
+		t.Errorf("Brightness = %d, want the last update stored", st.LastCommand.Brightness)
+	}
+}